@@ -0,0 +1,111 @@
+package approval
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CallbackRequest 审批系统回调参数
+type CallbackRequest struct {
+	ProcessID string `json:"process_id" binding:"required"`
+	Status    string `json:"status" binding:"required"`
+}
+
+// ListHandler 当前用户提交的审批请求列表，支持 page/page_size/status(逗号分隔)
+func (s *Service) ListHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+		if pageSize > 100 {
+			pageSize = 100
+		}
+		filter := ListFilter{
+			RequesterID: c.GetString("UserID"),
+			Page:        page,
+			PageSize:    pageSize,
+		}
+		for _, name := range strings.Split(c.Query("status"), ",") {
+			if status := ParseStatus(strings.TrimSpace(name)); status != StatusUnknown {
+				filter.Statuses = append(filter.Statuses, status)
+			}
+		}
+		records, total, err := s.List(c.Request.Context(), filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": http.StatusInternalServerError, "message": err.Error(), "data": nil})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"data":      records,
+			"page_no":   filter.Page,
+			"page_size": filter.PageSize,
+			"total":     total,
+			"message":   "success",
+		})
+	}
+}
+
+// GetHandler 查询单个审批请求，仅申请人可见
+func (s *Service) GetHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		record, err := s.Get(c.Request.Context(), c.Param("request_id"))
+		if err == nil && record.RequesterID != c.GetString("UserID") {
+			err = ErrPendingRequestNotFound
+		}
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "message": "success", "data": record})
+	}
+}
+
+// CancelHandler 申请人撤回审批中的请求
+func (s *Service) CancelHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.Param("request_id")
+		if err := s.Cancel(c.Request.Context(), requestID, c.GetString("UserID")); err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "message": "success", "data": gin.H{"request_id": requestID, "status": StatusCancelled.String()}})
+	}
+}
+
+// CallbackHandler 审批系统回调入口，status 取值见 Status.String()；只记录结果，审批通过的请求在后台回放
+func (s *Service) CallbackHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CallbackRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": err.Error(), "data": nil})
+			return
+		}
+		status := ParseStatus(strings.ToLower(strings.TrimSpace(req.Status)))
+		if status == StatusUnknown {
+			c.JSON(http.StatusBadRequest, gin.H{"code": http.StatusBadRequest, "message": "未知审批状态: " + req.Status, "data": nil})
+			return
+		}
+		err := s.Record(c.Request.Context(), req.ProcessID, status)
+		if err != nil && !errors.Is(err, ErrStatusConflict) {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": http.StatusOK, "message": "success", "data": gin.H{"process_id": req.ProcessID, "status": status.String()}})
+	}
+}
+
+func respondError(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrPendingRequestNotFound):
+		statusCode = http.StatusNotFound
+	case errors.Is(err, ErrNotRequester):
+		statusCode = http.StatusForbidden
+	case errors.Is(err, ErrStatusConflict):
+		statusCode = http.StatusConflict
+	}
+	c.JSON(statusCode, gin.H{"code": statusCode, "message": err.Error(), "data": nil})
+}
@@ -11,16 +11,25 @@ type ApprovalHandler interface {
 	CreateApprovalProcess(c *gin.Context, requestData map[string]interface{}) (processID string, err error)
 
 	// GetApprovalProcess 获取审批流程状态
-	GetApprovalProcess(c *gin.Context, processID string) (status int, err error)
+	GetApprovalProcess(c *gin.Context, processID string) (status Status, err error)
 
 	// ExecuteApprovedRequest 审批通过后执行原始请求
 	ExecuteApprovedRequest(c *gin.Context, processID string, requestData []byte) error
 }
 
+// RouteChecker 可选接口：处理器声明是否为指定路由(模板路径+方法)配置了审批流程。
+// 实现后 businessApproval=true 但未配置流程的路由在启动时告警，请求一律拒绝，不会跳过审批直接执行
+type RouteChecker interface {
+	HasRoute(routePath, method string) bool
+}
+
 // Config 审批配置
 type Config struct {
 	BusinessApproval bool            // 是否启用业务审批
 	Handler          ApprovalHandler // 审批处理器
+	Service          *Service        // 审批请求持久化与回放服务(可选)，设置后拦截的请求会落库并在审批通过后回放
+	RoutePath        string          // 路由模板路径，用于落库记录
+	Tips             string          // 路由说明，用于落库记录
 }
 
 // DefaultHandler 默认审批处理器(空实现)
@@ -34,8 +43,8 @@ func (h *DefaultHandler) CreateApprovalProcess(c *gin.Context, requestData map[s
 	return "", nil
 }
 
-func (h *DefaultHandler) GetApprovalProcess(c *gin.Context, processID string) (int, error) {
-	return 0, nil
+func (h *DefaultHandler) GetApprovalProcess(c *gin.Context, processID string) (Status, error) {
+	return StatusUnknown, nil
 }
 
 func (h *DefaultHandler) ExecuteApprovedRequest(c *gin.Context, processID string, requestData []byte) error {
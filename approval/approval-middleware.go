@@ -3,7 +3,10 @@ package approval
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/log"
+	"github.com/google/uuid"
 	"io"
 	"net/http"
 )
@@ -15,11 +18,44 @@ func ApprovalMiddleware(config Config) gin.HandlerFunc {
 		}
 	}
 	return func(c *gin.Context) {
+		// 审批通过后的回放请求直接放行
+		if ReplayFromContext(c.Request.Context()) != nil {
+			c.Next()
+			return
+		}
+		// 需要审批但未配置审批流程的路由直接拒绝
+		if checker, ok := config.Handler.(RouteChecker); ok {
+			routePath := config.RoutePath
+			if routePath == "" {
+				routePath = c.FullPath()
+			}
+			if !checker.HasRoute(routePath, c.Request.Method) {
+				log.Errorf("approval route not configured, route=%s, method=%s", routePath, c.Request.Method)
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "审批流程未配置"})
+				return
+			}
+		}
 		// 检查是否需要审批
 		if !config.Handler.ShouldApprove(c) {
 			c.Next()
 			return
 		}
+		// 落库模式下先保留原始请求体，用于审批通过后原样回放
+		var pending *PendingRequest
+		if config.Service != nil {
+			rawBody, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(rawBody))
+			pending = newPendingRequest(c, config, rawBody)
+			if err := config.Service.Submit(c.Request.Context(), pending); err != nil {
+				log.Errorf("approval pending request save failed, path=%s, err=%v", c.Request.URL.Path, err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "保存审批请求失败"})
+				return
+			}
+		}
 		// 构建包含所有请求信息的结构体
 		requestInfo := map[string]interface{}{
 			"method": c.Request.Method,
@@ -89,13 +125,58 @@ func ApprovalMiddleware(config Config) gin.HandlerFunc {
 			c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
 			requestInfo["rawBody"] = string(body)
 		}
+		if pending != nil {
+			requestInfo["request_id"] = pending.RequestID
+		}
 		// 创建审批流程
 		processID, err := config.Handler.CreateApprovalProcess(c, requestInfo)
 		if err != nil {
+			if pending != nil {
+				_, _ = config.Service.Store().Transition(c.Request.Context(), pending.RequestID, []Status{StatusPending}, StatusFailed, map[string]interface{}{"last_error": truncateError(err)})
+			}
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "创建审批流程失败"})
 			return
 		}
+		data := gin.H{"approval_id": processID, "status": StatusPending.String()}
+		if pending != nil {
+			pending.ProcessID = processID
+			if err := config.Service.Store().Save(c.Request.Context(), pending); err != nil {
+				log.Errorf("approval process id save failed, request_id=%s, process_id=%s, err=%v", pending.RequestID, processID, err)
+			}
+			data["request_id"] = pending.RequestID
+		}
 		// 返回审批响应
-		c.AbortWithStatusJSON(http.StatusOK, gin.H{"code": 200, "message": "数据已提交审批,请前往‘审批认证->审批管理->数据审批’中查看进度", "data": gin.H{"approval_id": processID, "status": "pending"}})
+		c.AbortWithStatusJSON(http.StatusOK, gin.H{"code": 200, "message": "数据已提交审批,请前往‘审批认证->审批管理->数据审批’中查看进度", "data": data})
+	}
+}
+
+// newPendingRequest 根据当前请求构造待审批记录，申请人身份取自认证中间件写入的 Principal/UserID
+func newPendingRequest(c *gin.Context, config Config, rawBody []byte) *PendingRequest {
+	pending := &PendingRequest{
+		RequestID:   uuid.NewString(),
+		RoutePath:   config.RoutePath,
+		Method:      c.Request.Method,
+		Path:        c.Request.URL.Path,
+		RawQuery:    c.Request.URL.RawQuery,
+		HeaderJSON:  encodeHeader(c.Request.Header),
+		ContentType: c.GetHeader("Content-Type"),
+		Body:        rawBody,
+		RequesterID: c.GetString("UserID"),
+		Tips:        config.Tips,
+	}
+	if pending.RoutePath == "" {
+		pending.RoutePath = c.FullPath()
+	}
+	if principal, ok := c.Get("Principal"); ok && principal != nil {
+		if payload, err := json.Marshal(principal); err == nil {
+			pending.PrincipalJSON = string(payload)
+			var claims struct {
+				TenantCode string `json:"tenant_code"`
+			}
+			if json.Unmarshal(payload, &claims) == nil {
+				pending.TenantCode = claims.TenantCode
+			}
+		}
 	}
+	return pending
 }
@@ -0,0 +1,60 @@
+package approval
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/goodbye-jack/go-common/storage/s3"
+)
+
+const defaultOffloadDir = "approval"
+
+type s3Offloader struct {
+	client *s3.Client
+	dir    string
+}
+
+// NewS3Offloader 将超过阈值的请求体保存到 S3，dir 为空时使用 approval 目录
+func NewS3Offloader(client *s3.Client, dir string) PayloadOffloader {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		dir = defaultOffloadDir
+	}
+	return &s3Offloader{client: client, dir: dir}
+}
+
+func (o *s3Offloader) Put(ctx context.Context, requestID string, payload []byte, contentType string) (string, error) {
+	if o == nil || o.client == nil {
+		return "", errors.New("approval s3 offloader client is nil")
+	}
+	objectKey, _, _, _, err := o.client.BuildObjectKey(o.dir, requestID, "bin")
+	if err != nil {
+		return "", err
+	}
+	if _, err := o.client.Upload(ctx, objectKey, bytes.NewReader(payload), int64(len(payload)), contentType); err != nil {
+		return "", err
+	}
+	return objectKey, nil
+}
+
+func (o *s3Offloader) Get(ctx context.Context, ref string) ([]byte, error) {
+	if o == nil || o.client == nil {
+		return nil, errors.New("approval s3 offloader client is nil")
+	}
+	object, err := o.client.Download(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	return io.ReadAll(object)
+}
+
+func (o *s3Offloader) Delete(ctx context.Context, ref string) error {
+	if o == nil || o.client == nil {
+		return errors.New("approval s3 offloader client is nil")
+	}
+	return o.client.Delete(ctx, ref)
+}
@@ -0,0 +1,70 @@
+package approval
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// ReplayInfo 审批通过后回放原始请求时携带的上下文信息
+type ReplayInfo struct {
+	RequestID string
+	ProcessID string
+	Subject   string
+	// Principal 原始申请人的 Principal JSON，由 http 包在认证阶段还原
+	Principal []byte
+}
+
+type replayContextKey struct{}
+
+// WithReplay 标记 ctx 为审批回放请求
+func WithReplay(ctx context.Context, info *ReplayInfo) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, replayContextKey{}, info)
+}
+
+// ReplayFromContext 获取回放信息，非回放请求返回 nil
+func ReplayFromContext(ctx context.Context) *ReplayInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(replayContextKey{}).(*ReplayInfo)
+	return info
+}
+
+// replayRecorder 记录回放响应，避免在非测试代码中依赖 httptest
+type replayRecorder struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func newReplayRecorder() *replayRecorder {
+	return &replayRecorder{header: http.Header{}}
+}
+
+func (r *replayRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *replayRecorder) Write(data []byte) (int, error) {
+	if r.code == 0 {
+		r.code = http.StatusOK
+	}
+	return r.body.Write(data)
+}
+
+func (r *replayRecorder) WriteHeader(statusCode int) {
+	if r.code == 0 {
+		r.code = statusCode
+	}
+}
+
+func (r *replayRecorder) statusCode() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
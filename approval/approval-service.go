@@ -0,0 +1,482 @@
+package approval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/coord"
+	"github.com/goodbye-jack/go-common/log"
+)
+
+const (
+	DefaultRequestTTL       = 72 * time.Hour
+	DefaultPollInterval     = 30 * time.Second
+	DefaultReplayTimeout    = 2 * time.Minute
+	DefaultOffloadThreshold = 256 * 1024
+	maxStoredResultBody     = 64 * 1024
+)
+
+var (
+	ErrNotRequester   = errors.New("approval request does not belong to current user")
+	ErrStatusConflict = errors.New("approval request status does not allow this operation")
+)
+
+// 回放时不携带原始凭证，身份由 ReplayInfo.Principal 还原
+var strippedReplayHeaders = []string{"Authorization", "Cookie", "Content-Length"}
+
+type ServiceOption func(*Service)
+
+func WithServiceName(name string) ServiceOption {
+	return func(s *Service) {
+		s.serviceName = strings.TrimSpace(name)
+	}
+}
+
+// WithRequestTTL 待审批请求的有效期，超时后由轮询任务标记为 expired
+func WithRequestTTL(ttl time.Duration) ServiceOption {
+	return func(s *Service) {
+		if ttl > 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+func WithPollInterval(interval time.Duration) ServiceOption {
+	return func(s *Service) {
+		if interval > 0 {
+			s.pollInterval = interval
+		}
+	}
+}
+
+// WithReplayTimeout 单次回放(含状态流转)的超时，回放不随触发它的回调请求取消
+func WithReplayTimeout(timeout time.Duration) ServiceOption {
+	return func(s *Service) {
+		if timeout > 0 {
+			s.replayTimeout = timeout
+		}
+	}
+}
+
+// WithLocker 指定后台轮询选举使用的分布式锁，未指定时按 orm.Redis -> orm.DB 自动选择；
+// 传 nil 时轮询在每个副本各自运行
+func WithLocker(locker coord.Locker) ServiceOption {
+	return func(s *Service) {
+		s.locker = locker
+		s.lockerSet = true
+	}
+}
+
+// WithOffloader 请求体超过 threshold 字节时写入外部存储，threshold<=0 使用默认 256KB
+func WithOffloader(offloader PayloadOffloader, threshold int64) ServiceOption {
+	return func(s *Service) {
+		s.offloader = offloader
+		if threshold > 0 {
+			s.offloadThreshold = threshold
+		}
+	}
+}
+
+// Service 审批请求的持久化、状态流转与审批通过后的回放
+type Service struct {
+	handler          ApprovalHandler
+	store            Store
+	offloader        PayloadOffloader
+	offloadThreshold int64
+	serviceName      string
+	ttl              time.Duration
+	pollInterval     time.Duration
+	replayTimeout    time.Duration
+	locker           coord.Locker
+	lockerSet        bool
+
+	mu     sync.RWMutex
+	engine http.Handler
+}
+
+func NewService(handler ApprovalHandler, store Store, opts ...ServiceOption) (*Service, error) {
+	if handler == nil {
+		return nil, errors.New("approval handler is required")
+	}
+	if store == nil {
+		return nil, errors.New("approval store is required")
+	}
+	service := &Service{
+		handler:          handler,
+		store:            store,
+		offloadThreshold: DefaultOffloadThreshold,
+		ttl:              DefaultRequestTTL,
+		pollInterval:     DefaultPollInterval,
+		replayTimeout:    DefaultReplayTimeout,
+	}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return service, nil
+}
+
+func (s *Service) Handler() ApprovalHandler {
+	return s.handler
+}
+
+func (s *Service) Store() Store {
+	return s.store
+}
+
+// SetEngine 设置回放目标（通常为 gin.Engine），未设置时回退到 Handler.ExecuteApprovedRequest
+func (s *Service) SetEngine(engine http.Handler) {
+	s.mu.Lock()
+	s.engine = engine
+	s.mu.Unlock()
+}
+
+func (s *Service) replayTarget() http.Handler {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.engine
+}
+
+// Submit 保存被拦截的请求，body 过大且配置了外部存储时只保存对象引用
+func (s *Service) Submit(ctx context.Context, req *PendingRequest) error {
+	if req == nil {
+		return errors.New("approval pending request is nil")
+	}
+	if req.ServiceName == "" {
+		req.ServiceName = s.serviceName
+	}
+	if req.Status == StatusUnknown {
+		req.Status = StatusPending
+	}
+	if req.ExpiresAtUnix == 0 && s.ttl > 0 {
+		req.ExpiresAtUnix = time.Now().Add(s.ttl).Unix()
+	}
+	req.BodySize = int64(len(req.Body))
+	if s.offloader != nil && req.BodySize > s.offloadThreshold {
+		ref, err := s.offloader.Put(ctx, req.RequestID, req.Body, req.ContentType)
+		if err != nil {
+			return fmt.Errorf("offload approval payload: %w", err)
+		}
+		req.BodyObjectRef = ref
+		req.Body = nil
+	}
+	return s.store.Save(ctx, req)
+}
+
+func (s *Service) Get(ctx context.Context, requestID string) (*PendingRequest, error) {
+	return s.store.Get(ctx, requestID)
+}
+
+func (s *Service) List(ctx context.Context, filter ListFilter) ([]PendingRequest, int64, error) {
+	if filter.ServiceName == "" {
+		filter.ServiceName = s.serviceName
+	}
+	return s.store.List(ctx, filter)
+}
+
+// Cancel 申请人撤回仍在审批中的请求
+func (s *Service) Cancel(ctx context.Context, requestID string, requester string) error {
+	record, err := s.store.Get(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if requester != "" && record.RequesterID != requester {
+		return ErrNotRequester
+	}
+	ok, err := s.store.Transition(ctx, record.RequestID, []Status{StatusPending}, StatusCancelled, map[string]interface{}{
+		"decided_at_unix": nowUnix(),
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrStatusConflict
+	}
+	s.cleanupPayload(ctx, record)
+	return nil
+}
+
+// Decide 处理审批结果（回调或轮询），审批通过时立即回放原始请求
+func (s *Service) Decide(ctx context.Context, processID string, status Status) error {
+	record, err := s.store.GetByProcessID(ctx, processID)
+	if err != nil {
+		return err
+	}
+	return s.decide(ctx, record, status)
+}
+
+// Record 记录审批结果后立即返回，审批通过的请求在后台回放，不阻塞审批系统的回调请求；
+// 回放未完成(如进程退出)时由轮询补偿执行已通过但未回放的请求
+func (s *Service) Record(ctx context.Context, processID string, status Status) error {
+	record, err := s.store.GetByProcessID(ctx, processID)
+	if err != nil {
+		return err
+	}
+	if status != StatusApproved {
+		return s.decide(ctx, record, status)
+	}
+	if err := s.approve(ctx, record); err != nil {
+		return err
+	}
+	go func(ctx context.Context) {
+		if err := s.Execute(ctx, record.RequestID); err != nil && !errors.Is(err, ErrStatusConflict) {
+			log.Warnf("approval execute failed, request_id=%s, err=%v", record.RequestID, err)
+		}
+	}(context.WithoutCancel(ctx))
+	return nil
+}
+
+// approve 将待审批请求标记为已通过，已是通过状态(回放前重复回调)时视为成功
+func (s *Service) approve(ctx context.Context, record *PendingRequest) error {
+	ok, err := s.store.Transition(ctx, record.RequestID, []Status{StatusPending}, StatusApproved, map[string]interface{}{
+		"decided_at_unix": nowUnix(),
+	})
+	if err != nil {
+		return err
+	}
+	if !ok && record.Status != StatusApproved {
+		return ErrStatusConflict
+	}
+	return nil
+}
+
+func (s *Service) decide(ctx context.Context, record *PendingRequest, status Status) error {
+	switch status {
+	case StatusPending, StatusUnknown:
+		return nil
+	case StatusApproved:
+		if err := s.approve(ctx, record); err != nil {
+			return err
+		}
+		return s.Execute(ctx, record.RequestID)
+	case StatusRejected, StatusCancelled, StatusExpired:
+		ok, err := s.store.Transition(ctx, record.RequestID, []Status{StatusPending}, status, map[string]interface{}{
+			"decided_at_unix": nowUnix(),
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrStatusConflict
+		}
+		s.cleanupPayload(ctx, record)
+		return nil
+	default:
+		return fmt.Errorf("unsupported approval decision status: %s", status)
+	}
+}
+
+// Execute 回放已审批通过的请求，同一请求只会被执行一次。回放脱离调用方 ctx 的取消
+// (如 Flowable 回调请求提前断开)，以独立超时执行，避免请求停留在 executing 状态
+func (s *Service) Execute(ctx context.Context, requestID string) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.replayTimeout)
+	defer cancel()
+	ok, err := s.store.Transition(ctx, requestID, []Status{StatusApproved}, StatusExecuting, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrStatusConflict
+	}
+	record, err := s.store.Get(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	code, body, execErr := s.replay(ctx, record)
+	updates := map[string]interface{}{
+		"executed_at_unix": nowUnix(),
+		"result_code":      code,
+		"result_body":      truncateResult(body),
+		"last_error":       "",
+	}
+	finalStatus := StatusExecuted
+	if execErr == nil && code >= http.StatusBadRequest {
+		execErr = fmt.Errorf("approval replay responded with status %d", code)
+	}
+	if execErr != nil {
+		finalStatus = StatusFailed
+		updates["last_error"] = truncateError(execErr)
+		log.Warnf("approval replay failed, request_id=%s, process_id=%s, err=%v", record.RequestID, record.ProcessID, execErr)
+	} else {
+		log.Infof("approval replay executed, request_id=%s, process_id=%s, code=%d", record.RequestID, record.ProcessID, code)
+	}
+	if _, err := s.store.Transition(ctx, requestID, []Status{StatusExecuting}, finalStatus, updates); err != nil {
+		return err
+	}
+	s.cleanupPayload(ctx, record)
+	return execErr
+}
+
+func (s *Service) replay(ctx context.Context, record *PendingRequest) (int, []byte, error) {
+	body, err := s.loadBody(ctx, record)
+	if err != nil {
+		return 0, nil, err
+	}
+	engine := s.replayTarget()
+	if engine == nil {
+		payload, err := json.Marshal(map[string]interface{}{
+			"request_id":   record.RequestID,
+			"method":       record.Method,
+			"path":         record.Path,
+			"query":        record.RawQuery,
+			"header":       decodeHeader(record.HeaderJSON),
+			"content_type": record.ContentType,
+			"body":         body,
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		c := s.newSyntheticContext(ctx, record)
+		if err := s.handler.ExecuteApprovedRequest(c, record.ProcessID, payload); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusOK, nil, nil
+	}
+
+	target := record.Path
+	if record.RawQuery != "" {
+		target += "?" + record.RawQuery
+	}
+	replayCtx := WithReplay(ctx, &ReplayInfo{
+		RequestID: record.RequestID,
+		ProcessID: record.ProcessID,
+		Subject:   record.RequesterID,
+		Principal: []byte(record.PrincipalJSON),
+	})
+	req, err := http.NewRequestWithContext(replayCtx, record.Method, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	for key, values := range decodeHeader(record.HeaderJSON) {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("X-Approval-Request-Id", record.RequestID)
+	recorder := newReplayRecorder()
+	engine.ServeHTTP(recorder, req)
+	return recorder.statusCode(), recorder.body.Bytes(), nil
+}
+
+func (s *Service) loadBody(ctx context.Context, record *PendingRequest) ([]byte, error) {
+	if record.BodyObjectRef == "" {
+		return record.Body, nil
+	}
+	if s.offloader == nil {
+		return nil, errors.New("approval payload offloaded but offloader is not configured")
+	}
+	return s.offloader.Get(ctx, record.BodyObjectRef)
+}
+
+func (s *Service) cleanupPayload(ctx context.Context, record *PendingRequest) {
+	if record == nil || record.BodyObjectRef == "" || s.offloader == nil {
+		return
+	}
+	if err := s.offloader.Delete(ctx, record.BodyObjectRef); err != nil {
+		log.Warnf("approval payload cleanup failed, request_id=%s, ref=%s, err=%v", record.RequestID, record.BodyObjectRef, err)
+	}
+}
+
+// newSyntheticContext 轮询/回放场景下构造 gin.Context，携带原申请人的 UserID
+func (s *Service) newSyntheticContext(ctx context.Context, record *PendingRequest) *gin.Context {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	c := &gin.Context{Request: req}
+	c.Set("UserID", record.RequesterID)
+	return c
+}
+
+// Poll 执行一轮：过期处理、查询审批状态、补偿执行已通过但未回放的请求
+func (s *Service) Poll(ctx context.Context) error {
+	pending, err := s.store.ListDue(ctx, StatusPending, 0)
+	if err != nil {
+		return err
+	}
+	now := nowUnix()
+	for i := range pending {
+		record := &pending[i]
+		if record.ExpiresAtUnix > 0 && record.ExpiresAtUnix <= now {
+			if err := s.decide(ctx, record, StatusExpired); err != nil && !errors.Is(err, ErrStatusConflict) {
+				log.Warnf("approval expire failed, request_id=%s, err=%v", record.RequestID, err)
+			}
+			continue
+		}
+		if record.ProcessID == "" {
+			continue
+		}
+		status, err := s.handler.GetApprovalProcess(s.newSyntheticContext(ctx, record), record.ProcessID)
+		if err != nil {
+			log.Warnf("approval status query failed, request_id=%s, process_id=%s, err=%v", record.RequestID, record.ProcessID, err)
+			continue
+		}
+		if err := s.decide(ctx, record, status); err != nil && !errors.Is(err, ErrStatusConflict) {
+			log.Warnf("approval decide failed, request_id=%s, status=%s, err=%v", record.RequestID, status, err)
+		}
+	}
+	approved, err := s.store.ListDue(ctx, StatusApproved, 0)
+	if err != nil {
+		return err
+	}
+	for _, record := range approved {
+		if err := s.Execute(ctx, record.RequestID); err != nil && !errors.Is(err, ErrStatusConflict) {
+			log.Warnf("approval execute failed, request_id=%s, err=%v", record.RequestID, err)
+		}
+	}
+	return nil
+}
+
+// Start 启动后台轮询，ctx 取消后退出。有可用的分布式锁时轮询以集群单例运行，只有 leader 副本过期与回放请求
+func (s *Service) Start(ctx context.Context) {
+	locker := s.locker
+	if !s.lockerSet {
+		locker = coord.DefaultLocker()
+	}
+	go coord.RunPeriodic(ctx, locker, "approval:"+s.serviceName, s.pollInterval, func(ctx context.Context) {
+		if err := s.Poll(ctx); err != nil {
+			log.Warnf("approval poll failed, err=%v", err)
+		}
+	})
+	log.Infof("approval poller started, service=%s, interval=%s, singleton=%t", s.serviceName, s.pollInterval.String(), locker != nil)
+}
+
+func encodeHeader(header http.Header) string {
+	cloned := header.Clone()
+	for _, key := range strippedReplayHeaders {
+		cloned.Del(key)
+	}
+	payload, err := json.Marshal(cloned)
+	if err != nil {
+		return ""
+	}
+	return string(payload)
+}
+
+func decodeHeader(raw string) http.Header {
+	header := http.Header{}
+	if raw == "" {
+		return header
+	}
+	_ = json.Unmarshal([]byte(raw), &header)
+	return header
+}
+
+func truncateResult(body []byte) string {
+	if len(body) > maxStoredResultBody {
+		body = body[:maxStoredResultBody]
+	}
+	return string(body)
+}
+
+func truncateError(err error) string {
+	message := err.Error()
+	if len(message) > 1000 {
+		message = message[:1000]
+	}
+	return message
+}
@@ -0,0 +1,55 @@
+package approval
+
+// Status 审批请求状态（数值与历史 approvalbridge 的 1/2 保持一致）
+type Status int
+
+const (
+	StatusUnknown   Status = 0
+	StatusPending   Status = 1 // 审批中
+	StatusApproved  Status = 2 // 审批通过，待执行
+	StatusRejected  Status = 3 // 审批驳回
+	StatusCancelled Status = 4 // 申请人撤回
+	StatusExpired   Status = 5 // 超时未审批
+	StatusExecuted  Status = 6 // 已回放执行成功
+	StatusFailed    Status = 7 // 回放执行失败
+	StatusExecuting Status = 8 // 回放执行中
+)
+
+var statusNames = map[Status]string{
+	StatusUnknown:   "unknown",
+	StatusPending:   "pending",
+	StatusApproved:  "approved",
+	StatusRejected:  "rejected",
+	StatusCancelled: "cancelled",
+	StatusExpired:   "expired",
+	StatusExecuted:  "executed",
+	StatusFailed:    "failed",
+	StatusExecuting: "executing",
+}
+
+func (s Status) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// IsFinal 是否为终态（终态不再被轮询/回调改变）
+func (s Status) IsFinal() bool {
+	switch s {
+	case StatusRejected, StatusCancelled, StatusExpired, StatusExecuted, StatusFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseStatus 解析状态名称，无法识别时返回 StatusUnknown
+func ParseStatus(name string) Status {
+	for status, statusName := range statusNames {
+		if statusName == name {
+			return status
+		}
+	}
+	return StatusUnknown
+}
@@ -0,0 +1,217 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	commonModel "github.com/goodbye-jack/go-common/model"
+	"github.com/goodbye-jack/go-common/orm/migrate"
+	"gorm.io/gorm"
+)
+
+var ErrPendingRequestNotFound = errors.New("approval pending request not found")
+
+// MigrationModule 待审批请求表的迁移模块
+const MigrationModule = "approval"
+
+func init() {
+	migrate.Register(MigrationModule, migrate.List{
+		{Version: "202610180001", Name: "create_approval_pending_requests", Models: []any{&PendingRequest{}}},
+	})
+}
+
+// PendingRequest 被审批中间件拦截、等待审批结果的原始请求
+type PendingRequest struct {
+	commonModel.ModelBase
+	RequestID      string `gorm:"size:64;uniqueIndex" json:"request_id"`
+	ProcessID      string `gorm:"size:128;index" json:"process_id"`
+	ServiceName    string `gorm:"size:64;index" json:"service_name"`
+	RoutePath      string `gorm:"size:255;index" json:"route_path"`
+	Method         string `gorm:"size:16" json:"method"`
+	Path           string `gorm:"size:255" json:"path"`
+	RawQuery       string `gorm:"type:text" json:"raw_query"`
+	HeaderJSON     string `gorm:"type:text" json:"-"`
+	ContentType    string `gorm:"size:128" json:"content_type"`
+	Body           []byte `json:"-"`
+	BodySize       int64  `json:"body_size"`
+	BodyObjectRef  string `gorm:"size:512" json:"-"`
+	PrincipalJSON  string `gorm:"type:text" json:"-"`
+	RequesterID    string `gorm:"size:128;index" json:"requester_id"`
+	TenantCode     string `gorm:"size:64;index" json:"tenant_code"`
+	Tips           string `gorm:"size:255" json:"tips"`
+	Status         Status `gorm:"index" json:"status"`
+	StatusName     string `gorm:"-" json:"status_name"`
+	ExpiresAtUnix  int64  `gorm:"index" json:"expires_at_unix"`
+	DecidedAtUnix  int64  `json:"decided_at_unix"`
+	ExecutedAtUnix int64  `json:"executed_at_unix"`
+	ResultCode     int    `json:"result_code"`
+	ResultBody     string `gorm:"type:text" json:"result_body"`
+	LastError      string `gorm:"size:1024" json:"last_error"`
+}
+
+func (PendingRequest) TableName() string {
+	return "approval_pending_requests"
+}
+
+// ListFilter 待审批请求查询条件
+type ListFilter struct {
+	RequesterID string
+	ServiceName string
+	Statuses    []Status
+	Page        int
+	PageSize    int
+}
+
+// Store 待审批请求持久化接口
+type Store interface {
+	Save(ctx context.Context, req *PendingRequest) error
+	Get(ctx context.Context, requestID string) (*PendingRequest, error)
+	GetByProcessID(ctx context.Context, processID string) (*PendingRequest, error)
+	List(ctx context.Context, filter ListFilter) ([]PendingRequest, int64, error)
+	// Transition 仅当当前状态属于 from 时才更新为 to，返回是否更新成功，用于避免重复执行
+	Transition(ctx context.Context, requestID string, from []Status, to Status, updates map[string]interface{}) (bool, error)
+	ListDue(ctx context.Context, status Status, limit int) ([]PendingRequest, error)
+}
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 基于 GORM 的待审批请求存储，首次使用时执行版本化迁移建表(migrations.mode=verify 时只校验)
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) session(ctx context.Context) (*gorm.DB, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("approval store db is nil")
+	}
+	if err := migrate.ApplyModule(ctx, s.db, MigrationModule); err != nil {
+		return nil, err
+	}
+	return s.db.WithContext(ctx), nil
+}
+
+func (s *gormStore) Save(ctx context.Context, req *PendingRequest) error {
+	if req == nil {
+		return nil
+	}
+	db, err := s.session(ctx)
+	if err != nil {
+		return err
+	}
+	if req.ID == 0 {
+		return db.Create(req).Error
+	}
+	return db.Save(req).Error
+}
+
+func (s *gormStore) Get(ctx context.Context, requestID string) (*PendingRequest, error) {
+	return s.first(ctx, "request_id = ?", strings.TrimSpace(requestID))
+}
+
+func (s *gormStore) GetByProcessID(ctx context.Context, processID string) (*PendingRequest, error) {
+	return s.first(ctx, "process_id = ?", strings.TrimSpace(processID))
+}
+
+func (s *gormStore) first(ctx context.Context, query string, value string) (*PendingRequest, error) {
+	if value == "" {
+		return nil, ErrPendingRequestNotFound
+	}
+	db, err := s.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	record := &PendingRequest{}
+	if err := db.Where(query, value).Order("id DESC").First(record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPendingRequestNotFound
+		}
+		return nil, err
+	}
+	record.StatusName = record.Status.String()
+	return record, nil
+}
+
+func (s *gormStore) List(ctx context.Context, filter ListFilter) ([]PendingRequest, int64, error) {
+	db, err := s.session(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := db.Model(&PendingRequest{})
+	if filter.RequesterID != "" {
+		query = query.Where("requester_id = ?", filter.RequesterID)
+	}
+	if filter.ServiceName != "" {
+		query = query.Where("service_name = ?", filter.ServiceName)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+	var records []PendingRequest
+	if err := query.Order("id DESC").Limit(filter.PageSize).Offset((filter.Page - 1) * filter.PageSize).Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	for i := range records {
+		records[i].StatusName = records[i].Status.String()
+	}
+	return records, total, nil
+}
+
+func (s *gormStore) Transition(ctx context.Context, requestID string, from []Status, to Status, updates map[string]interface{}) (bool, error) {
+	db, err := s.session(ctx)
+	if err != nil {
+		return false, err
+	}
+	values := map[string]interface{}{"status": to}
+	for key, value := range updates {
+		values[key] = value
+	}
+	query := db.Model(&PendingRequest{}).Where("request_id = ?", requestID)
+	if len(from) > 0 {
+		query = query.Where("status IN ?", from)
+	}
+	result := query.Updates(values)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (s *gormStore) ListDue(ctx context.Context, status Status, limit int) ([]PendingRequest, error) {
+	db, err := s.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	var records []PendingRequest
+	if err := db.Where("status = ?", status).Order("id ASC").Limit(limit).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// PayloadOffloader 大请求体外置存储（如 S3），数据库只保存对象引用
+type PayloadOffloader interface {
+	Put(ctx context.Context, requestID string, payload []byte, contentType string) (string, error)
+	Get(ctx context.Context, ref string) ([]byte, error)
+	Delete(ctx context.Context, ref string) error
+}
+
+func nowUnix() int64 {
+	return time.Now().Unix()
+}
@@ -0,0 +1,248 @@
+package approval
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fakeHandler struct {
+	status Status
+}
+
+func (h *fakeHandler) ShouldApprove(c *gin.Context) bool {
+	return true
+}
+
+func (h *fakeHandler) CreateApprovalProcess(c *gin.Context, requestData map[string]interface{}) (string, error) {
+	return "P-" + requestData["request_id"].(string), nil
+}
+
+func (h *fakeHandler) GetApprovalProcess(c *gin.Context, processID string) (Status, error) {
+	return h.status, nil
+}
+
+func (h *fakeHandler) ExecuteApprovedRequest(c *gin.Context, processID string, requestData []byte) error {
+	return nil
+}
+
+type memoryOffloader struct {
+	objects map[string][]byte
+}
+
+func (o *memoryOffloader) Put(ctx context.Context, requestID string, payload []byte, contentType string) (string, error) {
+	o.objects[requestID] = payload
+	return requestID, nil
+}
+
+func (o *memoryOffloader) Get(ctx context.Context, ref string) ([]byte, error) {
+	return o.objects[ref], nil
+}
+
+func (o *memoryOffloader) Delete(ctx context.Context, ref string) error {
+	delete(o.objects, ref)
+	return nil
+}
+
+type approvalFixture struct {
+	service  *Service
+	handler  *fakeHandler
+	engine   *gin.Engine
+	executed []string
+}
+
+func newApprovalFixture(t *testing.T, opts ...ServiceOption) *approvalFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	// 内存库每个连接各自独立，后台回放与测试共用同一个连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	fixture := &approvalFixture{handler: &fakeHandler{status: StatusPending}}
+	service, err := NewService(fixture.handler, NewGormStore(db), append([]ServiceOption{WithServiceName("svc")}, opts...)...)
+	if err != nil {
+		t.Fatalf("NewService error = %v", err)
+	}
+	fixture.service = service
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("UserID", "alice")
+		if info := ReplayFromContext(c.Request.Context()); info != nil {
+			c.Set("UserID", info.Subject)
+		}
+		c.Next()
+	})
+	middleware := ApprovalMiddleware(Config{BusinessApproval: true, Handler: fixture.handler, Service: service})
+	engine.POST("/orders", middleware, func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		fixture.executed = append(fixture.executed, c.GetString("UserID")+":"+string(body))
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+	service.SetEngine(engine)
+	fixture.engine = engine
+	return fixture
+}
+
+func (f *approvalFixture) submit(t *testing.T, body string) *PendingRequest {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/orders?source=test", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	recorder := httptest.NewRecorder()
+	f.engine.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("submit status = %d, body = %s", recorder.Code, recorder.Body.String())
+	}
+	records, total, err := f.service.List(context.Background(), ListFilter{RequesterID: "alice"})
+	if err != nil || total == 0 {
+		t.Fatalf("List() total = %d, err = %v", total, err)
+	}
+	return &records[0]
+}
+
+func TestApprovalMiddlewarePersistsAndReplaysOnApproval(t *testing.T) {
+	fixture := newApprovalFixture(t)
+	pending := fixture.submit(t, `{"amount":1}`)
+	if pending.Status != StatusPending || pending.ProcessID == "" {
+		t.Fatalf("pending = %+v", pending)
+	}
+	if len(fixture.executed) != 0 {
+		t.Fatalf("handler should not run before approval")
+	}
+	if decodeHeader(pending.HeaderJSON).Get("Authorization") != "" {
+		t.Fatalf("authorization header should not be persisted")
+	}
+
+	if err := fixture.service.Decide(context.Background(), pending.ProcessID, StatusApproved); err != nil {
+		t.Fatalf("Decide() error = %v", err)
+	}
+	if len(fixture.executed) != 1 || fixture.executed[0] != `alice:{"amount":1}` {
+		t.Fatalf("executed = %v", fixture.executed)
+	}
+	record, _ := fixture.service.Get(context.Background(), pending.RequestID)
+	if record.Status != StatusExecuted || record.ResultCode != http.StatusCreated {
+		t.Fatalf("record status = %s, code = %d", record.Status, record.ResultCode)
+	}
+
+	if err := fixture.service.Decide(context.Background(), pending.ProcessID, StatusApproved); err == nil {
+		t.Fatalf("second approval should be rejected")
+	}
+	if len(fixture.executed) != 1 {
+		t.Fatalf("request replayed twice")
+	}
+}
+
+func TestApprovalReplayOutlivesCallbackContext(t *testing.T) {
+	fixture := newApprovalFixture(t, WithReplayTimeout(time.Minute))
+	pending := fixture.submit(t, `{"amount":2}`)
+	if _, err := fixture.service.Store().Transition(context.Background(), pending.RequestID, nil, StatusApproved, nil); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+	// 回调请求已断开，回放仍需完成并落定最终状态
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := fixture.service.Execute(ctx, pending.RequestID); err != nil {
+		t.Fatalf("Execute() with canceled ctx error = %v", err)
+	}
+	record, _ := fixture.service.Get(context.Background(), pending.RequestID)
+	if len(fixture.executed) != 1 || record.Status != StatusExecuted {
+		t.Fatalf("executed = %v, status = %s", fixture.executed, record.Status)
+	}
+}
+
+func TestApprovalRecordReplaysInBackground(t *testing.T) {
+	fixture := newApprovalFixture(t)
+	pending := fixture.submit(t, `{"amount":3}`)
+	release := make(chan struct{})
+	fixture.service.SetEngine(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	// 回放阻塞时 Record 也必须立即返回，回调请求不等待业务执行
+	done := make(chan error, 1)
+	go func() { done <- fixture.service.Record(context.Background(), pending.ProcessID, StatusApproved) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Record() blocked on replay")
+	}
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		record, _ := fixture.service.Get(context.Background(), pending.RequestID)
+		if record != nil && record.Status == StatusExecuted && record.ResultCode == http.StatusCreated {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("background replay not finished, record = %+v", record)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestApprovalServiceCancelOnlyByRequester(t *testing.T) {
+	fixture := newApprovalFixture(t)
+	pending := fixture.submit(t, `{}`)
+
+	if err := fixture.service.Cancel(context.Background(), pending.RequestID, "bob"); err != ErrNotRequester {
+		t.Fatalf("Cancel(bob) error = %v, want ErrNotRequester", err)
+	}
+	if err := fixture.service.Cancel(context.Background(), pending.RequestID, "alice"); err != nil {
+		t.Fatalf("Cancel(alice) error = %v", err)
+	}
+	if err := fixture.service.Decide(context.Background(), pending.ProcessID, StatusApproved); err != ErrStatusConflict {
+		t.Fatalf("Decide after cancel error = %v, want ErrStatusConflict", err)
+	}
+}
+
+func TestApprovalServicePollExpiresAndApproves(t *testing.T) {
+	fixture := newApprovalFixture(t, WithRequestTTL(time.Hour))
+	expired := fixture.submit(t, `{"n":1}`)
+	_, _ = fixture.service.Store().Transition(context.Background(), expired.RequestID, nil, StatusPending, map[string]interface{}{"expires_at_unix": time.Now().Add(-time.Minute).Unix()})
+	approved := fixture.submit(t, `{"n":2}`)
+
+	fixture.handler.status = StatusApproved
+	if err := fixture.service.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if record, _ := fixture.service.Get(context.Background(), expired.RequestID); record.Status != StatusExpired {
+		t.Fatalf("expired status = %s", record.Status)
+	}
+	if record, _ := fixture.service.Get(context.Background(), approved.RequestID); record.Status != StatusExecuted {
+		t.Fatalf("approved status = %s", record.Status)
+	}
+}
+
+func TestApprovalServiceOffloadsLargePayload(t *testing.T) {
+	offloader := &memoryOffloader{objects: map[string][]byte{}}
+	fixture := newApprovalFixture(t, WithOffloader(offloader, 8))
+	pending := fixture.submit(t, `{"payload":"0123456789"}`)
+	if pending.BodyObjectRef == "" || len(offloader.objects) != 1 {
+		t.Fatalf("payload should be offloaded, ref = %q", pending.BodyObjectRef)
+	}
+
+	if err := fixture.service.Decide(context.Background(), pending.ProcessID, StatusApproved); err != nil {
+		t.Fatalf("Decide() error = %v", err)
+	}
+	if len(fixture.executed) != 1 || fixture.executed[0] != `alice:{"payload":"0123456789"}` {
+		t.Fatalf("executed = %v", fixture.executed)
+	}
+	if len(offloader.objects) != 0 {
+		t.Fatalf("offloaded payload should be cleaned up after execution")
+	}
+}
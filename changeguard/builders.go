@@ -163,6 +163,12 @@ func (b *PolicyBuilder) ChangedOnlyFields(fields ...string) *PolicyBuilder {
 	return b
 }
 
+// MaskViews 指定敏感字段的脱敏视图，如 {"contact.phone": "phone"}；未指定视图的敏感字段整体隐藏
+func (b *PolicyBuilder) MaskViews(values map[string]string) *PolicyBuilder {
+	b.profile.MaskViews = cloneStringMap(values)
+	return b
+}
+
 func (b *PolicyBuilder) DisplayNames(values map[string]string) *PolicyBuilder {
 	b.profile.DisplayNames = cloneStringMap(values)
 	return b
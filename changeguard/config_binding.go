@@ -55,6 +55,7 @@ type EngineConfig struct {
 	AutoStartWorkers          *bool    `mapstructure:"auto_start_workers"`
 	NotificationWorkerEnabled *bool    `mapstructure:"notification_worker_enabled"`
 	DriftWorkerEnabled        *bool    `mapstructure:"drift_worker_enabled"`
+	SingletonWorkers          *bool    `mapstructure:"singleton_workers"`
 	NotificationPollInterval  string   `mapstructure:"notification_poll_interval"`
 	DriftPollInterval         string   `mapstructure:"drift_poll_interval"`
 }
@@ -140,7 +141,7 @@ func (r *adminUserRecipientResolver) Resolve(ctx context.Context, event ChangeEv
 	phoneField := firstNonBlank(r.opts.PhoneField, "phone")
 	tenantField := firstNonBlank(r.opts.TenantField, "tenant_code")
 	rows := make([]map[string]any, 0)
-	db := orm.DB.GetDB().WithContext(ctx).Model(r.opts.UserModel).
+	db := orm.DB.WithContext(ctx).Model(r.opts.UserModel).
 		Select(phoneField).
 		Where("user_type = ? AND status = ?", userType, status)
 	if strings.TrimSpace(event.Principal.TenantCode) != "" {
@@ -253,6 +254,9 @@ func buildEngineOptionsFromConfig(serviceName string, cfg EngineConfig) EngineOp
 	if cfg.DriftWorkerEnabled != nil {
 		opts.DriftWorkerEnabled = *cfg.DriftWorkerEnabled
 	}
+	if cfg.SingletonWorkers != nil {
+		opts.SingletonWorkers = *cfg.SingletonWorkers
+	}
 	if duration, ok := parseOptionalDuration(cfg.NotificationPollInterval); ok {
 		opts.NotificationPollInterval = duration
 	}
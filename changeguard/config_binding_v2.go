@@ -95,6 +95,7 @@ type ScenarioOverrideConfig struct {
 	IgnoreFields         []string          `mapstructure:"ignore_fields"`
 	SensitiveFields      []string          `mapstructure:"sensitive_fields"`
 	ChangedOnlyFields    []string          `mapstructure:"changed_only_fields"`
+	MaskViews            map[string]string `mapstructure:"mask_views"`
 	DisplayNames         map[string]string `mapstructure:"display_names"`
 	SliceToMapBy         map[string]string `mapstructure:"slice_to_map_by"`
 	MaxDiffChanges       int               `mapstructure:"max_diff_changes"`
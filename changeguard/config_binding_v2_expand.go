@@ -149,6 +149,9 @@ func applyScenarioOverrides(builder *PolicyBuilder, overrides ScenarioOverrideCo
 	if overrides.ChangedOnlyFields != nil {
 		builder.profile.ChangedOnlyFields = append([]string{}, overrides.ChangedOnlyFields...)
 	}
+	if len(overrides.MaskViews) > 0 {
+		builder.profile.MaskViews = mergeStringMaps(builder.profile.MaskViews, overrides.MaskViews)
+	}
 	if len(overrides.DisplayNames) > 0 {
 		builder.profile.DisplayNames = mergeStringMaps(builder.profile.DisplayNames, overrides.DisplayNames)
 	}
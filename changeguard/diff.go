@@ -5,6 +5,8 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+
+	"github.com/goodbye-jack/go-common/mask"
 )
 
 func Compare(before, after map[string]any, policy PolicyProfile) (*DiffResult, error) {
@@ -49,8 +51,8 @@ func Compare(before, after map[string]any, policy PolicyProfile) (*DiffResult, e
 			change.After = afterVal
 		}
 		if change.Sensitive {
-			change.Before = maskedValue(beforeVal, beforeOK)
-			change.After = maskedValue(afterVal, afterOK)
+			change.Before = maskedValue(key, beforeVal, beforeOK, policy)
+			change.After = maskedValue(key, afterVal, afterOK, policy)
 		}
 		result.Changes = append(result.Changes, change)
 		if len(result.Summary) < policy.SummaryFieldLimit || policy.SummaryFieldLimit <= 0 {
@@ -178,11 +180,11 @@ func cloneAnyMap(src map[string]any) map[string]any {
 func maskNested(input map[string]any, policy PolicyProfile) map[string]any {
 	output := cloneAnyMap(input)
 	flat := flattenMap("", output)
-	for key := range flat {
+	for key, value := range flat {
 		if !matchesAny(key, policy.SensitiveFields) && !matchesAny(key, policy.ChangedOnlyFields) {
 			continue
 		}
-		setNestedValue(output, strings.Split(key, "."), "******")
+		setNestedValue(output, strings.Split(key, "."), maskedValue(key, value, true, policy))
 	}
 	return output
 }
@@ -206,9 +208,28 @@ func setNestedValue(target map[string]any, path []string, value any) {
 	}
 }
 
-func maskedValue(value any, ok bool) any {
+// maskedValue 敏感字段的展示值：默认整体隐藏，只有 MaskViews 显式指定视图的字符串字段部分隐藏
+// (如手机号 138****1234)；仅记录是否变更的字段始终整体隐藏
+func maskedValue(path string, value any, ok bool, policy PolicyProfile) any {
 	if !ok {
 		return nil
 	}
-	return "******"
+	text, isText := value.(string)
+	if !isText || text == "" || matchesAny(path, policy.ChangedOnlyFields) {
+		return "******"
+	}
+	view, found := maskViewFor(path, policy.MaskViews)
+	if !found || view == mask.ViewSecret {
+		return "******"
+	}
+	return mask.Mask(view, text)
+}
+
+func maskViewFor(path string, views map[string]string) (mask.View, bool) {
+	for pattern, view := range views {
+		if matchPath(path, pattern) {
+			return mask.View(strings.TrimSpace(view)), true
+		}
+	}
+	return "", false
 }
@@ -0,0 +1,44 @@
+package changeguard
+
+import "testing"
+
+func TestCompareMasksSensitiveFields(t *testing.T) {
+	policy := PolicyProfile{
+		SensitiveFields:   []string{"contact.phone", "password", "contact.code"},
+		ChangedOnlyFields: []string{"secret_note"},
+		MaskViews:         map[string]string{"contact.code": "id_card"},
+	}
+	before := map[string]any{"contact": map[string]any{"phone": "13800001234", "code": "110101199001011234"}, "password": "a", "secret_note": "x"}
+	after := map[string]any{"contact": map[string]any{"phone": "13900005678", "code": "110101199001015678"}, "password": "b", "secret_note": "y"}
+	result, err := Compare(before, after, policy)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	want := map[string][2]any{
+		"contact.code":  {"110***********1234", "110***********5678"},
+		"contact.phone": {"******", "******"},
+		"password":      {"******", "******"},
+		"secret_note":   {"******", "******"},
+	}
+	if len(result.Changes) != len(want) {
+		t.Fatalf("Changes = %+v", result.Changes)
+	}
+	for _, change := range result.Changes {
+		if w := want[change.Path]; change.Before != w[0] || change.After != w[1] || !change.Sensitive {
+			t.Fatalf("%s = %v -> %v, want %v", change.Path, change.Before, change.After, w)
+		}
+	}
+	if phone := result.AfterMasked["contact"].(map[string]any)["phone"]; phone != "******" {
+		t.Fatalf("AfterMasked phone = %v", phone)
+	}
+
+	// MaskViews 显式指定视图后才部分展示
+	policy.MaskViews["contact.phone"] = "phone"
+	result, err = Compare(before, after, policy)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	if phone := result.AfterMasked["contact"].(map[string]any)["phone"]; phone != "139****5678" {
+		t.Fatalf("AfterMasked phone with view = %v", phone)
+	}
+}
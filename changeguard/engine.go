@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/coord"
 	goodhttp "github.com/goodbye-jack/go-common/http"
 	"github.com/goodbye-jack/go-common/log"
 	"github.com/google/uuid"
@@ -30,6 +31,7 @@ type Engine struct {
 	baselineResolver  BaselineResolver
 	secondFactor      *secondFactorService
 	workers           *workerManager
+	workerLocker      coord.Locker
 }
 
 func NewEngine(opts EngineOptions) *Engine {
@@ -427,6 +429,25 @@ func (e *Engine) StopWorkers() {
 	e.workers.Stop()
 }
 
+// SetWorkerLocker 指定后台 worker 选举使用的分布式锁，未指定时按 orm.Redis -> orm.DB 自动选择。
+func (e *Engine) SetWorkerLocker(locker coord.Locker) {
+	if locker == nil {
+		return
+	}
+	e.workerLocker = locker
+}
+
+// resolveWorkerLocker 关闭 SingletonWorkers 或没有可用的锁后端时返回 nil，worker 在每个副本各自运行。
+func (e *Engine) resolveWorkerLocker() coord.Locker {
+	if !e.opts.SingletonWorkers {
+		return nil
+	}
+	if e.workerLocker != nil {
+		return e.workerLocker
+	}
+	return coord.DefaultLocker()
+}
+
 func (e *Engine) ensureWorkersStarted() {
 	if e == nil {
 		return
@@ -1,9 +1,28 @@
 package changeguard
 
-import "github.com/goodbye-jack/go-common/orm"
+import (
+	"context"
+
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/orm/migrate"
+)
+
+// MigrationModule changeguard 持久化表的迁移模块
+const MigrationModule = "changeguard"
+
+func init() {
+	migrate.Register(MigrationModule, migrate.List{
+		{
+			Version: "202610180001",
+			Name:    "create_changeguard_tables",
+			Models:  []any{&EventRecord{}, &VersionRecord{}, &DriftReportRecord{}, &SecondFactorChallengeRecord{}},
+		},
+	})
+}
 
 // autoMigrateChangeguardTables 统一初始化 changeguard 依赖的持久化表。
-// 这里集中处理，避免 sink/store/worker 各自懒建表造成时序问题和重复开销。
+// 这里集中处理，避免 sink/store/worker 各自懒建表造成时序问题和重复开销；
+// 表结构以版本化迁移维护，migrations.mode=verify 时只校验不执行。
 func autoMigrateChangeguardTables() error {
 	if orm.DB == nil {
 		return nil
@@ -12,17 +31,5 @@ func autoMigrateChangeguardTables() error {
 	if db == nil {
 		return nil
 	}
-	if err := db.AutoMigrate(&EventRecord{}); err != nil {
-		return err
-	}
-	if err := db.AutoMigrate(&VersionRecord{}); err != nil {
-		return err
-	}
-	if err := db.AutoMigrate(&DriftReportRecord{}); err != nil {
-		return err
-	}
-	if err := db.AutoMigrate(&SecondFactorChallengeRecord{}); err != nil {
-		return err
-	}
-	return nil
+	return migrate.ApplyModule(context.Background(), db, MigrationModule)
 }
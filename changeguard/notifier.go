@@ -170,7 +170,7 @@ func (d *Dispatcher) ProcessPending(ctx context.Context, limit int) error {
 		limit = 50
 	}
 	records := make([]EventRecord, 0, limit)
-	err := orm.DB.GetDB().WithContext(ctx).
+	err := orm.DB.WithContext(ctx).
 		Model(&EventRecord{}).
 		Where("notify_status IN ?", []string{"pending", "failed"}).
 		Order("id ASC").
@@ -268,7 +268,7 @@ func (d *Dispatcher) toEvent(record *EventRecord) (ChangeEvent, error) {
 }
 
 func (d *Dispatcher) markSuccess(ctx context.Context, record *EventRecord, status string) error {
-	return orm.DB.GetDB().WithContext(ctx).
+	return orm.DB.WithContext(ctx).
 		Model(&EventRecord{}).
 		Where("id = ?", record.ID).
 		Updates(map[string]any{
@@ -294,7 +294,7 @@ func (d *Dispatcher) markRetry(ctx context.Context, record *EventRecord, sourceE
 		metadata["notify_retry_at_unix"] = nextAtUnix
 	}
 	metadataJSON, _ := json.Marshal(metadata)
-	return orm.DB.GetDB().WithContext(ctx).
+	return orm.DB.WithContext(ctx).
 		Model(&EventRecord{}).
 		Where("id = ?", record.ID).
 		Updates(map[string]any{
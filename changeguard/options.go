@@ -26,6 +26,7 @@ type EngineOptions struct {
 	AutoStartWorkers          bool
 	NotificationWorkerEnabled bool
 	DriftWorkerEnabled        bool
+	SingletonWorkers          bool // 多副本部署时 worker 通过分布式锁选举只在一个副本运行
 	NotificationPollInterval  time.Duration
 	DriftPollInterval         time.Duration
 }
@@ -55,6 +56,7 @@ func DefaultEngineOptions(serviceName string) EngineOptions {
 		AutoStartWorkers:          true,
 		NotificationWorkerEnabled: true,
 		DriftWorkerEnabled:        true,
+		SingletonWorkers:          true,
 		NotificationPollInterval:  time.Minute,
 		DriftPollInterval:         10 * time.Minute,
 	}
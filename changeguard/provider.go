@@ -100,7 +100,7 @@ func (p *gormEntityProvider) load(s *Session) (*ResourceState, error) {
 	if err != nil {
 		return nil, err
 	}
-	db := orm.DB.GetDB().WithContext(sessionContext(s.Context)).Model(modelPtr)
+	db := orm.DB.WithContext(sessionContext(s.Context)).Model(modelPtr)
 	for key, value := range filters {
 		db = db.Where(key+" = ?", value)
 	}
@@ -548,7 +548,7 @@ func (s *secondFactorService) loadMatchingChallenge(c *gin.Context, session *Ses
 func (s *secondFactorService) loadLatestChallenge(ctx context.Context, userID, tenantCode, requestDigest string) (*secondFactorChallenge, error) {
 	if orm.DB != nil {
 		record := &SecondFactorChallengeRecord{}
-		err := orm.DB.GetDB().WithContext(ctx).
+		err := orm.DB.WithContext(ctx).
 			Model(&SecondFactorChallengeRecord{}).
 			Where("principal_user_id = ? AND principal_tenant_code = ? AND request_digest = ?", userID, tenantCode, requestDigest).
 			Order("id DESC").
@@ -573,7 +573,7 @@ func (s *secondFactorService) loadChallenge(ctx context.Context, challengeID str
 		return nil, nil
 	}
 	record := &SecondFactorChallengeRecord{}
-	err := orm.DB.GetDB().WithContext(ctx).Model(&SecondFactorChallengeRecord{}).Where("challenge_id = ?", challengeID).First(record).Error
+	err := orm.DB.WithContext(ctx).Model(&SecondFactorChallengeRecord{}).Where("challenge_id = ?", challengeID).First(record).Error
 	if err != nil {
 		return nil, nil
 	}
@@ -585,7 +585,7 @@ func (s *secondFactorService) loadChallengeByReplyToken(ctx context.Context, mob
 		return nil, nil
 	}
 	record := &SecondFactorChallengeRecord{}
-	err := orm.DB.GetDB().WithContext(ctx).
+	err := orm.DB.WithContext(ctx).
 		Model(&SecondFactorChallengeRecord{}).
 		Where("phone = ? AND reply_token = ? AND approval_status = ?", mobile, token, SecondFactorStatusPending).
 		Order("id DESC").
@@ -642,7 +642,7 @@ func (s *secondFactorService) saveChallenge(ctx context.Context, challenge *seco
 		ConsumedAtUnix:      challenge.ConsumedAtUnix,
 		MetadataJSON:        string(metadataJSON),
 	}
-	return orm.DB.GetDB().WithContext(ctx).Where("challenge_id = ?", challenge.ChallengeID).Assign(record).FirstOrCreate(&SecondFactorChallengeRecord{}).Error
+	return orm.DB.WithContext(ctx).Where("challenge_id = ?", challenge.ChallengeID).Assign(record).FirstOrCreate(&SecondFactorChallengeRecord{}).Error
 }
 
 func (s *secondFactorService) consumeChallenge(ctx context.Context, challengeID string) {
@@ -668,7 +668,7 @@ func (s *secondFactorService) resolveOperatorPhone(c *gin.Context, session *Sess
 		s.cfg.StatusField,
 	})
 	buildBaseDB := func() *gorm.DB {
-		db := orm.DB.GetDB().WithContext(c.Request.Context()).
+		db := orm.DB.WithContext(c.Request.Context()).
 			Model(s.spec.UserModel).
 			Select(strings.Join(lookupFields, ","))
 		if session.Principal.TenantCode != "" {
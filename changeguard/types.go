@@ -45,6 +45,7 @@ type PolicyProfile struct {
 	IgnoreFields            []string
 	SensitiveFields         []string
 	ChangedOnlyFields       []string
+	MaskViews               map[string]string // 敏感字段路径 -> mask 视图(phone/id_card/email 等)，未配置的敏感字段整体隐藏
 	DisplayNames            map[string]string
 	SliceToMapBy            map[string]string
 	MaxDiffChanges          int
@@ -102,7 +102,7 @@ func (s *GormVersionStore) Latest(ctx context.Context, serviceName, resourceKey,
 		return nil, nil
 	}
 	record := &VersionRecord{}
-	err := orm.DB.GetDB().WithContext(ctx).
+	err := orm.DB.WithContext(ctx).
 		Model(record).
 		Where("service_name = ? AND resource_key = ? AND resource_id = ?", serviceName, resourceKey, resourceID).
 		Order("version_no DESC").
@@ -118,7 +118,7 @@ func (s *GormVersionStore) ByVersion(ctx context.Context, serviceName, resourceK
 		return nil, nil
 	}
 	record := &VersionRecord{}
-	err := orm.DB.GetDB().WithContext(ctx).
+	err := orm.DB.WithContext(ctx).
 		Model(record).
 		Where("service_name = ? AND resource_key = ? AND resource_id = ? AND version_no = ?", serviceName, resourceKey, resourceID, versionNo).
 		First(record).Error
@@ -130,7 +130,7 @@ func (s *GormVersionStore) ByVersion(ctx context.Context, serviceName, resourceK
 
 func (s *GormVersionStore) nextVersionNo(ctx context.Context, serviceName, resourceKey, resourceID string) (int64, error) {
 	record := &VersionRecord{}
-	err := orm.DB.GetDB().WithContext(ctx).
+	err := orm.DB.WithContext(ctx).
 		Model(record).
 		Where("service_name = ? AND resource_key = ? AND resource_id = ?", serviceName, resourceKey, resourceID).
 		Order("version_no DESC").
@@ -5,11 +5,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/goodbye-jack/go-common/coord"
 	"github.com/goodbye-jack/go-common/log"
 )
 
 type workerManager struct {
 	engine *Engine
+	locker coord.Locker // 非空时 worker 以集群单例运行
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
@@ -32,6 +34,7 @@ func (m *workerManager) Start() {
 		return
 	}
 	m.once.Do(func() {
+		m.locker = m.engine.resolveWorkerLocker()
 		if m.engine.shouldRunNotificationWorker() {
 			m.startLoop("notify", m.engine.opts.NotificationPollInterval, func(ctx context.Context) error {
 				return m.engine.ProcessPendingNotifications(ctx, 50)
@@ -47,6 +50,7 @@ func (m *workerManager) Start() {
 
 // startLoop 启动单个周期任务。
 // 每个 worker 都独立 recover，避免某一类后台任务异常拖垮整个服务。
+// 配置了分布式锁时按 worker 选举 leader，多副本部署下只有 leader 执行，避免重复通知与重复检测。
 func (m *workerManager) startLoop(name string, interval time.Duration, fn func(context.Context) error) {
 	if interval <= 0 || fn == nil {
 		return
@@ -54,23 +58,18 @@ func (m *workerManager) startLoop(name string, interval time.Duration, fn func(c
 	m.wg.Add(1)
 	go func() {
 		defer m.wg.Done()
-		runWorkerSafely(name, func() error {
-			return fn(m.ctx)
+		coord.RunPeriodic(m.ctx, m.locker, m.lockName(name), interval, func(ctx context.Context) {
+			runWorkerSafely(name, func() error {
+				return fn(ctx)
+			})
 		})
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-m.ctx.Done():
-				return
-			case <-ticker.C:
-				runWorkerSafely(name, func() error {
-					return fn(m.ctx)
-				})
-			}
-		}
 	}()
-	log.Infof("changeguard worker started, service=%s, worker=%s, interval=%s", m.engine.opts.ServiceName, name, interval.String())
+	log.Infof("changeguard worker started, service=%s, worker=%s, interval=%s, singleton=%t", m.engine.opts.ServiceName, name, interval.String(), m.locker != nil)
+}
+
+// lockName 同一服务的同一 worker 共用一把选举锁
+func (m *workerManager) lockName(name string) string {
+	return "changeguard:" + m.engine.opts.ServiceName + ":" + name
 }
 
 // Stop 用于后续接入统一生命周期管理时优雅关闭后台 worker。
@@ -0,0 +1,48 @@
+package changeguard
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goodbye-jack/go-common/coord"
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/utils"
+)
+
+func TestWorkersRunAsClusterSingleton(t *testing.T) {
+	db := orm.NewOrm(filepath.Join(t.TempDir(), "worker.db"), utils.DBTypeSQLite, 5).GetDB()
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	locker := coord.NewTableLocker(db, "")
+
+	var runs [2]atomic.Int32
+	managers := make([]*workerManager, len(runs))
+	for i := range managers {
+		engine := NewEngine(DefaultEngineOptions("orders"))
+		engine.SetWorkerLocker(locker)
+		managers[i] = newWorkerManager(engine)
+		managers[i].locker = engine.resolveWorkerLocker()
+		managers[i].startLoop("drift", 20*time.Millisecond, func(context.Context) error {
+			runs[i].Add(1)
+			return nil
+		})
+	}
+	time.Sleep(300 * time.Millisecond)
+	for _, manager := range managers {
+		manager.Stop()
+	}
+	if a, b := runs[0].Load(), runs[1].Load(); (a == 0) == (b == 0) {
+		t.Fatalf("runs = %d/%d, want exactly one replica running", a, b)
+	}
+
+	opts := DefaultEngineOptions("orders")
+	opts.SingletonWorkers = false
+	engine := NewEngine(opts)
+	engine.SetWorkerLocker(locker)
+	if engine.resolveWorkerLocker() != nil {
+		t.Fatalf("SingletonWorkers=false should run workers on every replica")
+	}
+}
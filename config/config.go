@@ -501,6 +501,16 @@ func GetAppName() string { return GetConfigString("app.name") }
 
 func GetAppEnv() string { return GetConfigString("app.env") }
 
+// IsProduction app.env 或 GO_ENV/APP_ENV/RUN_ENV/CONFIG_ENV 为 prod/production 时视为生产环境
+func IsProduction() bool {
+	switch strings.ToLower(strings.TrimSpace(GetAppEnv())) {
+	case "prod", "production":
+		return true
+	}
+	blocked, _ := blockedByProductionLikeEnv()
+	return blocked
+}
+
 func GetServerAddr() string {
 	if addr := strings.TrimSpace(GetConfigString("server.addr")); addr != "" {
 		return addr
@@ -0,0 +1,301 @@
+package coord
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/orm/migrate"
+	ormredis "github.com/goodbye-jack/go-common/orm/redis"
+	"github.com/goodbye-jack/go-common/utils"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := orm.NewOrm(filepath.Join(t.TempDir(), "coord.db"), utils.DBTypeSQLite, 5).GetDB()
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db.DB() error = %v", err)
+	}
+	// sqlite 单连接串行访问，避免并发写入时 database is locked
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+func TestTableLocker(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	first := NewTableLocker(db, "")
+	second := NewTableLocker(db, "")
+
+	lock, err := first.TryLock(ctx, "job", time.Minute)
+	if err != nil || lock.Fence() != 1 {
+		t.Fatalf("TryLock() = %v, %v", lock, err)
+	}
+	if _, err := second.TryLock(ctx, "job", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("second TryLock() error = %v", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err := second.Lock(waitCtx, "job", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("second Lock() error = %v", err)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	select {
+	case <-lock.Lost():
+	default:
+		t.Fatalf("Lost() should be closed after Release")
+	}
+
+	next, err := second.TryLock(ctx, "job", time.Minute)
+	if err != nil || next.Fence() != 2 {
+		t.Fatalf("TryLock() after release = %v, %v; want fence 2", next, err)
+	}
+	// 旧令牌不能释放新持有者的锁
+	if err := first.(*locker).backend.release(ctx, "job", lock.Token()); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("release with stale token error = %v", err)
+	}
+	_ = next.Release(ctx)
+
+	// 崩溃副本遗留的过期锁会被接管，栅栏令牌继续递增
+	if err := db.Table(DefaultLockTable).Where("name = ?", "job").
+		Updates(map[string]interface{}{"token": "crashed", "expires_at": gorm.Expr("strftime('%Y-%m-%d %H:%M:%f', 'now', '-60 seconds')")}).Error; err != nil {
+		t.Fatalf("mark stale error = %v", err)
+	}
+	stale, err := first.TryLock(ctx, "job", time.Minute)
+	if err != nil || stale.Fence() != 3 {
+		t.Fatalf("TryLock() over stale lock = %v, %v; want fence 3", stale, err)
+	}
+	_ = stale.Release(ctx)
+}
+
+func TestTableLockerReadsPrimary(t *testing.T) {
+	ctx := context.Background()
+	primary := orm.NewOrm(filepath.Join(t.TempDir(), "primary.db"), utils.DBTypeSQLite, 5)
+	replica := orm.NewOrm(filepath.Join(t.TempDir(), "replica.db"), utils.DBTypeSQLite, 5)
+	if err := primary.SetReplicas(orm.ReplicaOptions{CheckInterval: time.Hour}, orm.Replica{Name: "r1", DB: replica}); err != nil {
+		t.Fatalf("SetReplicas() error = %v", err)
+	}
+	// 副本上没有锁表：任何落到副本的查询都会失败或读不到持有者
+	locker := NewTableLocker(primary.GetDB(), "")
+	lock, err := locker.TryLock(ctx, "job", time.Minute)
+	if err != nil || lock.Fence() != 1 {
+		t.Fatalf("TryLock() = %v, %v", lock, err)
+	}
+	if _, err := locker.TryLock(ctx, "job", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("second TryLock() error = %v", err)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+}
+
+func TestMigrationLock(t *testing.T) {
+	ctx := context.Background()
+	locker := NewTableLocker(openDB(t), "")
+	lock := MigrationLock(locker, "")
+	lost, unlock, err := lock(ctx)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := lock(waitCtx); !errors.Is(err, migrate.ErrLockTimeout) {
+		t.Fatalf("second lock() error = %v", err)
+	}
+	select {
+	case <-lost:
+		t.Fatalf("lost should stay open while the lock is held")
+	default:
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unlock() error = %v", err)
+	}
+}
+
+func TestLockWatchdog(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	locker := NewTableLocker(db, "")
+	lock, err := locker.TryLock(ctx, "renew", 150*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	// 超过 TTL 后仍被续期持有
+	time.Sleep(400 * time.Millisecond)
+	if _, err := locker.TryLock(ctx, "renew", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("TryLock() while renewed error = %v", err)
+	}
+	// 令牌被替换后看门狗判定锁丢失
+	db.Table(DefaultLockTable).Where("name = ?", "renew").Update("token", "other")
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatalf("Lost() not closed after takeover")
+	}
+	if err := lock.Release(ctx); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("Release() after takeover error = %v", err)
+	}
+}
+
+// failingBackend 获取成功，之后每次续期都失败(存储不可用)
+type failingBackend struct{}
+
+func (failingBackend) acquire(context.Context, string, string, time.Duration) (int64, error) {
+	return 1, nil
+}
+
+func (failingBackend) refresh(context.Context, string, string, time.Duration) error {
+	return errors.New("backend unavailable")
+}
+
+func (failingBackend) release(context.Context, string, string) error {
+	return nil
+}
+
+func TestLockWatchdogGivesUpBeforeLeaseExpires(t *testing.T) {
+	locker := &locker{backend: failingBackend{}}
+	ttl := 600 * time.Millisecond
+	acquiredAt := time.Now()
+	lock, err := locker.TryLock(context.Background(), "flaky", ttl)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	defer lock.Release(context.Background())
+	select {
+	case <-lock.Lost():
+		// 续期一直失败时必须在租约到期之前放弃，其他副本此时还不能获得锁
+		if elapsed := time.Since(acquiredAt); elapsed >= ttl {
+			t.Fatalf("Lost() closed after %s, want before lease expiry %s", elapsed, ttl)
+		}
+	case <-time.After(2 * ttl):
+		t.Fatalf("Lost() not closed while refresh keeps failing")
+	}
+}
+
+func TestElectorFailover(t *testing.T) {
+	db := openDB(t)
+	locker := NewTableLocker(db, "")
+	var stopped atomic.Int32
+	newElector := func() *Elector {
+		return NewElector(locker, "leader", 150*time.Millisecond, LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) { <-ctx.Done() },
+			OnStoppedLeading: func() { stopped.Add(1) },
+		})
+	}
+	electors := []*Elector{newElector(), newElector()}
+	cancels := make([]context.CancelFunc, len(electors))
+	var wg sync.WaitGroup
+	for i, elector := range electors {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels[i] = cancel
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			elector.Run(ctx)
+		}()
+	}
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+		wg.Wait()
+	}()
+
+	leader := waitLeader(t, electors, -1)
+	if electors[1-leader].IsLeader() {
+		t.Fatalf("both electors are leaders")
+	}
+	fence := electors[leader].Fence()
+	cancels[leader]()
+	next := waitLeader(t, electors, leader)
+	if electors[leader].IsLeader() || electors[next].Fence() <= fence {
+		t.Fatalf("failover leader = %d fence = %d, previous = %d fence = %d", next, electors[next].Fence(), leader, fence)
+	}
+	// 卸任回调在释放锁之后调用，可能晚于新 leader 当选
+	for deadline := time.Now().Add(time.Second); stopped.Load() == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stopped.Load() != 1 {
+		t.Fatalf("OnStoppedLeading called %d times, want 1", stopped.Load())
+	}
+}
+
+// waitLeader 等待除 exclude 之外的选举器当选
+func waitLeader(t *testing.T, electors []*Elector, exclude int) int {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for i, elector := range electors {
+			if i != exclude && elector.IsLeader() {
+				return i
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no leader elected")
+	return -1
+}
+
+func TestRunPeriodicSingleton(t *testing.T) {
+	db := openDB(t)
+	locker := NewTableLocker(db, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	counts := make([]atomic.Int32, 2)
+	var wg sync.WaitGroup
+	for i := range counts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			RunPeriodic(ctx, locker, "periodic", 20*time.Millisecond, func(context.Context) {
+				counts[i].Add(1)
+			})
+		}()
+	}
+	time.Sleep(300 * time.Millisecond)
+	cancel()
+	wg.Wait()
+	if a, b := counts[0].Load(), counts[1].Load(); (a == 0) == (b == 0) {
+		t.Fatalf("runs = %d/%d, want exactly one replica running", a, b)
+	}
+}
+
+func TestRedisLocker(t *testing.T) {
+	addr := os.Getenv("GO_COMMON_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("GO_COMMON_TEST_REDIS_ADDR is not configured")
+	}
+	client := ormredis.NewRedisFromClient(redis.NewClient(&redis.Options{Addr: addr, Password: os.Getenv("GO_COMMON_TEST_REDIS_PASSWORD")}), nil)
+	defer client.Close()
+	ctx := context.Background()
+	key := "test:" + time.Now().Format("150405.000000")
+	locker := NewRedisLocker(client, "")
+	defer client.Del(ctx, DefaultRedisPrefix+"{"+key+"}", DefaultRedisPrefix+"{"+key+"}:fence")
+
+	lock, err := locker.TryLock(ctx, key, 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if _, err := locker.TryLock(ctx, key, time.Second); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("second TryLock() error = %v", err)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	next, err := locker.TryLock(ctx, key, time.Second)
+	if err != nil || next.Fence() != lock.Fence()+1 {
+		t.Fatalf("TryLock() after release = %v, %v", next, err)
+	}
+	_ = next.Release(ctx)
+}
@@ -0,0 +1,125 @@
+package coord
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/goodbye-jack/go-common/log"
+)
+
+// LeaderCallbacks 选举回调
+type LeaderCallbacks struct {
+	// OnStartedLeading 当选后在独立协程中调用，ctx 在失去领导权或 Run 结束时取消
+	OnStartedLeading func(ctx context.Context)
+	// OnStoppedLeading 卸任后调用(OnStartedLeading 已返回)
+	OnStoppedLeading func()
+}
+
+// Elector 基于分布式锁的 leader 选举：持有锁的副本为 leader，看门狗续期失败即卸任，
+// 其他副本每 TTL/3 尝试一次，leader 异常退出后最多一个 TTL 内完成切换
+type Elector struct {
+	locker    Locker
+	name      string
+	ttl       time.Duration
+	callbacks LeaderCallbacks
+	fence     atomic.Int64
+}
+
+// NewElector 创建选举器，name 为选举使用的锁名，ttl<=0 时使用 DefaultTTL
+func NewElector(locker Locker, name string, ttl time.Duration, callbacks LeaderCallbacks) *Elector {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Elector{locker: locker, name: name, ttl: ttl, callbacks: callbacks}
+}
+
+// IsLeader 当前副本是否为 leader
+func (e *Elector) IsLeader() bool {
+	return e.fence.Load() > 0
+}
+
+// Fence 当前任期的栅栏令牌，非 leader 时为 0
+func (e *Elector) Fence() int64 {
+	return e.fence.Load()
+}
+
+// Run 参与选举直到 ctx 结束，阻塞调用；结束时若为 leader 会主动释放锁
+func (e *Elector) Run(ctx context.Context) {
+	for {
+		lock, err := e.locker.TryLock(ctx, e.name, e.ttl)
+		switch {
+		case err == nil:
+			e.lead(ctx, lock)
+		case !errors.Is(err, ErrNotAcquired) && ctx.Err() == nil:
+			log.Warnf("leader 选举获取锁失败，name=%s, err=%v", e.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.ttl / 3):
+		}
+	}
+}
+
+// lead 任期内运行回调，直到锁丢失或 ctx 结束
+func (e *Elector) lead(ctx context.Context, lock *Lock) {
+	leadCtx, cancel := context.WithCancel(ctx)
+	e.fence.Store(lock.Fence())
+	log.Infof("当选 leader，name=%s, fence=%d", e.name, lock.Fence())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.Warnf("leader 回调 panic，name=%s, panic=%v", e.name, recovered)
+			}
+		}()
+		if e.callbacks.OnStartedLeading != nil {
+			e.callbacks.OnStartedLeading(leadCtx)
+		}
+	}()
+	select {
+	case <-lock.Lost():
+		log.Warnf("失去 leader 身份，name=%s, fence=%d", e.name, lock.Fence())
+	case <-ctx.Done():
+	}
+	cancel()
+	<-done
+	e.fence.Store(0)
+	releaseCtx, releaseCancel := context.WithTimeout(context.Background(), e.ttl/3)
+	defer releaseCancel()
+	if err := lock.Release(releaseCtx); err != nil && !errors.Is(err, ErrLockLost) {
+		log.Warnf("leader 释放锁失败，name=%s, err=%v", e.name, err)
+	}
+	if e.callbacks.OnStoppedLeading != nil {
+		e.callbacks.OnStoppedLeading()
+	}
+}
+
+// RunPeriodic 以集群单例方式周期执行 fn：只有 leader 副本执行，当选后立即执行一轮，之后每 interval 一轮；
+// locker 为 nil 时按单机执行。阻塞直到 ctx 结束
+func RunPeriodic(ctx context.Context, locker Locker, name string, interval time.Duration, fn func(ctx context.Context)) {
+	if interval <= 0 || fn == nil {
+		return
+	}
+	loop := func(ctx context.Context) {
+		fn(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn(ctx)
+			}
+		}
+	}
+	if locker == nil {
+		loop(ctx)
+		return
+	}
+	NewElector(locker, name, 0, LeaderCallbacks{OnStartedLeading: loop}).Run(ctx)
+}
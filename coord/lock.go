@@ -0,0 +1,193 @@
+// Package coord 提供多副本部署下的协调原语：分布式锁(令牌校验释放、看门狗自动续期、栅栏令牌)
+// 与基于锁的 leader 选举，后端支持 Redis(orm/redis)与数据库锁表。
+package coord
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/orm"
+)
+
+const (
+	// DefaultTTL 锁租约时长，持有期间看门狗每 TTL/3 续期一次
+	DefaultTTL = 30 * time.Second
+	// DefaultRetryInterval Lock 阻塞等待时的重试间隔
+	DefaultRetryInterval = 200 * time.Millisecond
+)
+
+var (
+	// ErrNotAcquired 锁已被其他持有者占用
+	ErrNotAcquired = errors.New("lock not acquired")
+	// ErrLockLost 锁已过期或被其他持有者接管(令牌不匹配)
+	ErrLockLost = errors.New("lock lost")
+)
+
+// Locker 分布式锁
+type Locker interface {
+	// TryLock 尝试获取锁，被占用时立即返回 ErrNotAcquired；ttl<=0 时使用 DefaultTTL
+	TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error)
+	// Lock 阻塞直到获得锁或 ctx 结束
+	Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error)
+}
+
+// backend 锁存储，令牌不匹配时 refresh/release 返回 ErrLockLost
+type backend interface {
+	acquire(ctx context.Context, key, token string, ttl time.Duration) (fence int64, err error)
+	refresh(ctx context.Context, key, token string, ttl time.Duration) error
+	release(ctx context.Context, key, token string) error
+}
+
+type locker struct {
+	backend backend
+}
+
+func (l *locker) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	token := newToken()
+	// 租约从发起请求时开始计算，往返耗时不能算作持有时间
+	acquireStartedAt := time.Now()
+	fence, err := l.backend.acquire(ctx, key, token, ttl)
+	if err != nil {
+		return nil, err
+	}
+	lock := &Lock{
+		key:     key,
+		token:   token,
+		fence:   fence,
+		ttl:     ttl,
+		backend: l.backend,
+		lost:    make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go lock.watchdog(acquireStartedAt)
+	return lock, nil
+}
+
+func (l *locker) Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	for {
+		lock, err := l.TryLock(ctx, key, ttl)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lock, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w (%s): %v", ErrNotAcquired, key, ctx.Err())
+		case <-time.After(DefaultRetryInterval):
+		}
+	}
+}
+
+// Lock 已获得的锁，持有期间由看门狗自动续期，用完必须 Release
+type Lock struct {
+	key     string
+	token   string
+	fence   int64
+	ttl     time.Duration
+	backend backend
+
+	lost        chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+	lostOnce    sync.Once
+	releaseOnce sync.Once
+	releaseErr  error
+}
+
+// Key 锁名
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token 本次持有的随机令牌，释放与续期时校验
+func (l *Lock) Token() string {
+	return l.token
+}
+
+// Fence 栅栏令牌，同一把锁每次被获取时单调递增；
+// 下游写入时携带并拒绝比已见过的更小的值，可防止暂停后恢复的旧持有者覆盖新持有者的写入
+func (l *Lock) Fence() int64 {
+	return l.fence
+}
+
+// Lost 锁丢失(续期时发现被接管或租约已过期)或 Release 后关闭，持有者应尽快停止受保护的工作
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release 停止续期并按令牌释放锁，重复调用返回首次结果；锁已丢失时返回 ErrLockLost
+func (l *Lock) Release(ctx context.Context) error {
+	l.releaseOnce.Do(func() {
+		close(l.stop)
+		<-l.done
+		l.releaseErr = l.backend.release(ctx, l.key, l.token)
+		l.markLost()
+	})
+	return l.releaseErr
+}
+
+func (l *Lock) markLost() {
+	l.lostOnce.Do(func() {
+		close(l.lost)
+	})
+}
+
+// watchdog 每 TTL/3 续期；令牌不匹配时判定锁丢失。续期失败时租约按最近一次成功续期的发起时间计算，
+// 下一轮续期之前租约就会到期(已过 TTL-TTL/3)时提前判定锁丢失，避免新持有者接管后旧持有者仍在工作
+func (l *Lock) watchdog(leaseStartedAt time.Time) {
+	defer close(l.done)
+	interval := l.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			renewStartedAt := time.Now()
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := l.backend.refresh(ctx, l.key, l.token, l.ttl)
+			cancel()
+			switch {
+			case err == nil:
+				leaseStartedAt = renewStartedAt
+			case errors.Is(err, ErrLockLost):
+				log.Warnf("分布式锁已被接管，key=%s, fence=%d", l.key, l.fence)
+				l.markLost()
+				return
+			default:
+				log.Warnf("分布式锁续期失败，key=%s, err=%v", l.key, err)
+				if time.Since(leaseStartedAt) >= l.ttl-interval {
+					l.markLost()
+					return
+				}
+			}
+		}
+	}
+}
+
+// DefaultLocker 按 orm.Redis -> orm.DB 顺序选择锁实现，都未初始化时返回 nil
+func DefaultLocker() Locker {
+	if orm.Redis != nil {
+		return NewRedisLocker(orm.Redis, "")
+	}
+	if orm.DB != nil {
+		return NewTableLocker(orm.DB.GetDB(), "")
+	}
+	return nil
+}
+
+func newToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,35 @@
+package coord
+
+import (
+	"sync"
+
+	"github.com/goodbye-jack/go-common/orm/migrate"
+)
+
+// MigrationModule 默认锁表 coord_locks 的迁移模块，自定义锁表按表名注册为 coord_<table>
+const MigrationModule = "coord"
+
+var lockModules sync.Map
+
+func init() {
+	lockTableModule(DefaultLockTable)
+}
+
+// lockTableModule 返回锁表 table 的迁移模块，首次使用时注册；
+// 锁表以版本化迁移创建，migrations.mode=verify 时只校验不建表
+func lockTableModule(table string) string {
+	module := MigrationModule
+	if table != DefaultLockTable {
+		module = MigrationModule + "_" + table
+	}
+	if _, loaded := lockModules.LoadOrStore(module, struct{}{}); !loaded {
+		migrate.Register(module, migrate.List{
+			{
+				Version: "202610180001",
+				Name:    "create_" + table,
+				Models:  []any{migrate.TableModel{Name: table, Model: migrate.LockModel()}},
+			},
+		})
+	}
+	return module
+}
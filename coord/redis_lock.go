@@ -0,0 +1,75 @@
+package coord
+
+import (
+	"context"
+	"time"
+
+	ormredis "github.com/goodbye-jack/go-common/orm/redis"
+)
+
+// DefaultRedisPrefix Redis 锁键前缀
+const DefaultRedisPrefix = "go-common:lock:"
+
+// 锁键与栅栏计数键使用相同的 hash tag，保证集群模式下落在同一槽位
+const (
+	redisAcquireScript = `if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+  return redis.call('INCR', KEYS[2])
+end
+return 0`
+	redisRefreshScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`
+	redisReleaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0`
+)
+
+type redisBackend struct {
+	client *ormredis.Redis
+	prefix string
+}
+
+// NewRedisLocker 基于 Redis 的分布式锁(SET NX PX + Lua 令牌校验)，prefix 为空时使用 DefaultRedisPrefix；
+// 支持单点/哨兵/集群，栅栏令牌保存在 <key>:fence 计数器中
+func NewRedisLocker(client *ormredis.Redis, prefix string) Locker {
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	return &locker{backend: &redisBackend{client: client, prefix: prefix}}
+}
+
+func (b *redisBackend) keys(key string) []string {
+	lockKey := b.prefix + "{" + key + "}"
+	return []string{lockKey, lockKey + ":fence"}
+}
+
+func (b *redisBackend) acquire(ctx context.Context, key, token string, ttl time.Duration) (int64, error) {
+	fence, err := b.client.Eval(ctx, redisAcquireScript, b.keys(key), token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if fence == 0 {
+		return 0, ErrNotAcquired
+	}
+	return fence, nil
+}
+
+func (b *redisBackend) refresh(ctx context.Context, key, token string, ttl time.Duration) error {
+	return b.checked(b.client.Eval(ctx, redisRefreshScript, b.keys(key)[:1], token, ttl.Milliseconds()).Int64())
+}
+
+func (b *redisBackend) release(ctx context.Context, key, token string) error {
+	return b.checked(b.client.Eval(ctx, redisReleaseScript, b.keys(key)[:1], token).Int64())
+}
+
+func (b *redisBackend) checked(affected int64, err error) error {
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package coord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goodbye-jack/go-common/orm/migrate"
+	"gorm.io/gorm"
+)
+
+// DefaultLockTable 数据库锁表名
+const DefaultLockTable = "coord_locks"
+
+// tableBackend 复用 orm/migrate 的锁表实现(数据库时钟计算租约、读写走主库)，只做错误转换
+type tableBackend struct {
+	locks *migrate.LockTable
+}
+
+// NewTableLocker 基于数据库锁表的分布式锁，用于未部署 Redis 的环境；table 为空时使用 DefaultLockTable。
+// 插入新行或接管已过期的行即获得锁，接管时栅栏令牌加一；锁表首次使用时通过版本化迁移创建
+func NewTableLocker(db *gorm.DB, table string) Locker {
+	if table == "" {
+		table = DefaultLockTable
+	}
+	module := lockTableModule(table)
+	locks := migrate.NewLockTable(db, table).EnsureWith(func(ctx context.Context, db *gorm.DB) error {
+		return migrate.ApplyModule(ctx, db, module)
+	})
+	return &locker{backend: &tableBackend{locks: locks}}
+}
+
+func (b *tableBackend) acquire(ctx context.Context, key, token string, ttl time.Duration) (int64, error) {
+	fence, err := b.locks.Acquire(ctx, key, token, ttl)
+	return fence, tableError(err)
+}
+
+func (b *tableBackend) refresh(ctx context.Context, key, token string, ttl time.Duration) error {
+	return tableError(b.locks.Refresh(ctx, key, token, ttl))
+}
+
+func (b *tableBackend) release(ctx context.Context, key, token string) error {
+	return tableError(b.locks.Release(ctx, key, token))
+}
+
+func tableError(err error) error {
+	switch {
+	case errors.Is(err, migrate.ErrLockHeld):
+		return ErrNotAcquired
+	case errors.Is(err, migrate.ErrLockLost):
+		return ErrLockLost
+	}
+	return err
+}
+
+// MigrationLock 以 locker 中名为 key 的锁作为迁移锁，用于 migrate.WithLock，
+// 例如多副本共享 Redis 时：migrate.New(db, migrate.WithLock(coord.MigrationLock(coord.DefaultLocker(), "")))
+func MigrationLock(locker Locker, key string) migrate.LockFunc {
+	if key == "" {
+		key = "migrate"
+	}
+	return func(ctx context.Context) (<-chan struct{}, func() error, error) {
+		lock, err := locker.Lock(ctx, key, migrate.DefaultLockTTL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", migrate.ErrLockTimeout, err)
+		}
+		return lock.Lost(), func() error {
+			return lock.Release(context.Background())
+		}, nil
+	}
+}
@@ -0,0 +1,26 @@
+package datascope
+
+import (
+	"github.com/spf13/viper"
+)
+
+// ConfigKeyRules 数据权限规则列表，元素字段同 Rule
+const ConfigKeyRules = "datascope.rules"
+
+// LoadRulesFromConfig 读取 datascope.rules
+func LoadRulesFromConfig() ([]Rule, error) {
+	var rules []Rule
+	if err := viper.UnmarshalKey(ConfigKeyRules, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// RegisterFromConfig 将 datascope.rules 注册到默认引擎，返回规则条数
+func RegisterFromConfig() (int, error) {
+	rules, err := LoadRulesFromConfig()
+	if err != nil {
+		return 0, err
+	}
+	return len(rules), Register(rules...)
+}
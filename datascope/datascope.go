@@ -0,0 +1,383 @@
+package datascope
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/rbac"
+	"github.com/goodbye-jack/go-common/utils"
+)
+
+// Range 数据范围
+type Range string
+
+const (
+	RangeAll        Range = "all"          // 全部数据
+	RangeTenant     Range = "tenant"       // 本租户全部数据
+	RangeDeptAndSub Range = "dept_and_sub" // 本部门及下级部门
+	RangeDept       Range = "dept"         // 仅本部门
+	RangeCustom     Range = "custom"       // 指定部门(Rule.DeptCodes)
+	RangeSelf       Range = "self"         // 仅本人
+	RangeNone       Range = "none"         // 无数据权限
+)
+
+// 默认列名，可在 Rule 中按资源覆盖
+const (
+	DefaultTenantColumn = "tenant_code"
+	DefaultDeptColumn   = "dept_code"
+	DefaultOwnerColumn  = "created_by"
+)
+
+var (
+	ErrNoSubject     = errors.New("datascope subject not found in context")
+	ErrUnknownRange  = errors.New("datascope unknown range")
+	ErrEmptyResource = errors.New("datascope resource is empty")
+)
+
+// Rule 资源的一条数据权限规则。Roles 为空时对所有主体生效；
+// 主体命中多条规则时取并集，如"本部门及下级"与"本人"任一满足即可见
+type Rule struct {
+	Resource  string   `json:"resource" mapstructure:"resource"`
+	Roles     []string `json:"roles" mapstructure:"roles"`
+	Range     Range    `json:"range" mapstructure:"range"`
+	DeptCodes []string `json:"dept_codes" mapstructure:"dept_codes"`
+
+	Table        string `json:"table" mapstructure:"table"` // 联表查询时限定列所属表，Mongo 忽略
+	TenantColumn string `json:"tenant_column" mapstructure:"tenant_column"`
+	DeptColumn   string `json:"dept_column" mapstructure:"dept_column"`
+	OwnerColumn  string `json:"owner_column" mapstructure:"owner_column"`
+}
+
+func (r Rule) column(value, fallback string) string {
+	if value = strings.TrimSpace(value); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (r Rule) appliesTo(roles map[string]bool) bool {
+	if len(r.Roles) == 0 {
+		return true
+	}
+	for _, role := range r.Roles {
+		if roles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// Clause 一个过滤条件：Column IN Values
+type Clause struct {
+	Table  string   `json:"table,omitempty"`
+	Column string   `json:"column"`
+	Values []string `json:"values"`
+}
+
+// Condition 主体对资源的数据范围。Unrestricted 为 true 时不过滤，
+// 否则 Clauses 之间为 OR 关系，Clauses 为空表示无任何数据权限
+type Condition struct {
+	Resource     string   `json:"resource"`
+	Unrestricted bool     `json:"unrestricted"`
+	Clauses      []Clause `json:"clauses"`
+}
+
+// Denied 没有任何可见数据
+func (c *Condition) Denied() bool {
+	return c == nil || (!c.Unrestricted && len(c.Clauses) == 0)
+}
+
+// RoleResolver 获取主体在租户内生效的角色(含继承)
+type RoleResolver func(ctx context.Context, subject *Subject) ([]string, error)
+
+type Option func(*Engine)
+
+// WithDepartmentTree 指定部门树，dept_and_sub 范围依赖它展开下级部门
+func WithDepartmentTree(tree DepartmentTree) Option {
+	return func(e *Engine) {
+		e.tree = tree
+	}
+}
+
+// WithRoleResolver 替换 Principal.RoleCodes 为空时的角色来源，默认 rbac_* 表(含角色继承)
+func WithRoleResolver(resolver RoleResolver) Option {
+	return func(e *Engine) {
+		if resolver != nil {
+			e.roles = resolver
+		}
+	}
+}
+
+// WithDefaultRange 主体未命中任何规则时的数据范围，默认 none
+func WithDefaultRange(r Range) Option {
+	return func(e *Engine) {
+		e.defaultRange = r
+	}
+}
+
+// WithAdminRoles 拥有这些角色的主体不受数据权限限制，默认 utils.RoleAdministrator
+func WithAdminRoles(roles ...string) Option {
+	return func(e *Engine) {
+		e.adminRoles = append([]string{}, roles...)
+	}
+}
+
+// Engine 按资源规则与主体属性计算数据范围
+type Engine struct {
+	mu           sync.RWMutex
+	rules        map[string][]Rule
+	tree         DepartmentTree
+	roles        RoleResolver
+	defaultRange Range
+	adminRoles   []string
+}
+
+func NewEngine(opts ...Option) *Engine {
+	e := &Engine{
+		rules:        map[string][]Rule{},
+		roles:        rbacRoles,
+		defaultRange: RangeNone,
+		adminRoles:   []string{utils.RoleAdministrator},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Register 追加资源规则
+func (e *Engine) Register(rules ...Rule) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, rule := range rules {
+		rule.Resource = strings.TrimSpace(rule.Resource)
+		if rule.Resource == "" {
+			return ErrEmptyResource
+		}
+		if !validRange(rule.Range) {
+			return ErrUnknownRange
+		}
+		e.rules[rule.Resource] = append(e.rules[rule.Resource], rule)
+	}
+	return nil
+}
+
+// Replace 整体替换资源的规则，rules 为空时移除该资源
+func (e *Engine) Replace(resource string, rules []Rule) error {
+	for _, rule := range rules {
+		if !validRange(rule.Range) {
+			return ErrUnknownRange
+		}
+	}
+	resource = strings.TrimSpace(resource)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(rules) == 0 {
+		delete(e.rules, resource)
+		return nil
+	}
+	list := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		rule.Resource = resource
+		list = append(list, rule)
+	}
+	e.rules[resource] = list
+	return nil
+}
+
+// Rules 返回资源的规则副本
+func (e *Engine) Rules(resource string) []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]Rule{}, e.rules[resource]...)
+}
+
+// Resolve 计算 ctx 中主体对资源的数据范围
+func (e *Engine) Resolve(ctx context.Context, resource string) (*Condition, error) {
+	subject, ok := SubjectFromContext(ctx)
+	if !ok {
+		return nil, ErrNoSubject
+	}
+	return e.ResolveFor(ctx, subject, resource)
+}
+
+// ResolveFor 计算指定主体对资源的数据范围
+func (e *Engine) ResolveFor(ctx context.Context, subject *Subject, resource string) (*Condition, error) {
+	cond := &Condition{Resource: resource}
+	if subject == nil {
+		return cond, nil
+	}
+	roles, err := e.subjectRoles(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range e.adminRoles {
+		if roles[role] {
+			cond.Unrestricted = true
+			return cond, nil
+		}
+	}
+	rules := e.Rules(resource)
+	if len(rules) == 0 {
+		log.Warnf("datascope resource %s 未配置数据权限规则，按 %s 处理", resource, e.defaultRange)
+	}
+	matched := false
+	for _, rule := range rules {
+		if !rule.appliesTo(roles) {
+			continue
+		}
+		matched = true
+		if err := e.apply(ctx, cond, subject, rule); err != nil {
+			return nil, err
+		}
+		if cond.Unrestricted {
+			return cond, nil
+		}
+	}
+	if !matched {
+		if err := e.apply(ctx, cond, subject, Rule{Resource: resource, Range: e.defaultRange}); err != nil {
+			return nil, err
+		}
+	}
+	return cond, nil
+}
+
+func (e *Engine) apply(ctx context.Context, cond *Condition, subject *Subject, rule Rule) error {
+	add := func(column string, values []string) {
+		values = compactValues(values)
+		if len(values) == 0 {
+			return
+		}
+		for i := range cond.Clauses {
+			if cond.Clauses[i].Table == rule.Table && cond.Clauses[i].Column == column {
+				cond.Clauses[i].Values = compactValues(append(cond.Clauses[i].Values, values...))
+				return
+			}
+		}
+		cond.Clauses = append(cond.Clauses, Clause{Table: rule.Table, Column: column, Values: values})
+	}
+	switch rule.Range {
+	case RangeAll:
+		cond.Unrestricted, cond.Clauses = true, nil
+	case RangeTenant:
+		add(rule.column(rule.TenantColumn, DefaultTenantColumn), []string{subject.TenantCode})
+	case RangeDept:
+		add(rule.column(rule.DeptColumn, DefaultDeptColumn), subject.DeptCodes)
+	case RangeDeptAndSub:
+		codes := subject.DeptCodes
+		if e.tree != nil && len(codes) > 0 {
+			expanded, err := e.tree.Descendants(ctx, codes)
+			if err != nil {
+				return err
+			}
+			codes = expanded
+		}
+		add(rule.column(rule.DeptColumn, DefaultDeptColumn), codes)
+	case RangeCustom:
+		add(rule.column(rule.DeptColumn, DefaultDeptColumn), rule.DeptCodes)
+	case RangeSelf:
+		add(rule.column(rule.OwnerColumn, DefaultOwnerColumn), []string{subject.UserID})
+	case RangeNone, "":
+	default:
+		return ErrUnknownRange
+	}
+	return nil
+}
+
+func (e *Engine) subjectRoles(ctx context.Context, subject *Subject) (map[string]bool, error) {
+	roles := subject.Roles
+	if len(roles) == 0 && e.roles != nil && subject.UserID != "" {
+		var err error
+		if roles, err = e.roles(ctx, subject); err != nil {
+			return nil, err
+		}
+	}
+	ans := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		ans[role] = true
+	}
+	return ans, nil
+}
+
+// rbacRoles 从 rbac_* 表读取主体在租户内的角色并展开继承
+func rbacRoles(_ context.Context, subject *Subject) ([]string, error) {
+	direct, err := rbac.ListUserRolesInTenant(subject.UserID, subject.TenantCode)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	queue := append([]string{}, direct...)
+	var roles []string
+	for len(queue) > 0 {
+		role := queue[0]
+		queue = queue[1:]
+		if seen[role] {
+			continue
+		}
+		seen[role] = true
+		roles = append(roles, role)
+		inherits, err := rbac.ListRoleInherits(role)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, inherits...)
+	}
+	return roles, nil
+}
+
+func validRange(r Range) bool {
+	switch r {
+	case RangeAll, RangeTenant, RangeDeptAndSub, RangeDept, RangeCustom, RangeSelf, RangeNone:
+		return true
+	}
+	return false
+}
+
+func compactValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	ans := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		ans = append(ans, value)
+	}
+	return ans
+}
+
+var (
+	defaultEngine   = NewEngine()
+	defaultEngineMu sync.RWMutex
+)
+
+// Default 返回进程级默认引擎
+func Default() *Engine {
+	defaultEngineMu.RLock()
+	defer defaultEngineMu.RUnlock()
+	return defaultEngine
+}
+
+// SetDefault 替换进程级默认引擎(如需挂载部门树)
+func SetDefault(e *Engine) {
+	if e == nil {
+		return
+	}
+	defaultEngineMu.Lock()
+	defer defaultEngineMu.Unlock()
+	defaultEngine = e
+}
+
+// Register 向默认引擎追加资源规则
+func Register(rules ...Rule) error {
+	return Default().Register(rules...)
+}
+
+// Resolve 使用默认引擎计算 ctx 中主体对资源的数据范围
+func Resolve(ctx context.Context, resource string) (*Condition, error) {
+	return Default().Resolve(ctx, resource)
+}
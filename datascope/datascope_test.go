@@ -0,0 +1,157 @@
+package datascope
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	commonhttp "github.com/goodbye-jack/go-common/http"
+	"github.com/goodbye-jack/go-common/ldap"
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type order struct {
+	ID         uint `gorm:"primaryKey"`
+	TenantCode string
+	DeptCode   string
+	CreatedBy  string
+}
+
+func departments() []*ldap.Department {
+	return []*ldap.Department{
+		{DN: "ou=hq,ou=departments,dc=example", Code: "hq"},
+		{DN: "ou=sales,ou=departments,dc=example", Code: "sales", ParentDN: "ou=hq,ou=departments,dc=example"},
+		{DN: "ou=east,ou=departments,dc=example", Code: "east", ParentDN: "OU=sales, ou=departments,dc=example"},
+		{DN: "ou=rd,ou=departments,dc=example", Code: "rd", ParentDN: "ou=hq,ou=departments,dc=example"},
+	}
+}
+
+func newTestEngine(t *testing.T) *Engine {
+	t.Helper()
+	engine := NewEngine(
+		WithDepartmentTree(NewDepartmentIndex(departments())),
+		WithRoleResolver(func(context.Context, *Subject) ([]string, error) { return nil, nil }),
+	)
+	if err := engine.Register(
+		Rule{Resource: "orders", Roles: []string{"MANAGER"}, Range: RangeDeptAndSub},
+		Rule{Resource: "orders", Range: RangeSelf},
+		Rule{Resource: "orders", Roles: []string{"AUDITOR"}, Range: RangeTenant},
+	); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	return engine
+}
+
+func TestDepartmentIndexDescendants(t *testing.T) {
+	codes, _ := NewDepartmentIndex(departments()).Descendants(context.Background(), []string{"sales"})
+	sort.Strings(codes)
+	if !reflect.DeepEqual(codes, []string{"east", "sales"}) {
+		t.Fatalf("Descendants() = %v", codes)
+	}
+}
+
+func TestResolveUnionOfRules(t *testing.T) {
+	engine := newTestEngine(t)
+	ctx := context.Background()
+
+	cond, err := engine.ResolveFor(ctx, &Subject{UserID: "alice", DeptCodes: []string{"sales"}, Roles: []string{"MANAGER"}}, "orders")
+	if err != nil {
+		t.Fatalf("ResolveFor() error = %v", err)
+	}
+	want := []Clause{
+		{Column: DefaultDeptColumn, Values: []string{"sales", "east"}},
+		{Column: DefaultOwnerColumn, Values: []string{"alice"}},
+	}
+	if !reflect.DeepEqual(cond.Clauses, want) {
+		t.Fatalf("Clauses = %+v, want %+v", cond.Clauses, want)
+	}
+
+	cond, _ = engine.ResolveFor(ctx, &Subject{UserID: "root", Roles: []string{utils.RoleAdministrator}}, "orders")
+	if !cond.Unrestricted {
+		t.Fatalf("administrator should be unrestricted")
+	}
+	cond, _ = engine.ResolveFor(ctx, &Subject{UserID: "bob"}, "invoices")
+	if !cond.Denied() {
+		t.Fatalf("resource without rules should be denied, got %+v", cond)
+	}
+	if err := engine.Register(Rule{Resource: "orders", Range: "everything"}); err != ErrUnknownRange {
+		t.Fatalf("Register(unknown range) error = %v", err)
+	}
+}
+
+func TestScopeFiltersRows(t *testing.T) {
+	db := orm.NewOrm(filepath.Join(t.TempDir(), "datascope.db"), utils.DBTypeSQLite, 5)
+	db.AutoMigrate(&order{})
+	rows := []order{
+		{TenantCode: "t1", DeptCode: "sales", CreatedBy: "x"},
+		{TenantCode: "t1", DeptCode: "east", CreatedBy: "y"},
+		{TenantCode: "t1", DeptCode: "rd", CreatedBy: "alice"},
+		{TenantCode: "t2", DeptCode: "rd", CreatedBy: "z"},
+	}
+	ctx := context.Background()
+	for i := range rows {
+		if err := db.Create(ctx, &rows[i]); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	engine := newTestEngine(t)
+	SetDefault(engine)
+	defer SetDefault(NewEngine())
+
+	// 主体取自 gin.Context 中的 Principal
+	principal := &commonhttp.Principal{
+		Type:       commonhttp.PrincipalAdmin,
+		Subject:    "alice",
+		TenantCode: "t1",
+		RoleCodes:  []string{"MANAGER"},
+		Attributes: map[string]any{AttrDeptCodes: []any{"sales"}},
+	}
+	principalCtx := context.WithValue(ctx, "Principal", principal)
+	var got []order
+	if err := db.Scopes(Scope(principalCtx, "orders")).FindAll(ctx, &got); err != nil {
+		t.Fatalf("FindAll() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("manager rows = %d, want 3", len(got))
+	}
+
+	// 作用域不会污染原 Orm
+	got = nil
+	if err := db.FindAll(ctx, &got); err != nil || len(got) != 4 {
+		t.Fatalf("unscoped rows = %d, %v", len(got), err)
+	}
+
+	auditorCtx := WithSubject(ctx, &Subject{UserID: "carol", TenantCode: "t2", Roles: []string{"AUDITOR"}})
+	got = nil
+	if err := db.Scopes(Scope(auditorCtx, "orders")).FindAll(ctx, &got, "dept_code = ?", "rd"); err != nil {
+		t.Fatalf("FindAll() error = %v", err)
+	}
+	if len(got) != 1 || got[0].TenantCode != "t2" {
+		t.Fatalf("auditor rows = %+v", got)
+	}
+
+	if err := db.Scopes(Scope(ctx, "orders")).FindAll(ctx, &got); err != ErrNoSubject {
+		t.Fatalf("missing subject error = %v", err)
+	}
+}
+
+func TestBsonFilter(t *testing.T) {
+	engine := newTestEngine(t)
+	cond, _ := engine.ResolveFor(context.Background(), &Subject{UserID: "alice"}, "orders")
+	want := bson.M{DefaultOwnerColumn: bson.M{"$in": bson.A{"alice"}}}
+	if got := cond.BsonFilter(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("BsonFilter() = %v, want %v", got, want)
+	}
+	merged := MergeFilter(bson.M{"status": 1}, cond.BsonFilter())
+	if and, ok := merged["$and"].(bson.A); !ok || len(and) != 2 {
+		t.Fatalf("MergeFilter() = %v", merged)
+	}
+	denied := (&Condition{}).BsonFilter()
+	if _, ok := denied["_id"]; !ok {
+		t.Fatalf("denied filter = %v", denied)
+	}
+}
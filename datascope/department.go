@@ -0,0 +1,120 @@
+package datascope
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goodbye-jack/go-common/ldap"
+	"github.com/goodbye-jack/go-common/log"
+)
+
+// DefaultDepartmentTTL LDAP 部门树缓存时间
+const DefaultDepartmentTTL = 5 * time.Minute
+
+// DepartmentTree 部门层级数据来源
+type DepartmentTree interface {
+	// Descendants 返回 codes 及其全部下级部门编码
+	Descendants(ctx context.Context, codes []string) ([]string, error)
+}
+
+// DepartmentIndex 内存中的部门树，按 ParentDN 建立上下级关系
+type DepartmentIndex struct {
+	children map[string][]string
+}
+
+// NewDepartmentIndex 由部门列表建立部门树，ParentDN 指向不存在的部门时视为根部门
+func NewDepartmentIndex(departments []*ldap.Department) *DepartmentIndex {
+	codeByDN := make(map[string]string, len(departments))
+	for _, dept := range departments {
+		if dept != nil && dept.Code != "" {
+			codeByDN[normalizeDN(dept.DN)] = dept.Code
+		}
+	}
+	idx := &DepartmentIndex{children: map[string][]string{}}
+	for _, dept := range departments {
+		if dept == nil || dept.Code == "" {
+			continue
+		}
+		if parent, ok := codeByDN[normalizeDN(dept.ParentDN)]; ok && parent != dept.Code {
+			idx.children[parent] = append(idx.children[parent], dept.Code)
+		}
+	}
+	return idx
+}
+
+func (idx *DepartmentIndex) Descendants(_ context.Context, codes []string) ([]string, error) {
+	seen := map[string]bool{}
+	ans := make([]string, 0, len(codes))
+	queue := append([]string{}, codes...)
+	for len(queue) > 0 {
+		code := queue[0]
+		queue = queue[1:]
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		ans = append(ans, code)
+		queue = append(queue, idx.children[code]...)
+	}
+	return ans, nil
+}
+
+// LDAPDepartmentTree 从 LDAP 读取部门并缓存 ttl，过期后下次查询时重新加载
+type LDAPDepartmentTree struct {
+	dir ldap.Ldap
+	ttl time.Duration
+
+	mu       sync.Mutex
+	index    *DepartmentIndex
+	loadedAt time.Time
+}
+
+func NewLDAPDepartmentTree(dir ldap.Ldap, ttl time.Duration) *LDAPDepartmentTree {
+	if ttl <= 0 {
+		ttl = DefaultDepartmentTTL
+	}
+	return &LDAPDepartmentTree{dir: dir, ttl: ttl}
+}
+
+func (t *LDAPDepartmentTree) Descendants(ctx context.Context, codes []string) ([]string, error) {
+	index, err := t.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return index.Descendants(ctx, codes)
+}
+
+// Invalidate 丢弃缓存，部门变更后调用
+func (t *LDAPDepartmentTree) Invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.index = nil
+}
+
+func (t *LDAPDepartmentTree) load(ctx context.Context) (*DepartmentIndex, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.index != nil && time.Since(t.loadedAt) < t.ttl {
+		return t.index, nil
+	}
+	departments, err := t.dir.ListDepartment(ctx)
+	if err != nil {
+		if t.index != nil { // LDAP 暂不可用时沿用旧数据
+			log.Warnf("datascope 加载 LDAP 部门失败，沿用缓存: %v", err)
+			return t.index, nil
+		}
+		return nil, err
+	}
+	t.index, t.loadedAt = NewDepartmentIndex(departments), time.Now()
+	return t.index, nil
+}
+
+func normalizeDN(dn string) string {
+	parts := strings.Split(strings.ToLower(dn), ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return strings.Join(parts, ",")
+}
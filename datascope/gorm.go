@@ -0,0 +1,51 @@
+package datascope
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormScope 将数据范围转换为 GORM Scope，列名经方言转义；无数据权限时追加恒假条件
+func (c *Condition) GormScope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if c != nil && c.Unrestricted {
+			return db
+		}
+		if c.Denied() {
+			return db.Where("1 = 0")
+		}
+		exprs := make([]clause.Expression, 0, len(c.Clauses))
+		for _, item := range c.Clauses {
+			values := make([]interface{}, 0, len(item.Values))
+			for _, value := range item.Values {
+				values = append(values, value)
+			}
+			exprs = append(exprs, clause.IN{Column: clause.Column{Table: item.Table, Name: item.Column}, Values: values})
+		}
+		if len(exprs) == 1 {
+			return db.Where(exprs[0])
+		}
+		return db.Where(clause.Or(exprs...))
+	}
+}
+
+// Scope 使用默认引擎生成 ctx 中主体对资源的 GORM Scope，计算失败时查询返回该错误：
+//
+//	orm.DB.Scopes(datascope.Scope(c, "orders")).FindAll(c, &orders)
+func Scope(ctx context.Context, resource string) func(*gorm.DB) *gorm.DB {
+	return Default().Scope(ctx, resource)
+}
+
+// Scope 生成 ctx 中主体对资源的 GORM Scope
+func (e *Engine) Scope(ctx context.Context, resource string) func(*gorm.DB) *gorm.DB {
+	cond, err := e.Resolve(ctx, resource)
+	if err != nil {
+		return func(db *gorm.DB) *gorm.DB {
+			_ = db.AddError(err)
+			return db
+		}
+	}
+	return cond.GormScope()
+}
@@ -0,0 +1,66 @@
+package datascope
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// BsonFilter 将数据范围转换为 Mongo 过滤条件，无数据权限时返回不匹配任何文档的条件
+func (c *Condition) BsonFilter() bson.M {
+	if c != nil && c.Unrestricted {
+		return bson.M{}
+	}
+	if c.Denied() {
+		return bson.M{"_id": bson.M{"$in": bson.A{}}}
+	}
+	ors := make(bson.A, 0, len(c.Clauses))
+	for _, item := range c.Clauses {
+		values := make(bson.A, 0, len(item.Values))
+		for _, value := range item.Values {
+			values = append(values, value)
+		}
+		ors = append(ors, bson.M{item.Column: bson.M{"$in": values}})
+	}
+	if len(ors) == 1 {
+		return ors[0].(bson.M)
+	}
+	return bson.M{"$or": ors}
+}
+
+// Filter 使用默认引擎生成 ctx 中主体对资源的 Mongo 过滤条件
+func Filter(ctx context.Context, resource string) (bson.M, error) {
+	return Default().Filter(ctx, resource)
+}
+
+// Filter 生成 ctx 中主体对资源的 Mongo 过滤条件
+func (e *Engine) Filter(ctx context.Context, resource string) (bson.M, error) {
+	cond, err := e.Resolve(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+	return cond.BsonFilter(), nil
+}
+
+// Apply 将数据范围与业务过滤条件以 $and 合并，可直接传给 mongodb.Mongo 的查询方法：
+//
+//	filter, err := datascope.Apply(c, "orders", bson.M{"status": 1})
+//	rows, total, err := orm.Mongo.FindPageWithCtx(c, "orders", filter, 1, 20, nil)
+func Apply(ctx context.Context, resource string, filter bson.M) (bson.M, error) {
+	scope, err := Filter(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+	return MergeFilter(filter, scope), nil
+}
+
+// MergeFilter 以 $and 合并两个过滤条件，任一为空时直接返回另一个
+func MergeFilter(filter, scope bson.M) bson.M {
+	if len(scope) == 0 {
+		return filter
+	}
+	if len(filter) == 0 {
+		return scope
+	}
+	return bson.M{"$and": bson.A{filter, scope}}
+}
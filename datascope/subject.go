@@ -0,0 +1,85 @@
+package datascope
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	commonhttp "github.com/goodbye-jack/go-common/http"
+)
+
+// Principal.Attributes 中的部门属性，值可为 string(逗号分隔) / []string / []any
+const (
+	AttrDeptCodes = "dept_codes"
+	AttrDeptCode  = "dept_code"
+)
+
+// Subject 数据权限计算所需的主体属性
+type Subject struct {
+	UserID     string
+	TenantCode string
+	DeptCodes  []string
+	Roles      []string
+}
+
+type subjectContextKey struct{}
+
+// WithSubject 在 ctx 中显式携带主体，优先于 gin.Context 中的 Principal
+func WithSubject(ctx context.Context, subject *Subject) context.Context {
+	return context.WithValue(ctx, subjectContextKey{}, subject)
+}
+
+// SubjectFromContext 依次读取 WithSubject 写入的主体、ctx 中的 Principal(如 *gin.Context)
+func SubjectFromContext(ctx context.Context) (*Subject, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	if subject, ok := ctx.Value(subjectContextKey{}).(*Subject); ok && subject != nil {
+		return subject, true
+	}
+	if principal, ok := ctx.Value("Principal").(*commonhttp.Principal); ok && principal != nil {
+		return SubjectFromPrincipal(principal), true
+	}
+	return nil, false
+}
+
+// SubjectFromPrincipal 由 Principal 构造主体，部门取自 Attributes[dept_codes] 或 Attributes[dept_code]
+func SubjectFromPrincipal(principal *commonhttp.Principal) *Subject {
+	if principal == nil {
+		return nil
+	}
+	subject := &Subject{
+		UserID:     principal.Subject,
+		TenantCode: principal.TenantCode,
+		Roles:      append([]string{}, principal.RoleCodes...),
+	}
+	if principal.Type == commonhttp.PrincipalAnonymous {
+		subject.UserID = ""
+	}
+	for _, key := range []string{AttrDeptCodes, AttrDeptCode} {
+		if codes := attributeStrings(principal.Attributes[key]); len(codes) > 0 {
+			subject.DeptCodes = codes
+			break
+		}
+	}
+	return subject
+}
+
+func attributeStrings(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return compactValues(strings.Split(v, ","))
+	case []string:
+		return compactValues(v)
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return compactValues(values)
+	default:
+		return compactValues([]string{fmt.Sprint(v)})
+	}
+}
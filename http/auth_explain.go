@@ -0,0 +1,343 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/config"
+	"github.com/goodbye-jack/go-common/rbac"
+	"github.com/goodbye-jack/go-common/utils"
+)
+
+// ConfigKeyExposeDenyReason 为 true 且非生产环境时，401/403 响应携带拒绝原因码
+const ConfigKeyExposeDenyReason = "security.auth.expose_deny_reason"
+
+// DenyReasonHeader 拒绝原因码响应头
+const DenyReasonHeader = "X-Deny-Reason"
+
+// 拒绝原因码
+const (
+	DenyReasonUnauthenticated = "unauthenticated"
+	DenyReasonSsoRejected     = "sso_rejected"
+	DenyReasonPrincipalType   = "principal_type_not_allowed"
+	DenyReasonTokenSource     = "token_source_not_allowed"
+	DenyReasonGuard           = "guard_denied"
+	DenyReasonRbacDenied      = "rbac_denied"
+	DenyReasonRbacError       = "rbac_error"
+	DenyReasonPolicyDenied    = "policy_denied"
+	DenyReasonTenantMismatch  = "tenant_mismatch"
+)
+
+// 判定步骤结果
+const (
+	ExplainPass = "pass"
+	ExplainFail = "fail"
+	ExplainSkip = "skip"
+	ExplainInfo = "info" // 仅供参考，不参与判定
+)
+
+type guardError struct {
+	name string
+	err  error
+}
+
+func (e *guardError) Error() string { return e.err.Error() }
+
+func (e *guardError) Unwrap() error { return e.err }
+
+func denyReasonOf(err error) string {
+	var ge *guardError
+	switch {
+	case errors.Is(err, errMissingPrincipal):
+		return DenyReasonUnauthenticated
+	case errors.Is(err, errPrincipalTypeNotAllowed):
+		return DenyReasonPrincipalType
+	case errors.Is(err, errTokenSourceNotAllowed):
+		return DenyReasonTokenSource
+	case errors.As(err, &ge):
+		return DenyReasonGuard
+	}
+	return DenyReasonPolicyDenied
+}
+
+func denyReasonExposed() bool {
+	return config.GetConfigBool(ConfigKeyExposeDenyReason) && !config.IsProduction()
+}
+
+// abortDenied 终止请求；允许暴露原因时通过响应头与响应体返回原因码，生产环境始终只返回状态码
+func abortDenied(c *gin.Context, statusCode int, reason string) {
+	if !denyReasonExposed() {
+		c.AbortWithStatus(statusCode)
+		return
+	}
+	c.Header(DenyReasonHeader, reason)
+	c.AbortWithStatusJSON(statusCode, gin.H{
+		"data":    gin.H{"reason": reason},
+		"message": reason,
+	})
+}
+
+// ExplainRequest 权限解释请求。Principal 为空时按 Subject/PrincipalType/TokenSource/Tenant 构造主体，
+// Subject 为空视为匿名；Query/Body 供依赖请求参数的 Guard 使用；Grant/Revoke 为 what-if 角色变更
+type ExplainRequest struct {
+	Subject       string          `json:"subject"`
+	PrincipalType PrincipalType   `json:"principal_type"`
+	TokenSource   string          `json:"token_source"`
+	Tenant        string          `json:"tenant"`
+	Principal     *Principal      `json:"principal"`
+	Path          string          `json:"path"`
+	Method        string          `json:"method"`
+	Query         string          `json:"query"`
+	Body          json.RawMessage `json:"body"`
+	Grant         []string        `json:"grant"`
+	Revoke        []string        `json:"revoke"`
+}
+
+func (r *ExplainRequest) principal() *Principal {
+	if r.Principal != nil {
+		return r.Principal
+	}
+	subject := strings.TrimSpace(r.Subject)
+	if subject == "" || subject == utils.UserAnonymous {
+		return NewAnonymousPrincipal()
+	}
+	return &Principal{
+		Type:        r.PrincipalType,
+		Subject:     subject,
+		TenantCode:  strings.TrimSpace(r.Tenant),
+		TokenSource: r.TokenSource,
+	}
+}
+
+// ExplainStep 判定过程中的一步
+type ExplainStep struct {
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// AccessExplanation 一次请求的完整鉴权判定过程，Reason 为第一个失败步骤的原因码
+type AccessExplanation struct {
+	Service       string            `json:"service"`
+	Path          string            `json:"path"`
+	Method        string            `json:"method"`
+	Route         string            `json:"route"`
+	Policy        string            `json:"policy"`
+	Principal     *Principal        `json:"principal"`
+	Tenant        string            `json:"tenant"`
+	RequiredRoles []string          `json:"required_roles"`
+	Allowed       bool              `json:"allowed"`
+	Reason        string            `json:"reason,omitempty"`
+	Steps         []ExplainStep     `json:"steps"`
+	Rbac          *rbac.Explanation `json:"rbac,omitempty"`
+}
+
+func (a *AccessExplanation) addStep(step ExplainStep) {
+	a.Steps = append(a.Steps, step)
+	if step.Result == ExplainFail && a.Allowed {
+		a.Allowed, a.Reason = false, step.Reason
+	}
+}
+
+// ExplainAccess 按 LoginRequiredMiddleware 与 RbacMiddleware 的顺序重放鉴权判定并记录每一步，
+// 不短路以便一次看到全部失败原因。Path 可为路由模板或实际请求路径
+func (s *HTTPServer) ExplainAccess(ctx context.Context, req ExplainRequest) (*AccessExplanation, error) {
+	method := strings.ToUpper(strings.TrimSpace(req.Method))
+	if strings.TrimSpace(req.Path) == "" || method == "" {
+		return nil, &ParameterError{Code: http.StatusBadRequest, Message: "path and method are required"}
+	}
+	route, params := s.matchRoute(req.Path, method)
+	principal := req.principal()
+	if route == nil { // 未注册路由在 LoginRequiredMiddleware 中按匿名处理
+		principal = NewAnonymousPrincipal()
+	}
+	c, err := newExplainContext(ctx, req, method, params, principal)
+	if err != nil {
+		return nil, &ParameterError{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	ans := &AccessExplanation{
+		Service:   s.service_name,
+		Path:      req.Path,
+		Method:    method,
+		Route:     req.Path,
+		Principal: principal,
+		Tenant:    ResolveRequestTenant(c),
+		Allowed:   true,
+	}
+
+	policy := (*AuthPolicy)(nil)
+	if route == nil {
+		ans.addStep(ExplainStep{Name: "route", Result: ExplainSkip, Detail: "未找到已注册路由，按匿名主体执行 RBAC 鉴权"})
+	} else {
+		ans.Route = route.Url
+		policy = route.EffectiveAuthPolicy()
+		ans.addStep(ExplainStep{Name: "route", Result: ExplainPass, Detail: route.Url})
+	}
+	if policy != nil {
+		ans.Policy = policy.Name
+		ans.RequiredRoles = append([]string{}, policy.RequiredRoles...)
+		explainAuthPolicy(ans, c, principal, policy)
+	}
+
+	if policy != nil && !policy.EnforceRBAC {
+		ans.addStep(ExplainStep{Name: "rbac", Result: ExplainSkip, Detail: "路由策略未启用 RBAC"})
+		return ans, nil
+	}
+	var change *rbac.RoleChange
+	if len(req.Grant) > 0 || len(req.Revoke) > 0 {
+		change = &rbac.RoleChange{Grant: req.Grant, Revoke: req.Revoke}
+	}
+	rbacReq := rbac.NewTenantReq(GetUser(c), ans.Tenant, s.service_name, ans.Route, method).
+		WithAttributeLoader(func() rbac.Attributes { return requestAttributes(c) })
+	explanation, err := RbacClient.Explain(rbacReq, change)
+	if err != nil {
+		ans.addStep(ExplainStep{Name: "rbac", Result: ExplainFail, Reason: DenyReasonRbacError, Detail: err.Error()})
+		return ans, nil
+	}
+	ans.Rbac = explanation
+	if len(ans.RequiredRoles) > 0 {
+		ans.Steps = append(ans.Steps, requiredRolesStep(ans.RequiredRoles, explanation))
+	}
+	for _, cond := range explanation.Conditions {
+		step := ExplainStep{Name: "condition", Result: ExplainInfo, Detail: fmt.Sprintf("%v => %v", cond.Policy, cond.Result)}
+		if cond.Error != "" {
+			step.Detail = fmt.Sprintf("%v => %s", cond.Policy, cond.Error)
+		}
+		ans.Steps = append(ans.Steps, step)
+	}
+	if explanation.Allowed {
+		ans.addStep(ExplainStep{Name: "rbac", Result: ExplainPass, Detail: fmt.Sprintf("命中策略 %v", explanation.MatchedPolicy)})
+	} else {
+		ans.addStep(ExplainStep{Name: "rbac", Result: ExplainFail, Reason: DenyReasonRbacDenied, Detail: fmt.Sprintf("缺少角色 %v", explanation.MissingRoles)})
+	}
+	return ans, nil
+}
+
+// explainAuthPolicy 对应 handleRouteAuthPolicy 的认证、主体类型、凭证来源与 Guard 检查
+func explainAuthPolicy(ans *AccessExplanation, c *gin.Context, principal *Principal, policy *AuthPolicy) {
+	anonymous := principal.Type == PrincipalAnonymous
+	if anonymous && policy.RequireAuth {
+		ans.addStep(ExplainStep{Name: "authentication", Result: ExplainFail, Reason: DenyReasonUnauthenticated, Detail: "路由要求登录"})
+	} else {
+		ans.addStep(ExplainStep{Name: "authentication", Result: ExplainPass})
+	}
+	if len(policy.AllowedPrincipalTypes) == 0 {
+		ans.addStep(ExplainStep{Name: "principal_type", Result: ExplainSkip, Detail: "未限制主体类型"})
+	} else if err := checkPrincipalType(principal, policy); err != nil {
+		reason := DenyReasonPrincipalType
+		if anonymous {
+			reason = DenyReasonUnauthenticated
+		}
+		ans.addStep(ExplainStep{Name: "principal_type", Result: ExplainFail, Reason: reason, Detail: fmt.Sprintf("%s 不在 %v 中", principal.Type, policy.AllowedPrincipalTypes)})
+	} else {
+		ans.addStep(ExplainStep{Name: "principal_type", Result: ExplainPass, Detail: string(principal.Type)})
+	}
+	if len(policy.AllowedTokenSources) == 0 {
+		ans.addStep(ExplainStep{Name: "token_source", Result: ExplainSkip, Detail: "未限制凭证来源"})
+	} else if err := checkTokenSource(principal, policy); err != nil {
+		ans.addStep(ExplainStep{Name: "token_source", Result: ExplainFail, Reason: DenyReasonTokenSource, Detail: fmt.Sprintf("%s 不在 %v 中", principal.TokenSource, policy.AllowedTokenSources)})
+	} else {
+		ans.addStep(ExplainStep{Name: "token_source", Result: ExplainPass, Detail: principal.TokenSource})
+	}
+	for _, guard := range policy.Guards {
+		if guard == nil {
+			continue
+		}
+		name := "guard:" + guard.Name()
+		if err := guard.Check(c, principal); err != nil {
+			ans.addStep(ExplainStep{Name: name, Result: ExplainFail, Reason: DenyReasonGuard, Detail: err.Error()})
+			continue
+		}
+		ans.addStep(ExplainStep{Name: name, Result: ExplainPass})
+	}
+}
+
+// requiredRolesStep RequiredRoles 由 RBAC 策略落实，这里只展示主体持有情况
+func requiredRolesStep(required []string, explanation *rbac.Explanation) ExplainStep {
+	held := map[string]bool{explanation.Subject: true}
+	for _, rp := range explanation.Roles {
+		held[rp.Role] = true
+	}
+	var has, missing []string
+	for _, role := range required {
+		if held[role] {
+			has = append(has, role)
+		} else {
+			missing = append(missing, role)
+		}
+	}
+	return ExplainStep{Name: "required_roles", Result: ExplainInfo, Detail: fmt.Sprintf("持有 %v，未持有 %v", has, missing)}
+}
+
+// newExplainContext 构造与被解释请求等价的 gin.Context，供 Guard 读取参数
+func newExplainContext(ctx context.Context, req ExplainRequest, method string, params gin.Params, principal *Principal) (*gin.Context, error) {
+	target := req.Path
+	if query := strings.TrimPrefix(req.Query, "?"); query != "" {
+		target += "?" + query
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Body) > 0 {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if tenant := strings.TrimSpace(req.Tenant); tenant != "" {
+		httpReq.Header.Set(utils.TenantHeaderName, tenant)
+	}
+	// 需要绑定 Engine 才能读取表单参数，响应写入丢弃
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, c.Params = httpReq, params
+	SetPrincipal(c, principal)
+	return c, nil
+}
+
+func (s *HTTPServer) matchRoute(path, method string) (*Route, gin.Params) {
+	if route := findRouteByPathAndMethod(s.routes, path, method); route != nil {
+		return route, nil
+	}
+	for _, route := range s.routes {
+		params, ok := matchRoutePath(route.Url, path)
+		if !ok {
+			continue
+		}
+		for _, routeMethod := range route.Methods {
+			if strings.EqualFold(routeMethod, method) {
+				return route, params
+			}
+		}
+	}
+	return nil, nil
+}
+
+// matchRoutePath 按 gin 路由语法(:name 单段、*name 余下全部)匹配实际路径
+func matchRoutePath(pattern, path string) (gin.Params, bool) {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	var params gin.Params
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "*") {
+			rest := "/" + strings.Join(pathParts[min(i, len(pathParts)):], "/")
+			return append(params, gin.Param{Key: part[1:], Value: rest}), true
+		}
+		if i >= len(pathParts) {
+			return nil, false
+		}
+		if strings.HasPrefix(part, ":") {
+			params = append(params, gin.Param{Key: part[1:], Value: pathParts[i]})
+			continue
+		}
+		if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, len(patternParts) == len(pathParts)
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/rbac"
+	"github.com/spf13/viper"
+)
+
+func TestExplainAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := rbac.Configure(rbac.Options{AdapterType: rbac.AdapterMemory}); err != nil {
+		t.Fatalf("rbac.Configure() error = %v", err)
+	}
+	server := NewHTTPServer("svc")
+	server.RouteWithPolicy("/orders/:id", "", []string{"POST"}, Admin(
+		WithRequiredRoles("EDITOR"),
+		WithGuard(ForbidRequestFields("user_id")),
+	), func(c *gin.Context) {})
+	for _, route := range server.routes {
+		if err := RbacClient.AddActionPolicies(route.ToRbacPolicy()); err != nil {
+			t.Fatalf("AddActionPolicies() error = %v", err)
+		}
+	}
+	if err := RbacClient.AddGroupingPolicy("EDITOR", "VIEWER"); err != nil {
+		t.Fatalf("AddGroupingPolicy() error = %v", err)
+	}
+	if err := RbacClient.AddTenantGroupingPolicy("alice", "EDITOR", "t1"); err != nil {
+		t.Fatalf("AddTenantGroupingPolicy() error = %v", err)
+	}
+
+	req := ExplainRequest{Subject: "alice", PrincipalType: PrincipalAdmin, Tenant: "t1", Path: "/orders/42", Method: "post"}
+	ans, err := server.ExplainAccess(context.Background(), req)
+	if err != nil || !ans.Allowed || ans.Route != "/orders/:id" {
+		t.Fatalf("ExplainAccess() = %+v, %v", ans, err)
+	}
+	if ans.Rbac == nil || len(ans.Rbac.MatchedPolicy) == 0 {
+		t.Fatalf("rbac explanation missing matched policy: %+v", ans.Rbac)
+	}
+
+	// 所有失败步骤都会记录，原因码取第一个
+	req.PrincipalType = PrincipalCustomer
+	req.Body = []byte(`{"user_id":"9"}`)
+	req.Revoke = []string{"EDITOR"}
+	ans, err = server.ExplainAccess(context.Background(), req)
+	if err != nil || ans.Allowed || ans.Reason != DenyReasonPrincipalType {
+		t.Fatalf("ExplainAccess() = %+v, %v", ans, err)
+	}
+	failed := map[string]string{}
+	for _, step := range ans.Steps {
+		if step.Result == ExplainFail {
+			failed[step.Name] = step.Reason
+		}
+	}
+	if failed["guard:forbid_request_fields"] != DenyReasonGuard || failed["rbac"] != DenyReasonRbacDenied {
+		t.Fatalf("failed steps = %v", failed)
+	}
+	if ok, _ := RbacClient.Enforce(rbac.NewTenantReq("alice", "t1", "svc", "/orders/:id", "POST")); !ok {
+		t.Fatalf("what-if revoke should not change real policies")
+	}
+}
+
+func TestRbacMiddlewareExposesDenyReason(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := rbac.Configure(rbac.Options{AdapterType: rbac.AdapterMemory}); err != nil {
+		t.Fatalf("rbac.Configure() error = %v", err)
+	}
+	engine := gin.New()
+	engine.Use(RbacMiddleware("svc"))
+	engine.GET("/secret", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	serve := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest("GET", "/secret", bytes.NewReader(nil)))
+		return recorder
+	}
+	viper.Set(ConfigKeyExposeDenyReason, false)
+	if recorder := serve(); recorder.Code != http.StatusForbidden || recorder.Header().Get(DenyReasonHeader) != "" {
+		t.Fatalf("status = %d, reason = %q", recorder.Code, recorder.Header().Get(DenyReasonHeader))
+	}
+	viper.Set(ConfigKeyExposeDenyReason, true)
+	defer viper.Set(ConfigKeyExposeDenyReason, false)
+	if recorder := serve(); recorder.Code != http.StatusForbidden || recorder.Header().Get(DenyReasonHeader) != DenyReasonRbacDenied {
+		t.Fatalf("status = %d, reason = %q", recorder.Code, recorder.Header().Get(DenyReasonHeader))
+	}
+	viper.Set("app.env", "production")
+	defer viper.Set("app.env", "")
+	if recorder := serve(); recorder.Header().Get(DenyReasonHeader) != "" {
+		t.Fatalf("production should not expose deny reason")
+	}
+}
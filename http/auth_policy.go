@@ -36,6 +36,8 @@ type AuthPolicy struct {
 	Guards                []Guard
 	FailureMode           FailureMode
 	Description           string
+	Idempotency           *IdempotencyPolicy
+	Condition             string // RBAC 条件表达式，非空时角色授权仅在条件满足时生效
 }
 
 type PolicyOption func(*AuthPolicy)
@@ -1,6 +1,12 @@
 package http
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/orm/audit"
+)
 
 type PrincipalType string
 
@@ -67,3 +73,28 @@ func GetPrincipal(c *gin.Context) (*Principal, bool) {
 	principal, ok := value.(*Principal)
 	return principal, ok && principal != nil
 }
+
+func init() {
+	audit.RegisterResolver(func(ctx context.Context) (audit.Actor, bool) {
+		c, ok := ctx.(*gin.Context)
+		if !ok {
+			return audit.Actor{}, false
+		}
+		return auditActor(c)
+	})
+}
+
+// auditActor 由 Principal 构造 orm 审计插件使用的操作人，匿名主体不记录操作人；
+// 隔离租户只取自主体自身的 TenantCode，不采信客户端可控的 X-Tenant 请求头，
+// 无主体或主体无租户时返回空租户(租户模型的读写由审计插件拒绝)
+func auditActor(c *gin.Context) (audit.Actor, bool) {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		return audit.Actor{}, true
+	}
+	actor := audit.Actor{TenantCode: strings.TrimSpace(principal.TenantCode)}
+	if principal.Type != PrincipalAnonymous {
+		actor.UserID = principal.Subject
+	}
+	return actor, true
+}
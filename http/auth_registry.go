@@ -237,6 +237,12 @@ func ResolvePrincipalFromRequest(c *gin.Context) (*Principal, error) {
 	return nil, errors.New("principal resolver not found")
 }
 
+var (
+	errMissingPrincipal        = errors.New("missing principal")
+	errPrincipalTypeNotAllowed = errors.New("principal type not allowed")
+	errTokenSourceNotAllowed   = errors.New("token source not allowed")
+)
+
 func ValidateAuthPolicy(principal *Principal, policy *AuthPolicy) error {
 	if policy == nil {
 		return nil
@@ -245,33 +251,36 @@ func ValidateAuthPolicy(principal *Principal, policy *AuthPolicy) error {
 		if policy.AllowsAnonymous() {
 			return nil
 		}
-		return errors.New("missing principal")
+		return errMissingPrincipal
 	}
-	if len(policy.AllowedPrincipalTypes) > 0 {
-		match := false
-		for _, allowed := range policy.AllowedPrincipalTypes {
-			if allowed == principal.Type {
-				match = true
-				break
-			}
-		}
-		if !match {
-			return errors.New("principal type not allowed")
-		}
+	if err := checkPrincipalType(principal, policy); err != nil {
+		return err
 	}
-	if len(policy.AllowedTokenSources) > 0 {
-		match := false
-		for _, allowed := range policy.AllowedTokenSources {
-			if strings.EqualFold(allowed, principal.TokenSource) {
-				match = true
-				break
-			}
+	return checkTokenSource(principal, policy)
+}
+
+func checkPrincipalType(principal *Principal, policy *AuthPolicy) error {
+	if len(policy.AllowedPrincipalTypes) == 0 {
+		return nil
+	}
+	for _, allowed := range policy.AllowedPrincipalTypes {
+		if allowed == principal.Type {
+			return nil
 		}
-		if !match {
-			return errors.New("token source not allowed")
+	}
+	return errPrincipalTypeNotAllowed
+}
+
+func checkTokenSource(principal *Principal, policy *AuthPolicy) error {
+	if len(policy.AllowedTokenSources) == 0 {
+		return nil
+	}
+	for _, allowed := range policy.AllowedTokenSources {
+		if strings.EqualFold(allowed, principal.TokenSource) {
+			return nil
 		}
 	}
-	return nil
+	return errTokenSourceNotAllowed
 }
 
 func logAuthResolveFailure(routePath string, err error) {
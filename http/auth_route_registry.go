@@ -26,8 +26,10 @@ type AuthRouteRegistryEntry struct {
 	ResourceScopeWorkspace string    `json:"resource_scope_workspace"`
 	ResourceScopeOwner     string    `json:"resource_scope_owner"`
 	GuardNames             []string  `json:"guard_names"`
+	Condition              string    `json:"condition"`
 	LegacySso              bool      `json:"legacy_sso"`
 	BusinessApproval       bool      `json:"business_approval"`
+	Idempotent             bool      `json:"idempotent"`
 	UpdatedAt              time.Time `json:"updated_at"`
 }
 
@@ -54,6 +56,8 @@ func BuildAuthRouteRegistry(routes []*Route) []AuthRouteRegistryEntry {
 			entry.EnforceRBAC = policy.EnforceRBAC
 			entry.PrincipalTypes = principalTypesToStrings(policy.AllowedPrincipalTypes)
 			entry.GuardNames = guardNames(policy.Guards)
+			entry.Idempotent = policy.Idempotency != nil
+			entry.Condition = policy.Condition
 			if policy.ResourceScope != nil {
 				entry.ResourceScopeTenant = policy.ResourceScope.TenantMode
 				entry.ResourceScopeWorkspace = policy.ResourceScope.WorkspaceMode
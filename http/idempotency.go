@@ -0,0 +1,569 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/log"
+	commonModel "github.com/goodbye-jack/go-common/model"
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/orm/migrate"
+	"github.com/goodbye-jack/go-common/utils"
+	goredis "github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultIdempotencyHeader     = "Idempotency-Key"
+	IdempotencyReplayedHeader    = "Idempotent-Replayed"
+	IdempotencyStatusProcessing  = "processing"
+	IdempotencyStatusCompleted   = "completed"
+	idempotencyKeyRequiredCode   = "IDEMPOTENCY_KEY_REQUIRED"
+	idempotencyInProgressCode    = "IDEMPOTENCY_IN_PROGRESS"
+	idempotencyKeyMismatchCode   = "IDEMPOTENCY_KEY_MISMATCH"
+	idempotencyBodyTooLargeCode  = "IDEMPOTENCY_BODY_TOO_LARGE"
+	idempotencyRedisKeyPrefix    = "http:idempotency:"
+	defaultIdempotencyTTL        = 24 * time.Hour
+	defaultIdempotencyLockTTL    = time.Minute
+	maxIdempotencyKeyLength      = 255
+	maxIdempotencyReplayBodySize = 1 << 20
+	defaultIdempotencyMaxBody    = 10 << 20
+)
+
+// ErrIdempotencyReservationLost 占位已过期并被其他请求重新占用，Complete 不再写入结果
+var ErrIdempotencyReservationLost = errors.New("idempotency reservation is held by another request")
+
+// IdempotencyPolicy 路由级幂等配置，通过 WithIdempotency 挂到 AuthPolicy 上。
+type IdempotencyPolicy struct {
+	HeaderName string        // 幂等键请求头，默认 Idempotency-Key
+	TTL        time.Duration // 已完成响应的保留时长
+	LockTTL    time.Duration // 处理中占位的最长保留时长，防止进程崩溃后永久占用
+	Required   bool          // 缺少幂等键时是否直接拒绝
+	MaxBody    int64         // 计算指纹时读取的请求体上限，超出返回 413，默认 10MB
+}
+
+func (p IdempotencyPolicy) normalize() IdempotencyPolicy {
+	p.HeaderName = strings.TrimSpace(p.HeaderName)
+	if p.HeaderName == "" {
+		p.HeaderName = DefaultIdempotencyHeader
+	}
+	if p.TTL <= 0 {
+		p.TTL = defaultIdempotencyTTL
+	}
+	if p.LockTTL <= 0 {
+		p.LockTTL = defaultIdempotencyLockTTL
+	}
+	if p.MaxBody <= 0 {
+		p.MaxBody = defaultIdempotencyMaxBody
+	}
+	return p
+}
+
+func WithIdempotency(policy IdempotencyPolicy) PolicyOption {
+	return func(p *AuthPolicy) {
+		normalized := policy.normalize()
+		p.Idempotency = &normalized
+	}
+}
+
+// IdempotencyEntry 一次幂等请求的存储内容：请求指纹 + 捕获到的响应。
+type IdempotencyEntry struct {
+	Key         string    `json:"key"`
+	Fingerprint string    `json:"fingerprint"`
+	Status      string    `json:"status"`
+	StatusCode  int       `json:"status_code"`
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	Token       string    `json:"token,omitempty"` // 处理中占位的持有者令牌
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// IdempotencyStore 幂等记录存储。
+// Reserve 在键不存在时写入处理中占位并返回 reserved=true；键已存在时返回已有记录。
+// Complete 只在 entry.Token 仍持有处理中占位时写入结果，否则返回 ErrIdempotencyReservationLost；
+// Release 只删除令牌为 token 的占位，避免占位过期后误删其他请求重新写入的占位。
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, entry IdempotencyEntry, ttl time.Duration) (*IdempotencyEntry, bool, error)
+	Complete(ctx context.Context, entry IdempotencyEntry, ttl time.Duration) error
+	Release(ctx context.Context, key, token string) error
+}
+
+var (
+	idempotencyStoreMu sync.RWMutex
+	idempotencyStore   IdempotencyStore
+)
+
+// SetIdempotencyStore 显式指定全局幂等存储；未指定时按 orm.Redis -> orm.DB 顺序自动选择。
+func SetIdempotencyStore(store IdempotencyStore) {
+	idempotencyStoreMu.Lock()
+	defer idempotencyStoreMu.Unlock()
+	idempotencyStore = store
+}
+
+func resolveIdempotencyStore() IdempotencyStore {
+	idempotencyStoreMu.RLock()
+	store := idempotencyStore
+	idempotencyStoreMu.RUnlock()
+	if store != nil {
+		return store
+	}
+	if orm.Redis != nil {
+		return NewRedisIdempotencyStore(orm.Redis.GetClient())
+	}
+	if orm.DB != nil {
+		return NewGormIdempotencyStore(orm.DB.GetDB())
+	}
+	return nil
+}
+
+// IdempotencyMiddleware 按 主体+路由+幂等键 去重：
+// 首次请求正常执行并保存响应，重复请求直接回放；处理中或请求体不一致的重复请求返回 409。
+func IdempotencyMiddleware(policy IdempotencyPolicy, store IdempotencyStore) gin.HandlerFunc {
+	policy = policy.normalize()
+	return func(c *gin.Context) {
+		if isSafeHTTPMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		rawKey := strings.TrimSpace(c.GetHeader(policy.HeaderName))
+		if rawKey == "" {
+			if policy.Required {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+					"code":    idempotencyKeyRequiredCode,
+					"message": "缺少幂等键请求头 " + policy.HeaderName,
+				})
+				return
+			}
+			c.Next()
+			return
+		}
+		if len(rawKey) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"code":    idempotencyKeyRequiredCode,
+				"message": "幂等键长度超出限制",
+			})
+			return
+		}
+		activeStore := store
+		if activeStore == nil {
+			activeStore = resolveIdempotencyStore()
+		}
+		if activeStore == nil {
+			log.Warnf("idempotency store unavailable, path=%s, key ignored", c.FullPath())
+			c.Next()
+			return
+		}
+
+		var bodyBytes []byte
+		if c.Request.Body != nil {
+			var err error
+			bodyBytes, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, policy.MaxBody))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+						"code":    idempotencyBodyTooLargeCode,
+						"message": "请求体超出幂等校验上限",
+					})
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "读取请求体失败"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		}
+		ctx := c.Request.Context()
+		scopedKey := buildIdempotencyScopedKey(c, rawKey)
+		fingerprint := buildIdempotencyFingerprint(c, bodyBytes)
+		token := newIdempotencyToken()
+		existing, reserved, err := activeStore.Reserve(ctx, IdempotencyEntry{
+			Key:         scopedKey,
+			Fingerprint: fingerprint,
+			Status:      IdempotencyStatusProcessing,
+			Token:       token,
+			ExpiresAt:   time.Now().Add(policy.LockTTL),
+		}, policy.LockTTL)
+		if err != nil {
+			log.Warnf("idempotency reserve failed, path=%s, err=%v", c.FullPath(), err)
+			c.Next()
+			return
+		}
+		if !reserved {
+			respondIdempotencyDuplicate(c, existing, fingerprint)
+			return
+		}
+
+		completed := false
+		defer func() {
+			if !completed {
+				_ = activeStore.Release(context.WithoutCancel(ctx), scopedKey, token)
+			}
+		}()
+		writer := &bodyLogWriter{
+			ResponseWriter: c.Writer,
+			body:           bytes.NewBuffer(nil),
+		}
+		c.Writer = writer
+		c.Next()
+
+		statusCode := c.Writer.Status()
+		if statusCode >= http.StatusInternalServerError || writer.body.Len() > maxIdempotencyReplayBodySize {
+			// 服务端错误允许客户端重试；超大响应不做回放，直接释放占位
+			return
+		}
+		err = activeStore.Complete(context.WithoutCancel(ctx), IdempotencyEntry{
+			Key:         scopedKey,
+			Fingerprint: fingerprint,
+			Status:      IdempotencyStatusCompleted,
+			StatusCode:  statusCode,
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			Body:        append([]byte{}, writer.body.Bytes()...),
+			Token:       token,
+			ExpiresAt:   time.Now().Add(policy.TTL),
+		}, policy.TTL)
+		if errors.Is(err, ErrIdempotencyReservationLost) {
+			// 处理超过 LockTTL，键已被其他请求占用：按冲突处理，不覆盖对方的占位或结果
+			log.Warnf("idempotency conflict, reservation lost before complete, path=%s", c.FullPath())
+			return
+		}
+		if err != nil {
+			log.Warnf("idempotency complete failed, path=%s, err=%v", c.FullPath(), err)
+			return
+		}
+		completed = true
+	}
+}
+
+func respondIdempotencyDuplicate(c *gin.Context, existing *IdempotencyEntry, fingerprint string) {
+	if existing == nil {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"code":    idempotencyInProgressCode,
+			"message": "相同幂等键的请求正在处理中，请稍后重试",
+		})
+		return
+	}
+	if existing.Fingerprint != fingerprint {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"code":    idempotencyKeyMismatchCode,
+			"message": "幂等键已被不同的请求内容使用",
+		})
+		return
+	}
+	if existing.Status != IdempotencyStatusCompleted {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"code":    idempotencyInProgressCode,
+			"message": "相同幂等键的请求正在处理中，请稍后重试",
+		})
+		return
+	}
+	if existing.ContentType != "" {
+		c.Header("Content-Type", existing.ContentType)
+	}
+	c.Header(IdempotencyReplayedHeader, "true")
+	c.Status(existing.StatusCode)
+	_, _ = c.Writer.Write(existing.Body)
+	c.Abort()
+}
+
+func isSafeHTTPMethod(method string) bool {
+	switch strings.ToUpper(strings.TrimSpace(method)) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+func buildIdempotencyScopedKey(c *gin.Context, rawKey string) string {
+	subject := utils.UserAnonymous
+	if principal, ok := GetPrincipal(c); ok && strings.TrimSpace(principal.Subject) != "" {
+		subject = strings.TrimSpace(principal.Subject)
+	} else if user := strings.TrimSpace(c.GetString("UserID")); user != "" {
+		subject = user
+	}
+	routePath := c.FullPath()
+	if routePath == "" {
+		routePath = c.Request.URL.Path
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{subject, strings.ToUpper(c.Request.Method), routePath, rawKey}, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+func newIdempotencyToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+func buildIdempotencyFingerprint(c *gin.Context, bodyBytes []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(strings.ToUpper(c.Request.Method)))
+	hash.Write([]byte("|" + c.Request.URL.Path + "|" + c.Request.URL.RawQuery + "|"))
+	hash.Write(bodyBytes)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ---------------- Redis 存储 ----------------
+
+// idempotencyReleaseScript 令牌匹配时才删除占位，已完成的记录不带令牌不会被删除
+const idempotencyReleaseScript = `local raw = redis.call('GET', KEYS[1])
+if raw and cjson.decode(raw).token == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+// idempotencyCompleteScript 令牌仍持有处理中占位时才写入结果，避免覆盖其他请求重新写入的占位
+const idempotencyCompleteScript = `local raw = redis.call('GET', KEYS[1])
+if not raw then
+	return 0
+end
+local current = cjson.decode(raw)
+if current.status ~= ARGV[1] or current.token ~= ARGV[2] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[3], 'PX', ARGV[4])
+return 1`
+
+type redisIdempotencyStore struct {
+	client goredis.UniversalClient
+}
+
+func NewRedisIdempotencyStore(client goredis.UniversalClient) IdempotencyStore {
+	return &redisIdempotencyStore{client: client}
+}
+
+func (s *redisIdempotencyStore) Reserve(ctx context.Context, entry IdempotencyEntry, ttl time.Duration) (*IdempotencyEntry, bool, error) {
+	if s == nil || s.client == nil {
+		return nil, false, errors.New("idempotency redis client is nil")
+	}
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := s.client.SetNX(ctx, idempotencyRedisKeyPrefix+entry.Key, payload, ttl).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		return nil, true, nil
+	}
+	raw, err := s.client.Get(ctx, idempotencyRedisKeyPrefix+entry.Key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		// 占位恰好过期，按处理中返回，让客户端重试
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	existing := &IdempotencyEntry{}
+	if err := json.Unmarshal(raw, existing); err != nil {
+		return nil, false, err
+	}
+	return existing, false, nil
+}
+
+func (s *redisIdempotencyStore) Complete(ctx context.Context, entry IdempotencyEntry, ttl time.Duration) error {
+	if s == nil || s.client == nil {
+		return errors.New("idempotency redis client is nil")
+	}
+	token := entry.Token
+	entry.Token = ""
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	written, err := s.client.Eval(ctx, idempotencyCompleteScript, []string{idempotencyRedisKeyPrefix + entry.Key},
+		IdempotencyStatusProcessing, token, payload, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if written == 0 {
+		return ErrIdempotencyReservationLost
+	}
+	return nil
+}
+
+func (s *redisIdempotencyStore) Release(ctx context.Context, key, token string) error {
+	if s == nil || s.client == nil {
+		return nil
+	}
+	return s.client.Eval(ctx, idempotencyReleaseScript, []string{idempotencyRedisKeyPrefix + key}, token).Err()
+}
+
+// ---------------- 数据库存储 ----------------
+
+type IdempotencyRecord struct {
+	commonModel.ModelBase
+	IdempotencyKey string `gorm:"size:64;uniqueIndex" json:"idempotency_key"`
+	Fingerprint    string `gorm:"size:64" json:"fingerprint"`
+	Status         string `gorm:"size:16;index" json:"status"`
+	Token          string `gorm:"size:32" json:"-"`
+	StatusCode     int    `json:"status_code"`
+	ContentType    string `gorm:"size:128" json:"content_type"`
+	Body           []byte `json:"body"`
+	ExpiresAtUnix  int64  `gorm:"index" json:"expires_at_unix"`
+}
+
+func (IdempotencyRecord) TableName() string {
+	return "http_idempotency_records"
+}
+
+// IdempotencyMigrationModule 幂等记录表的迁移模块
+const IdempotencyMigrationModule = "http_idempotency"
+
+func init() {
+	migrate.Register(IdempotencyMigrationModule, migrate.List{
+		{Version: "202610180001", Name: "create_http_idempotency_records", Models: []any{&IdempotencyRecord{}}},
+	})
+}
+
+type gormIdempotencyStore struct {
+	db *gorm.DB
+}
+
+func NewGormIdempotencyStore(db *gorm.DB) IdempotencyStore {
+	return &gormIdempotencyStore{db: db}
+}
+
+// ensureTable 以版本化迁移建表，migrations.mode=verify 时只校验
+func (s *gormIdempotencyStore) ensureTable(ctx context.Context) error {
+	return migrate.ApplyModule(ctx, s.db, IdempotencyMigrationModule)
+}
+
+func (s *gormIdempotencyStore) Reserve(ctx context.Context, entry IdempotencyEntry, ttl time.Duration) (*IdempotencyEntry, bool, error) {
+	if s == nil || s.db == nil {
+		return nil, false, errors.New("idempotency db is nil")
+	}
+	if err := s.ensureTable(ctx); err != nil {
+		return nil, false, err
+	}
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+	// 过期记录物理删除，保证唯一索引可以被重新占用
+	if err := db.Unscoped().Where("idempotency_key = ? AND expires_at_unix <= ?", entry.Key, now.Unix()).Delete(&IdempotencyRecord{}).Error; err != nil {
+		return nil, false, err
+	}
+	record := &IdempotencyRecord{
+		IdempotencyKey: entry.Key,
+		Fingerprint:    entry.Fingerprint,
+		Status:         IdempotencyStatusProcessing,
+		Token:          entry.Token,
+		ExpiresAtUnix:  now.Add(ttl).Unix(),
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil, true, nil
+	}
+	existing := &IdempotencyRecord{}
+	if err := db.Where("idempotency_key = ?", entry.Key).First(existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return idempotencyRecordToEntry(existing), false, nil
+}
+
+func (s *gormIdempotencyStore) Complete(ctx context.Context, entry IdempotencyEntry, ttl time.Duration) error {
+	if s == nil || s.db == nil {
+		return errors.New("idempotency db is nil")
+	}
+	result := s.db.WithContext(ctx).Model(&IdempotencyRecord{}).
+		Where("idempotency_key = ? AND token = ? AND status = ?", entry.Key, entry.Token, IdempotencyStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":          IdempotencyStatusCompleted,
+			"token":           "",
+			"status_code":     entry.StatusCode,
+			"content_type":    entry.ContentType,
+			"body":            entry.Body,
+			"expires_at_unix": time.Now().Add(ttl).Unix(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdempotencyReservationLost
+	}
+	return nil
+}
+
+func (s *gormIdempotencyStore) Release(ctx context.Context, key, token string) error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.WithContext(ctx).Unscoped().
+		Where("idempotency_key = ? AND status = ? AND token = ?", key, IdempotencyStatusProcessing, token).
+		Delete(&IdempotencyRecord{}).Error
+}
+
+func idempotencyRecordToEntry(record *IdempotencyRecord) *IdempotencyEntry {
+	return &IdempotencyEntry{
+		Key:         record.IdempotencyKey,
+		Fingerprint: record.Fingerprint,
+		Status:      record.Status,
+		StatusCode:  record.StatusCode,
+		ContentType: record.ContentType,
+		Body:        record.Body,
+		ExpiresAt:   time.Unix(record.ExpiresAtUnix, 0),
+	}
+}
+
+// ---------------- 内存存储（单实例/测试） ----------------
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]IdempotencyEntry
+}
+
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{entries: map[string]IdempotencyEntry{}}
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, entry IdempotencyEntry, ttl time.Duration) (*IdempotencyEntry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.entries[entry.Key]; ok && time.Now().Before(existing.ExpiresAt) {
+		copied := existing
+		return &copied, false, nil
+	}
+	entry.ExpiresAt = time.Now().Add(ttl)
+	s.entries[entry.Key] = entry
+	return nil, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, entry IdempotencyEntry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.entries[entry.Key]
+	if !ok || existing.Status != IdempotencyStatusProcessing || existing.Token != entry.Token || !time.Now().Before(existing.ExpiresAt) {
+		return ErrIdempotencyReservationLost
+	}
+	entry.Token = ""
+	entry.ExpiresAt = time.Now().Add(ttl)
+	s.entries[entry.Key] = entry
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.entries[key]; ok && existing.Status == IdempotencyStatusProcessing && existing.Token == token {
+		delete(s.entries, key)
+	}
+	return nil
+}
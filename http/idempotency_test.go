@@ -0,0 +1,225 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newIdempotencyTestEngine(store IdempotencyStore, calls *int) *gin.Engine {
+	engine := gin.New()
+	engine.POST("/orders", IdempotencyMiddleware(IdempotencyPolicy{}, store), func(c *gin.Context) {
+		*calls++
+		c.JSON(http.StatusCreated, gin.H{"order_no": "O-1", "calls": *calls})
+	})
+	return engine
+}
+
+func doIdempotentPost(engine *gin.Engine, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/orders", bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(DefaultIdempotencyHeader, key)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestIdempotencyMiddlewareReplaysStoredResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	engine := newIdempotencyTestEngine(NewMemoryIdempotencyStore(), &calls)
+
+	first := doIdempotentPost(engine, "k-1", `{"amount":1}`)
+	second := doIdempotentPost(engine, "k-1", `{"amount":1}`)
+
+	if calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Fatalf("replay = %d %s, want %d %s", second.Code, second.Body.String(), first.Code, first.Body.String())
+	}
+	if second.Header().Get(IdempotencyReplayedHeader) != "true" {
+		t.Fatalf("replayed header missing")
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsMismatchedPayload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	engine := newIdempotencyTestEngine(NewMemoryIdempotencyStore(), &calls)
+
+	doIdempotentPost(engine, "k-2", `{"amount":1}`)
+	recorder := doIdempotentPost(engine, "k-2", `{"amount":2}`)
+
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409", recorder.Code)
+	}
+	if calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsInFlightDuplicate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := NewMemoryIdempotencyStore()
+	engine := gin.New()
+	var inner *httptest.ResponseRecorder
+	engine.POST("/orders", IdempotencyMiddleware(IdempotencyPolicy{}, store), func(c *gin.Context) {
+		if inner == nil {
+			inner = doIdempotentPost(engine, "k-3", `{}`)
+		}
+		c.Status(http.StatusOK)
+	})
+
+	doIdempotentPost(engine, "k-3", `{}`)
+	if inner == nil || inner.Code != http.StatusConflict {
+		t.Fatalf("in-flight duplicate should be rejected with 409")
+	}
+}
+
+func TestIdempotencyMiddlewareRequiredKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/orders", IdempotencyMiddleware(IdempotencyPolicy{Required: true}, NewMemoryIdempotencyStore()), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	recorder := doIdempotentPost(engine, "", `{}`)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", recorder.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareReleasesOnServerError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	engine := gin.New()
+	engine.POST("/orders", IdempotencyMiddleware(IdempotencyPolicy{}, NewMemoryIdempotencyStore()), func(c *gin.Context) {
+		calls++
+		c.Status(http.StatusInternalServerError)
+	})
+
+	doIdempotentPost(engine, "k-4", `{}`)
+	doIdempotentPost(engine, "k-4", `{}`)
+	if calls != 2 {
+		t.Fatalf("handler calls = %d, want 2", calls)
+	}
+}
+
+func TestIdempotencyMiddlewareLimitsBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	engine := gin.New()
+	engine.POST("/orders", IdempotencyMiddleware(IdempotencyPolicy{MaxBody: 8}, NewMemoryIdempotencyStore()), func(c *gin.Context) {
+		calls++
+		c.Status(http.StatusOK)
+	})
+
+	recorder := doIdempotentPost(engine, "k-6", `{"amount":100}`)
+	if recorder.Code != http.StatusRequestEntityTooLarge || calls != 0 {
+		t.Fatalf("status = %d, calls = %d, want 413 without calling handler", recorder.Code, calls)
+	}
+}
+
+func TestIdempotencyStoreReleaseChecksToken(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	ctx := context.Background()
+	for name, store := range map[string]IdempotencyStore{"memory": NewMemoryIdempotencyStore(), "gorm": NewGormIdempotencyStore(db)} {
+		entry := IdempotencyEntry{Key: "k-7", Fingerprint: "fp", Status: IdempotencyStatusProcessing, Token: "owner-b", ExpiresAt: time.Now().Add(time.Minute)}
+		if _, reserved, err := store.Reserve(ctx, entry, time.Minute); err != nil || !reserved {
+			t.Fatalf("%s Reserve() = %v, %v", name, reserved, err)
+		}
+		// 占位过期后被其他请求接管，原持有者随后释放不能删除新的占位
+		if err := store.Release(ctx, "k-7", "owner-a"); err != nil {
+			t.Fatalf("%s Release() error = %v", name, err)
+		}
+		if _, reserved, _ := store.Reserve(ctx, entry, time.Minute); reserved {
+			t.Fatalf("%s reservation released by a stale token", name)
+		}
+		if err := store.Release(ctx, "k-7", "owner-b"); err != nil {
+			t.Fatalf("%s Release() error = %v", name, err)
+		}
+		if _, reserved, _ := store.Reserve(ctx, entry, time.Minute); !reserved {
+			t.Fatalf("%s reservation should be released by its owner", name)
+		}
+	}
+}
+
+func TestIdempotencyStoreCompleteChecksToken(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	ctx := context.Background()
+	for name, store := range map[string]IdempotencyStore{"memory": NewMemoryIdempotencyStore(), "gorm": NewGormIdempotencyStore(db)} {
+		stale := IdempotencyEntry{Key: "k-8", Fingerprint: "fp", Status: IdempotencyStatusProcessing, Token: "owner-a"}
+		if _, reserved, err := store.Reserve(ctx, stale, time.Second); err != nil || !reserved {
+			t.Fatalf("%s Reserve() = %v, %v", name, reserved, err)
+		}
+		// 原占位过期后被 owner-b 重新占用
+		time.Sleep(1100 * time.Millisecond)
+		fresh := stale
+		fresh.Token = "owner-b"
+		if _, reserved, err := store.Reserve(ctx, fresh, time.Minute); err != nil || !reserved {
+			t.Fatalf("%s re-Reserve() = %v, %v", name, reserved, err)
+		}
+		done := IdempotencyEntry{Key: "k-8", Fingerprint: "fp", Status: IdempotencyStatusCompleted, StatusCode: http.StatusCreated, Token: "owner-a"}
+		if err := store.Complete(ctx, done, time.Minute); !errors.Is(err, ErrIdempotencyReservationLost) {
+			t.Fatalf("%s stale Complete() error = %v, want ErrIdempotencyReservationLost", name, err)
+		}
+		existing, _, _ := store.Reserve(ctx, fresh, time.Minute)
+		if existing == nil || existing.Status != IdempotencyStatusProcessing {
+			t.Fatalf("%s reservation of owner-b overwritten: %+v", name, existing)
+		}
+		done.Token = "owner-b"
+		if err := store.Complete(ctx, done, time.Minute); err != nil {
+			t.Fatalf("%s owner Complete() error = %v", name, err)
+		}
+		existing, _, _ = store.Reserve(ctx, fresh, time.Minute)
+		if existing == nil || existing.Status != IdempotencyStatusCompleted || existing.StatusCode != http.StatusCreated {
+			t.Fatalf("%s completed entry = %+v", name, existing)
+		}
+	}
+}
+
+func TestGormIdempotencyStoreReplays(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	calls := 0
+	engine := newIdempotencyTestEngine(NewGormIdempotencyStore(db), &calls)
+
+	first := doIdempotentPost(engine, "k-5", `{"amount":1}`)
+	second := doIdempotentPost(engine, "k-5", `{"amount":1}`)
+	if calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Fatalf("replay body = %s, want %s", second.Body.String(), first.Body.String())
+	}
+}
+
+func TestWithIdempotencyAddsRouteMiddleware(t *testing.T) {
+	route := NewRouteWithPolicy("svc", "/orders", "", []string{"POST"}, AnyUser(WithIdempotency(IdempotencyPolicy{})), func(c *gin.Context) {})
+	if route.AuthPolicy.Idempotency == nil || route.AuthPolicy.Idempotency.HeaderName != DefaultIdempotencyHeader {
+		t.Fatalf("Idempotency = %+v", route.AuthPolicy.Idempotency)
+	}
+	if len(route.GetHandlersChain()) != 2 {
+		t.Fatalf("handler chain length = %d, want 2", len(route.GetHandlersChain()))
+	}
+}
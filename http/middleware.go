@@ -5,8 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/approval"
 	"github.com/goodbye-jack/go-common/config"
 	"github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/mask"
+	"github.com/goodbye-jack/go-common/orm/audit"
 	"github.com/goodbye-jack/go-common/rbac"
 	"github.com/goodbye-jack/go-common/utils"
 	"io/ioutil"
@@ -54,18 +57,21 @@ func RbacMiddleware(serviceName string) gin.HandlerFunc {
 			}
 		}
 		user := GetUser(c)
-		req := rbac.NewReq(
+		req := rbac.NewTenantReq(
 			user,
+			ResolveRequestTenant(c),
 			serviceName,
 			routePath,
 			c.Request.Method,
-		)
+		).WithAttributeLoader(func() rbac.Attributes { return requestAttributes(c) })
 		ok, err := RbacClient.Enforce(req)
 		if err != nil {
-			log.Errorf("RbacMiddleware/Enforce(%v), %v", *req, err)
+			log.Errorf("RbacMiddleware/Enforce(%v), %v", req.ToArr(), err)
+			abortDenied(c, http.StatusForbidden, DenyReasonRbacError)
+			return
 		}
 		if !ok {
-			c.AbortWithStatus(http.StatusForbidden)
+			abortDenied(c, http.StatusForbidden, DenyReasonRbacDenied)
 			return
 		}
 		c.Next()
@@ -113,11 +119,11 @@ func handleRouteAuthPolicy(c *gin.Context, route *Route) {
 		return
 	}
 
-	principal, err := ResolvePrincipalFromRequest(c)
+	principal, err := resolveRequestPrincipal(c)
 	if err != nil {
 		logAuthResolveFailure(route.Url, err)
 		if policy.RequireAuth {
-			c.AbortWithStatus(http.StatusUnauthorized)
+			abortDenied(c, http.StatusUnauthorized, DenyReasonUnauthenticated)
 			return
 		}
 		principal = NewAnonymousPrincipal()
@@ -125,13 +131,13 @@ func handleRouteAuthPolicy(c *gin.Context, route *Route) {
 
 	if principal == nil {
 		if policy.RequireAuth {
-			c.AbortWithStatus(http.StatusUnauthorized)
+			abortDenied(c, http.StatusUnauthorized, DenyReasonUnauthenticated)
 			return
 		}
 		principal = NewAnonymousPrincipal()
 	}
 	if !runLegacySsoVerification(c, route) {
-		c.AbortWithStatus(http.StatusUnauthorized)
+		abortDenied(c, http.StatusUnauthorized, DenyReasonSsoRejected)
 		return
 	}
 
@@ -145,14 +151,14 @@ func handleRouteAuthPolicy(c *gin.Context, route *Route) {
 			}
 		}
 		log.Warnf("auth policy denied, path=%s, principal_type=%s, token_source=%s, err=%v", route.Url, principalType, tokenSource, err)
-		statusCode := http.StatusForbidden
+		statusCode, reason := http.StatusForbidden, denyReasonOf(err)
 		if principal.Type == PrincipalAnonymous {
-			statusCode = http.StatusUnauthorized
+			statusCode, reason = http.StatusUnauthorized, DenyReasonUnauthenticated
 		}
 		if policy.FailureMode == FailureModeUnauthorized {
 			statusCode = http.StatusUnauthorized
 		}
-		c.AbortWithStatus(statusCode)
+		abortDenied(c, statusCode, reason)
 		return
 	}
 
@@ -163,6 +169,26 @@ func handleRouteAuthPolicy(c *gin.Context, route *Route) {
 	c.Next()
 }
 
+// resolveRequestPrincipal 审批回放请求不携带凭证，直接还原原申请人的 Principal
+func resolveRequestPrincipal(c *gin.Context) (*Principal, error) {
+	info := approval.ReplayFromContext(c.Request.Context())
+	if info == nil {
+		return ResolvePrincipalFromRequest(c)
+	}
+	if len(info.Principal) == 0 {
+		return nil, nil
+	}
+	principal := &Principal{}
+	if err := json.Unmarshal(info.Principal, principal); err != nil {
+		return nil, err
+	}
+	if principal.Attributes == nil {
+		principal.Attributes = map[string]any{}
+	}
+	principal.Attributes["approval_request_id"] = info.RequestID
+	return principal, nil
+}
+
 func validateRoutePolicy(c *gin.Context, principal *Principal, route *Route) error {
 	if route == nil {
 		return nil
@@ -179,7 +205,7 @@ func validateRoutePolicy(c *gin.Context, principal *Principal, route *Route) err
 			continue
 		}
 		if err := guard.Check(c, principal); err != nil {
-			return err
+			return &guardError{name: guard.Name(), err: err}
 		}
 	}
 	return nil
@@ -214,17 +240,26 @@ func getCurrentRoute(c *gin.Context) *Route {
 	return route
 }
 
+// TenantMiddleware 解析请求租户(Principal.TenantCode 优先，其次 X-Tenant 请求头)写入上下文，
+// 并携带 audit.Actor 供 orm 审计插件填充创建人/租户、隔离查询；请求头与登录租户不一致时拒绝。
+// 审计隔离租户只取自 Principal，仅凭请求头声明的租户不能读写租户模型
 func TenantMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log.Info("TenantMiddleware()")
-		tenant := c.Request.Header.Get(utils.TenantHeaderName)
-
+		header := strings.TrimSpace(c.Request.Header.Get(utils.TenantHeaderName))
+		tenant := ResolveRequestTenant(c)
+		if header != "" && header != tenant {
+			log.Warnf("tenant header mismatch, path=%s, header=%s, principal_tenant=%s", c.Request.URL.Path, header, tenant)
+			abortDenied(c, http.StatusForbidden, DenyReasonTenantMismatch)
+			return
+		}
+		c.Set(utils.TenantContextName, tenant)
 		ctx := context.WithValue(
 			c.Request.Context(),
 			utils.TenantContextName,
 			tenant,
 		)
-
+		actor, _ := auditActor(c)
+		ctx = audit.WithActor(ctx, actor)
 		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
@@ -465,45 +500,20 @@ func buildResponseContent(contentType string, bodyBytes []byte) string {
 	return "[binary response omitted]"
 }
 
+// maskSensitiveHeaderValue 按 mask 全局规则脱敏请求头，Authorization/Cookie 等整体隐藏
 func maskSensitiveHeaderValue(key string, value string) string {
-	lowerKey := strings.ToLower(strings.TrimSpace(key))
-	switch lowerKey {
-	case "authorization", "cookie", "set-cookie":
-		if strings.TrimSpace(value) == "" {
-			return value
-		}
-		return "***"
-	default:
+	if strings.TrimSpace(value) == "" {
 		return value
 	}
-}
-
-func sanitizePayload(value interface{}) interface{} {
-	switch typedValue := value.(type) {
-	case map[string]interface{}:
-		result := make(map[string]interface{}, len(typedValue))
-		for key, item := range typedValue {
-			if isSensitiveField(key) {
-				result[key] = "***"
-				continue
-			}
-			result[key] = sanitizePayload(item)
-		}
-		return result
-	case []interface{}:
-		result := make([]interface{}, len(typedValue))
-		for idx, item := range typedValue {
-			result[idx] = sanitizePayload(item)
-		}
-		return result
-	default:
-		return typedValue
+	if view, ok := mask.ViewOf(key); ok {
+		return mask.Mask(view, value)
 	}
+	return value
 }
 
-func isSensitiveField(key string) bool {
-	lowerKey := strings.ToLower(strings.TrimSpace(key))
-	return strings.Contains(lowerKey, "password") || strings.Contains(lowerKey, "pwd") || strings.Contains(lowerKey, "token") || strings.Contains(lowerKey, "secret")
+// sanitizePayload 按 mask 全局规则脱敏请求体，业务可通过 mask.Register 追加字段
+func sanitizePayload(value interface{}) interface{} {
+	return mask.Payload(value)
 }
 
 func sanitizeRawText(text string) (string, bool) {
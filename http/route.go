@@ -7,6 +7,7 @@ import (
 	"github.com/goodbye-jack/go-common/rbac"
 	"github.com/goodbye-jack/go-common/utils"
 	"strings"
+	"sync"
 )
 
 type Route struct {
@@ -23,6 +24,35 @@ type Route struct {
 	handlerFunc      gin.HandlerFunc   // 主处理函数
 	BusinessApproval bool              // 是否需要业务审批
 	middlewares      []gin.HandlerFunc // 中间件链(新增)
+	approvalAttached bool              // 是否已挂载业务审批中间件
+	// resolveRoles 按当前角色目录重新计算 DefaultRoles，角色目录热加载后调用；false 表示声明的角色已不在目录中
+	resolveRoles func() ([]string, bool)
+}
+
+// routeRolesMu 保护角色目录热加载时对 DefaultRoles 的替换
+var routeRolesMu sync.RWMutex
+
+// defaultRoles 读取 DefaultRoles，与角色目录热加载并发安全
+func (r *Route) defaultRoles() []string {
+	routeRolesMu.RLock()
+	defer routeRolesMu.RUnlock()
+	return r.DefaultRoles
+}
+
+// refreshDefaultRoles 按当前角色目录重新计算 DefaultRoles；声明的角色已不在目录中时保留原值，
+// 避免路由因角色被移除而退化为匿名可访问
+func (r *Route) refreshDefaultRoles() {
+	if r.resolveRoles == nil {
+		return
+	}
+	roles, ok := r.resolveRoles()
+	if !ok {
+		log.Errorf("角色目录重新加载后路由声明的角色已不存在，保留原默认角色：URL=%s, roles=%v", r.Url, r.defaultRoles())
+		return
+	}
+	routeRolesMu.Lock()
+	r.DefaultRoles = roles
+	routeRolesMu.Unlock()
 }
 
 // GenUniqueKey 生成路由唯一键（URL+Method）
@@ -30,7 +60,10 @@ func (m *Route) GenUniqueKey(method string) string {
 	return fmt.Sprintf("%s-%s", strings.TrimSuffix(m.Url, "/"), strings.ToUpper(method))
 }
 
+// Deprecated: 路由角色改由 rbac.DefaultRoleCatalog() 解析，此处仅保留内置角色的旧映射供外部代码兼容，不再参与路由鉴权
 var RoleMapping = map[string][]string{}
+
+// Deprecated: 使用 rbac.DefaultRoleCatalog().Resolve 解析角色别名
 var RoleMappingPrecise = map[string]string{}
 
 func init() {
@@ -57,21 +90,22 @@ func NewRoute(service_name string, url string, tips string, methods []string, ro
 	if len(methods) == 0 {
 		log.Fatal("NewRoute methods is empty")
 	}
-	if _, ok := RoleMapping[role]; !ok {
+	defaultRoles, ok := rbac.DefaultRoleCatalog().RouteRoles(role)
+	if !ok {
 		log.Fatalf("the role %v is invalid", role)
 	}
 	internalRole, err := rbac.EnsureInternalRole(resource, action)
 	if err != nil {
 		log.Fatalf("ensure internal role error, %v", err)
 	}
-	return &Route{
+	route := &Route{
 		ServiceName:      service_name,
 		Tips:             tips,
 		Sso:              sso,
 		AuthPolicy:       nil,
 		Url:              url,
 		Methods:          methods,
-		DefaultRoles:     RoleMapping[role],
+		DefaultRoles:     defaultRoles,
 		Resource:         resource,
 		Action:           action,
 		InternalRole:     internalRole,
@@ -79,24 +113,22 @@ func NewRoute(service_name string, url string, tips string, methods []string, ro
 		BusinessApproval: business_approval,
 		middlewares:      []gin.HandlerFunc{}, // 初始化空中间件链
 	}
+	route.resolveRoles = func() ([]string, bool) {
+		return rbac.DefaultRoleCatalog().RouteRoles(role)
+	}
+	return route
 }
 
 func NewRouteForRA(serviceName string, url string, tips string, methods []string, roles []string, resource string, action string, sso bool, businessApproval bool, handlerFunc gin.HandlerFunc) *Route {
 	if len(methods) == 0 {
 		log.Fatal("NewRoute methods is empty")
 	}
-	var newRoles []string
-	for _, role := range roles {
-		if _, ok := rbac.GetRoleMapping(role); ok { // 代表权限在初始化的权限角色中,
-			// 可以进行访问,这块应该是脱离common 的 但是还是等后面重新设计吧 OK
-			newRoles = append(newRoles, role)
-		}
-	}
+	newRoles, _ := knownRouteRoles(roles)
 	internalRole, err := rbac.EnsureInternalRole(resource, action)
 	if err != nil {
 		log.Fatalf("ensure internal role error, %v", err)
 	}
-	return &Route{
+	route := &Route{
 		ServiceName:      serviceName,
 		Tips:             tips,
 		Sso:              sso,
@@ -111,24 +143,22 @@ func NewRouteForRA(serviceName string, url string, tips string, methods []string
 		BusinessApproval: businessApproval,
 		middlewares:      []gin.HandlerFunc{}, // 初始化空中间件链
 	}
+	route.resolveRoles = func() ([]string, bool) {
+		return knownRouteRoles(roles)
+	}
+	return route
 }
 
 func NewRouteCommon(serviceName string, url string, tips string, methods []string, roles []string, resource string, action string, sso bool, businessApproval bool, handlerFunc gin.HandlerFunc) *Route {
 	if len(methods) == 0 {
 		log.Fatal("NewRoute methods is empty")
 	}
-	var newRoles []string
-	for _, role := range roles {
-		if _, ok := rbac.GetRoleMapping(role); ok { // 代表权限在初始化的权限角色中,
-			// 可以进行访问,这块应该是脱离common 的 但是还是等后面重新设计吧 OK
-			newRoles = append(newRoles, role)
-		}
-	}
+	newRoles, _ := knownRouteRoles(roles)
 	internalRole, err := rbac.EnsureInternalRole(resource, action)
 	if err != nil {
 		log.Fatalf("ensure internal role error, %v", err)
 	}
-	return &Route{
+	route := &Route{
 		ServiceName:      serviceName,
 		Tips:             tips,
 		Sso:              sso,
@@ -143,6 +173,22 @@ func NewRouteCommon(serviceName string, url string, tips string, methods []strin
 		BusinessApproval: businessApproval,
 		middlewares:      []gin.HandlerFunc{}, // 初始化空中间件链
 	}
+	route.resolveRoles = func() ([]string, bool) {
+		return knownRouteRoles(roles)
+	}
+	return route
+}
+
+// knownRouteRoles 只保留角色目录中的角色，并展开其下级角色；声明了角色但都不在目录中时返回 false
+func knownRouteRoles(roles []string) ([]string, bool) {
+	catalog := rbac.DefaultRoleCatalog()
+	var known []string
+	for _, role := range roles {
+		if _, ok := catalog.Resolve(role); ok {
+			known = append(known, role)
+		}
+	}
+	return catalog.ExpandRoles(known), len(known) > 0 || len(roles) == 0
 }
 
 // AddMiddleware 添加中间件到路由
@@ -179,25 +225,32 @@ func (r *Route) ToRbacPolicy() []rbac.Policy {
 	if policy != nil && !policy.EnforceRBAC {
 		return nil
 	}
+	newPolicy := rbac.NewActionPolicy
+	if cond := routeCondition(r); cond != "" {
+		newPolicy = func(dom, sub, obj, act string) rbac.Policy {
+			return rbac.NewConditionalActionPolicy(dom, sub, obj, act, cond)
+		}
+	}
 	var ans []rbac.Policy
 	for _, method := range r.Methods {
 		if r.InternalRole != "" {
 			ans = append(
 				ans,
-				rbac.NewActionPolicy(r.ServiceName, r.InternalRole, r.Url, method),
+				newPolicy(r.ServiceName, r.InternalRole, r.Url, method),
 			)
 			continue
 		}
-		if len(r.DefaultRoles) == 0 {
+		defaultRoles := r.defaultRoles()
+		if len(defaultRoles) == 0 {
 			ans = append(
 				ans,
-				rbac.NewActionPolicy(r.ServiceName, utils.UserAnonymous, r.Url, method),
+				newPolicy(r.ServiceName, utils.UserAnonymous, r.Url, method),
 			)
 		}
-		for _, role := range r.DefaultRoles {
+		for _, role := range defaultRoles {
 			ans = append(
 				ans,
-				rbac.NewActionPolicy(r.ServiceName, role, r.Url, method),
+				newPolicy(r.ServiceName, role, r.Url, method),
 			)
 		}
 	}
@@ -215,7 +268,7 @@ func (r *Route) EffectiveAuthPolicy() *AuthPolicy {
 		Name:           "legacy",
 		RequireAuth:    r.Sso,
 		AllowAnonymous: !r.Sso,
-		RequiredRoles:  append([]string{}, r.DefaultRoles...),
+		RequiredRoles:  append([]string{}, r.defaultRoles()...),
 		EnforceRBAC:    true,
 		FailureMode:    FailureModeUnauthorized,
 		Description:    "legacy route policy inferred from RouteAPI",
@@ -231,7 +284,6 @@ func NewRouteWithPolicy(serviceName string, url string, tips string, methods []s
 		AuthPolicy:       &policy,
 		Url:              url,
 		Methods:          methods,
-		DefaultRoles:     append([]string{}, policy.RequiredRoles...),
 		Resource:         "",
 		Action:           "",
 		InternalRole:     "",
@@ -239,8 +291,16 @@ func NewRouteWithPolicy(serviceName string, url string, tips string, methods []s
 		BusinessApproval: false,
 		middlewares:      []gin.HandlerFunc{},
 	}
-	if len(route.DefaultRoles) == 0 && policy.EnforceRBAC {
-		route.DefaultRoles = []string{utils.UserAnonymous}
+	route.resolveRoles = func() ([]string, bool) {
+		roles := rbac.DefaultRoleCatalog().ExpandRoles(policy.RequiredRoles)
+		if len(roles) == 0 && policy.EnforceRBAC {
+			roles = []string{utils.UserAnonymous}
+		}
+		return roles, true
+	}
+	route.DefaultRoles, _ = route.resolveRoles()
+	if policy.Idempotency != nil { // 幂等中间件需在其他路由中间件(审批/changeguard)之前挂载
+		route.AddMiddleware(IdempotencyMiddleware(*policy.Idempotency, nil))
 	}
 	return route
 }
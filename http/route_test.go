@@ -0,0 +1,76 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/rbac"
+)
+
+func TestRoleCatalogReloadResyncsRoutePolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Chdir(t.TempDir()) // 重新同步时会在工作目录写入路由快照
+	if err := rbac.Configure(rbac.Options{AdapterType: rbac.AdapterMemory}); err != nil {
+		t.Fatalf("rbac.Configure() error = %v", err)
+	}
+	original := rbac.DefaultRoleCatalog()
+	defer rbac.SetRoleCatalog(original)
+	setCatalog := func(extra ...rbac.RoleDefinition) {
+		catalog, err := rbac.NewRoleCatalog(append(original.Roles(), extra...)...)
+		if err != nil {
+			t.Fatalf("NewRoleCatalog() error = %v", err)
+		}
+		rbac.SetRoleCatalog(catalog)
+	}
+	setCatalog(rbac.RoleDefinition{Code: "REVIEWER"})
+
+	server := NewHTTPServer("svc-catalog")
+	server.Route("/reports", []string{"GET"}, "REVIEWER", "", "", true, func(c *gin.Context) {})
+	server.RouteAPI("/audits", "", []string{"GET"}, []string{"REVIEWER"}, "", "", true, false, func(c *gin.Context) {})
+	server.syncRbacPolicies()
+	server.prepared.Store(true)
+	if err := RbacClient.AddTenantGroupingPolicy("bob", "AUDITOR", "t1"); err != nil {
+		t.Fatalf("AddTenantGroupingPolicy() error = %v", err)
+	}
+	if ok, _ := RbacClient.Enforce(rbac.NewTenantReq("bob", "t1", "svc-catalog", "/reports", "GET")); ok {
+		t.Fatalf("AUDITOR should not access /reports before it joins the catalog")
+	}
+
+	setCatalog(rbac.RoleDefinition{Code: "REVIEWER"}, rbac.RoleDefinition{Code: "AUDITOR", Parents: []string{"REVIEWER"}})
+	for _, path := range []string{"/reports", "/audits"} {
+		if ok, err := RbacClient.Enforce(rbac.NewTenantReq("bob", "t1", "svc-catalog", path, "GET")); !ok {
+			t.Fatalf("AUDITOR should access %s after catalog reload, err = %v", path, err)
+		}
+	}
+
+	// 路由声明的角色被移出目录时保留原默认角色，不能退化为匿名可访问
+	setCatalog()
+	for _, route := range server.routes {
+		if route.Url == "/audits" && len(route.defaultRoles()) != 2 {
+			t.Fatalf("roles of /audits = %v, want previous roles kept", route.defaultRoles())
+		}
+	}
+}
+
+func TestRemovedGrantPrefixRevokedOnPrepare(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if err := rbac.Configure(rbac.Options{AdapterType: rbac.AdapterMemory}); err != nil {
+		t.Fatalf("rbac.Configure() error = %v", err)
+	}
+	if err := RbacClient.AddGroupingPolicy("carol", "OPS"); err != nil {
+		t.Fatalf("AddGroupingPolicy() error = %v", err)
+	}
+	server := NewHTTPServer("svc-prefix")
+	server.GrantPrefix("OPS", "/api/v1/ops", "GET")
+	server.syncRbacPolicies()
+	if ok, err := RbacClient.Enforce(rbac.NewReq("carol", "svc-prefix", "/api/v1/ops/jobs", "GET")); !ok {
+		t.Fatalf("GrantPrefix should allow OPS, err = %v", err)
+	}
+
+	// 新版本代码删除了 GrantPrefix，重新部署后授权应被撤销
+	redeployed := NewHTTPServer("svc-prefix")
+	redeployed.syncRbacPolicies()
+	if ok, _ := RbacClient.Enforce(rbac.NewReq("carol", "svc-prefix", "/api/v1/ops/jobs", "GET")); ok {
+		t.Fatalf("removed GrantPrefix should be revoked on Prepare")
+	}
+}
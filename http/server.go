@@ -17,6 +17,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -69,9 +70,12 @@ type HTTPServer struct {
 	opRecordFn       OpRecordFn
 	accessRecordFn   AccessRecordFn
 	approvalHandler  approval.ApprovalHandler
+	approvalService  *approval.Service
 	extraMiddlewares []gin.HandlerFunc
 	globalPrefix     string          // 路由全局前缀 新增字段（增量，不影响原有逻辑）
 	registeredKeys   map[string]bool // 已注册路由唯一键（URL-Method）
+	prefixPolicies   []rbac.Policy   // GrantPrefix 登记的前缀授权
+	prepared         atomic.Bool     // Prepare 已执行，角色目录热加载后需重新同步 RBAC 策略
 }
 
 func init() {
@@ -122,18 +126,48 @@ func InitServer(serviceName string) {
 
 func NewHTTPServer(service_name string) *HTTPServer {
 	applyGinModeFromConfig()
+	loadRoleCatalog()
 	routes := []*Route{
 		NewRoute(service_name, "/ping", "健康检查", []string{"GET"}, utils.RoleIdle, "", "", false, false, func(c *gin.Context) {
 			c.String(http.StatusOK, "Pong")
 		}),
 	}
-	return &HTTPServer{
+	s := &HTTPServer{
 		service_name:     service_name,
 		routes:           routes,
 		router:           gin.Default(),
 		extraMiddlewares: []gin.HandlerFunc{},
 		registeredKeys:   make(map[string]bool), // 初始化已注册路由缓存
 	}
+	rbac.OnRoleCatalogChange(func(*rbac.RoleCatalog) { s.refreshRouteRoles() })
+	return s
+}
+
+// refreshRouteRoles 角色目录替换后重新计算各路由的默认角色，Prepare 之后还会重新同步 RBAC 策略
+func (s *HTTPServer) refreshRouteRoles() {
+	for _, route := range s.routes {
+		route.refreshDefaultRoles()
+	}
+	if !s.prepared.Load() {
+		return
+	}
+	s.syncRbacPolicies()
+	WriteAuthRouteRegistrySnapshot(s.service_name, s.routes)
+	log.Infof("角色目录已更新，重新同步路由 RBAC 策略，service=%s", s.service_name)
+}
+
+var roleCatalogOnce sync.Once
+
+// loadRoleCatalog 配置了角色目录时在注册路由前加载并校验，定义无效(环、未知上级等)时拒绝启动
+func loadRoleCatalog() {
+	roleCatalogOnce.Do(func() {
+		if !rbac.RoleCatalogConfigured() {
+			return
+		}
+		if err := rbac.StartRoleCatalogFromConfig(context.Background()); err != nil {
+			log.Fatalf("RBAC角色目录校验失败: %v", err)
+		}
+	})
 }
 
 func applyGinModeFromConfig() {
@@ -277,14 +311,8 @@ func (s *HTTPServer) RouteAPI(path string, tips string, methods []string, roles
 		panic(fmt.Sprintf("RouteAPI unsupported arg count: %d", len(args)))
 	}
 
+	// 业务审批中间件在 Prepare 时挂载，审批处理器可在路由注册之后再设置
 	route := NewRouteCommon(s.service_name, path, tips, methods, roles, resource, action, sso, businessApproval, fn)
-	if businessApproval && s.approvalHandler != nil { // 添加业务审批中间件(如果需要)
-		aConfig := approval.Config{
-			BusinessApproval: businessApproval,
-			Handler:          s.approvalHandler,
-		}
-		route.AddMiddleware(approval.ApprovalMiddleware(aConfig))
-	}
 	s.routes = append(s.routes, route)
 	//// 关键：注册到 gin 引擎（适配你的 Route 结构体字段）
 	//for _, method := range methods {
@@ -345,6 +373,57 @@ func (s *HTTPServer) SetApprovalHandler(handler approval.ApprovalHandler) {
 	s.approvalHandler = handler
 }
 
+// UseApprovalService 启用审批请求落库与回放，需在 Prepare 之前调用，与业务审批路由的注册顺序无关：
+// 审批中间件在 Prepare 时统一挂载。同时注册申请人查询/撤回接口与审批系统回调接口，Prepare 时将 gin 引擎设置为回放目标
+func (s *HTTPServer) UseApprovalService(service *approval.Service) {
+	if service == nil {
+		return
+	}
+	s.approvalService = service
+	s.approvalHandler = service.Handler()
+	s.RouteWithPolicy("/api/v1/approval/requests", "审批请求列表", []string{"GET"}, AnyUser(), service.ListHandler())
+	s.RouteWithPolicy("/api/v1/approval/requests/:request_id", "审批请求详情", []string{"GET"}, AnyUser(), service.GetHandler())
+	s.RouteWithPolicy("/api/v1/approval/requests/:request_id/cancel", "撤回审批请求", []string{"POST"}, AnyUser(), service.CancelHandler())
+	s.RouteWithPolicy("/api/v1/approval/callback", "审批结果回调", []string{"POST"}, Internal(), service.CallbackHandler())
+}
+
+// GrantPrefix 授权角色访问本服务 prefix 下的全部路由(含之后新增的路由)，methods 为空时不限方法。
+// 策略在 Prepare 时写入并标记为本服务代码声明，每次 Prepare 先清理再按当前声明重建，
+// 删除 GrantPrefix 调用后重新部署即撤销授权
+func (s *HTTPServer) GrantPrefix(role, prefix string, methods ...string) {
+	s.prefixPolicies = append(s.prefixPolicies, rbac.NewPrefixPolicy(s.service_name, role, prefix, methods...))
+}
+
+// UseRbacReconcile 注册 RBAC 分组策略漂移报告(GET)与修复(POST)接口，仅管理员可用
+func (s *HTTPServer) UseRbacReconcile() {
+	s.RouteWithPolicy("/api/v1/rbac/drift", "RBAC 授予漂移报告", []string{"GET"}, Admin(), func(c *gin.Context) {
+		report, err := rbac.DiffGroupingPolicies()
+		JsonResponseNew(c, report, err)
+	})
+	s.RouteWithPolicy("/api/v1/rbac/reconcile", "修复 RBAC 授予漂移", []string{"POST"}, Admin(), func(c *gin.Context) {
+		var req struct {
+			RemoveExtra bool `json:"remove_extra"`
+		}
+		_ = c.ShouldBindJSON(&req)
+		report, err := rbac.ReconcileGroupingPolicies(rbac.ReconcileOptions{RemoveExtra: req.RemoveExtra})
+		JsonResponseNew(c, report, err)
+	})
+}
+
+// UseRbacExplain 注册权限解释接口，返回指定主体访问某路由的完整判定过程，
+// 请求体携带 grant/revoke 时模拟角色变更后判定，仅管理员可用
+func (s *HTTPServer) UseRbacExplain() {
+	s.RouteWithPolicy("/api/v1/rbac/explain", "RBAC 权限解释", []string{"POST"}, Admin(), func(c *gin.Context) {
+		var req ExplainRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			JsonResponseNew(c, nil, &ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		ans, err := s.ExplainAccess(c.Request.Context(), req)
+		JsonResponseNew(c, ans, err)
+	})
+}
+
 func (s *HTTPServer) SetOpRecordFn(fn OpRecordFn) {
 	s.opRecordFn = fn
 }
@@ -353,15 +432,26 @@ func (s *HTTPServer) SetAccessRecordFn(fn AccessRecordFn) {
 	s.accessRecordFn = fn
 }
 
-func (s *HTTPServer) Prepare() {
+// syncRbacPolicies 以当前路由重建本服务的 RBAC 策略
+func (s *HTTPServer) syncRbacPolicies() {
 	var policies []rbac.Policy
-	log.Infof("HTTPServer.Prepare registering routes, service=%s, route_count=%d", s.service_name, len(s.routes))
 	for i, route := range s.routes { // 1. 收集所有路由的RBAC策略和路由信息
-		log.Debugf("route[%d] path=%s methods=%v roles=%v", i+1, route.Url, route.Methods, route.DefaultRoles)
+		log.Debugf("route[%d] path=%s methods=%v roles=%v", i+1, route.Url, route.Methods, route.defaultRoles())
 		policies = append(policies, route.ToRbacPolicy()...)
 	}
-	_ = RbacClient.DeletePoliciesByService(s.service_name)              // 2. 清理旧策略
-	RbacClient.AddActionPolicies(policies)                              // 3. 添加RBAC策略
+	_ = RbacClient.DeletePoliciesByService(s.service_name)         // 2. 清理旧策略(含上次声明的前缀授权)
+	if err := RbacClient.AddActionPolicies(policies); err != nil { // 3. 添加RBAC策略
+		log.Errorf("HTTPServer.Prepare AddActionPolicies failed, service=%s, %v", s.service_name, err)
+	}
+	if err := RbacClient.AddDeclaredPatternPolicies(s.prefixPolicies); err != nil {
+		log.Errorf("HTTPServer.Prepare AddDeclaredPatternPolicies failed, service=%s, %v", s.service_name, err)
+	}
+}
+
+func (s *HTTPServer) Prepare() {
+	log.Infof("HTTPServer.Prepare registering routes, service=%s, route_count=%d", s.service_name, len(s.routes))
+	s.syncRbacPolicies()
+	s.prepared.Store(true)
 	s.router.SetTrustedProxies([]string{"127.0.0.1", "192.168.0.0/24"}) // 3. 设置全局中间件(注意顺序)
 	// 4. 全局中间件(作用于所有路由)
 	// 先注册用户自定义额外中间件，再注册内置中间件，确保用户安全中间件可最早生效
@@ -374,6 +464,7 @@ func (s *HTTPServer) Prepare() {
 		TenantMiddleware(),                                                // 租户隔离
 		RecordRequestMiddleware(s.routes, s.opRecordFn, s.accessRecordFn), // 操作/访问记录
 	)
+	s.attachApprovalMiddlewares()
 	// 5. 直接注册路由（不再使用routeInfos）
 	for _, route := range s.routes {
 		handlers := route.GetHandlersChain()   // 获取该路由的完整处理链（中间件+主处理函数）
@@ -389,9 +480,41 @@ func (s *HTTPServer) Prepare() {
 			s.MarkRouteRegistered(route.Url, method)
 		}
 	}
+	if s.approvalService != nil {
+		s.approvalService.SetEngine(s.router)
+	}
 	WriteAuthRouteRegistrySnapshot(s.service_name, s.routes)
 }
 
+// attachApprovalMiddlewares 为 businessApproval=true 的路由添加业务审批中间件，
+// 处理器实现 approval.RouteChecker 时校验每个路由都配置了审批流程(未配置的请求会被拒绝)
+func (s *HTTPServer) attachApprovalMiddlewares() {
+	if s.approvalHandler == nil {
+		return
+	}
+	checker, _ := s.approvalHandler.(approval.RouteChecker)
+	for _, route := range s.routes {
+		if !route.BusinessApproval || route.approvalAttached {
+			continue
+		}
+		if checker != nil {
+			for _, method := range route.Methods {
+				if !checker.HasRoute(route.Url, strings.ToUpper(method)) {
+					log.Errorf("[业务审批] 路由未配置审批流程，请求将被拒绝：URL=%s, Method=%s", route.Url, method)
+				}
+			}
+		}
+		route.AddMiddleware(approval.ApprovalMiddleware(approval.Config{
+			BusinessApproval: true,
+			Handler:          s.approvalHandler,
+			Service:          s.approvalService,
+			RoutePath:        route.Url,
+			Tips:             route.Tips,
+		}))
+		route.approvalAttached = true
+	}
+}
+
 // Use 注册额外的全局中间件(将在 Prepare 时最先挂载)
 func (s *HTTPServer) Use(middlewares ...gin.HandlerFunc) {
 	if len(middlewares) == 0 {
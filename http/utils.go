@@ -37,6 +37,20 @@ func GetTenant(c *gin.Context) string {
 	return tenant
 }
 
+// ResolveRequestTenant 返回参与 RBAC 鉴权的租户：优先取 Principal.TenantCode，其次取 X-Tenant 请求头，
+// 均为空时返回空串(仅全局授予的角色生效)
+func ResolveRequestTenant(c *gin.Context) string {
+	if principal, ok := GetPrincipal(c); ok {
+		if tenant := strings.TrimSpace(principal.TenantCode); tenant != "" {
+			return tenant
+		}
+	}
+	if c == nil || c.Request == nil {
+		return utils.TenantAnonymous
+	}
+	return strings.TrimSpace(c.Request.Header.Get(utils.TenantHeaderName))
+}
+
 func AddTokenCookie(c *gin.Context, token string, tokenExpired int) {
 	tokenName := ResolveCookieTokenName()
 	domainName := config.GetConfigString(utils.ConfigNameDomain)
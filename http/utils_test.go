@@ -1,8 +1,79 @@
 package http
 
 import (
+	"net/http/httptest"
 	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/orm/audit"
+	"github.com/goodbye-jack/go-common/utils"
 )
 
 func TestJsonResponse(t *testing.T) {
 }
+
+func TestResolveRequestTenant(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/orders", nil)
+	c.Request.Header.Set(utils.TenantHeaderName, " tenant-b ")
+	if got := ResolveRequestTenant(c); got != "tenant-b" {
+		t.Fatalf("header tenant = %q, want tenant-b", got)
+	}
+	SetPrincipal(c, &Principal{Subject: "alice", TenantCode: "tenant-a"})
+	if got := ResolveRequestTenant(c); got != "tenant-a" {
+		t.Fatalf("principal tenant = %q, want tenant-a", got)
+	}
+}
+
+func TestTenantMiddlewareCarriesActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		SetPrincipal(c, &Principal{Type: PrincipalAdmin, Subject: "alice", TenantCode: "tenant-a"})
+	}, TenantMiddleware())
+	var actor audit.Actor
+	router.GET("/orders", func(c *gin.Context) {
+		actor, _ = audit.ActorFromContext(c.Request.Context())
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/orders", nil))
+	if recorder.Code != 200 || actor.UserID != "alice" || actor.TenantCode != "tenant-a" {
+		t.Fatalf("status = %d, actor = %+v", recorder.Code, actor)
+	}
+	if fromGin, _ := audit.ActorFromContext(gin.CreateTestContextOnly(recorder, router)); fromGin != (audit.Actor{}) {
+		t.Fatalf("gin context without principal resolved actor %+v", fromGin)
+	}
+
+	recorder = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/orders", nil)
+	req.Header.Set(utils.TenantHeaderName, "tenant-b")
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != 403 {
+		t.Fatalf("mismatched tenant status = %d, want 403", recorder.Code)
+	}
+}
+
+func TestTenantMiddlewareIgnoresHeaderForTenantlessPrincipal(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		SetPrincipal(c, &Principal{Type: PrincipalAnonymous, Subject: "anonymous"})
+	}, TenantMiddleware())
+	var fromRequest, fromGin audit.Actor
+	router.GET("/orders", func(c *gin.Context) {
+		fromRequest, _ = audit.ActorFromContext(c.Request.Context())
+		fromGin, _ = audit.ActorFromContext(c)
+	})
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/orders", nil)
+	req.Header.Set(utils.TenantHeaderName, "tenant-b")
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != 200 {
+		t.Fatalf("status = %d", recorder.Code)
+	}
+	if fromRequest.TenantCode != "" || fromGin.TenantCode != "" || fromRequest.UserID != "" {
+		t.Fatalf("anonymous actor took header tenant: request=%+v gin=%+v", fromRequest, fromGin)
+	}
+}
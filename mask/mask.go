@@ -0,0 +1,215 @@
+// Package mask 敏感数据脱敏视图，供请求日志、变更审计等场景共用
+package mask
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// View 脱敏视图，决定值的展示形式
+type View string
+
+const (
+	ViewSecret   View = "secret"    // 整体隐藏，如密码、令牌
+	ViewPhone    View = "phone"     // 手机号：138****1234
+	ViewIDCard   View = "id_card"   // 身份证号：110***********1234
+	ViewEmail    View = "email"     // 邮箱：z***@example.com
+	ViewBankCard View = "bank_card" // 银行卡号：****1234
+	ViewName     View = "name"      // 姓名：张*、欧阳**
+)
+
+// Placeholder 整体隐藏时的占位符
+const Placeholder = "***"
+
+// Mask 按视图脱敏字符串，空值原样返回，格式不符合视图时整体隐藏
+func Mask(view View, value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return value
+	}
+	switch view {
+	case ViewPhone:
+		return keepEnds(value, 3, 4)
+	case ViewIDCard:
+		return keepEnds(value, 3, 4)
+	case ViewBankCard:
+		return keepEnds(value, 0, 4)
+	case ViewEmail:
+		at := strings.LastIndex(value, "@")
+		if at <= 0 {
+			return Placeholder
+		}
+		first, _ := utf8.DecodeRuneInString(value)
+		return string(first) + Placeholder + value[at:]
+	case ViewName:
+		runes := []rune(value)
+		if len(runes) == 1 {
+			return Placeholder
+		}
+		return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+	}
+	return Placeholder
+}
+
+// keepEnds 保留首尾若干字符，中间用 * 替换；长度不足时整体隐藏
+func keepEnds(value string, head, tail int) string {
+	runes := []rune(value)
+	if len(runes) <= head+tail {
+		return Placeholder
+	}
+	return string(runes[:head]) + strings.Repeat("*", len(runes)-head-tail) + string(runes[len(runes)-tail:])
+}
+
+// Rules 字段名到脱敏视图的规则，字段名忽略大小写、下划线与中划线
+type Rules struct {
+	mu       sync.RWMutex
+	exact    map[string]View
+	contains []containsRule
+}
+
+type containsRule struct {
+	substr string
+	view   View
+}
+
+// NewRules 创建空规则
+func NewRules() *Rules {
+	return &Rules{exact: map[string]View{}}
+}
+
+// DefaultRules 内置规则：密码/令牌/密钥类整体隐藏，手机号、身份证号、邮箱、银行卡号按格式部分隐藏
+func DefaultRules() *Rules {
+	r := NewRules()
+	for _, substr := range []string{"password", "pwd", "token", "secret", "authorization", "cookie"} {
+		r.Contains(substr, ViewSecret)
+	}
+	r.Set(ViewPhone, "phone", "mobile", "telephone", "phone_number", "mobile_phone")
+	r.Set(ViewIDCard, "id_card", "id_no", "id_number", "identity_card", "id_card_no")
+	r.Set(ViewEmail, "email", "mail")
+	r.Set(ViewBankCard, "bank_card", "bank_card_no", "card_no", "bank_account")
+	return r
+}
+
+// Set 为精确匹配的字段名设置视图
+func (r *Rules) Set(view View, fields ...string) *Rules {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, field := range fields {
+		if key := normalize(field); key != "" {
+			r.exact[key] = view
+		}
+	}
+	return r
+}
+
+// Contains 字段名包含 substr 时使用视图，精确匹配优先
+func (r *Rules) Contains(substr string, view View) *Rules {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if key := normalize(substr); key != "" {
+		r.contains = append(r.contains, containsRule{substr: key, view: view})
+	}
+	return r
+}
+
+// ViewOf 字段对应的视图，字段可为 a.b.c 形式的路径，按最后一段匹配
+func (r *Rules) ViewOf(field string) (View, bool) {
+	if idx := strings.LastIndex(field, "."); idx >= 0 {
+		field = field[idx+1:]
+	}
+	key := normalize(field)
+	if key == "" {
+		return "", false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if view, ok := r.exact[key]; ok {
+		return view, true
+	}
+	for _, rule := range r.contains {
+		if strings.Contains(key, rule.substr) {
+			return rule.view, true
+		}
+	}
+	return "", false
+}
+
+// Value 按字段规则脱敏单个值，ok 为 false 表示字段不敏感；非字符串值整体隐藏
+func (r *Rules) Value(field string, value any) (any, bool) {
+	view, ok := r.ViewOf(field)
+	if !ok {
+		return value, false
+	}
+	if text, isText := value.(string); isText {
+		return Mask(view, text), true
+	}
+	if value == nil {
+		return nil, true
+	}
+	return Placeholder, true
+}
+
+// Payload 递归脱敏 JSON 解码后的 map/slice，返回新对象，不修改入参
+func (r *Rules) Payload(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(typed))
+		for key, item := range typed {
+			view, ok := r.ViewOf(key)
+			if !ok || (view != ViewSecret && isContainer(item)) {
+				result[key] = r.Payload(item)
+				continue
+			}
+			result[key], _ = r.Value(key, item)
+		}
+		return result
+	case []any:
+		result := make([]any, len(typed))
+		for idx, item := range typed {
+			result[idx] = r.Payload(item)
+		}
+		return result
+	}
+	return value
+}
+
+func isContainer(value any) bool {
+	switch value.(type) {
+	case map[string]any, []any:
+		return true
+	}
+	return false
+}
+
+func normalize(field string) string {
+	field = strings.ToLower(strings.TrimSpace(field))
+	return strings.NewReplacer("_", "", "-", "").Replace(field)
+}
+
+var defaultRules = DefaultRules()
+
+// Default 全局规则，业务可通过 Register 追加
+func Default() *Rules {
+	return defaultRules
+}
+
+// Register 向全局规则追加精确匹配的字段
+func Register(view View, fields ...string) {
+	defaultRules.Set(view, fields...)
+}
+
+// ViewOf 按全局规则查找字段视图
+func ViewOf(field string) (View, bool) {
+	return defaultRules.ViewOf(field)
+}
+
+// Value 按全局规则脱敏单个值
+func Value(field string, value any) (any, bool) {
+	return defaultRules.Value(field, value)
+}
+
+// Payload 按全局规则递归脱敏
+func Payload(value any) any {
+	return defaultRules.Payload(value)
+}
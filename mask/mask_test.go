@@ -0,0 +1,54 @@
+package mask
+
+import "testing"
+
+func TestMask(t *testing.T) {
+	cases := []struct {
+		view  View
+		value string
+		want  string
+	}{
+		{ViewPhone, "13800001234", "138****1234"},
+		{ViewIDCard, "110101199001011234", "110***********1234"},
+		{ViewBankCard, "6222020200001234", "************1234"},
+		{ViewEmail, "zhang@example.com", "z***@example.com"},
+		{ViewEmail, "invalid", Placeholder},
+		{ViewName, "张三", "张*"},
+		{ViewSecret, "p@ss", Placeholder},
+		{ViewPhone, "123", Placeholder},
+		{ViewPhone, "", ""},
+	}
+	for _, tc := range cases {
+		if got := Mask(tc.view, tc.value); got != tc.want {
+			t.Errorf("Mask(%s, %q) = %q, want %q", tc.view, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestRulesPayload(t *testing.T) {
+	rules := DefaultRules().Set(ViewName, "real_name")
+	if view, ok := rules.ViewOf("user.Mobile-Phone"); !ok || view != ViewPhone {
+		t.Fatalf("ViewOf(user.Mobile-Phone) = %s, %v", view, ok)
+	}
+	payload := map[string]any{
+		"password":  "secret",
+		"realName":  "李四",
+		"phone":     "13800001234",
+		"remark":    "ok",
+		"contacts":  []any{map[string]any{"email": "li@example.com"}},
+		"tokenInfo": map[string]any{"value": "abc"},
+	}
+	got := rules.Payload(payload).(map[string]any)
+	if got["password"] != Placeholder || got["realName"] != "李*" || got["phone"] != "138****1234" || got["remark"] != "ok" {
+		t.Fatalf("Payload() = %v", got)
+	}
+	if got["tokenInfo"] != Placeholder {
+		t.Fatalf("secret container = %v", got["tokenInfo"])
+	}
+	if email := got["contacts"].([]any)[0].(map[string]any)["email"]; email != "l***@example.com" {
+		t.Fatalf("nested email = %v", email)
+	}
+	if payload["phone"] != "13800001234" {
+		t.Fatalf("Payload() modified input")
+	}
+}
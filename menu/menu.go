@@ -0,0 +1,450 @@
+package menu
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goodbye-jack/go-common/model"
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/orm/migrate"
+	"github.com/goodbye-jack/go-common/rbac"
+	"github.com/goodbye-jack/go-common/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const DefaultMenuTable = "menus"
+
+// 菜单类型
+const (
+	MenuTypeDirectory uint8 = 1 // 目录
+	MenuTypePage      uint8 = 2 // 页面
+	MenuTypeAction    uint8 = 3 // 按钮/接口
+)
+
+// Visible / Status 取值
+const (
+	Hidden   = 0
+	Visible  = 1
+	Disabled = 0
+	Enabled  = 1
+)
+
+var (
+	ErrMenuNotFound    = errors.New("menu not found")
+	ErrMenuHasChildren = errors.New("menu has children")
+	ErrMenuCycle       = errors.New("menu parent cannot be itself or its descendant")
+)
+
+// MenuRole 菜单与角色编码的关联表，MenuBase.RoleCode 逗号串的规范化形式
+type MenuRole struct {
+	ID        uint   `gorm:"primaryKey"`
+	MenuID    uint   `gorm:"index;uniqueIndex:uniq_menu_role"`
+	RoleCode  string `gorm:"size:128;index;uniqueIndex:uniq_menu_role"`
+	CreatedAt time.Time
+}
+
+func (MenuRole) TableName() string {
+	return "menu_roles"
+}
+
+// RoleResolver 获取用户的直接角色编码
+type RoleResolver func(uid string) ([]string, error)
+
+type Option func(*Service)
+
+// WithMenuTable 指定菜单表名，默认 menus（业务侧 type Menu struct{ model.MenuBase } 的默认表名）
+func WithMenuTable(table string) Option {
+	return func(s *Service) {
+		if strings.TrimSpace(table) != "" {
+			s.table = strings.TrimSpace(table)
+		}
+	}
+}
+
+// WithRoleResolver 替换用户角色来源，默认 rbac.ListUserRoles
+func WithRoleResolver(resolver RoleResolver) Option {
+	return func(s *Service) {
+		if resolver != nil {
+			s.roles = resolver
+		}
+	}
+}
+
+// WithInheritResolver 替换角色继承来源，默认 rbac.ListRoleInherits
+func WithInheritResolver(resolver func(roleCode string) ([]string, error)) Option {
+	return func(s *Service) {
+		if resolver != nil {
+			s.inherits = resolver
+		}
+	}
+}
+
+// WithAdminRoles 拥有这些角色的用户可见全部菜单，默认 utils.RoleAdministrator
+func WithAdminRoles(roles ...string) Option {
+	return func(s *Service) {
+		s.adminRoles = normalizeRoleCodes(roles)
+	}
+}
+
+type Service struct {
+	db         *gorm.DB
+	table      string
+	roles      RoleResolver
+	inherits   func(roleCode string) ([]string, error)
+	adminRoles []string
+}
+
+func NewService(db *gorm.DB, opts ...Option) (*Service, error) {
+	if db == nil {
+		return nil, errors.New("menu service db is nil")
+	}
+	s := &Service{
+		db:         db,
+		table:      DefaultMenuTable,
+		roles:      rbac.ListUserRoles,
+		inherits:   rbac.ListRoleInherits,
+		adminRoles: []string{utils.RoleAdministrator},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// 菜单表与关联表以版本化迁移维护，migrations.mode=verify 时只校验不建表
+	if err := migrate.ApplyModule(context.Background(), db, MigrationModule, menuTableModule(s.table)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewServiceFromORM 基于全局 orm.DB 创建菜单服务
+func NewServiceFromORM(opts ...Option) (*Service, error) {
+	if orm.DB == nil {
+		return nil, errors.New("orm.DB not initialized")
+	}
+	return NewService(orm.DB.GetDB(), opts...)
+}
+
+func (s *Service) menus(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.table)
+}
+
+// ListFilter 菜单查询条件，零值表示不过滤
+type ListFilter struct {
+	ServiceName string
+	ParentID    *uint
+	MenuType    uint8
+}
+
+func (s *Service) List(ctx context.Context, filter ListFilter) ([]model.MenuBase, error) {
+	query := s.menus(ctx)
+	if filter.ServiceName != "" {
+		query = query.Where("service_name = ?", filter.ServiceName)
+	}
+	if filter.ParentID != nil {
+		query = query.Where("parent_id = ?", *filter.ParentID)
+	}
+	if filter.MenuType != 0 {
+		query = query.Where("menu_type = ?", filter.MenuType)
+	}
+	var menus []model.MenuBase
+	if err := query.Order("sort asc").Order("id asc").Find(&menus).Error; err != nil {
+		return nil, err
+	}
+	return menus, nil
+}
+
+func (s *Service) Get(ctx context.Context, id uint) (*model.MenuBase, error) {
+	menu := &model.MenuBase{}
+	if err := s.menus(ctx).Where("id = ?", id).First(menu).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMenuNotFound
+		}
+		return nil, err
+	}
+	return menu, nil
+}
+
+// Create 新建菜单，roleCodes 为空时使用 menu.RoleCode 中的逗号串
+func (s *Service) Create(ctx context.Context, menu *model.MenuBase, roleCodes []string) error {
+	if menu == nil {
+		return errors.New("menu is nil")
+	}
+	menu.ID = 0
+	if menu.ParentId != 0 {
+		if _, err := s.Get(ctx, menu.ParentId); err != nil {
+			return err
+		}
+	}
+	codes := resolveRoleCodes(menu, roleCodes)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(s.table).Create(menu).Error; err != nil {
+			return err
+		}
+		return replaceMenuRoles(tx, menu.ID, codes)
+	})
+}
+
+// Update 更新菜单基本信息与角色，roleCodes 为 nil 时使用 menu.RoleCode
+func (s *Service) Update(ctx context.Context, menu *model.MenuBase, roleCodes []string) error {
+	if menu == nil || menu.ID == 0 {
+		return errors.New("menu id is required")
+	}
+	existing, err := s.Get(ctx, menu.ID)
+	if err != nil {
+		return err
+	}
+	if menu.ParentId != existing.ParentId {
+		if err := s.checkParent(ctx, menu.ID, menu.ParentId); err != nil {
+			return err
+		}
+	}
+	codes := resolveRoleCodes(menu, roleCodes)
+	menu.CreatedAt = existing.CreatedAt
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(s.table).Select("*").Omit("created_at", "deleted_at").Where("id = ?", menu.ID).Updates(menu).Error; err != nil {
+			return err
+		}
+		return replaceMenuRoles(tx, menu.ID, codes)
+	})
+}
+
+// Delete 删除菜单，存在子菜单时返回 ErrMenuHasChildren。
+// 物理删除：(path, method) 上有唯一索引，软删除的行会让之后的 Create/SyncFromRoutes 冲突
+func (s *Service) Delete(ctx context.Context, id uint) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	var children int64
+	if err := s.menus(ctx).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return ErrMenuHasChildren
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(s.table).Unscoped().Where("id = ?", id).Delete(&model.MenuBase{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("menu_id = ?", id).Delete(&MenuRole{}).Error
+	})
+}
+
+// Reorder 将 orderedIDs 移动到 parentID 下并按给定顺序重排 sort
+func (s *Service) Reorder(ctx context.Context, parentID uint, orderedIDs []uint) error {
+	for _, id := range orderedIDs {
+		if err := s.checkParent(ctx, id, parentID); err != nil {
+			return err
+		}
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for index, id := range orderedIDs {
+			result := tx.Table(s.table).Where("id = ?", id).Updates(map[string]interface{}{
+				"parent_id": parentID,
+				"sort":      index + 1,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrMenuNotFound
+			}
+		}
+		return nil
+	})
+}
+
+// checkParent 校验 parentID 存在且不是 id 本身或其子孙
+func (s *Service) checkParent(ctx context.Context, id uint, parentID uint) error {
+	for current := parentID; current != 0; {
+		if current == id {
+			return ErrMenuCycle
+		}
+		parent, err := s.Get(ctx, current)
+		if err != nil {
+			return err
+		}
+		current = parent.ParentId
+	}
+	return nil
+}
+
+// RoleCodes 返回菜单关联的角色编码（menu_id -> codes）
+func (s *Service) RoleCodes(ctx context.Context, menuIDs []uint) (map[uint][]string, error) {
+	result := make(map[uint][]string, len(menuIDs))
+	if len(menuIDs) == 0 {
+		return result, nil
+	}
+	var rows []MenuRole
+	if err := s.db.WithContext(ctx).Where("menu_id IN ?", menuIDs).Order("id asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.MenuID] = append(result[row.MenuID], row.RoleCode)
+	}
+	return result, nil
+}
+
+// EffectiveRoles 用户直接角色 + 继承角色（去重）
+func (s *Service) EffectiveRoles(uid string, extra ...string) ([]string, error) {
+	seed := append([]string{}, extra...)
+	if strings.TrimSpace(uid) != "" && uid != utils.UserAnonymous {
+		direct, err := s.roles(uid)
+		if err != nil {
+			return nil, err
+		}
+		seed = append(seed, direct...)
+	}
+	seen := map[string]struct{}{}
+	queue := normalizeRoleCodes(seed)
+	result := make([]string, 0, len(queue))
+	for len(queue) > 0 {
+		code := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		result = append(result, code)
+		inherited, err := s.inherits(code)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, normalizeRoleCodes(inherited)...)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// SyncRoute 由路由同步菜单时使用的最小路由描述
+type SyncRoute struct {
+	ServiceName string
+	Path        string
+	Method      string
+	Title       string
+	RoleCodes   []string
+}
+
+// SyncFromRoutes 按 path+method 补齐接口类菜单；已存在的菜单只更新服务名和空标题，保留人工维护的层级、排序与角色
+func (s *Service) SyncFromRoutes(ctx context.Context, parentID uint, routes []SyncRoute) (int, error) {
+	created := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, route := range routes {
+			method := strings.ToUpper(strings.TrimSpace(route.Method))
+			path := strings.TrimSpace(route.Path)
+			if path == "" || method == "" {
+				continue
+			}
+			existing := &model.MenuBase{}
+			err := tx.Table(s.table).Where("path = ? AND method = ?", path, method).First(existing).Error
+			if err == nil {
+				updates := map[string]interface{}{"service_name": route.ServiceName}
+				if existing.MenuTitle == "" {
+					updates["menu_title"] = route.Title
+				}
+				if err := tx.Table(s.table).Where("id = ?", existing.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			codes := normalizeRoleCodes(route.RoleCodes)
+			menu := &model.MenuBase{
+				ParentId:    parentID,
+				MenuName:    firstNonBlank(route.Title, path),
+				MenuType:    MenuTypeAction,
+				MenuTitle:   route.Title,
+				ServiceName: route.ServiceName,
+				Path:        path,
+				Method:      method,
+				Visible:     Hidden,
+				Status:      Enabled,
+				RoleCode:    strings.Join(codes, ","),
+			}
+			if err := tx.Table(s.table).Create(menu).Error; err != nil {
+				return err
+			}
+			if err := replaceMenuRoles(tx, menu.ID, codes); err != nil {
+				return err
+			}
+			created++
+		}
+		return nil
+	})
+	return created, err
+}
+
+// NormalizeRoleJoinTable 将历史数据中 RoleCode 逗号串回填到 menu_roles
+func (s *Service) NormalizeRoleJoinTable(ctx context.Context) error {
+	var menus []model.MenuBase
+	if err := s.menus(ctx).Find(&menus).Error; err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range menus {
+			if err := replaceMenuRoles(tx, menus[i].ID, SplitRoleCodes(menus[i].RoleCode)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func replaceMenuRoles(tx *gorm.DB, menuID uint, codes []string) error {
+	if err := tx.Where("menu_id = ?", menuID).Delete(&MenuRole{}).Error; err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	rows := make([]MenuRole, 0, len(codes))
+	for _, code := range codes {
+		rows = append(rows, MenuRole{MenuID: menuID, RoleCode: code})
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// resolveRoleCodes 规范化角色编码并回写 RoleCode 逗号串，保持两种表示一致
+func resolveRoleCodes(menu *model.MenuBase, roleCodes []string) []string {
+	codes := normalizeRoleCodes(roleCodes)
+	if roleCodes == nil {
+		codes = SplitRoleCodes(menu.RoleCode)
+	}
+	menu.RoleCode = strings.Join(codes, ",")
+	return codes
+}
+
+// SplitRoleCodes 解析逗号分隔的角色编码
+func SplitRoleCodes(value string) []string {
+	return normalizeRoleCodes(strings.Split(value, ","))
+}
+
+func normalizeRoleCodes(codes []string) []string {
+	seen := make(map[string]struct{}, len(codes))
+	result := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		result = append(result, code)
+	}
+	return result
+}
+
+func firstNonBlank(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package menu
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goodbye-jack/go-common/model"
+	"github.com/goodbye-jack/go-common/orm/migrate"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	userRoles := map[string][]string{"alice": {"EDITOR"}, "root": {"ADMINISTRATOR_ROLE"}}
+	inherits := map[string][]string{"EDITOR": {"VIEWER"}}
+	service, err := NewService(db,
+		WithRoleResolver(func(uid string) ([]string, error) { return userRoles[uid], nil }),
+		WithInheritResolver(func(code string) ([]string, error) { return inherits[code], nil }),
+	)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return service
+}
+
+func createMenu(t *testing.T, service *Service, parentID uint, path string, sort int, roleCode string) *model.MenuBase {
+	t.Helper()
+	menu := &model.MenuBase{ParentId: parentID, MenuName: path, Path: path, Method: "GET", Sort: sort, Visible: Visible, Status: Enabled, RoleCode: roleCode}
+	if err := service.Create(context.Background(), menu, nil); err != nil {
+		t.Fatalf("Create(%s) error = %v", path, err)
+	}
+	return menu
+}
+
+func collectPaths(nodes []*Node) []string {
+	var paths []string
+	for _, node := range nodes {
+		paths = append(paths, node.Path)
+		paths = append(paths, collectPaths(node.Children)...)
+	}
+	return paths
+}
+
+func TestTreeForUserFiltersByInheritedRoles(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+	system := createMenu(t, service, 0, "/system", 2, "ADMIN_ONLY")
+	createMenu(t, service, system.ID, "/system/users", 1, "ADMIN_ONLY")
+	createMenu(t, service, system.ID, "/system/logs", 2, " VIEWER , VIEWER")
+	content := createMenu(t, service, 0, "/content", 1, "")
+	createMenu(t, service, content.ID, "/content/edit", 1, "EDITOR")
+
+	tree, err := service.TreeForUser(ctx, "", "alice")
+	if err != nil {
+		t.Fatalf("TreeForUser() error = %v", err)
+	}
+	got := collectPaths(tree)
+	want := []string{"/content", "/content/edit", "/system", "/system/logs"}
+	if len(got) != len(want) {
+		t.Fatalf("paths = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("paths = %v, want %v", got, want)
+		}
+	}
+
+	adminTree, _ := service.TreeForUser(ctx, "", "root")
+	if len(collectPaths(adminTree)) != 5 {
+		t.Fatalf("admin paths = %v", collectPaths(adminTree))
+	}
+
+	codes, _ := service.RoleCodes(ctx, []uint{3})
+	if len(codes[3]) != 1 || codes[3][0] != "VIEWER" {
+		t.Fatalf("role codes = %v, want [VIEWER]", codes[3])
+	}
+}
+
+func TestReorderAndCycleProtection(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+	a := createMenu(t, service, 0, "/a", 1, "")
+	b := createMenu(t, service, 0, "/b", 2, "")
+	child := createMenu(t, service, a.ID, "/a/child", 1, "")
+
+	if err := service.Reorder(ctx, 0, []uint{b.ID, a.ID}); err != nil {
+		t.Fatalf("Reorder() error = %v", err)
+	}
+	tree, _ := service.Tree(ctx, "")
+	if tree[0].ID != b.ID || tree[1].ID != a.ID {
+		t.Fatalf("root order = %d,%d", tree[0].ID, tree[1].ID)
+	}
+	if err := service.Reorder(ctx, child.ID, []uint{a.ID}); err != ErrMenuCycle {
+		t.Fatalf("Reorder into descendant error = %v, want ErrMenuCycle", err)
+	}
+	if err := service.Delete(ctx, a.ID); err != ErrMenuHasChildren {
+		t.Fatalf("Delete parent error = %v, want ErrMenuHasChildren", err)
+	}
+}
+
+func TestSyncFromRoutesKeepsManualChanges(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+	routes := []SyncRoute{
+		{ServiceName: "svc", Path: "/api/orders", Method: "get", Title: "订单列表", RoleCodes: []string{"EDITOR"}},
+		{ServiceName: "svc", Path: "/api/orders", Method: "POST", Title: "新建订单"},
+	}
+	created, err := service.SyncFromRoutes(ctx, 0, routes)
+	if err != nil || created != 2 {
+		t.Fatalf("SyncFromRoutes() created = %d, err = %v", created, err)
+	}
+	menus, _ := service.List(ctx, ListFilter{ServiceName: "svc"})
+	menus[0].Sort = 9
+	if err := service.Update(ctx, &menus[0], []string{"VIEWER"}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	created, err = service.SyncFromRoutes(ctx, 0, routes)
+	if err != nil || created != 0 {
+		t.Fatalf("second sync created = %d, err = %v", created, err)
+	}
+	menu, _ := service.Get(ctx, menus[0].ID)
+	if menu.Sort != 9 || menu.RoleCode != "VIEWER" {
+		t.Fatalf("manual changes lost: sort = %d, role_code = %s", menu.Sort, menu.RoleCode)
+	}
+}
+
+func TestDeleteThenResync(t *testing.T) {
+	service := newTestService(t)
+	ctx := context.Background()
+	routes := []SyncRoute{{ServiceName: "svc", Path: "/api/orders", Method: "GET", Title: "订单列表"}}
+	if created, err := service.SyncFromRoutes(ctx, 0, routes); err != nil || created != 1 {
+		t.Fatalf("SyncFromRoutes() created = %d, err = %v", created, err)
+	}
+	menus, _ := service.List(ctx, ListFilter{ServiceName: "svc"})
+	if err := service.Delete(ctx, menus[0].ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if created, err := service.SyncFromRoutes(ctx, 0, routes); err != nil || created != 1 {
+		t.Fatalf("resync after delete created = %d, err = %v", created, err)
+	}
+	menus, _ = service.List(ctx, ListFilter{ServiceName: "svc"})
+	if err := service.Delete(ctx, menus[0].ID); err != nil {
+		t.Fatalf("second Delete() error = %v", err)
+	}
+	createMenu(t, service, 0, "/api/orders", 1, "")
+}
+
+func TestNewServiceMigratesCustomTable(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "menu.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	if _, err := NewService(db, WithMenuTable("admin_menus")); err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if !db.Migrator().HasTable("admin_menus") || !db.Migrator().HasIndex("admin_menus", "uniq_path_method") || !db.Migrator().HasTable(&MenuRole{}) {
+		t.Fatalf("menu tables should be created by migrations")
+	}
+	var modules []string
+	db.Table(migrate.DefaultTable).Order("module").Pluck("module", &modules)
+	if strings.Join(modules, ",") != "menu,menu_admin_menus" {
+		t.Fatalf("migration history = %v", modules)
+	}
+}
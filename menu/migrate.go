@@ -0,0 +1,35 @@
+package menu
+
+import (
+	"sync"
+
+	"github.com/goodbye-jack/go-common/model"
+	"github.com/goodbye-jack/go-common/orm/migrate"
+)
+
+// MigrationModule 菜单角色关联表的迁移模块；菜单表名可配置，按表名注册为 menu_<table>
+const MigrationModule = "menu"
+
+var tableModules sync.Map
+
+func init() {
+	migrate.Register(MigrationModule, migrate.List{
+		{Version: "202610180001", Name: "create_menu_roles", Models: []any{&MenuRole{}}},
+	})
+	menuTableModule(DefaultMenuTable)
+}
+
+// menuTableModule 返回菜单表 table 的迁移模块，首次使用时注册
+func menuTableModule(table string) string {
+	module := MigrationModule + "_" + table
+	if _, loaded := tableModules.LoadOrStore(module, struct{}{}); !loaded {
+		migrate.Register(module, migrate.List{
+			{
+				Version: "202610180001",
+				Name:    "create_" + table,
+				Models:  []any{migrate.TableModel{Name: table, Model: &model.MenuBase{}}},
+			},
+		})
+	}
+	return module
+}
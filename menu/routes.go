@@ -0,0 +1,158 @@
+package menu
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	commonhttp "github.com/goodbye-jack/go-common/http"
+	"github.com/goodbye-jack/go-common/model"
+)
+
+const DefaultRoutePrefix = "/api/v1/menus"
+
+type menuRequest struct {
+	model.MenuBase
+	// RoleCodes 为 nil 时使用 role_code 逗号串
+	RoleCodes []string `json:"role_codes"`
+}
+
+type reorderRequest struct {
+	ParentID uint   `json:"parent_id"`
+	IDs      []uint `json:"ids" binding:"required"`
+}
+
+type syncRequest struct {
+	ParentID uint `json:"parent_id"`
+}
+
+// Register 注册菜单管理接口：管理端 CRUD/排序/同步需 Admin，当前用户菜单树需登录
+func Register(server *commonhttp.HTTPServer, service *Service) {
+	if server == nil || service == nil {
+		return
+	}
+	h := &handlers{server: server, service: service}
+	prefix := DefaultRoutePrefix
+	server.RouteWithPolicy(prefix+"/mine", "当前用户菜单树", []string{http.MethodGet}, commonhttp.AnyUser(), h.mine)
+	server.RouteWithPolicy(prefix+"/tree", "菜单树", []string{http.MethodGet}, commonhttp.Admin(), h.tree)
+	server.RouteWithPolicy(prefix, "新建菜单", []string{http.MethodPost}, commonhttp.Admin(), h.create)
+	server.RouteWithPolicy(prefix+"/:id", "更新菜单", []string{http.MethodPut}, commonhttp.Admin(), h.update)
+	server.RouteWithPolicy(prefix+"/:id", "删除菜单", []string{http.MethodDelete}, commonhttp.Admin(), h.delete)
+	server.RouteWithPolicy(prefix+"/reorder", "菜单排序", []string{http.MethodPost}, commonhttp.Admin(), h.reorder)
+	server.RouteWithPolicy(prefix+"/sync", "从路由同步菜单", []string{http.MethodPost}, commonhttp.Admin(), h.sync)
+}
+
+// SyncRoutesFromHTTP 将已注册的路由转换为菜单同步项
+func SyncRoutesFromHTTP(routes []*commonhttp.Route) []SyncRoute {
+	result := make([]SyncRoute, 0, len(routes))
+	for _, route := range routes {
+		if route == nil {
+			continue
+		}
+		for _, method := range route.Methods {
+			result = append(result, SyncRoute{
+				ServiceName: route.ServiceName,
+				Path:        route.Url,
+				Method:      method,
+				Title:       route.Tips,
+				RoleCodes:   route.DefaultRoles,
+			})
+		}
+	}
+	return result
+}
+
+type handlers struct {
+	server  *commonhttp.HTTPServer
+	service *Service
+}
+
+func (h *handlers) mine(c *gin.Context) {
+	var extraRoles []string
+	if principal, ok := commonhttp.GetPrincipal(c); ok {
+		extraRoles = principal.RoleCodes
+	}
+	tree, err := h.service.TreeForUser(c.Request.Context(), c.Query("service_name"), commonhttp.GetUser(c), extraRoles...)
+	commonhttp.JsonResponseNew(c, tree, err)
+}
+
+func (h *handlers) tree(c *gin.Context) {
+	tree, err := h.service.Tree(c.Request.Context(), c.Query("service_name"))
+	commonhttp.JsonResponseNew(c, tree, err)
+}
+
+func (h *handlers) create(c *gin.Context) {
+	var req menuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	menu := req.MenuBase
+	err := h.service.Create(c.Request.Context(), &menu, req.RoleCodes)
+	commonhttp.JsonResponseNew(c, menu, wrapError(err))
+}
+
+func (h *handlers) update(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	var req menuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	menu := req.MenuBase
+	menu.ID = id
+	err := h.service.Update(c.Request.Context(), &menu, req.RoleCodes)
+	commonhttp.JsonResponseNew(c, menu, wrapError(err))
+}
+
+func (h *handlers) delete(c *gin.Context) {
+	id, ok := parseID(c)
+	if !ok {
+		return
+	}
+	err := h.service.Delete(c.Request.Context(), id)
+	commonhttp.JsonResponseNew(c, gin.H{"id": id}, wrapError(err))
+}
+
+func (h *handlers) reorder(c *gin.Context) {
+	var req reorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	err := h.service.Reorder(c.Request.Context(), req.ParentID, req.IDs)
+	commonhttp.JsonResponseNew(c, gin.H{"parent_id": req.ParentID, "ids": req.IDs}, wrapError(err))
+}
+
+func (h *handlers) sync(c *gin.Context) {
+	var req syncRequest
+	_ = c.ShouldBindJSON(&req)
+	created, err := h.service.SyncFromRoutes(c.Request.Context(), req.ParentID, SyncRoutesFromHTTP(h.server.GetRoutes()))
+	commonhttp.JsonResponseNew(c, gin.H{"created": created}, wrapError(err))
+}
+
+func parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: "invalid menu id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func wrapError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrMenuNotFound):
+		return &commonhttp.BusinessError{Code: http.StatusNotFound, Message: err.Error()}
+	case errors.Is(err, ErrMenuHasChildren), errors.Is(err, ErrMenuCycle):
+		return &commonhttp.BusinessError{Code: http.StatusConflict, Message: err.Error()}
+	default:
+		return err
+	}
+}
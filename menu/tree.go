@@ -0,0 +1,145 @@
+package menu
+
+import (
+	"context"
+	"sort"
+
+	"github.com/goodbye-jack/go-common/model"
+	"github.com/goodbye-jack/go-common/utils"
+)
+
+// Node 菜单树节点
+type Node struct {
+	model.MenuBase
+	RoleCodes []string `json:"role_codes"`
+	Children  []*Node  `json:"children"`
+}
+
+// Tree 返回完整菜单树（管理端使用），serviceName 为空时不过滤服务
+func (s *Service) Tree(ctx context.Context, serviceName string) ([]*Node, error) {
+	nodes, err := s.loadNodes(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(nodes, nil), nil
+}
+
+// TreeForUser 返回用户可见的菜单树：仅启用且显示的菜单，按用户有效角色(含继承)过滤，
+// extraRoles 可传入 Principal.RoleCodes 等请求上下文中的角色
+func (s *Service) TreeForUser(ctx context.Context, serviceName string, uid string, extraRoles ...string) ([]*Node, error) {
+	roles, err := s.EffectiveRoles(uid, extraRoles...)
+	if err != nil {
+		return nil, err
+	}
+	return s.TreeForRoles(ctx, serviceName, roles)
+}
+
+// TreeForRoles 按给定的有效角色过滤菜单树；未配置角色或包含 anonymous 的菜单对所有人可见，
+// 停用菜单连同子菜单一起隐藏，父菜单无权限但存在可见子菜单时保留父菜单作为容器
+func (s *Service) TreeForRoles(ctx context.Context, serviceName string, roles []string) ([]*Node, error) {
+	nodes, err := s.loadNodes(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	roleSet := make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		roleSet[role] = struct{}{}
+	}
+	admin := false
+	for _, role := range s.adminRoles {
+		if _, ok := roleSet[role]; ok {
+			admin = true
+			break
+		}
+	}
+	allowed := func(node *Node) bool {
+		if node.Visible != Visible {
+			return false
+		}
+		if admin || len(node.RoleCodes) == 0 {
+			return true
+		}
+		for _, code := range node.RoleCodes {
+			if code == utils.UserAnonymous {
+				return true
+			}
+			if _, ok := roleSet[code]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	return buildTree(nodes, allowed), nil
+}
+
+func (s *Service) loadNodes(ctx context.Context, serviceName string) ([]*Node, error) {
+	menus, err := s.List(ctx, ListFilter{ServiceName: serviceName})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(menus))
+	for _, menu := range menus {
+		ids = append(ids, menu.ID)
+	}
+	roleCodes, err := s.RoleCodes(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*Node, 0, len(menus))
+	for _, menu := range menus {
+		codes := roleCodes[menu.ID]
+		if codes == nil {
+			codes = SplitRoleCodes(menu.RoleCode)
+		}
+		nodes = append(nodes, &Node{MenuBase: menu, RoleCodes: codes, Children: []*Node{}})
+	}
+	return nodes, nil
+}
+
+// buildTree 组装树并按 sort/id 排序；allowed 为 nil 时不做过滤。父节点缺失的菜单挂到根上
+func buildTree(nodes []*Node, allowed func(node *Node) bool) []*Node {
+	byID := make(map[uint]*Node, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+	}
+	roots := make([]*Node, 0)
+	for _, node := range nodes {
+		parent, ok := byID[node.ParentId]
+		if node.ParentId == 0 || !ok || parent == node {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+	if allowed != nil {
+		roots = filterTree(roots, allowed)
+	}
+	sortTree(roots)
+	return roots
+}
+
+func filterTree(nodes []*Node, allowed func(node *Node) bool) []*Node {
+	result := make([]*Node, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Status != Enabled {
+			continue
+		}
+		node.Children = filterTree(node.Children, allowed)
+		if allowed(node) || len(node.Children) > 0 {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+func sortTree(nodes []*Node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Sort != nodes[j].Sort {
+			return nodes[i].Sort < nodes[j].Sort
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+	for _, node := range nodes {
+		sortTree(node.Children)
+	}
+}
@@ -0,0 +1,18 @@
+package model
+
+// AuditBase 创建人/更新人，按上下文中的操作人自动填充(orm/audit 插件)
+type AuditBase struct {
+	CreatedBy string `gorm:"size:64" json:"created_by" audit:"created_by"` // 创建人
+	UpdatedBy string `gorm:"size:64" json:"updated_by" audit:"updated_by"` // 最后更新人
+}
+
+// TenantBase 租户编码，创建时自动填充，查询/更新/删除自动追加租户条件，禁止跨租户写入；
+// 上下文无租户时拒绝读写，跨租户任务需显式 audit.WithoutTenant
+type TenantBase struct {
+	TenantCode string `gorm:"size:64;index" json:"tenant_code" audit:"tenant"` // 租户编码
+}
+
+// VersionBase 乐观锁版本号，更新时校验并加一，版本不一致返回 audit.ErrOptimisticLock
+type VersionBase struct {
+	Version int64 `gorm:"not null;default:1" json:"version" audit:"version"` // 版本号
+}
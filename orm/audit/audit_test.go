@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/goodbye-jack/go-common/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type auditOrder struct {
+	model.ModelBase
+	model.AuditBase
+	model.TenantBase
+	model.VersionBase
+	Name string `gorm:"size:64"`
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "audit.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.Use(Plugin{}); err != nil {
+		t.Fatalf("Use(Plugin) error = %v", err)
+	}
+	if err := db.AutoMigrate(&auditOrder{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+	return db
+}
+
+func TestAuditColumnsAndTenantScope(t *testing.T) {
+	db := openTestDB(t)
+	alice := WithActor(context.Background(), Actor{UserID: "alice", TenantCode: "tenant-a"})
+	bob := WithActor(context.Background(), Actor{UserID: "bob", TenantCode: "tenant-b"})
+
+	order := auditOrder{Name: "a1"}
+	if err := db.WithContext(alice).Create(&order).Error; err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if order.CreatedBy != "alice" || order.UpdatedBy != "alice" || order.TenantCode != "tenant-a" || order.Version != 1 {
+		t.Fatalf("created order = %+v", order)
+	}
+	if err := db.WithContext(bob).Create(&[]auditOrder{{Name: "b1"}, {Name: "b2"}}).Error; err != nil {
+		t.Fatalf("batch Create() error = %v", err)
+	}
+	if err := db.WithContext(alice).Create(&auditOrder{Name: "x", TenantBase: model.TenantBase{TenantCode: "tenant-b"}}).Error; !errors.Is(err, ErrCrossTenant) {
+		t.Fatalf("cross-tenant create error = %v", err)
+	}
+
+	var count int64
+	db.WithContext(bob).Model(&auditOrder{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("tenant-b count = %d, want 2", count)
+	}
+	db.WithContext(WithoutTenant(bob)).Model(&auditOrder{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("bypass count = %d, want 3", count)
+	}
+	if err := db.WithContext(bob).First(&auditOrder{}, order.ID).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("cross-tenant First() error = %v", err)
+	}
+
+	// 其他租户的更新、删除不影响本租户数据
+	if err := db.WithContext(bob).Model(&auditOrder{}).Where("id = ?", order.ID).Update("name", "hacked").Error; err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := db.WithContext(bob).Delete(&auditOrder{}, order.ID).Error; err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	var reloaded auditOrder
+	if err := db.WithContext(alice).First(&reloaded, order.ID).Error; err != nil || reloaded.Name != "a1" {
+		t.Fatalf("reloaded = %+v, %v", reloaded, err)
+	}
+	if err := db.WithContext(alice).Model(&reloaded).Update("tenant_code", "tenant-b").Error; !errors.Is(err, ErrCrossTenant) {
+		t.Fatalf("tenant change error = %v", err)
+	}
+
+	carol := WithActor(context.Background(), Actor{UserID: "carol", TenantCode: "tenant-a"})
+	if err := db.WithContext(carol).Model(&reloaded).Select("Name").Updates(&auditOrder{Name: "a2"}).Error; err != nil {
+		t.Fatalf("Updates() error = %v", err)
+	}
+	db.WithContext(alice).First(&reloaded, order.ID)
+	if reloaded.Name != "a2" || reloaded.UpdatedBy != "carol" || reloaded.CreatedBy != "alice" || reloaded.Version != 2 {
+		t.Fatalf("updated order = %+v", reloaded)
+	}
+}
+
+func TestTenantModelWithoutTenantFailsClosed(t *testing.T) {
+	db := openTestDB(t)
+	alice := WithActor(context.Background(), Actor{UserID: "alice", TenantCode: "tenant-a"})
+	order := auditOrder{Name: "a1"}
+	if err := db.WithContext(alice).Create(&order).Error; err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	for name, ctx := range map[string]context.Context{
+		"no actor":  context.Background(),
+		"anonymous": WithActor(context.Background(), Actor{}),
+	} {
+		var count int64
+		if err := db.WithContext(ctx).Model(&auditOrder{}).Count(&count).Error; err != nil || count != 0 {
+			t.Fatalf("%s: count = %d, %v; want 0", name, count, err)
+		}
+		var orders []auditOrder
+		if err := db.WithContext(ctx).Find(&orders).Error; err != nil || len(orders) != 0 {
+			t.Fatalf("%s: Find() = %d rows, %v; want none", name, len(orders), err)
+		}
+		if err := db.WithContext(ctx).Create(&auditOrder{Name: "x", TenantBase: model.TenantBase{TenantCode: "tenant-a"}}).Error; !errors.Is(err, ErrNoTenant) {
+			t.Fatalf("%s: Create() error = %v, want ErrNoTenant", name, err)
+		}
+		if err := db.WithContext(ctx).Model(&auditOrder{}).Where("id = ?", order.ID).Update("name", "hacked").Error; !errors.Is(err, ErrNoTenant) {
+			t.Fatalf("%s: Update() error = %v, want ErrNoTenant", name, err)
+		}
+		if err := db.WithContext(ctx).Delete(&auditOrder{}, order.ID).Error; !errors.Is(err, ErrNoTenant) {
+			t.Fatalf("%s: Delete() error = %v, want ErrNoTenant", name, err)
+		}
+	}
+
+	// 只有 WithoutTenant 可以跳过隔离
+	bypass := WithoutTenant(context.Background())
+	var count int64
+	if err := db.WithContext(bypass).Model(&auditOrder{}).Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("bypass count = %d, %v; want 1", count, err)
+	}
+	if err := db.WithContext(bypass).Model(&auditOrder{}).Where("id = ?", order.ID).Update("name", "ops").Error; err != nil {
+		t.Fatalf("bypass Update() error = %v", err)
+	}
+	var reloaded auditOrder
+	if err := db.WithContext(alice).First(&reloaded, order.ID).Error; err != nil || reloaded.Name != "ops" {
+		t.Fatalf("reloaded = %+v, %v", reloaded, err)
+	}
+}
+
+func TestOptimisticLock(t *testing.T) {
+	db := openTestDB(t)
+	ctx := WithActor(context.Background(), Actor{UserID: "alice", TenantCode: "tenant-a"})
+	order := auditOrder{Name: "v1"}
+	if err := db.WithContext(ctx).Create(&order).Error; err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	var first, second auditOrder
+	db.WithContext(ctx).First(&first, order.ID)
+	db.WithContext(ctx).First(&second, order.ID)
+
+	first.Name = "v2"
+	if err := db.WithContext(ctx).Save(&first).Error; err != nil || first.Version != 2 {
+		t.Fatalf("Save() = %d, %v", first.Version, err)
+	}
+	second.Name = "stale"
+	if err := db.WithContext(ctx).Save(&second).Error; !errors.Is(err, ErrOptimisticLock) {
+		t.Fatalf("stale Save() error = %v", err)
+	}
+	if second.Version != 1 {
+		t.Fatalf("stale version = %d, want restored 1", second.Version)
+	}
+	if err := db.WithContext(ctx).Model(&first).Update("name", "v3").Error; err != nil || first.Version != 3 {
+		t.Fatalf("Update() = %d, %v", first.Version, err)
+	}
+	var stored auditOrder
+	db.WithContext(ctx).First(&stored, order.ID)
+	if stored.Name != "v3" || stored.Version != 3 {
+		t.Fatalf("stored = %+v", stored)
+	}
+}
@@ -0,0 +1,79 @@
+// Package audit 模型审计列与多租户隔离：按上下文中的操作人自动填充创建人/更新人/租户，
+// 查询、更新、删除自动追加租户条件，并基于版本列实现乐观锁
+package audit
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/goodbye-jack/go-common/utils"
+)
+
+// Actor 当前操作人
+type Actor struct {
+	UserID     string
+	TenantCode string
+}
+
+// Resolver 从上下文解析操作人，如 http 包从 gin.Context 的 Principal 解析
+type Resolver func(ctx context.Context) (Actor, bool)
+
+type actorContextKey struct{}
+
+type bypassContextKey struct{}
+
+var (
+	resolverMu sync.RWMutex
+	resolvers  []Resolver
+)
+
+// RegisterResolver 注册操作人解析器，按注册顺序尝试
+func RegisterResolver(resolver Resolver) {
+	if resolver == nil {
+		return
+	}
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+	resolvers = append(resolvers, resolver)
+}
+
+// WithActor 在 ctx 中显式携带操作人，优先于解析器，适用于异步任务、消息消费等无请求上下文的场景
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext 依次读取 WithActor 写入的操作人、已注册的解析器、TenantMiddleware 写入的租户
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	if ctx == nil {
+		return Actor{}, false
+	}
+	if actor, ok := ctx.Value(actorContextKey{}).(Actor); ok {
+		return actor, true
+	}
+	resolverMu.RLock()
+	defer resolverMu.RUnlock()
+	for _, resolver := range resolvers {
+		if actor, ok := resolver(ctx); ok {
+			return actor, true
+		}
+	}
+	if tenant, ok := ctx.Value(utils.TenantContextName).(string); ok && strings.TrimSpace(tenant) != "" {
+		return Actor{TenantCode: strings.TrimSpace(tenant)}, true
+	}
+	return Actor{}, false
+}
+
+// WithoutTenant 显式跳过租户隔离(跨租户的运维、统计任务)，审计列照常填充
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassContextKey{}, true)
+}
+
+// TenantBypassed ctx 是否跳过租户隔离
+func TenantBypassed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	bypass, _ := ctx.Value(bypassContextKey{}).(bool)
+	return bypass
+}
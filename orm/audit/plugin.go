@@ -0,0 +1,356 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Tag 审计字段的结构体标签，取值见 Tag* 常量，model.AuditBase/TenantBase/VersionBase 已声明
+const Tag = "audit"
+
+const (
+	TagCreatedBy = "created_by"
+	TagUpdatedBy = "updated_by"
+	TagTenant    = "tenant"
+	TagVersion   = "version"
+)
+
+var (
+	// ErrCrossTenant 写入或更新的租户与当前操作人租户不一致
+	ErrCrossTenant = errors.New("audit: cross-tenant write denied")
+	// ErrOptimisticLock 版本号已变化(记录被他人修改或已不存在)，未更新任何行
+	ErrOptimisticLock = errors.New("audit: optimistic lock conflict")
+	// ErrNoTenant 租户模型的写入缺少租户且未通过 WithoutTenant 显式跳过隔离
+	ErrNoTenant = errors.New("audit: tenant required")
+)
+
+const (
+	tenantClauseKey   = "audit:tenant"
+	versionSettingKey = "audit:version"
+)
+
+// Plugin gorm 插件，只作用于声明了 audit 标签的模型。orm.Open 已自动注册，
+// 直接使用 gorm.Open 时需 db.Use(audit.Plugin{})；Raw/Exec 手写 SQL 不受影响
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "audit"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := []error{
+		db.Callback().Create().Before("gorm:create").Register("audit:before_create", beforeCreate),
+		db.Callback().Query().Before("gorm:query").Register("audit:tenant_query", scopeTenant),
+		db.Callback().Row().Before("gorm:row").Register("audit:tenant_row", scopeTenant),
+		db.Callback().Delete().Before("gorm:delete").Register("audit:tenant_delete", scopeTenantWrite),
+		db.Callback().Update().Before("gorm:update").Register("audit:before_update", beforeUpdate),
+		db.Callback().Update().After("gorm:update").Register("audit:after_update", afterUpdate),
+	}
+	return errors.Join(callbacks...)
+}
+
+type fields struct {
+	createdBy *schema.Field
+	updatedBy *schema.Field
+	tenant    *schema.Field
+	version   *schema.Field
+}
+
+func (f *fields) empty() bool {
+	return f.createdBy == nil && f.updatedBy == nil && f.tenant == nil && f.version == nil
+}
+
+var fieldsCache sync.Map // *schema.Schema -> *fields
+
+func fieldsOf(s *schema.Schema) *fields {
+	if s == nil {
+		return &fields{}
+	}
+	if cached, ok := fieldsCache.Load(s); ok {
+		return cached.(*fields)
+	}
+	f := &fields{}
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(field.Tag.Get(Tag))) {
+		case TagCreatedBy:
+			f.createdBy = field
+		case TagUpdatedBy:
+			f.updatedBy = field
+		case TagTenant:
+			f.tenant = field
+		case TagVersion:
+			f.version = field
+		}
+	}
+	actual, _ := fieldsCache.LoadOrStore(s, f)
+	return actual.(*fields)
+}
+
+// tenantOf 当前需要隔离的租户；bypassed 表示已通过 WithoutTenant 显式跳过隔离
+func tenantOf(db *gorm.DB) (tenant string, bypassed bool) {
+	if TenantBypassed(db.Statement.Context) {
+		return "", true
+	}
+	actor, _ := ActorFromContext(db.Statement.Context)
+	return strings.TrimSpace(actor.TenantCode), false
+}
+
+// scopeTenant 为租户模型的查询追加 tenant_code = ? 条件；解析不到租户时追加 1 = 0，
+// 只有 WithoutTenant 才能读取全部租户的数据
+func scopeTenant(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	f := fieldsOf(db.Statement.Schema)
+	if f.tenant == nil {
+		return
+	}
+	if _, ok := db.Statement.Clauses[tenantClauseKey]; ok {
+		return
+	}
+	tenant, bypassed := tenantOf(db)
+	if bypassed {
+		return
+	}
+	var expr clause.Expression = clause.Expr{SQL: "1 = 0"}
+	if tenant != "" {
+		expr = clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.tenant.DBName}, Value: tenant}
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{expr}})
+	db.Statement.Clauses[tenantClauseKey] = clause.Clause{}
+}
+
+// scopeTenantWrite 租户模型的删除/更新缺少租户时返回 ErrNoTenant，其余同 scopeTenant
+func scopeTenantWrite(db *gorm.DB) {
+	if db.Error != nil || fieldsOf(db.Statement.Schema).tenant == nil {
+		return
+	}
+	if tenant, bypassed := tenantOf(db); tenant == "" && !bypassed {
+		db.AddError(ErrNoTenant)
+		return
+	}
+	scopeTenant(db)
+}
+
+func beforeCreate(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	f := fieldsOf(db.Statement.Schema)
+	if f.empty() {
+		return
+	}
+	actor, _ := ActorFromContext(db.Statement.Context)
+	tenant, bypassed := tenantOf(db)
+	if f.tenant != nil && tenant == "" && !bypassed {
+		db.AddError(ErrNoTenant)
+		return
+	}
+	switch dest := db.Statement.Dest.(type) {
+	case map[string]interface{}:
+		db.AddError(fillMap(f, dest, actor, tenant))
+		return
+	case []map[string]interface{}:
+		for _, row := range dest {
+			if db.AddError(fillMap(f, row, actor, tenant)) != nil {
+				return
+			}
+		}
+		return
+	}
+	switch rv := db.Statement.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if db.AddError(fillStruct(db, f, reflect.Indirect(rv.Index(i)), actor, tenant)) != nil {
+				return
+			}
+		}
+	case reflect.Struct:
+		db.AddError(fillStruct(db, f, rv, actor, tenant))
+	}
+}
+
+// fillStruct 新建记录：补充创建人、更新人、租户与初始版本号，显式指定其他租户时拒绝
+func fillStruct(db *gorm.DB, f *fields, rv reflect.Value, actor Actor, tenant string) error {
+	ctx := db.Statement.Context
+	setIfZero := func(field *schema.Field, value interface{}) error {
+		if field == nil {
+			return nil
+		}
+		if _, zero := field.ValueOf(ctx, rv); zero {
+			return field.Set(ctx, rv, value)
+		}
+		return nil
+	}
+	if actor.UserID != "" {
+		if err := setIfZero(f.createdBy, actor.UserID); err != nil {
+			return err
+		}
+		if err := setIfZero(f.updatedBy, actor.UserID); err != nil {
+			return err
+		}
+	}
+	if f.tenant != nil && tenant != "" {
+		if value, zero := f.tenant.ValueOf(ctx, rv); !zero && fmt.Sprint(value) != tenant {
+			return fmt.Errorf("%w: %v", ErrCrossTenant, value)
+		}
+		if err := setIfZero(f.tenant, tenant); err != nil {
+			return err
+		}
+	}
+	return setIfZero(f.version, 1)
+}
+
+func fillMap(f *fields, values map[string]interface{}, actor Actor, tenant string) error {
+	lookup := func(field *schema.Field) (interface{}, bool) {
+		if value, ok := values[field.Name]; ok {
+			return value, true
+		}
+		value, ok := values[field.DBName]
+		return value, ok
+	}
+	setIfAbsent := func(field *schema.Field, value interface{}) {
+		if field == nil {
+			return
+		}
+		if _, ok := lookup(field); !ok {
+			values[field.DBName] = value
+		}
+	}
+	if actor.UserID != "" {
+		setIfAbsent(f.createdBy, actor.UserID)
+		setIfAbsent(f.updatedBy, actor.UserID)
+	}
+	if f.tenant != nil && tenant != "" {
+		if value, ok := lookup(f.tenant); ok && fmt.Sprint(value) != tenant {
+			return fmt.Errorf("%w: %v", ErrCrossTenant, value)
+		}
+		setIfAbsent(f.tenant, tenant)
+	}
+	setIfAbsent(f.version, 1)
+	return nil
+}
+
+func beforeUpdate(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	stmt := db.Statement
+	f := fieldsOf(stmt.Schema)
+	if f.empty() {
+		return
+	}
+	if f.tenant != nil {
+		if tenant, _ := tenantOf(db); tenant != "" {
+			if value, ok := updatingValue(stmt, f.tenant); ok && fmt.Sprint(value) != tenant {
+				db.AddError(fmt.Errorf("%w: %v", ErrCrossTenant, value))
+				return
+			}
+		}
+		if scopeTenantWrite(db); db.Error != nil {
+			return
+		}
+	}
+	if actor, _ := ActorFromContext(stmt.Context); actor.UserID != "" && f.updatedBy != nil {
+		setColumn(stmt, f.updatedBy, actor.UserID)
+	}
+	lockVersion(db, f.version)
+}
+
+// updatingValue 本次更新中显式给出的字段值(map 的键或结构体的非零字段)
+func updatingValue(stmt *gorm.Statement, field *schema.Field) (interface{}, bool) {
+	switch dest := stmt.Dest.(type) {
+	case map[string]interface{}:
+		if value, ok := dest[field.Name]; ok {
+			return value, true
+		}
+		value, ok := dest[field.DBName]
+		return value, ok
+	}
+	rv := reflect.Indirect(reflect.ValueOf(stmt.Dest))
+	if rv.Kind() != reflect.Struct || rv.Type() != stmt.Schema.ModelType {
+		return nil, false
+	}
+	value, zero := field.ValueOf(stmt.Context, rv)
+	return value, !zero
+}
+
+// setColumn 设置更新列，Select 限定了列时一并加入
+func setColumn(stmt *gorm.Statement, field *schema.Field, value interface{}) {
+	if len(stmt.Selects) > 0 && !selected(stmt.Selects, field) {
+		stmt.Selects = append(stmt.Selects, field.DBName)
+	}
+	stmt.SetColumn(field.DBName, value, true)
+}
+
+// lockVersion 模型带有非零版本号时追加 version = 当前值 条件并把版本号加一
+func lockVersion(db *gorm.DB, field *schema.Field) {
+	stmt := db.Statement
+	if field == nil || stmt.ReflectValue.Kind() != reflect.Struct || !stmt.ReflectValue.CanAddr() {
+		return
+	}
+	if _, explicit := updatingValue(stmt, field); explicit {
+		if _, isMap := stmt.Dest.(map[string]interface{}); isMap {
+			return // map 中显式给出版本号时由调用方自行控制
+		}
+	}
+	current, zero := field.ValueOf(stmt.Context, stmt.ReflectValue)
+	if zero {
+		return
+	}
+	next, ok := increment(current)
+	if !ok {
+		return
+	}
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: current},
+	}})
+	stmt.Settings.Store(versionSettingKey, current)
+	setColumn(stmt, field, next)
+}
+
+func afterUpdate(db *gorm.DB) {
+	current, ok := db.Statement.Settings.LoadAndDelete(versionSettingKey)
+	if !ok {
+		return
+	}
+	if db.Error == nil && db.RowsAffected > 0 {
+		return
+	}
+	if f := fieldsOf(db.Statement.Schema); f.version != nil {
+		_ = f.version.Set(db.Statement.Context, db.Statement.ReflectValue, current)
+	}
+	if db.Error == nil {
+		db.AddError(ErrOptimisticLock)
+	}
+}
+
+func increment(value interface{}) (interface{}, bool) {
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() + 1, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() + 1, true
+	}
+	return nil, false
+}
+
+func selected(selects []string, field *schema.Field) bool {
+	for _, name := range selects {
+		if name == "*" || name == field.Name || name == field.DBName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package dialect
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+)
+
+// excludedAlias MERGE INTO 中待写入数据的别名，与 PostgreSQL ON CONFLICT 的 excluded 一致，
+// 使 clause.AssignmentColumns 生成的 "excluded"."col" 在两种语法下都可用
+const excludedAlias = "excluded"
+
+// buildFetchLimit 以 SQL:2008 的 OFFSET ... ROWS FETCH NEXT ... ROWS ONLY 生成分页，
+// 达梦、Oracle 12c+ 通用，替代改写 SQL 字符串的 ROW_NUMBER/TOP 方案
+func buildFetchLimit(c clause.Clause, builder clause.Builder) {
+	limit, ok := c.Expression.(clause.Limit)
+	if !ok {
+		c.Build(builder)
+		return
+	}
+	if limit.Offset > 0 {
+		builder.WriteString("OFFSET ")
+		builder.WriteString(strconv.Itoa(limit.Offset))
+		builder.WriteString(" ROWS")
+	}
+	if limit.Limit != nil && *limit.Limit >= 0 {
+		if limit.Offset > 0 {
+			builder.WriteByte(' ')
+		}
+		builder.WriteString("FETCH NEXT ")
+		builder.WriteString(strconv.Itoa(*limit.Limit))
+		builder.WriteString(" ROWS ONLY")
+	}
+}
+
+// quoteTo 双引号转义标识符，"schema.table" 按段分别转义，已带引号的段保持不变
+func quoteTo(writer clause.Writer, str string) {
+	for i, part := range strings.Split(str, ".") {
+		if i > 0 {
+			writer.WriteByte('.')
+		}
+		if len(part) >= 2 && part[0] == '"' && part[len(part)-1] == '"' {
+			writer.WriteString(part)
+			continue
+		}
+		writer.WriteByte('"')
+		writer.WriteString(strings.ReplaceAll(part, `"`, `""`))
+		writer.WriteByte('"')
+	}
+}
+
+// mergeCreate 在 create(gorm:create 或 execCreate) 的基础上把 ON CONFLICT 转换为 MERGE INTO，
+// 其余插入仍交给 create
+func mergeCreate(create func(*gorm.DB)) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+		if _, ok := db.Statement.Clauses["ON CONFLICT"]; !ok || db.Statement.Schema == nil {
+			create(db)
+			return
+		}
+		if db.Statement.SQL.Len() == 0 {
+			if err := buildMerge(db.Statement); err != nil {
+				_ = db.AddError(err)
+				return
+			}
+		}
+		execStatement(db)
+	}
+}
+
+// execCreate 与 gorm:create 一样生成 INSERT，但不读取 LastInsertId(Oracle 驱动不支持)，
+// 自增主键由 assignSequenceIDs 预先取号
+func execCreate(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	if db.Statement.Schema != nil && !db.Statement.Unscoped {
+		for _, c := range db.Statement.Schema.CreateClauses {
+			db.Statement.AddClause(c)
+		}
+	}
+	if db.Statement.SQL.Len() == 0 {
+		db.Statement.SQL.Grow(180)
+		db.Statement.AddClauseIfNotExists(clause.Insert{})
+		db.Statement.AddClause(callbacks.ConvertToCreateValues(db.Statement))
+		db.Statement.Build(db.Statement.BuildClauses...)
+	}
+	execStatement(db)
+}
+
+func execStatement(db *gorm.DB) {
+	if db.DryRun || db.Error != nil {
+		return
+	}
+	result, err := db.Statement.ConnPool.ExecContext(db.Statement.Context, db.Statement.SQL.String(), db.Statement.Vars...)
+	if db.AddError(err) == nil {
+		db.RowsAffected, _ = result.RowsAffected()
+	}
+}
+
+// buildMerge 生成
+//
+//	MERGE INTO "t" USING (SELECT ? AS "a", ? AS "b" FROM DUAL UNION ALL ...) "excluded"
+//	ON ("t"."a" = "excluded"."a")
+//	WHEN MATCHED THEN UPDATE SET "b" = "excluded"."b"
+//	WHEN NOT MATCHED THEN INSERT ("a","b") VALUES ("excluded"."a","excluded"."b")
+//
+// 冲突列默认为主键；MERGE 不返回自增主键，需要主键时请先查询
+func buildMerge(stmt *gorm.Statement) error {
+	values := callbacks.ConvertToCreateValues(stmt)
+	onConflict, _ := stmt.Clauses["ON CONFLICT"].Expression.(clause.OnConflict)
+	if onConflict.OnConstraint != "" {
+		return errors.New("merge upsert does not support ON CONSTRAINT, use conflict columns")
+	}
+	if len(values.Columns) == 0 || len(values.Values) == 0 {
+		return gorm.ErrEmptySlice
+	}
+	conflictColumns := onConflict.Columns
+	if len(conflictColumns) == 0 {
+		for _, field := range stmt.Schema.PrimaryFields {
+			conflictColumns = append(conflictColumns, clause.Column{Name: field.DBName})
+		}
+	}
+	if len(conflictColumns) == 0 {
+		return errors.New("merge upsert requires conflict columns or primary key")
+	}
+	onColumns := make(map[string]bool, len(conflictColumns))
+	for _, column := range conflictColumns {
+		onColumns[column.Name] = true
+	}
+
+	stmt.WriteString("MERGE INTO ")
+	stmt.WriteQuoted(stmt.Table)
+	stmt.WriteString(" USING (")
+	for i, row := range values.Values {
+		if i > 0 {
+			stmt.WriteString(" UNION ALL ")
+		}
+		stmt.WriteString("SELECT ")
+		for j, column := range values.Columns {
+			if j > 0 {
+				stmt.WriteString(", ")
+			}
+			stmt.AddVar(stmt, row[j])
+			stmt.WriteString(" AS ")
+			stmt.WriteQuoted(column.Name)
+		}
+		stmt.WriteString(" FROM DUAL")
+	}
+	stmt.WriteString(") ")
+	stmt.WriteQuoted(excludedAlias)
+	stmt.WriteString(" ON (")
+	for i, column := range conflictColumns {
+		if i > 0 {
+			stmt.WriteString(" AND ")
+		}
+		stmt.WriteQuoted(clause.Column{Table: stmt.Table, Name: column.Name})
+		stmt.WriteString(" = ")
+		stmt.WriteQuoted(clause.Column{Table: excludedAlias, Name: column.Name})
+	}
+	stmt.WriteString(")")
+
+	if !onConflict.DoNothing {
+		// 达梦/Oracle 不允许更新 ON 子句中的列
+		updates := make(clause.Set, 0, len(onConflict.DoUpdates))
+		for _, assignment := range onConflict.DoUpdates {
+			if !onColumns[assignment.Column.Name] {
+				updates = append(updates, assignment)
+			}
+		}
+		if len(updates) > 0 {
+			stmt.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+			for i, assignment := range updates {
+				if i > 0 {
+					stmt.WriteString(", ")
+				}
+				stmt.WriteQuoted(clause.Column{Name: assignment.Column.Name})
+				stmt.WriteString(" = ")
+				stmt.AddVar(stmt, assignment.Value)
+			}
+			if len(onConflict.Where.Exprs) > 0 {
+				stmt.WriteString(" WHERE ")
+				onConflict.Where.Build(stmt)
+			}
+		}
+	}
+
+	stmt.WriteString(" WHEN NOT MATCHED THEN INSERT (")
+	for i, column := range values.Columns {
+		if i > 0 {
+			stmt.WriteByte(',')
+		}
+		stmt.WriteQuoted(column.Name)
+	}
+	stmt.WriteString(") VALUES (")
+	for i, column := range values.Columns {
+		if i > 0 {
+			stmt.WriteByte(',')
+		}
+		stmt.WriteQuoted(clause.Column{Table: excludedAlias, Name: column.Name})
+	}
+	stmt.WriteString(")")
+	return nil
+}
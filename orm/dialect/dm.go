@@ -0,0 +1,188 @@
+package dialect
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DMDriverName 达梦 database/sql 驱动名
+	DMDriverName = "dm"
+	// dmMaxVarcharSize 页大小 8K 时 VARCHAR/VARBINARY 的最大长度，超出使用 CLOB/BLOB
+	dmMaxVarcharSize = 8188
+	// dmIdentifierMaxLength 达梦标识符最大长度
+	dmIdentifierMaxLength = 128
+)
+
+// DMConfig 达梦方言配置
+type DMConfig struct {
+	DriverName string        // 默认 dm
+	DSN        string        // dm://user:password@host:port?schema=xxx
+	Conn       gorm.ConnPool // 已有连接，设置后忽略 DriverName/DSN
+}
+
+// DMDialector 达梦方言：双引号标识符、OFFSET/FETCH 分页、MERGE INTO 实现 upsert，
+// 迁移器基于 USER_* 数据字典
+type DMDialector struct {
+	*DMConfig
+}
+
+// OpenDM 以 DSN 创建达梦方言，驱动由 github.com/jasonlabz/gorm-dm-driver 引入的官方驱动注册
+func OpenDM(dsn string) gorm.Dialector {
+	return &DMDialector{DMConfig: &DMConfig{DSN: dsn}}
+}
+
+// NewDM 以配置创建达梦方言
+func NewDM(config DMConfig) gorm.Dialector {
+	return &DMDialector{DMConfig: &config}
+}
+
+// NewDMDialector 以 DSN 创建达梦方言
+//
+// Deprecated: 使用 OpenDM
+func NewDMDialector(dsn string) gorm.Dialector {
+	return OpenDM(dsn)
+}
+
+func (d DMDialector) Name() string {
+	return "dm"
+}
+
+func (d DMDialector) Apply(config *gorm.Config) error {
+	if config.NamingStrategy == nil {
+		config.NamingStrategy = schema.NamingStrategy{IdentifierMaxLength: dmIdentifierMaxLength}
+	}
+	return nil
+}
+
+func (d DMDialector) Initialize(db *gorm.DB) (err error) {
+	callbackConfig := &callbacks.Config{
+		CreateClauses: []string{"INSERT", "VALUES"},
+		UpdateClauses: []string{"UPDATE", "SET", "WHERE"},
+		DeleteClauses: []string{"DELETE", "FROM", "WHERE"},
+	}
+	callbacks.RegisterDefaultCallbacks(db, callbackConfig)
+	if err = db.Callback().Create().Replace("gorm:create", mergeCreate(callbacks.Create(callbackConfig))); err != nil {
+		return err
+	}
+	db.ClauseBuilders["LIMIT"] = buildFetchLimit
+
+	if d.Conn != nil {
+		db.ConnPool = d.Conn
+		return nil
+	}
+	driverName := d.DriverName
+	if driverName == "" {
+		driverName = DMDriverName
+	}
+	db.ConnPool, err = sql.Open(driverName, d.DSN)
+	return err
+}
+
+func (d DMDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return DMMigrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}
+}
+
+func (d DMDialector) DefaultValueOf(*schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (d DMDialector) BindVarTo(writer clause.Writer, _ *gorm.Statement, _ interface{}) {
+	writer.WriteByte('?')
+}
+
+func (d DMDialector) QuoteTo(writer clause.Writer, str string) {
+	quoteTo(writer, str)
+}
+
+func (d DMDialector) Explain(sql string, vars ...interface{}) string {
+	return logger.ExplainSQL(sql, nil, `'`, vars...)
+}
+
+// DataTypeOf 类型映射：json/text 映射为 CLOB，超长字符串与二进制映射为 CLOB/BLOB，自增列使用 IDENTITY
+func (d DMDialector) DataTypeOf(field *schema.Field) string {
+	switch field.DataType {
+	case schema.Bool:
+		return "BIT"
+	case schema.Int, schema.Uint:
+		size := field.Size
+		if field.DataType == schema.Uint {
+			size++
+		}
+		sqlType := "BIGINT"
+		switch {
+		case size <= 8:
+			sqlType = "TINYINT"
+		case size <= 16:
+			sqlType = "SMALLINT"
+		case size <= 32:
+			sqlType = "INT"
+		}
+		if field.AutoIncrement {
+			sqlType += " IDENTITY(1,1)"
+		}
+		return sqlType
+	case schema.Float:
+		if field.Precision > 0 {
+			if field.Scale > 0 {
+				return fmt.Sprintf("DECIMAL(%d,%d)", field.Precision, field.Scale)
+			}
+			return fmt.Sprintf("DECIMAL(%d)", field.Precision)
+		}
+		if field.Size <= 32 {
+			return "REAL"
+		}
+		return "DOUBLE"
+	case schema.String:
+		switch {
+		case field.Size <= 0:
+			return "VARCHAR(255)"
+		case field.Size <= dmMaxVarcharSize:
+			return fmt.Sprintf("VARCHAR(%d)", field.Size)
+		default:
+			return "CLOB"
+		}
+	case schema.Time:
+		if field.Precision > 0 {
+			return fmt.Sprintf("TIMESTAMP(%d)", field.Precision)
+		}
+		return "TIMESTAMP"
+	case schema.Bytes:
+		if field.Size > 0 && field.Size <= dmMaxVarcharSize {
+			return fmt.Sprintf("VARBINARY(%d)", field.Size)
+		}
+		return "BLOB"
+	}
+	return largeObjectType(string(field.DataType), "CLOB", "BLOB")
+}
+
+// largeObjectType 把其他数据库常见的 json/text/blob 类型换成目标方言的大对象类型，其余原样返回
+func largeObjectType(sqlType, textType, binaryType string) string {
+	switch strings.ToLower(sqlType) {
+	case "json", "jsonb", "text", "tinytext", "mediumtext", "longtext", "clob":
+		return textType
+	case "blob", "tinyblob", "mediumblob", "longblob", "bytea":
+		return binaryType
+	}
+	return sqlType
+}
+
+func (d DMDialector) SavePoint(tx *gorm.DB, name string) error {
+	return tx.Exec("SAVEPOINT " + name).Error
+}
+
+func (d DMDialector) RollbackTo(tx *gorm.DB, name string) error {
+	return tx.Exec("ROLLBACK TO SAVEPOINT " + name).Error
+}
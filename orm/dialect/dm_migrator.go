@@ -0,0 +1,426 @@
+package dialect
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// DMMigrator 达梦迁移器，表、列、索引与约束信息来自 USER_*(带模式名时为 ALL_*) 数据字典，
+// 与 Oracle 的数据字典兼容，OracleMigrator 在此基础上实现
+type DMMigrator struct {
+	migrator.Migrator
+	charLength bool // 字符串列按字符计长(Oracle CHAR 语义)，长度取 CHAR_LENGTH
+}
+
+// dictionary 数据字典查询范围
+type dictionary struct {
+	prefix string
+	owner  string
+	table  string
+}
+
+func dictionaryOf(table string) dictionary {
+	if i := strings.LastIndex(table, "."); i > 0 {
+		return dictionary{prefix: "ALL_", owner: strings.Trim(table[:i], `"`), table: strings.Trim(table[i+1:], `"`)}
+	}
+	return dictionary{prefix: "USER_", table: strings.Trim(table, `"`)}
+}
+
+func (d dictionary) view(name string) string {
+	return d.prefix + name
+}
+
+// where 表名条件；未加引号创建的对象以大写保存，因此同时匹配原名与大写
+func (d dictionary) where(alias string) (string, []interface{}) {
+	column := func(name string) string {
+		if alias == "" {
+			return name
+		}
+		return alias + "." + name
+	}
+	query := column("TABLE_NAME") + " IN ?"
+	args := []interface{}{nameVariants(d.table)}
+	if d.owner != "" {
+		query += " AND " + column("OWNER") + " IN ?"
+		args = append(args, nameVariants(d.owner))
+	}
+	return query, args
+}
+
+func nameVariants(name string) []string {
+	if upper := strings.ToUpper(name); upper != name {
+		return []string{name, upper}
+	}
+	return []string{name}
+}
+
+// qualified 带模式名的表上，索引名同样加上模式名
+func (d dictionary) qualified(name string) string {
+	if d.owner == "" {
+		return name
+	}
+	return d.owner + "." + name
+}
+
+func (m DMMigrator) CurrentDatabase() (name string) {
+	m.DB.Raw("SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM DUAL").Row().Scan(&name)
+	return
+}
+
+func (m DMMigrator) GetTables() (tableList []string, err error) {
+	err = m.DB.Raw("SELECT TABLE_NAME FROM USER_TABLES").Scan(&tableList).Error
+	return
+}
+
+// FullDataTypeOf 列定义，DEFAULT 需在 NOT NULL 之前
+func (m DMMigrator) FullDataTypeOf(field *schema.Field) (expr clause.Expr) {
+	expr.SQL = m.DataTypeOf(field)
+	if field.HasDefaultValue && (field.DefaultValueInterface != nil || field.DefaultValue != "") {
+		if value, ok := field.DefaultValueInterface.(bool); ok {
+			expr.SQL += " DEFAULT " + map[bool]string{true: "1", false: "0"}[value]
+		} else if field.DefaultValueInterface != nil {
+			defaultStmt := &gorm.Statement{Vars: []interface{}{field.DefaultValueInterface}}
+			m.Dialector.BindVarTo(defaultStmt, defaultStmt, field.DefaultValueInterface)
+			expr.SQL += " DEFAULT " + m.Dialector.Explain(defaultStmt.SQL.String(), field.DefaultValueInterface)
+		} else if field.DefaultValue != "(-)" {
+			expr.SQL += " DEFAULT " + field.DefaultValue
+		}
+	}
+	if field.NotNull {
+		expr.SQL += " NOT NULL"
+	}
+	return
+}
+
+func (m DMMigrator) HasTable(value interface{}) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		dict := dictionaryOf(stmt.Table)
+		where, args := dict.where("")
+		return m.DB.Raw("SELECT COUNT(*) FROM "+dict.view("TABLES")+" WHERE "+where, args...).Row().Scan(&count)
+	})
+	return count > 0
+}
+
+func (m DMMigrator) HasColumn(value interface{}, field string) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		name := field
+		if stmt.Schema != nil {
+			if f := stmt.Schema.LookUpField(field); f != nil {
+				name = f.DBName
+			}
+		}
+		dict := dictionaryOf(stmt.Table)
+		where, args := dict.where("")
+		return m.DB.Raw("SELECT COUNT(*) FROM "+dict.view("TAB_COLUMNS")+" WHERE "+where+" AND COLUMN_NAME IN ?",
+			append(args, nameVariants(name))...).Row().Scan(&count)
+	})
+	return count > 0
+}
+
+// AlterColumn 使用 ALTER TABLE ... MODIFY；IDENTITY 不能修改，可空列显式声明 NULL
+func (m DMMigrator) AlterColumn(value interface{}, field string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema == nil {
+			return errors.New("failed to get schema")
+		}
+		f := stmt.Schema.LookUpField(field)
+		if f == nil {
+			return fmt.Errorf("failed to look up field with name: %s", field)
+		}
+		expr := m.DB.Migrator().FullDataTypeOf(f)
+		expr.SQL = strings.Replace(expr.SQL, " IDENTITY(1,1)", "", 1)
+		if !f.NotNull && !f.PrimaryKey {
+			expr.SQL += " NULL"
+		}
+		return m.DB.Exec("ALTER TABLE ? MODIFY ? ?", m.CurrentTable(stmt), clause.Column{Name: f.DBName}, expr).Error
+	})
+}
+
+// keyColumn 列上的主键/单列唯一约束
+type keyColumn struct {
+	primary bool
+	unique  bool
+}
+
+func (m DMMigrator) keyColumns(dict dictionary) (map[string]keyColumn, error) {
+	where, args := dict.where("c")
+	join := "cc.CONSTRAINT_NAME = c.CONSTRAINT_NAME"
+	if dict.owner != "" {
+		join += " AND cc.OWNER = c.OWNER"
+	}
+	rows, err := m.DB.Raw("SELECT c.CONSTRAINT_NAME, c.CONSTRAINT_TYPE, cc.COLUMN_NAME FROM "+dict.view("CONSTRAINTS")+" c JOIN "+
+		dict.view("CONS_COLUMNS")+" cc ON "+join+" WHERE "+where+" AND c.CONSTRAINT_TYPE IN ('P', 'U')", args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	type constraint struct {
+		kind    string
+		columns []string
+	}
+	constraints := map[string]*constraint{}
+	for rows.Next() {
+		var name, kind, column string
+		if err := rows.Scan(&name, &kind, &column); err != nil {
+			return nil, err
+		}
+		if constraints[name] == nil {
+			constraints[name] = &constraint{kind: kind}
+		}
+		constraints[name].columns = append(constraints[name].columns, column)
+	}
+	keys := map[string]keyColumn{}
+	for _, c := range constraints {
+		for _, column := range c.columns {
+			key := keys[column]
+			if c.kind == "P" {
+				key.primary = true
+			} else if len(c.columns) == 1 {
+				key.unique = true
+			}
+			keys[column] = key
+		}
+	}
+	return keys, rows.Err()
+}
+
+func (m DMMigrator) ColumnTypes(value interface{}) ([]gorm.ColumnType, error) {
+	columnTypes := make([]gorm.ColumnType, 0)
+	err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		dict := dictionaryOf(stmt.Table)
+		keys, err := m.keyColumns(dict)
+		if err != nil {
+			return err
+		}
+		// 大小写不敏感库中列名以大写返回，换回模型中的列名以免 AutoMigrate 重复加列
+		dbNames := map[string]string{}
+		if stmt.Schema != nil {
+			for _, dbName := range stmt.Schema.DBNames {
+				dbNames[strings.ToUpper(dbName)] = dbName
+			}
+		}
+		charLength := "DATA_LENGTH"
+		if m.charLength {
+			charLength = "CHAR_LENGTH"
+		}
+		where, args := dict.where("")
+		rows, err := m.DB.Raw("SELECT COLUMN_NAME, DATA_TYPE, DATA_LENGTH, "+charLength+", DATA_PRECISION, DATA_SCALE, NULLABLE, DATA_DEFAULT FROM "+
+			dict.view("TAB_COLUMNS")+" WHERE "+where+" ORDER BY COLUMN_ID", args...).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				name, dataType, nullable string
+				length, charLength       sql.NullInt64
+				precision, scale         sql.NullInt64
+				defaultValue             sql.NullString
+			)
+			if err := rows.Scan(&name, &dataType, &length, &charLength, &precision, &scale, &nullable, &defaultValue); err != nil {
+				return err
+			}
+			// Oracle 的 DATA_TYPE 带精度，如 TIMESTAMP(6) WITH TIME ZONE
+			if open, end := strings.IndexByte(dataType, '('), strings.IndexByte(dataType, ')'); open > 0 && end > open {
+				dataType = dataType[:open] + dataType[end+1:]
+			}
+			key := keys[name]
+			if dbName, ok := dbNames[strings.ToUpper(name)]; ok && dbName != name && strings.ToUpper(name) == name {
+				name = dbName
+			}
+			column := &migrator.ColumnType{
+				NameValue:       sql.NullString{String: name, Valid: true},
+				DataTypeValue:   sql.NullString{String: dataType, Valid: true},
+				ColumnTypeValue: sql.NullString{String: dataType, Valid: true},
+				NullableValue:   sql.NullBool{Bool: nullable == "Y", Valid: true},
+				PrimaryKeyValue: sql.NullBool{Bool: key.primary, Valid: true},
+				UniqueValue:     sql.NullBool{Bool: key.unique, Valid: true},
+				// 非定长/小数类型的长度与精度视为 0，避免回落到未设置的 SQLColumnType
+				LengthValue:      sql.NullInt64{Valid: true},
+				DecimalSizeValue: sql.NullInt64{Valid: true},
+				ScaleValue:       sql.NullInt64{Valid: true},
+			}
+			switch strings.ToUpper(dataType) {
+			case "CHAR", "CHARACTER", "VARCHAR", "VARCHAR2", "NVARCHAR", "NVARCHAR2":
+				column.LengthValue = charLength
+				column.ColumnTypeValue.String = fmt.Sprintf("%s(%d)", dataType, charLength.Int64)
+			case "BINARY", "VARBINARY", "RAW":
+				column.LengthValue = length
+				column.ColumnTypeValue.String = fmt.Sprintf("%s(%d)", dataType, length.Int64)
+			case "DEC", "DECIMAL", "NUMERIC", "NUMBER":
+				if precision.Valid {
+					column.DecimalSizeValue = precision
+					column.ScaleValue = sql.NullInt64{Int64: scale.Int64, Valid: true}
+					column.ColumnTypeValue.String = fmt.Sprintf("%s(%d,%d)", dataType, precision.Int64, scale.Int64)
+					if scale.Int64 == 0 {
+						column.ColumnTypeValue.String = fmt.Sprintf("%s(%d)", dataType, precision.Int64)
+					}
+					// 类型名带上精度，与 DataTypeOf 的 NUMBER(20)/DECIMAL(18,2) 整体比较，避免每次迁移都判定长度变化
+					column.DataTypeValue.String = column.ColumnTypeValue.String
+				}
+			}
+			if value := strings.Trim(strings.TrimSpace(defaultValue.String), "'"); defaultValue.Valid && value != "" {
+				column.DefaultValueValue = sql.NullString{String: value, Valid: true}
+			}
+			columnTypes = append(columnTypes, column)
+		}
+		return rows.Err()
+	})
+	return columnTypes, err
+}
+
+var dmTypeAliases = map[string][]string{
+	"int":       {"integer"},
+	"integer":   {"int"},
+	"bit":       {"boolean", "bool"},
+	"varchar":   {"varchar2"},
+	"varchar2":  {"varchar"},
+	"dec":       {"decimal", "numeric"},
+	"decimal":   {"numeric", "dec"},
+	"numeric":   {"decimal", "dec"},
+	"double":    {"double precision", "float"},
+	"float":     {"double"},
+	"text":      {"clob"},
+	"clob":      {"text"},
+	"image":     {"blob"},
+	"timestamp": {"datetime"},
+	"datetime":  {"timestamp"},
+}
+
+func (m DMMigrator) GetTypeAliases(databaseTypeName string) []string {
+	return dmTypeAliases[strings.ToLower(databaseTypeName)]
+}
+
+// BuildIndexOptions 达梦不支持前缀索引长度与 COLLATE
+func (m DMMigrator) BuildIndexOptions(opts []schema.IndexOption, stmt *gorm.Statement) (results []interface{}) {
+	for _, opt := range opts {
+		str := stmt.Quote(opt.DBName)
+		if opt.Expression != "" {
+			str = opt.Expression
+		}
+		if opt.Sort != "" {
+			str += " " + opt.Sort
+		}
+		results = append(results, clause.Expr{SQL: str})
+	}
+	return
+}
+
+func (m DMMigrator) CreateIndex(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema == nil {
+			return errors.New("failed to get schema")
+		}
+		idx := stmt.Schema.LookIndex(name)
+		if idx == nil {
+			return fmt.Errorf("failed to create index with name %s", name)
+		}
+		createIndexSQL := "CREATE "
+		if idx.Class != "" {
+			createIndexSQL += idx.Class + " "
+		}
+		createIndexSQL += "INDEX ? ON ??"
+		if idx.Option != "" {
+			createIndexSQL += " " + idx.Option
+		}
+		dict := dictionaryOf(stmt.Table)
+		return m.DB.Exec(createIndexSQL, clause.Table{Name: dict.qualified(idx.Name)}, m.CurrentTable(stmt),
+			m.BuildIndexOptions(idx.Fields, stmt)).Error
+	})
+}
+
+func (m DMMigrator) HasIndex(value interface{}, name string) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema != nil {
+			if idx := stmt.Schema.LookIndex(name); idx != nil {
+				name = idx.Name
+			}
+		}
+		dict := dictionaryOf(stmt.Table)
+		where, args := dict.where("")
+		return m.DB.Raw("SELECT COUNT(*) FROM "+dict.view("INDEXES")+" WHERE "+where+" AND INDEX_NAME IN ?",
+			append(args, nameVariants(name))...).Row().Scan(&count)
+	})
+	return count > 0
+}
+
+// DropIndex 达梦索引名在模式内唯一，DROP INDEX 不带表名
+func (m DMMigrator) DropIndex(value interface{}, name string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema != nil {
+			if idx := stmt.Schema.LookIndex(name); idx != nil {
+				name = idx.Name
+			}
+		}
+		return m.DB.Exec("DROP INDEX ?", clause.Table{Name: dictionaryOf(stmt.Table).qualified(name)}).Error
+	})
+}
+
+func (m DMMigrator) RenameIndex(value interface{}, oldName, newName string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		dict := dictionaryOf(stmt.Table)
+		return m.DB.Exec("ALTER INDEX ? RENAME TO ?", clause.Table{Name: dict.qualified(oldName)}, clause.Column{Name: newName}).Error
+	})
+}
+
+func (m DMMigrator) GetIndexes(value interface{}) ([]gorm.Index, error) {
+	indexes := make([]gorm.Index, 0)
+	err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		dict := dictionaryOf(stmt.Table)
+		where, args := dict.where("i")
+		rows, err := m.DB.Raw("SELECT i.INDEX_NAME, i.UNIQUENESS, ic.COLUMN_NAME, c.CONSTRAINT_TYPE FROM "+dict.view("INDEXES")+" i JOIN "+
+			dict.view("IND_COLUMNS")+" ic ON ic.INDEX_NAME = i.INDEX_NAME LEFT JOIN "+dict.view("CONSTRAINTS")+
+			" c ON c.INDEX_NAME = i.INDEX_NAME AND c.CONSTRAINT_TYPE = 'P' WHERE "+where+" ORDER BY i.INDEX_NAME, ic.COLUMN_POSITION", args...).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		byName := map[string]*migrator.Index{}
+		for rows.Next() {
+			var name, uniqueness, column string
+			var constraintType sql.NullString
+			if err := rows.Scan(&name, &uniqueness, &column, &constraintType); err != nil {
+				return err
+			}
+			idx := byName[name]
+			if idx == nil {
+				idx = &migrator.Index{
+					TableName:       stmt.Table,
+					NameValue:       name,
+					PrimaryKeyValue: sql.NullBool{Bool: constraintType.String == "P", Valid: true},
+					UniqueValue:     sql.NullBool{Bool: uniqueness == "UNIQUE", Valid: true},
+				}
+				byName[name] = idx
+				indexes = append(indexes, idx)
+			}
+			idx.ColumnList = append(idx.ColumnList, column)
+		}
+		return rows.Err()
+	})
+	return indexes, err
+}
+
+func (m DMMigrator) HasConstraint(value interface{}, name string) bool {
+	var count int64
+	m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		constraint, table := m.GuessConstraintInterfaceAndTable(stmt, name)
+		if constraint != nil {
+			name = constraint.GetName()
+		}
+		dict := dictionaryOf(table)
+		where, args := dict.where("")
+		return m.DB.Raw("SELECT COUNT(*) FROM "+dict.view("CONSTRAINTS")+" WHERE "+where+" AND CONSTRAINT_NAME IN ?",
+			append(args, nameVariants(name))...).Row().Scan(&count)
+	})
+	return count > 0
+}
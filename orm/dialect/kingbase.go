@@ -0,0 +1,74 @@
+package dialect
+
+import (
+	"strings"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// KingbaseDriverName 人大金仓 database/sql 驱动名(gitea.com/kingbase/gokb)
+const KingbaseDriverName = "kingbase"
+
+// KingbaseDialector 人大金仓方言，基于 PostgreSQL 兼容模式：分页使用 LIMIT/OFFSET，
+// upsert 使用 ON CONFLICT，迁移器复用 PostgreSQL 迁移器并替换类型映射
+type KingbaseDialector struct {
+	*postgres.Dialector
+}
+
+// OpenKingbase 以 DSN 创建人大金仓方言
+func OpenKingbase(dsn string) gorm.Dialector {
+	return NewKingbase(postgres.Config{DSN: dsn})
+}
+
+// NewKingbase 以配置创建人大金仓方言，DriverName 默认为 kingbase
+func NewKingbase(config postgres.Config) gorm.Dialector {
+	if config.DriverName == "" {
+		config.DriverName = KingbaseDriverName
+	}
+	return &KingbaseDialector{Dialector: postgres.New(config).(*postgres.Dialector)}
+}
+
+func (d KingbaseDialector) Name() string {
+	return "kingbase"
+}
+
+func (d KingbaseDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return KingbaseMigrator{Migrator: postgres.Migrator{Migrator: migrator.Migrator{Config: migrator.Config{
+		DB:                          db,
+		Dialector:                   d,
+		CreateIndexAfterCreateTable: true,
+	}}}}
+}
+
+// DataTypeOf 在 PostgreSQL 映射的基础上把 MySQL/Oracle 风格的 longtext/clob/blob 换成 text/bytea
+func (d KingbaseDialector) DataTypeOf(field *schema.Field) string {
+	switch strings.ToLower(string(field.DataType)) {
+	case "json", "jsonb":
+		return strings.ToLower(string(field.DataType))
+	case "datetime":
+		return d.Dialector.DataTypeOf(&schema.Field{DataType: schema.Time, Precision: field.Precision})
+	}
+	if sqlType := largeObjectType(string(field.DataType), "text", "bytea"); sqlType != string(field.DataType) {
+		return sqlType
+	}
+	return d.Dialector.DataTypeOf(field)
+}
+
+// KingbaseMigrator 人大金仓迁移器
+type KingbaseMigrator struct {
+	postgres.Migrator
+}
+
+func (m KingbaseMigrator) GetTypeAliases(databaseTypeName string) []string {
+	aliases := m.Migrator.GetTypeAliases(databaseTypeName)
+	switch strings.ToLower(databaseTypeName) {
+	case "text":
+		aliases = append(aliases, "clob")
+	case "bytea":
+		aliases = append(aliases, "blob")
+	}
+	return aliases
+}
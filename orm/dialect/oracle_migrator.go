@@ -0,0 +1,138 @@
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// OracleMigrator Oracle 迁移器，数据字典查询复用 DMMigrator，另外维护自增主键的序列
+type OracleMigrator struct {
+	DMMigrator
+}
+
+// quote 以方言规则转义标识符
+func (m OracleMigrator) quote(name string) string {
+	var builder strings.Builder
+	m.Dialector.QuoteTo(&builder, name)
+	return builder.String()
+}
+
+// FullDataTypeOf 自增主键以序列的 NEXTVAL 作为默认值，手写 INSERT 不带主键时同样取号
+func (m OracleMigrator) FullDataTypeOf(field *schema.Field) clause.Expr {
+	if field.Schema == nil || sequenceField(field.Schema) != field {
+		return m.DMMigrator.FullDataTypeOf(field)
+	}
+	expr := clause.Expr{SQL: m.DataTypeOf(field) + " DEFAULT " + m.quote(sequenceName(field)) + ".NEXTVAL"}
+	if field.NotNull {
+		expr.SQL += " NOT NULL"
+	}
+	return expr
+}
+
+// HasSequence 序列是否存在，name 可带模式名
+func (m OracleMigrator) HasSequence(name string) bool {
+	var count int64
+	dict := dictionaryOf(name)
+	query, args := "SELECT COUNT(*) FROM "+dict.view("SEQUENCES")+" WHERE SEQUENCE_NAME IN ?", []interface{}{nameVariants(dict.table)}
+	if dict.owner != "" {
+		query += " AND SEQUENCE_OWNER IN ?"
+		args = append(args, nameVariants(dict.owner))
+	}
+	m.DB.Raw(query, args...).Row().Scan(&count)
+	return count > 0
+}
+
+// CreateTable 先创建自增主键使用的序列，再建表
+func (m OracleMigrator) CreateTable(values ...interface{}) error {
+	for _, value := range m.ReorderModels(values, false) {
+		if err := m.RunWithValue(value, func(stmt *gorm.Statement) error {
+			if field := sequenceField(stmt.Schema); field != nil {
+				if name := sequenceName(field); !m.HasSequence(name) {
+					return m.DB.Exec("CREATE SEQUENCE ?", clause.Table{Name: name}).Error
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := m.DMMigrator.CreateTable(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropTable Oracle 23c 之前不支持 DROP TABLE IF EXISTS，先查数据字典；同时删除主键序列
+func (m OracleMigrator) DropTable(values ...interface{}) error {
+	values = m.ReorderModels(values, false)
+	for i := len(values) - 1; i >= 0; i-- {
+		if err := m.RunWithValue(values[i], func(stmt *gorm.Statement) error {
+			if m.HasTable(values[i]) {
+				if err := m.DB.Exec("DROP TABLE ? CASCADE CONSTRAINTS", m.CurrentTable(stmt)).Error; err != nil {
+					return err
+				}
+			}
+			if field := sequenceField(stmt.Schema); field != nil {
+				if name := sequenceName(field); m.HasSequence(name) {
+					return m.DB.Exec("DROP SEQUENCE ?", clause.Table{Name: name}).Error
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AlterColumn 使用 ALTER TABLE ... MODIFY；Oracle 对未变化的 NULL/NOT NULL 报 ORA-01442/01451，
+// 只有可空性变化时才声明
+func (m OracleMigrator) AlterColumn(value interface{}, field string) error {
+	return m.RunWithValue(value, func(stmt *gorm.Statement) error {
+		if stmt.Schema == nil {
+			return errors.New("failed to get schema")
+		}
+		f := stmt.Schema.LookUpField(field)
+		if f == nil {
+			return fmt.Errorf("failed to look up field with name: %s", field)
+		}
+		expr := m.FullDataTypeOf(f)
+		expr.SQL = strings.TrimSuffix(expr.SQL, " NOT NULL")
+		columnTypes, err := m.ColumnTypes(value)
+		if err != nil {
+			return err
+		}
+		for _, columnType := range columnTypes {
+			if columnType.Name() != f.DBName {
+				continue
+			}
+			if nullable, ok := columnType.Nullable(); ok && nullable == f.NotNull {
+				if f.NotNull {
+					expr.SQL += " NOT NULL"
+				} else {
+					expr.SQL += " NULL"
+				}
+			}
+		}
+		return m.DB.Exec("ALTER TABLE ? MODIFY ? ?", m.CurrentTable(stmt), clause.Column{Name: f.DBName}, expr).Error
+	})
+}
+
+var oracleTypeAliases = map[string][]string{
+	"number":        {"numeric", "decimal", "integer"},
+	"varchar2":      {"varchar", "nvarchar2"},
+	"nvarchar2":     {"varchar2"},
+	"clob":          {"nclob", "text"},
+	"raw":           {"varbinary"},
+	"binary_double": {"double", "float"},
+	"binary_float":  {"real"},
+}
+
+func (m OracleMigrator) GetTypeAliases(databaseTypeName string) []string {
+	return oracleTypeAliases[strings.ToLower(databaseTypeName)]
+}
@@ -0,0 +1,192 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type cryptUser struct {
+	ID        uint     `gorm:"primaryKey"`
+	Name      string   `gorm:"size:64"`
+	Phone     string   `gorm:"serializer:encrypted;size:255"`
+	PhoneBidx string   `gorm:"size:32;index" blind_index:"Phone"`
+	Tags      []string `gorm:"serializer:encrypted;type:text"`
+}
+
+func testKeyring(t *testing.T, active string) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(active, map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	}, []byte("blind-index-key"))
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return k
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "crypt.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err := db.Use(Plugin{}); err != nil {
+		t.Fatalf("Use(Plugin) error = %v", err)
+	}
+	if err := db.AutoMigrate(&cryptUser{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+	return db
+}
+
+func rawPhone(t *testing.T, db *gorm.DB, id uint) string {
+	t.Helper()
+	var raw string
+	if err := db.Raw("SELECT phone FROM crypt_users WHERE id = ?", id).Scan(&raw).Error; err != nil {
+		t.Fatalf("raw query: %v", err)
+	}
+	return raw
+}
+
+var phoneScope = Scope{Table: "crypt_users", Column: "phone"}
+
+func TestKeyringRotation(t *testing.T) {
+	k1 := testKeyring(t, "k1")
+	ciphertext, err := k1.Encrypt(phoneScope, []byte("13800001234"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	again, _ := k1.Encrypt(phoneScope, []byte("13800001234"))
+	if !strings.HasPrefix(ciphertext, "enc:k1:") || ciphertext == again {
+		t.Fatalf("ciphertext = %q, again = %q", ciphertext, again)
+	}
+	k2 := testKeyring(t, "k2")
+	plaintext, err := k2.Decrypt(phoneScope, ciphertext)
+	if err != nil || string(plaintext) != "13800001234" {
+		t.Fatalf("Decrypt() = %q, %v", plaintext, err)
+	}
+	if !k2.NeedsRotation(ciphertext) || k1.NeedsRotation(ciphertext) || !k1.NeedsRotation("13800001234") {
+		t.Fatalf("NeedsRotation mismatch")
+	}
+	if _, err := k1.Decrypt(phoneScope, "enc:k9:"+ciphertext[len("enc:k1:"):]); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("unknown key error = %v", err)
+	}
+	// 密文绑定表、列与密钥 ID，复制到其他列或改写密钥 ID 后无法解密
+	if _, err := k1.Decrypt(Scope{Table: "crypt_users", Column: "name"}, ciphertext); err == nil {
+		t.Fatalf("ciphertext moved to another column should not decrypt")
+	}
+	if _, err := k1.Decrypt(Scope{Table: "audit_users", Column: "phone"}, ciphertext); err == nil {
+		t.Fatalf("ciphertext moved to another table should not decrypt")
+	}
+	if _, err := k1.Decrypt(phoneScope, "enc:k2:"+ciphertext[len("enc:k1:"):]); err == nil {
+		t.Fatalf("ciphertext with a rewritten key id should not decrypt")
+	}
+	if _, err := NewKeyring("k3", map[string][]byte{"k3": []byte("short")}, nil); err == nil {
+		t.Fatalf("invalid key length should fail")
+	}
+	a, _ := k1.BlindIndex(phoneScope, " 13800001234 ")
+	b, _ := k2.BlindIndex(phoneScope, "13800001234")
+	if a != b || len(a) != blindIndexBytes*2 {
+		t.Fatalf("BlindIndex = %q / %q", a, b)
+	}
+	if other, _ := k1.BlindIndex(Scope{Table: "crypt_users", Column: "mobile"}, "13800001234"); other == a {
+		t.Fatalf("blind index keys should differ per field")
+	}
+}
+
+func TestEncryptedSerializerAndBlindIndex(t *testing.T) {
+	SetKeyring(testKeyring(t, "k1"))
+	defer SetKeyring(nil)
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	user := cryptUser{Name: "alice", Phone: "13800001234", Tags: []string{"vip"}}
+	if err := db.WithContext(ctx).Create(&user).Error; err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if raw := rawPhone(t, db, user.ID); !strings.HasPrefix(raw, "enc:k1:") || strings.Contains(raw, "1380000") {
+		t.Fatalf("raw phone = %q", raw)
+	}
+	bidx, _ := BlindIndex(phoneScope, "13800001234")
+	var found cryptUser
+	if err := db.Where("phone_bidx = ?", bidx).First(&found).Error; err != nil {
+		t.Fatalf("query by blind index: %v", err)
+	}
+	if found.Phone != "13800001234" || len(found.Tags) != 1 || found.Tags[0] != "vip" {
+		t.Fatalf("found = %+v", found)
+	}
+
+	// map 更新同样加密并重算盲索引，模型字段保持明文
+	if err := db.Model(&found).Update("phone", "13900005678").Error; err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if found.Phone != "13900005678" {
+		t.Fatalf("model phone = %q", found.Phone)
+	}
+	if raw := rawPhone(t, db, user.ID); !IsEncrypted(raw) {
+		t.Fatalf("raw phone after map update = %q", raw)
+	}
+	bidx, _ = BlindIndex(phoneScope, "13900005678")
+	if err := db.Where("phone_bidx = ?", bidx).First(&cryptUser{}).Error; err != nil {
+		t.Fatalf("blind index not refreshed: %v", err)
+	}
+
+	// Select 指定源字段时盲索引列一并更新
+	found.Phone = "13700000000"
+	if err := db.Model(&found).Select("Phone").Updates(&found).Error; err != nil {
+		t.Fatalf("Updates() error = %v", err)
+	}
+	bidx, _ = BlindIndex(phoneScope, "13700000000")
+	if err := db.Where("phone_bidx = ?", bidx).First(&cryptUser{}).Error; err != nil {
+		t.Fatalf("blind index not refreshed on select: %v", err)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	SetKeyring(testKeyring(t, "k1"))
+	defer SetKeyring(nil)
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	for _, phone := range []string{"13800000001", "13800000002", "13800000003"} {
+		if err := db.Create(&cryptUser{Phone: phone}).Error; err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	// 存量明文数据
+	if err := db.Exec("INSERT INTO crypt_users (id, phone) VALUES (10, '13800000010')").Error; err != nil {
+		t.Fatalf("insert plaintext: %v", err)
+	}
+
+	SetKeyring(testKeyring(t, "k2"))
+	updated, err := Rekey(ctx, db, &cryptUser{}, 2)
+	if err != nil || updated != 4 {
+		t.Fatalf("Rekey() = %d, %v", updated, err)
+	}
+	if updated, err = Rekey(ctx, db, &cryptUser{}, 2); err != nil || updated != 0 {
+		t.Fatalf("second Rekey() = %d, %v", updated, err)
+	}
+	if raw := rawPhone(t, db, 10); !strings.HasPrefix(raw, "enc:k2:") {
+		t.Fatalf("legacy row raw phone = %q", raw)
+	}
+	var users []cryptUser
+	if err := db.Order("id").Find(&users).Error; err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(users) != 4 || users[3].Phone != "13800000010" {
+		t.Fatalf("users = %+v", users)
+	}
+	bidx, _ := BlindIndex(phoneScope, "13800000010")
+	if users[3].PhoneBidx != bidx {
+		t.Fatalf("legacy blind index = %q, want %q", users[3].PhoneBidx, bidx)
+	}
+}
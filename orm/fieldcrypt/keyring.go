@@ -0,0 +1,219 @@
+// Package fieldcrypt 字段级加密：AES-GCM 加密序列化器(带密钥 ID，支持轮换)与等值查询用的盲索引
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	configActiveKey     = "security.field_encryption.active_key"
+	configKeys          = "security.field_encryption.keys"
+	configBlindIndexKey = "security.field_encryption.blind_index_key"
+
+	// cipherPrefix 密文前缀，格式为 enc:<密钥ID>:<base64(nonce|密文)>
+	cipherPrefix = "enc:"
+	// blindIndexBytes 盲索引截取的 HMAC 字节数，适度截断以降低相同值的可关联性
+	blindIndexBytes = 16
+)
+
+var (
+	// ErrNoKeyring 未配置加密密钥
+	ErrNoKeyring = errors.New("fieldcrypt: keyring not configured")
+	// ErrUnknownKey 密文使用的密钥 ID 不在密钥环中
+	ErrUnknownKey = errors.New("fieldcrypt: unknown key id")
+	// ErrMalformed 密文格式错误
+	ErrMalformed = errors.New("fieldcrypt: malformed ciphertext")
+)
+
+// Scope 密文所属的表与列。加密时与密钥 ID 一起作为 AES-GCM 的附加认证数据(AAD)，
+// 密文被复制到其他表/列或密钥 ID 被篡改后无法解密；盲索引按 Scope 派生独立的 HMAC 密钥，
+// 不同字段的相同明文得到不同的索引值
+type Scope struct {
+	Table  string
+	Column string
+}
+
+func (s Scope) String() string {
+	return s.Table + "." + s.Column
+}
+
+// aad 附加认证数据：表、列与密钥 ID，以 0 字节分隔避免拼接歧义
+func (s Scope) aad(keyID string) []byte {
+	return []byte("fieldcrypt\x00" + s.Table + "\x00" + s.Column + "\x00" + keyID)
+}
+
+// Keyring 加密密钥环：active 用于加密，其余密钥仅用于解密历史数据；indexKey 用于派生各字段的盲索引密钥
+type Keyring struct {
+	active   string
+	aeads    map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// NewKeyring 创建密钥环，密钥长度须为 16/24/32 字节(AES-128/192/256)，密钥 ID 不能包含冒号
+func NewKeyring(active string, keys map[string][]byte, indexKey []byte) (*Keyring, error) {
+	active = strings.TrimSpace(active)
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("fieldcrypt: active key %q not found", active)
+	}
+	k := &Keyring{active: active, aeads: make(map[string]cipher.AEAD, len(keys)), indexKey: indexKey}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("fieldcrypt: invalid key id %q", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: key %q: %w", id, err)
+		}
+		k.aeads[id] = aead
+	}
+	return k, nil
+}
+
+// ActiveKey 当前用于加密的密钥 ID
+func (k *Keyring) ActiveKey() string {
+	return k.active
+}
+
+// Encrypt 使用当前密钥加密 scope 字段的值，每次生成随机 nonce，相同明文的密文不同
+func (k *Keyring) Encrypt(scope Scope, plaintext []byte) (string, error) {
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, scope.aad(k.active))
+	return cipherPrefix + k.active + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 按密文中的密钥 ID 解密 scope 字段的值，表、列或密钥 ID 与加密时不一致时认证失败
+func (k *Keyring) Decrypt(scope Scope, ciphertext string) ([]byte, error) {
+	id, payload, ok := split(ciphertext)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], scope.aad(id))
+}
+
+// NeedsRotation 密文不是由当前密钥加密(含未加密的历史明文)时返回 true
+func (k *Keyring) NeedsRotation(value string) bool {
+	id, _, ok := split(value)
+	return !ok || id != k.active
+}
+
+// BlindIndex 计算 scope 字段的盲索引，用于密文字段的等值查询：字段密钥为 HMAC(indexKey, 表.列)，
+// 对去除首尾空白的明文做 HMAC-SHA256 并截断
+func (k *Keyring) BlindIndex(scope Scope, value string) (string, error) {
+	if len(k.indexKey) == 0 {
+		return "", errors.New("fieldcrypt: blind index key not configured")
+	}
+	fieldKey := hmac.New(sha256.New, k.indexKey)
+	fieldKey.Write([]byte(scope.String()))
+	mac := hmac.New(sha256.New, fieldKey.Sum(nil))
+	mac.Write([]byte(strings.TrimSpace(value)))
+	return hex.EncodeToString(mac.Sum(nil)[:blindIndexBytes]), nil
+}
+
+// IsEncrypted 值是否为本包生成的密文
+func IsEncrypted(value string) bool {
+	_, _, ok := split(value)
+	return ok
+}
+
+func split(value string) (id, payload string, ok bool) {
+	rest, found := strings.CutPrefix(value, cipherPrefix)
+	if !found {
+		return "", "", false
+	}
+	id, payload, found = strings.Cut(rest, ":")
+	return id, payload, found && id != "" && payload != ""
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring *Keyring
+	defaultLoaded  bool
+)
+
+// SetKeyring 设置全局密钥环，序列化器与盲索引均使用全局密钥环；传 nil 时恢复为按配置加载
+func SetKeyring(k *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring, defaultLoaded = k, k != nil
+}
+
+// DefaultKeyring 全局密钥环，未通过 SetKeyring 设置时按 security.field_encryption.* 配置加载
+func DefaultKeyring() (*Keyring, error) {
+	defaultMu.RLock()
+	k, loaded := defaultKeyring, defaultLoaded
+	defaultMu.RUnlock()
+	if loaded {
+		return k, nil
+	}
+	k, err := FromConfig()
+	if err != nil {
+		return nil, err
+	}
+	SetKeyring(k)
+	return k, nil
+}
+
+// FromConfig 按全局配置创建密钥环，keys 为 密钥ID -> base64 编码密钥；配置中的密钥 ID 统一按小写处理。
+// 直接读取 viper 而不依赖 config 包，orm 可自动注册插件而不产生循环依赖
+func FromConfig() (*Keyring, error) {
+	encoded := viper.GetStringMapString(configKeys)
+	if len(encoded) == 0 {
+		return nil, ErrNoKeyring
+	}
+	keys := make(map[string][]byte, len(encoded))
+	for id, value := range encoded {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("fieldcrypt: decode key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	var indexKey []byte
+	if value := strings.TrimSpace(viper.GetString(configBlindIndexKey)); value != "" {
+		var err error
+		if indexKey, err = base64.StdEncoding.DecodeString(value); err != nil {
+			return nil, fmt.Errorf("fieldcrypt: decode blind index key: %w", err)
+		}
+	}
+	return NewKeyring(strings.ToLower(viper.GetString(configActiveKey)), keys, indexKey)
+}
+
+// BlindIndex 使用全局密钥环计算 scope(加密源字段所在的表与列)的盲索引，查询示例：
+//
+//	bidx, err := fieldcrypt.BlindIndex(fieldcrypt.Scope{Table: "users", Column: "phone"}, phone)
+//	db.Where("phone_bidx = ?", bidx)
+func BlindIndex(scope Scope, value string) (string, error) {
+	k, err := DefaultKeyring()
+	if err != nil {
+		return "", err
+	}
+	return k.BlindIndex(scope, value)
+}
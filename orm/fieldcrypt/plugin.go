@@ -0,0 +1,245 @@
+package fieldcrypt
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// BlindIndexTag 盲索引列的结构体标签，值为加密源字段名，如：
+//
+//	Phone     string `gorm:"serializer:encrypted;size:255"`
+//	PhoneBidx string `gorm:"size:32;index" blind_index:"Phone"`
+const BlindIndexTag = "blind_index"
+
+// Plugin gorm 插件：写入时按源字段明文计算盲索引列；gorm 对 map 形式的 Create/Updates/Update
+// 不调用序列化器，插件负责加密其中的加密字段，避免明文落库。orm.Open 已自动注册，
+// 直接使用 gorm.Open 时需 db.Use(fieldcrypt.Plugin{})
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "fieldcrypt"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("fieldcrypt:before_create", beforeCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("fieldcrypt:before_update", beforeUpdate); err != nil {
+		return err
+	}
+	return db.Callback().Update().After("gorm:update").Register("fieldcrypt:after_update", afterUpdate)
+}
+
+// plainSettingKey 保存 map 更新的原始明文，更新后回写到模型，避免模型字段留下密文
+const plainSettingKey = "fieldcrypt:plain"
+
+// blindIndex 盲索引列与其加密源字段
+type blindIndex struct {
+	index  *schema.Field
+	source *schema.Field
+}
+
+type schemaInfo struct {
+	encrypted []*schema.Field
+	indexes   []blindIndex
+}
+
+var schemaCache sync.Map // *schema.Schema -> *schemaInfo
+
+func infoOf(s *schema.Schema) *schemaInfo {
+	if cached, ok := schemaCache.Load(s); ok {
+		return cached.(*schemaInfo)
+	}
+	info := &schemaInfo{}
+	for _, field := range s.Fields {
+		if isEncrypted(field) && field.DBName != "" {
+			info.encrypted = append(info.encrypted, field)
+		}
+	}
+	for _, field := range s.Fields {
+		name := strings.TrimSpace(field.Tag.Get(BlindIndexTag))
+		if name == "" || field.DBName == "" {
+			continue
+		}
+		if source := s.LookUpField(name); source != nil {
+			info.indexes = append(info.indexes, blindIndex{index: field, source: source})
+		}
+	}
+	actual, _ := schemaCache.LoadOrStore(s, info)
+	return actual.(*schemaInfo)
+}
+
+func isEncrypted(field *schema.Field) bool {
+	return strings.EqualFold(field.TagSettings["SERIALIZER"], SerializerName)
+}
+
+// indexValue 按源字段值计算盲索引，空值对应空索引
+func indexValue(source *schema.Field, value interface{}) (string, error) {
+	plaintext, null, err := encode(value)
+	if err != nil || null || len(plaintext) == 0 {
+		return "", err
+	}
+	return BlindIndex(scopeOf(source), string(plaintext))
+}
+
+func beforeCreate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	info := infoOf(db.Statement.Schema)
+	if len(info.encrypted) == 0 && len(info.indexes) == 0 {
+		return
+	}
+	switch dest := db.Statement.Dest.(type) {
+	case map[string]interface{}:
+		values, err := encryptMap(db.Statement.Schema, info, dest)
+		if db.AddError(err) == nil {
+			db.Statement.Dest = values
+		}
+		return
+	case []map[string]interface{}:
+		rows := make([]map[string]interface{}, len(dest))
+		for i, row := range dest {
+			values, err := encryptMap(db.Statement.Schema, info, row)
+			if db.AddError(err) != nil {
+				return
+			}
+			rows[i] = values
+		}
+		db.Statement.Dest = rows
+		return
+	}
+	if len(info.indexes) == 0 {
+		return
+	}
+	switch rv := db.Statement.ReflectValue; rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if db.AddError(setIndexes(db, info, reflect.Indirect(rv.Index(i)))) != nil {
+				return
+			}
+		}
+	case reflect.Struct:
+		db.AddError(setIndexes(db, info, rv))
+	}
+}
+
+func beforeUpdate(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return
+	}
+	stmt := db.Statement
+	info := infoOf(stmt.Schema)
+	if len(info.encrypted) == 0 && len(info.indexes) == 0 {
+		return
+	}
+	if dest, ok := stmt.Dest.(map[string]interface{}); ok {
+		values, err := encryptMap(stmt.Schema, info, dest)
+		if db.AddError(err) == nil {
+			stmt.Settings.Store(plainSettingKey, dest)
+			stmt.Dest = values
+		}
+		return
+	}
+	updating := reflect.Indirect(reflect.ValueOf(stmt.Dest))
+	if updating.Kind() != reflect.Struct || updating.Type() != stmt.Schema.ModelType {
+		return
+	}
+	if !updating.CanAddr() {
+		// 传值的结构体无法回写，复制一份可寻址的副本
+		copied := reflect.New(updating.Type())
+		copied.Elem().Set(updating)
+		stmt.Dest = copied.Interface()
+		updating = copied.Elem()
+	}
+	for _, idx := range info.indexes {
+		if len(stmt.Selects) > 0 && selected(stmt.Selects, idx.source) && !selected(stmt.Selects, idx.index) {
+			stmt.Selects = append(stmt.Selects, idx.index.DBName)
+		}
+	}
+	db.AddError(setIndexes(db, info, updating))
+}
+
+// afterUpdate gorm 会把 map 中的值写回模型，此处恢复为明文
+func afterUpdate(db *gorm.DB) {
+	plain, ok := db.Statement.Settings.LoadAndDelete(plainSettingKey)
+	if !ok || db.Statement.ReflectValue.Kind() != reflect.Struct || !db.Statement.ReflectValue.CanAddr() {
+		return
+	}
+	for key, value := range plain.(map[string]interface{}) {
+		if field := db.Statement.Schema.LookUpField(key); field != nil && isEncrypted(field) && !isExpression(value) {
+			_ = field.Set(db.Statement.Context, db.Statement.ReflectValue, value)
+		}
+	}
+}
+
+// setIndexes 为结构体计算盲索引列，源字段为空时索引为空字符串；Updates 不更新零值字段，不会清空已有索引
+func setIndexes(db *gorm.DB, info *schemaInfo, rv reflect.Value) error {
+	ctx := db.Statement.Context
+	for _, idx := range info.indexes {
+		value := idx.source.ReflectValueOf(ctx, rv).Interface()
+		hash, err := indexValue(idx.source, value)
+		if err != nil {
+			return err
+		}
+		if err := idx.index.Set(ctx, rv, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encryptMap 复制 map 并加密其中的加密字段，同时补充对应的盲索引列
+func encryptMap(s *schema.Schema, info *schemaInfo, values map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		result[key] = value
+	}
+	for key, value := range values {
+		field := s.LookUpField(key)
+		if field == nil || isExpression(value) {
+			continue
+		}
+		for _, idx := range info.indexes {
+			if idx.source == field {
+				hash, err := indexValue(field, value)
+				if err != nil {
+					return nil, err
+				}
+				result[idx.index.DBName] = hash
+			}
+		}
+		if isEncrypted(field) {
+			encrypted, err := EncryptedSerializer{}.Value(context.Background(), field, reflect.Value{}, value)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = encrypted
+		}
+	}
+	return result, nil
+}
+
+func selected(selects []string, field *schema.Field) bool {
+	for _, name := range selects {
+		if name == "*" || name == field.Name || name == field.DBName {
+			return true
+		}
+	}
+	return false
+}
+
+// isExpression gorm.Expr、子查询等表达式不加密
+func isExpression(value interface{}) bool {
+	switch value.(type) {
+	case clause.Expression, *gorm.DB:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package fieldcrypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultRekeyBatchSize Rekey 默认每批处理的行数
+const DefaultRekeyBatchSize = 500
+
+// Rekey 按主键分批扫描 model 对应的表，把非当前密钥加密的值(含存量明文)用当前密钥重新加密，
+// 并重算对应的盲索引列，返回更新的行数。用于密钥轮换与明文数据迁移，可重复执行
+func Rekey(ctx context.Context, db *gorm.DB, model interface{}, batchSize int) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultRekeyBatchSize
+	}
+	k, err := DefaultKeyring()
+	if err != nil {
+		return 0, err
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return 0, err
+	}
+	info := infoOf(stmt.Schema)
+	if len(info.encrypted) == 0 {
+		return 0, nil
+	}
+	if len(stmt.Schema.PrimaryFields) != 1 {
+		return 0, errors.New("fieldcrypt: rekey requires a single primary key")
+	}
+	pk := stmt.Schema.PrimaryFields[0].DBName
+	columns := []string{pk}
+	for _, field := range info.encrypted {
+		columns = append(columns, field.DBName)
+	}
+
+	tx := db.WithContext(ctx).Session(&gorm.Session{NewDB: true})
+	var updated int64
+	var last interface{}
+	for {
+		query := tx.Table(stmt.Schema.Table).Select(columns).Order(clause.OrderByColumn{Column: clause.Column{Name: pk}}).Limit(batchSize)
+		if last != nil {
+			query = query.Where(clause.Gt{Column: clause.Column{Name: pk}, Value: last})
+		}
+		var rows []map[string]interface{}
+		if err := query.Find(&rows).Error; err != nil {
+			return updated, err
+		}
+		for _, row := range rows {
+			changes, err := rekeyRow(k, stmt.Schema.Table, info, row)
+			if err != nil {
+				return updated, fmt.Errorf("fieldcrypt: rekey %s %v: %w", stmt.Schema.Table, row[pk], err)
+			}
+			if len(changes) == 0 {
+				continue
+			}
+			// 直接按表名更新，不经过模型的序列化器与插件，避免重复加密
+			if err := tx.Table(stmt.Schema.Table).Where(clause.Eq{Column: clause.Column{Name: pk}, Value: row[pk]}).Updates(changes).Error; err != nil {
+				return updated, err
+			}
+			updated++
+		}
+		if len(rows) < batchSize {
+			return updated, nil
+		}
+		last = rows[len(rows)-1][pk]
+	}
+}
+
+// rekeyRow 计算一行需要更新的列
+func rekeyRow(k *Keyring, table string, info *schemaInfo, row map[string]interface{}) (map[string]interface{}, error) {
+	changes := map[string]interface{}{}
+	for _, field := range info.encrypted {
+		scope := Scope{Table: table, Column: field.DBName}
+		var raw string
+		switch v := row[field.DBName].(type) {
+		case string:
+			raw = v
+		case []byte:
+			raw = string(v)
+		}
+		if raw == "" || !k.NeedsRotation(raw) {
+			continue
+		}
+		plaintext := []byte(raw)
+		if IsEncrypted(raw) {
+			var err error
+			if plaintext, err = k.Decrypt(scope, raw); err != nil {
+				return nil, err
+			}
+		}
+		ciphertext, err := k.Encrypt(scope, plaintext)
+		if err != nil {
+			return nil, err
+		}
+		changes[field.DBName] = ciphertext
+		for _, idx := range info.indexes {
+			if idx.source == field {
+				if changes[idx.index.DBName], err = k.BlindIndex(scope, string(plaintext)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,130 @@
+package fieldcrypt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName 序列化器注册名，模型字段使用 gorm:"serializer:encrypted"
+const SerializerName = "encrypted"
+
+func init() {
+	schema.RegisterSerializer(SerializerName, EncryptedSerializer{})
+}
+
+// EncryptedSerializer AES-GCM 加密序列化器：string/[]byte 直接加密，其他类型先 JSON 编码再加密；
+// 密文为 base64 文本，列类型使用 varchar/text 即可在 MySQL、PostgreSQL、达梦、人大金仓等库通用。
+// 空值与 nil 不加密；读取到非密文的历史明文时原样返回，便于存量数据逐步迁移(见 Rekey)
+type EncryptedSerializer struct{}
+
+// Scan 解密数据库值并写入字段
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+	var text string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("fieldcrypt: unsupported db value %T for field %s", dbValue, field.Name)
+	}
+	if text != "" {
+		plaintext := []byte(text)
+		if IsEncrypted(text) {
+			k, err := DefaultKeyring()
+			if err != nil {
+				return err
+			}
+			if plaintext, err = k.Decrypt(scopeOf(field), text); err != nil {
+				return fmt.Errorf("fieldcrypt: decrypt field %s: %w", field.Name, err)
+			}
+		}
+		if err := decode(plaintext, fieldValue); err != nil {
+			return fmt.Errorf("fieldcrypt: decode field %s: %w", field.Name, err)
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value 加密字段值
+func (EncryptedSerializer) Value(_ context.Context, field *schema.Field, _ reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, null, err := encode(fieldValue)
+	if err != nil {
+		return nil, err
+	}
+	if null && !field.NotNull {
+		return nil, nil
+	}
+	if len(plaintext) == 0 {
+		return "", nil
+	}
+	k, err := DefaultKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return k.Encrypt(scopeOf(field), plaintext)
+}
+
+// Encrypt 使用全局密钥环加密 scope 字段的单个值，供手写 SQL 使用
+func Encrypt(scope Scope, value interface{}) (interface{}, error) {
+	field := &schema.Field{DBName: scope.Column, Schema: &schema.Schema{Table: scope.Table}}
+	return EncryptedSerializer{}.Value(context.Background(), field, reflect.Value{}, value)
+}
+
+// scopeOf 字段所属模型的默认表名与列名，db.Table 指定的表名不影响密文绑定
+func scopeOf(field *schema.Field) Scope {
+	scope := Scope{Column: field.DBName}
+	if field.Schema != nil {
+		scope.Table = field.Schema.Table
+	}
+	return scope
+}
+
+// encode 字段值转为待加密明文，null 表示 nil 指针或 JSON null
+func encode(value interface{}) (plaintext []byte, null bool, err error) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, true, nil
+		}
+		rv = rv.Elem()
+	}
+	switch {
+	case !rv.IsValid():
+		return nil, true, nil
+	case rv.Kind() == reflect.String:
+		return []byte(rv.String()), false, nil
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		return rv.Bytes(), rv.IsNil(), nil
+	}
+	data, err := json.Marshal(rv.Interface())
+	if err == nil && string(data) == "null" {
+		return nil, true, nil
+	}
+	return data, false, err
+}
+
+// decode 明文写入 target(指向字段类型的指针)
+func decode(plaintext []byte, target reflect.Value) error {
+	elem := target.Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem.Set(reflect.New(elem.Type().Elem()))
+		elem = elem.Elem()
+	}
+	switch {
+	case elem.Kind() == reflect.String:
+		elem.SetString(string(plaintext))
+		return nil
+	case elem.Kind() == reflect.Slice && elem.Type().Elem().Kind() == reflect.Uint8:
+		elem.SetBytes(plaintext)
+		return nil
+	}
+	return json.Unmarshal(plaintext, elem.Addr().Interface())
+}
@@ -0,0 +1,25 @@
+package orm
+
+import (
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// LikeEscape LIKE 转义字符，选用各方言字符串字面量中都无需转义的 '!'
+const LikeEscape = "!"
+
+var likeEscaper = strings.NewReplacer(LikeEscape, LikeEscape+LikeEscape, "%", LikeEscape+"%", "_", LikeEscape+"_")
+
+// EscapeLike 转义 value 中的 %、_ 与转义字符本身，配合 ESCAPE '!' 按字面匹配
+func EscapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+// Contains 列值包含 value 的 LIKE 条件，value 中的通配符按字面匹配
+func Contains(column, value string) clause.Expression {
+	return clause.Expr{
+		SQL:  "? LIKE ? ESCAPE '" + LikeEscape + "'",
+		Vars: []any{clause.Column{Name: column}, "%" + EscapeLike(value) + "%"},
+	}
+}
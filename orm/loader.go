@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/viper"
 	"sort"
 	"strings"
+	"time"
 )
 
 // InitAllDB 总初始化入口（逻辑不变，仅替换Redis/Mongo的初始化函数）
@@ -32,6 +33,12 @@ func InitAllDB(v *viper.Viper) error {
 			err = initRelationalDB(v, utils.DBTypeDM)
 		case "kingbase":
 			err = initRelationalDB(v, utils.DBTypeKingBase)
+		case "oracle":
+			err = initRelationalDB(v, utils.DBTypeOracle)
+		case "sqlserver", "mssql":
+			err = initRelationalDBByKey(v, dbType, utils.DBTypeSqlserver)
+		case "sqlite", "sqlite3":
+			err = initRelationalDBByKey(v, dbType, utils.DBTypeSQLite)
 		// NoSQL数据库（对齐关系型初始化逻辑）
 		case "redis":
 			err = initNoSQLDB(v, utils.DBTypeRedis)
@@ -74,24 +81,26 @@ func initRelationalDBByKey(v *viper.Viper, instanceKey string, dbType utils.DBTy
 		if dsn == "" {
 			return fmt.Errorf("%s实例[%s] DSN为空", dbType, instanceName)
 		}
-		// 3. 创建ORM实例（逻辑不变）
+		// 3. 创建ORM实例并按配置设置连接池，未配置的项使用 Options 默认值
 		slowTime := v.GetInt(fmt.Sprintf("databases.%s.%s.slow_time", instanceKey, instanceName))
-		ormInstance := NewOrm(dsn, dbType, slowTime, cfg.GetLogMode())
-		if ormInstance == nil {
-			return fmt.Errorf("%s实例[%s]创建ORM实例失败", dbType, instanceName)
+		ormInstance, err := Open(dsn, dbType, OptionsFromConfig(cfg, slowTime))
+		if err != nil {
+			return fmt.Errorf("%s实例[%s]创建ORM实例失败：%w", dbType, instanceName, err)
 		}
-		// 4. 配置连接池（逻辑不变）
 		sqlDB, err := ormInstance.DB()
 		if err != nil {
 			return fmt.Errorf("%s实例[%s]获取SQL DB失败：%w", dbType, instanceName, err)
 		}
-		sqlDB.SetMaxOpenConns(cfg.MaxOpenConn)
-		sqlDB.SetMaxIdleConns(cfg.MaxIdleConn)
-		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifeTime)
-		// 5. 测试连接（逻辑不变）
+		// 4. 测试连接
 		if err := sqlDB.Ping(); err != nil {
 			return fmt.Errorf("%s实例[%s] Ping失败：%w", dbType, instanceName, err)
 		}
+		// 5. 集群模式且配置了只读副本：连接副本并开启读写分离，未配置时与此前一样只连主库
+		if cfg.Mode == utils.DBModeCluster && len(cfg.Replicas) > 0 {
+			if err := initReplicas(ormInstance, cfg, slowTime); err != nil {
+				return fmt.Errorf("%s实例[%s]初始化只读副本失败：%w", dbType, instanceName, err)
+			}
+		}
 		// 6. 统一赋值：所有关系型数据库都存入RelationalMap，default实例赋值到DB
 		RelationalMap[instanceName] = ormInstance
 		if instanceName == "default" {
@@ -103,6 +112,34 @@ func initRelationalDBByKey(v *viper.Viper, instanceKey string, dbType utils.DBTy
 	return nil
 }
 
+// initReplicas 按配置创建只读副本并挂到主库实例上，副本暂不可用时不阻断启动，由健康检查恢复
+func initReplicas(primary *Orm, cfg *dbconfig.Config, slowTime int) error {
+	replicas := make([]Replica, 0, len(cfg.Replicas))
+	for i, replicaCfg := range cfg.ReplicaConfigs() {
+		name := cfg.ReplicaName(i)
+		dsn := replicaCfg.GenDSN()
+		if dsn == "" {
+			return fmt.Errorf("只读副本[%s] DSN为空", name)
+		}
+		opts := OptionsFromConfig(replicaCfg, slowTime)
+		opts.SkipInitialPing = true
+		replica, err := Open(dsn, cfg.DBType, opts)
+		if err != nil {
+			return fmt.Errorf("只读副本[%s]创建ORM实例失败：%w", name, err)
+		}
+		replicas = append(replicas, Replica{Name: name, DB: replica})
+	}
+	if err := primary.SetReplicas(ReplicaOptions{
+		Strategy:      cfg.ReadStrategy,
+		CheckInterval: cfg.ReplicaCheckInterval,
+		MaxFailures:   cfg.ReplicaMaxFailures,
+	}, replicas...); err != nil {
+		return err
+	}
+	log.Infof("【%s初始化】读写分离已开启，只读副本%d个", cfg.DBType, len(replicas))
+	return nil
+}
+
 // ---------------- NoSQL数据库通用初始化（Redis/Mongo 统一逻辑） ----------------
 func initNoSQLDB(v *viper.Viper, dbType utils.DBType) error {
 	instanceKey := string(dbType)
@@ -137,9 +174,12 @@ func initNoSQLDB(v *viper.Viper, dbType utils.DBType) error {
 		)
 		switch dbType {
 		case utils.DBTypeRedis:
-			redisInstance = redis.NewRedis(dsn, dbType, timeout, cfg)
-			if redisInstance == nil {
-				return fmt.Errorf("%s实例[%s]创建客户端失败", dbType, instanceName)
+			if cfg.ConnectTimeout <= 0 {
+				cfg.ConnectTimeout = time.Duration(timeout) * time.Second
+			}
+			redisInstance, err = redis.Open(&redis.Config{Config: *cfg})
+			if err != nil {
+				return fmt.Errorf("%s实例[%s]创建客户端失败：%w", dbType, instanceName, err)
 			}
 		case utils.DBTypeMongo:
 			mongoInstance = mongodb.NewMongo(dsn, dbType, timeout, cfg)
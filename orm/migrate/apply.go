@@ -0,0 +1,93 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/goodbye-jack/go-common/config"
+	"gorm.io/gorm"
+)
+
+const (
+	configMode        = "migrations.mode"
+	configTable       = "migrations.table"
+	configLockTimeout = "migrations.lock_timeout"
+
+	// ModeAuto 启动时自动执行库内置表的待执行迁移(默认)
+	ModeAuto = "auto"
+	// ModeVerify 只校验，存在待执行迁移时返回 ErrPendingMigrations，由运维评审后执行
+	ModeVerify = "verify"
+)
+
+// ErrPendingMigrations verify 模式下存在未执行的迁移
+var ErrPendingMigrations = errors.New("pending migrations")
+
+// FromConfig 按 migrations.* 配置创建迁移执行器，opts 可覆盖配置
+func FromConfig(db *gorm.DB, opts ...Option) *Migrator {
+	base := []Option{
+		WithTable(config.GetConfigString(configTable)),
+		WithLockTimeout(config.GetConfigDuration(configLockTimeout, DefaultLockTimeout)),
+	}
+	return New(db, append(base, opts...)...)
+}
+
+type appliedKey struct {
+	pool   *sql.DB
+	module string
+}
+
+// applied 本进程内已确认为最新的 (连接池, 模块)，避免重复初始化时反复加锁检查
+var applied sync.Map
+
+// ApplyModule 供库内部建表使用：migrations.mode=auto 时执行模块的待执行迁移，
+// verify 时只检查，存在待执行迁移则返回 ErrPendingMigrations
+func ApplyModule(ctx context.Context, db *gorm.DB, modules ...string) error {
+	if db == nil {
+		return errors.New("migrate: db is nil")
+	}
+	var pending []string
+	var keys []appliedKey
+	pool, _ := db.DB()
+	for _, module := range modules {
+		key := appliedKey{pool: pool, module: module}
+		if _, ok := applied.Load(key); ok && pool != nil {
+			continue
+		}
+		pending = append(pending, module)
+		keys = append(keys, key)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	m := FromConfig(db, WithModules(pending...))
+	switch mode := strings.ToLower(strings.TrimSpace(config.GetConfigString(configMode))); mode {
+	case "", ModeAuto:
+		if err := m.Up(ctx); err != nil {
+			return err
+		}
+	case ModeVerify:
+		statuses, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(statuses) > 0 {
+			names := make([]string, 0, len(statuses))
+			for _, status := range statuses {
+				names = append(names, status.Module+"/"+status.Version+"_"+status.Name)
+			}
+			return fmt.Errorf("%w: %s", ErrPendingMigrations, strings.Join(names, ", "))
+		}
+	default:
+		return fmt.Errorf("unsupported %s %q, want auto or verify", configMode, mode)
+	}
+	if pool != nil {
+		for _, key := range keys {
+			applied.Store(key, struct{}{})
+		}
+	}
+	return nil
+}
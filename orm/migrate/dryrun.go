@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// recorder 记录模式的连接：读操作正常执行(AutoMigrate 需要查询表结构)，写操作只记录不执行
+type recorder struct {
+	gorm.ConnPool
+	dialector  gorm.Dialector
+	statements []string
+}
+
+var readOnlyPrefixes = []string{"SELECT", "WITH", "SHOW", "PRAGMA", "DESC", "EXPLAIN"}
+
+func readOnly(query string) bool {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(query, prefix) {
+			// PRAGMA 赋值会修改连接状态
+			return prefix != "PRAGMA" || !strings.Contains(query, "=")
+		}
+	}
+	return false
+}
+
+func (r *recorder) record(query string, args ...any) {
+	r.statements = append(r.statements, r.dialector.Explain(strings.TrimSpace(query), args...))
+}
+
+func (r *recorder) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	r.record(query, args...)
+	return driver.RowsAffected(0), nil
+}
+
+func (r *recorder) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if readOnly(query) {
+		return r.ConnPool.QueryContext(ctx, query, args...)
+	}
+	// 带 RETURNING 的写操作：记录后返回空结果集
+	r.record(query, args...)
+	return emptyDB.QueryContext(ctx, query)
+}
+
+func (r *recorder) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if readOnly(query) {
+		return r.ConnPool.QueryRowContext(ctx, query, args...)
+	}
+	r.record(query, args...)
+	return emptyDB.QueryRowContext(ctx, query)
+}
+
+// Commit/Rollback 使记录模式下的 Transaction 走 SavePoint 分支而不是开启真实事务
+func (r *recorder) Commit() error   { return nil }
+func (r *recorder) Rollback() error { return nil }
+
+// emptyDB 任何语句都返回空结果集的连接，用于在记录模式下应答写操作
+var emptyDB = sql.OpenDB(emptyConnector{})
+
+type emptyConnector struct{}
+
+func (c emptyConnector) Connect(context.Context) (driver.Conn, error) { return emptyConn{}, nil }
+func (c emptyConnector) Driver() driver.Driver                        { return c }
+func (c emptyConnector) Open(string) (driver.Conn, error)             { return emptyConn{}, nil }
+
+type emptyConn struct{}
+
+func (emptyConn) Prepare(string) (driver.Stmt, error) { return emptyStmt{}, nil }
+func (emptyConn) Close() error                        { return nil }
+func (emptyConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("dry-run connection does not support transactions")
+}
+
+type emptyStmt struct{}
+
+func (emptyStmt) Close() error                               { return nil }
+func (emptyStmt) NumInput() int                              { return -1 }
+func (emptyStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+func (emptyStmt) Query([]driver.Value) (driver.Rows, error)  { return emptyRows{}, nil }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string         { return nil }
+func (emptyRows) Close() error              { return nil }
+func (emptyRows) Next([]driver.Value) error { return io.EOF }
@@ -0,0 +1,291 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultLockTTL 迁移锁的租约时长，持有期间定期续期，进程崩溃后租约到期自动释放
+	DefaultLockTTL   = 2 * time.Minute
+	lockRetryBackoff = 500 * time.Millisecond
+	// migrationLockName 迁移锁在锁表中占用的行
+	migrationLockName = "migrate"
+)
+
+var (
+	// ErrLockTimeout 等待迁移锁超时
+	ErrLockTimeout = errors.New("wait for migration lock timeout")
+	// ErrLockHeld 锁被其他持有者占用且租约未过期
+	ErrLockHeld = errors.New("lock is held by another owner")
+	// ErrLockLost 令牌不匹配：锁已被释放，或租约过期后被其他持有者接管
+	ErrLockLost = errors.New("lock lost")
+)
+
+// LockFunc 获取迁移锁，阻塞直到获得锁或 ctx 结束；lost 在锁丢失(续期失败、被接管)时关闭，
+// 迁移随之中止，unlock 释放锁。默认为 NewTableLock，也可通过 coord.MigrationLock 使用 Redis 等分布式锁
+type LockFunc func(ctx context.Context) (lost <-chan struct{}, unlock func() error, err error)
+
+// lockRow 锁表中每把锁一行，释放后保留行以延续栅栏令牌
+type lockRow struct {
+	Name      string `gorm:"primaryKey;size:191"`
+	Token     string `gorm:"size:64"`
+	Owner     string `gorm:"size:128"`
+	Fence     int64
+	ExpiresAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+// LockTable 基于数据库锁表的租约锁，迁移锁与 coord.NewTableLocker 共用此实现：
+// 插入新行或接管已过期的行即获得锁，接管时栅栏令牌加一。过期时间由数据库时钟计算并比较，
+// 不受副本之间时钟偏差的影响；所有读写都走主库，避免读写分离时读到落后的副本
+type LockTable struct {
+	db      *gorm.DB
+	table   string
+	dialect utils.DBType
+	owner   string
+	ensure  func(ctx context.Context, db *gorm.DB) error
+	mu      sync.Mutex
+	inited  bool
+}
+
+// NewLockTable 创建锁表 table 上的租约锁，锁表首次使用时自动创建
+func NewLockTable(db *gorm.DB, table string) *LockTable {
+	host, _ := os.Hostname()
+	return &LockTable{
+		db:      db,
+		table:   table,
+		dialect: DialectOf(db),
+		owner:   fmt.Sprintf("%s:%d:%s", host, os.Getpid(), randomHex(4)),
+		ensure: func(ctx context.Context, db *gorm.DB) error {
+			return db.Table(table).AutoMigrate(&lockRow{})
+		},
+	}
+}
+
+// LockModel 锁表的模型，配合 TableModel 与 EnsureWith 以版本化迁移创建锁表
+func LockModel() any {
+	return &lockRow{}
+}
+
+// EnsureWith 替换锁表的建表方式，默认首次使用时 AutoMigrate；
+// 迁移锁自身的锁表只能 AutoMigrate，其余锁表(如 coord 锁表)应改为执行版本化迁移
+func (t *LockTable) EnsureWith(ensure func(ctx context.Context, db *gorm.DB) error) *LockTable {
+	if ensure != nil {
+		t.ensure = ensure
+	}
+	return t
+}
+
+// ensureTable 建表成功后不再重复执行；多个副本同时建表失败时由下一次获取重试
+func (t *LockTable) ensureTable(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inited {
+		return nil
+	}
+	ctx = orm.UsePrimary(ctx)
+	if err := t.ensure(ctx, t.db.Session(&gorm.Session{NewDB: true, Context: ctx})); err != nil {
+		return err
+	}
+	t.inited = true
+	return nil
+}
+
+func (t *LockTable) session(ctx context.Context) *gorm.DB {
+	return t.db.Session(&gorm.Session{NewDB: true, Context: orm.UsePrimary(ctx)}).Table(t.table)
+}
+
+// at 数据库时钟当前时间加 d 的 SQL 表达式(d 可为负)，毫秒精度
+func (t *LockTable) at(d time.Duration) clause.Expr {
+	ms := d.Milliseconds()
+	switch t.dialect {
+	case utils.DBTypeSQLite:
+		return gorm.Expr("strftime('%Y-%m-%d %H:%M:%f', 'now', ?)", fmt.Sprintf("%+.3f seconds", float64(ms)/1000))
+	case utils.DBTypeMySQL:
+		return gorm.Expr("DATE_ADD(NOW(3), INTERVAL ? MICROSECOND)", ms*1000)
+	case utils.DBTypeSqlserver:
+		return gorm.Expr("DATEADD(millisecond, ?, SYSDATETIME())", ms)
+	case utils.DBTypeDM:
+		return gorm.Expr("DATEADD(MS, ?, LOCALTIMESTAMP)", ms)
+	case utils.DBTypeOracle:
+		return gorm.Expr("SYSTIMESTAMP + NUMTODSINTERVAL(?, 'SECOND')", float64(ms)/1000)
+	default: // postgres、kingbase
+		return gorm.Expr(fmt.Sprintf("CURRENT_TIMESTAMP + INTERVAL '%d milliseconds'", ms))
+	}
+}
+
+// Acquire 以 token 获取名为 name 的锁并返回栅栏令牌，锁被占用时返回 ErrLockHeld
+func (t *LockTable) Acquire(ctx context.Context, name, token string, ttl time.Duration) (int64, error) {
+	if err := t.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	// 接管已过期(或已释放)的锁，并发接管时只有一个副本能更新成功
+	result := t.session(ctx).Where("name = ? AND expires_at < ?", name, t.at(0)).Updates(map[string]interface{}{
+		"token":      token,
+		"owner":      t.owner,
+		"fence":      gorm.Expr("fence + 1"),
+		"expires_at": t.at(ttl),
+		"updated_at": t.at(0),
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// 行已存在且未过期即被占用；先查询避免每次重试都因主键冲突打印错误日志
+		if t.exists(ctx, name) {
+			return 0, ErrLockHeld
+		}
+		err := t.session(ctx).Create(map[string]interface{}{
+			"name":       name,
+			"token":      token,
+			"owner":      t.owner,
+			"fence":      1,
+			"expires_at": t.at(ttl),
+			"updated_at": t.at(0),
+		}).Error
+		if err != nil {
+			// 并发首次创建时主键冲突表示锁被其他副本持有，其余错误原样返回
+			if t.exists(ctx, name) {
+				return 0, ErrLockHeld
+			}
+			return 0, err
+		}
+		return 1, nil
+	}
+	var row lockRow
+	if err := t.session(ctx).Select("fence").Where("name = ? AND token = ?", name, token).Take(&row).Error; err != nil {
+		return 0, err
+	}
+	return row.Fence, nil
+}
+
+// Refresh 续期 token 持有的锁，令牌不匹配时返回 ErrLockLost
+func (t *LockTable) Refresh(ctx context.Context, name, token string, ttl time.Duration) error {
+	result := t.session(ctx).Where("name = ? AND token = ?", name, token).Updates(map[string]interface{}{
+		"expires_at": t.at(ttl),
+		"updated_at": t.at(0),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	// MySQL 的 RowsAffected 只统计实际变化的行，同一毫秒内续期可能为 0，需再确认令牌
+	if result.RowsAffected == 0 && !t.held(ctx, name, token) {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release 释放 token 持有的锁，保留行以延续栅栏令牌；令牌不匹配时返回 ErrLockLost
+func (t *LockTable) Release(ctx context.Context, name, token string) error {
+	// 过期时间置为数据库时钟之前，保证随后的获取立即可以接管
+	result := t.session(ctx).Where("name = ? AND token = ?", name, token).Updates(map[string]interface{}{
+		"token":      "",
+		"expires_at": t.at(-time.Millisecond),
+		"updated_at": t.at(0),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (t *LockTable) exists(ctx context.Context, name string) bool {
+	var count int64
+	t.session(ctx).Where("name = ?", name).Count(&count)
+	return count > 0
+}
+
+func (t *LockTable) held(ctx context.Context, name, token string) bool {
+	var count int64
+	t.session(ctx).Where("name = ? AND token = ?", name, token).Count(&count)
+	return count > 0
+}
+
+// NewTableLock 基于数据库锁表 table 的迁移锁(占用其中名为 migrate 的行)，
+// 持有期间每 ttl/3 续期，持有者崩溃后租约到期可被其他副本接管。
+// 令牌被接管，或续期失败且下次续期前租约可能到期时判定锁丢失，关闭 lost 中止迁移
+func NewTableLock(db *gorm.DB, table string, ttl time.Duration) LockFunc {
+	if ttl <= 0 {
+		ttl = DefaultLockTTL
+	}
+	locks := NewLockTable(db, table)
+	return func(ctx context.Context) (<-chan struct{}, func() error, error) {
+		token := randomHex(16)
+		var lastErr error
+		for {
+			if _, lastErr = locks.Acquire(ctx, migrationLockName, token, ttl); lastErr == nil {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return nil, nil, fmt.Errorf("%w (%s): %v", ErrLockTimeout, table, lastErr)
+			case <-time.After(lockRetryBackoff):
+			}
+		}
+
+		lost := make(chan struct{})
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			interval := ttl / 3
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			renewedAt := time.Now()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					refreshCtx, cancel := context.WithTimeout(context.Background(), interval)
+					err := locks.Refresh(refreshCtx, migrationLockName, token, ttl)
+					cancel()
+					if err == nil {
+						renewedAt = time.Now()
+						continue
+					}
+					log.Warnf("迁移锁 %s 续期失败: %v", table, err)
+					if errors.Is(err, ErrLockLost) || time.Since(renewedAt)+interval >= ttl {
+						log.Errorf("迁移锁 %s 已丢失，中止迁移", table)
+						close(lost)
+						return
+					}
+				}
+			}
+		}()
+
+		var once sync.Once
+		return lost, func() error {
+			var err error
+			once.Do(func() {
+				close(stop)
+				wg.Wait()
+				err = locks.Release(context.Background(), migrationLockName, token)
+			})
+			return err
+		}, nil
+	}
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
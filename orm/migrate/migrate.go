@@ -0,0 +1,590 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/utils"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultTable 迁移历史表
+	DefaultTable = "schema_migrations"
+	// DefaultLockTimeout 等待迁移锁的默认时长
+	DefaultLockTimeout = time.Minute
+)
+
+var (
+	// ErrChecksumMismatch 已执行的迁移脚本被修改
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrIrreversible 迁移未提供回滚脚本
+	ErrIrreversible = errors.New("migration is irreversible")
+	// ErrUnknownMigration 历史表中的版本在当前迁移源中不存在
+	ErrUnknownMigration = errors.New("migration not found in sources")
+)
+
+var versionPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// Migration 一个版本化的结构变更，按 Version 升序执行，执行顺序为 Up(SQL) → Models → UpFunc：
+//   - Up/Down 为 SQL 脚本，多条语句以分号分隔，存储过程等整体语句放在
+//     "-- +migrate StatementBegin" 与 "-- +migrate StatementEnd" 之间
+//   - Models 通过 GORM AutoMigrate 建表/补列，由方言生成 DDL，回滚时删除这些表；
+//     表名可配置的模型用 TableModel 指定表名。模型结构不计入校验和，
+//     之后给模型加列或索引时新增一个引用该模型的版本，由 AutoMigrate 补齐
+//   - UpFunc/DownFunc 为 Go 迁移，适合数据修复等无法用 SQL 表达的变更
+type Migration struct {
+	Version       string
+	Name          string
+	Up            string
+	Down          string
+	UpFunc        func(tx *gorm.DB) error
+	DownFunc      func(tx *gorm.DB) error
+	Models        []any
+	NoTransaction bool // 不在事务中执行，如 PostgreSQL 的 CREATE INDEX CONCURRENTLY
+}
+
+// TableModel 以指定表名迁移模型，用于表名可配置的表(如菜单表、coord 锁表)
+type TableModel struct {
+	Name  string
+	Model any
+}
+
+func (m Migration) reversible() bool {
+	return strings.TrimSpace(m.Down) != "" || m.DownFunc != nil || len(m.Models) > 0
+}
+
+func (m Migration) validate() error {
+	if !versionPattern.MatchString(m.Version) {
+		return fmt.Errorf("invalid migration version %q", m.Version)
+	}
+	if strings.TrimSpace(m.Up) == "" && m.UpFunc == nil && len(m.Models) == 0 {
+		return fmt.Errorf("migration %s has nothing to apply", m.Version)
+	}
+	return nil
+}
+
+// compareVersion 按数值比较版本号，长度不同的纯数字版本也能正确排序
+func compareVersion(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// Status 迁移状态
+type Status struct {
+	Module    string     `json:"module"`
+	Version   string     `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified"` // 已执行但脚本校验和与历史记录不一致
+	Unknown   bool       `json:"unknown"`  // 历史表中存在但迁移源中已没有
+}
+
+// record 迁移历史表记录
+type record struct {
+	Module      string    `gorm:"primaryKey;size:64"`
+	Version     string    `gorm:"primaryKey;size:32"`
+	Name        string    `gorm:"size:255"`
+	Checksum    string    `gorm:"size:64"`
+	AppliedAt   time.Time `gorm:"index"`
+	ExecutionMs int64
+}
+
+// planned 某个模块中的一次迁移及其校验和
+type planned struct {
+	Migration
+	module   string
+	checksum string
+}
+
+func (p planned) String() string {
+	return fmt.Sprintf("%s/%s_%s", p.module, p.Version, p.Name)
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db          *gorm.DB
+	dialect     utils.DBType
+	table       string
+	modules     []string
+	lockTimeout time.Duration
+	lock        LockFunc
+	ensureMu    sync.Mutex
+	ensured     bool
+}
+
+// Option Migrator 配置项
+type Option func(*Migrator)
+
+// WithTable 指定迁移历史表，默认 schema_migrations；迁移锁表为 "<table>_lock"
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		if table = strings.TrimSpace(table); table != "" {
+			m.table = table
+		}
+	}
+}
+
+// WithDialect 指定加载脚本时使用的方言，默认由 db 的 Dialector 推断
+func WithDialect(dialect utils.DBType) Option {
+	return func(m *Migrator) {
+		if dialect != "" {
+			m.dialect = dialect
+		}
+	}
+}
+
+// WithModules 只处理指定模块，按给定顺序执行；默认处理全部已注册模块(按注册顺序)
+func WithModules(modules ...string) Option {
+	return func(m *Migrator) {
+		m.modules = append([]string{}, modules...)
+	}
+}
+
+// WithLockTimeout 等待迁移锁的最长时间
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		if timeout > 0 {
+			m.lockTimeout = timeout
+		}
+	}
+}
+
+// WithLock 自定义迁移锁，默认使用数据库锁表 "<table>_lock"
+func WithLock(lock LockFunc) Option {
+	return func(m *Migrator) {
+		m.lock = lock
+	}
+}
+
+// New 创建迁移执行器
+//
+//	m := migrate.New(orm.DB.GetDB(), migrate.WithModules("app"))
+//	if err := m.Up(ctx); err != nil { ... }
+func New(db *gorm.DB, opts ...Option) *Migrator {
+	m := &Migrator{
+		db:          db,
+		dialect:     DialectOf(db),
+		table:       DefaultTable,
+		lockTimeout: DefaultLockTimeout,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.lock == nil {
+		m.lock = NewTableLock(db, m.table+"_lock", 0)
+	}
+	return m
+}
+
+// DialectOf 推断 db 的方言；人大金仓复用 postgres Dialector，通过 DriverName 区分
+func DialectOf(db *gorm.DB) utils.DBType {
+	if db == nil || db.Dialector == nil {
+		return ""
+	}
+	if pg, ok := db.Dialector.(*postgres.Dialector); ok && pg.Config != nil && pg.DriverName == "kingbase" {
+		return utils.DBTypeKingBase
+	}
+	switch name := db.Dialector.Name(); name {
+	case "sqlite", "sqlite3":
+		return utils.DBTypeSQLite
+	default:
+		return utils.DBType(name)
+	}
+}
+
+// Dialect 当前使用的方言
+func (m *Migrator) Dialect() utils.DBType {
+	return m.dialect
+}
+
+// load 加载各模块的迁移并按版本排序
+func (m *Migrator) load() ([]planned, error) {
+	modules := m.modules
+	if len(modules) == 0 {
+		modules = Modules()
+	}
+	var plans []planned
+	for _, module := range modules {
+		migrations, err := load(module, m.dialect)
+		if err != nil {
+			return nil, err
+		}
+		for _, migration := range migrations {
+			plans = append(plans, planned{Migration: migration, module: module, checksum: checksum(migration)})
+		}
+	}
+	return plans, nil
+}
+
+// session 迁移的读写都走主库，避免读写分离时从落后的副本读到旧的迁移历史
+func (m *Migrator) session(ctx context.Context) *gorm.DB {
+	return m.db.WithContext(orm.UsePrimary(ctx))
+}
+
+func (m *Migrator) ensureTable(db *gorm.DB) error {
+	m.ensureMu.Lock()
+	defer m.ensureMu.Unlock()
+	if m.ensured {
+		return nil
+	}
+	if err := db.Table(m.table).AutoMigrate(&record{}); err != nil {
+		return fmt.Errorf("create %s failed: %w", m.table, err)
+	}
+	m.ensured = true
+	return nil
+}
+
+// history 读取已执行的迁移，键为 module/version
+func (m *Migrator) history(ctx context.Context) (map[string]record, error) {
+	db := m.session(ctx)
+	if err := m.ensureTable(db); err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := db.Table(m.table).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[string]record, len(records))
+	for _, item := range records {
+		applied[item.Module+"/"+item.Version] = item
+	}
+	return applied, nil
+}
+
+// Status 所有迁移的执行状态，包括历史表中存在但迁移源已删除的版本
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	plans, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.history(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(plans))
+	known := make(map[string]bool, len(plans))
+	modules := map[string]bool{}
+	for _, plan := range plans {
+		key := plan.module + "/" + plan.Version
+		known[key] = true
+		modules[plan.module] = true
+		status := Status{Module: plan.module, Version: plan.Version, Name: plan.Name}
+		if item, ok := applied[key]; ok {
+			appliedAt := item.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = item.Checksum != plan.checksum
+		}
+		statuses = append(statuses, status)
+	}
+	for key, item := range applied {
+		if known[key] || !modules[item.Module] {
+			continue
+		}
+		appliedAt := item.AppliedAt
+		statuses = append(statuses, Status{Module: item.Module, Version: item.Version, Name: item.Name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+	}
+	return statuses, nil
+}
+
+// pending 待执行的迁移；已执行的迁移脚本被修改时返回 ErrChecksumMismatch
+func (m *Migrator) pending(ctx context.Context) ([]planned, error) {
+	plans, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.history(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []planned
+	for _, plan := range plans {
+		item, ok := applied[plan.module+"/"+plan.Version]
+		if !ok {
+			pending = append(pending, plan)
+			continue
+		}
+		if item.Checksum != plan.checksum {
+			return nil, fmt.Errorf("%w: %s (recorded %s, current %s)", ErrChecksumMismatch, plan, item.Checksum, plan.checksum)
+		}
+	}
+	return pending, nil
+}
+
+// Pending 待执行的迁移
+func (m *Migrator) Pending(ctx context.Context) ([]Status, error) {
+	pending, err := m.pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(pending))
+	for _, plan := range pending {
+		statuses = append(statuses, Status{Module: plan.module, Version: plan.Version, Name: plan.Name})
+	}
+	return statuses, nil
+}
+
+// withLock 持有分布式锁执行 fn，保证多副本同时启动时只有一个在迁移；
+// 锁丢失时取消传给 fn 的 ctx，执行中的迁移事务随之回滚，返回 ErrLockLost
+func (m *Migrator) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	lockCtx, cancel := context.WithTimeout(ctx, m.lockTimeout)
+	lost, unlock, err := m.lock(lockCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			log.Warnf("释放迁移锁失败: %v", err)
+		}
+	}()
+	runCtx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	go func() {
+		select {
+		case <-lost:
+			abort(ErrLockLost)
+		case <-runCtx.Done():
+		}
+	}()
+	if err := fn(runCtx); err != nil {
+		if cause := context.Cause(runCtx); errors.Is(cause, ErrLockLost) {
+			return fmt.Errorf("migration aborted: %w (%v)", cause, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Up 执行全部待执行的迁移，每个迁移与其历史记录在同一事务中提交
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(ctx context.Context) error {
+		pending, err := m.pending(ctx)
+		if err != nil {
+			return err
+		}
+		for _, plan := range pending {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := m.apply(ctx, plan); err != nil {
+				return fmt.Errorf("migrate %s failed: %w", plan, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) apply(ctx context.Context, plan planned) error {
+	start := time.Now()
+	run := func(tx *gorm.DB) error {
+		if err := runUp(tx, plan.Migration); err != nil {
+			return err
+		}
+		return tx.Table(m.table).Create(&record{
+			Module:      plan.module,
+			Version:     plan.Version,
+			Name:        plan.Name,
+			Checksum:    plan.checksum,
+			AppliedAt:   time.Now(),
+			ExecutionMs: time.Since(start).Milliseconds(),
+		}).Error
+	}
+	db := m.session(ctx)
+	var err error
+	if plan.NoTransaction {
+		err = run(db)
+	} else {
+		err = db.Transaction(run)
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("迁移 %s 执行完成，耗时 %v", plan, time.Since(start))
+	return nil
+}
+
+func runUp(tx *gorm.DB, migration Migration) error {
+	for _, stmt := range splitStatements(migration.Up) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	var models []any
+	for _, model := range migration.Models {
+		if table, ok := model.(TableModel); ok {
+			if err := tx.Table(table.Name).AutoMigrate(table.Model); err != nil {
+				return err
+			}
+			continue
+		}
+		models = append(models, model)
+	}
+	if len(models) > 0 {
+		if err := tx.Migrator().AutoMigrate(models...); err != nil {
+			return err
+		}
+	}
+	if migration.UpFunc != nil {
+		return migration.UpFunc(tx)
+	}
+	return nil
+}
+
+func runDown(tx *gorm.DB, migration Migration) error {
+	if migration.DownFunc != nil {
+		if err := migration.DownFunc(tx); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range splitStatements(migration.Down) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	for i := len(migration.Models) - 1; i >= 0; i-- {
+		model := migration.Models[i]
+		if table, ok := model.(TableModel); ok {
+			model = table.Name
+		}
+		if err := tx.Migrator().DropTable(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down 按执行顺序倒序回滚最近的 steps 个迁移
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return nil
+	}
+	return m.withLock(ctx, func(ctx context.Context) error {
+		plans, err := m.load()
+		if err != nil {
+			return err
+		}
+		applied, err := m.history(ctx)
+		if err != nil {
+			return err
+		}
+		byKey := make(map[string]planned, len(plans))
+		modules := map[string]bool{}
+		for _, plan := range plans {
+			byKey[plan.module+"/"+plan.Version] = plan
+			modules[plan.module] = true
+		}
+		records := make([]record, 0, len(applied))
+		for _, item := range applied {
+			if modules[item.Module] {
+				records = append(records, item)
+			}
+		}
+		sort.Slice(records, func(i, j int) bool {
+			if !records[i].AppliedAt.Equal(records[j].AppliedAt) {
+				return records[i].AppliedAt.After(records[j].AppliedAt)
+			}
+			return compareVersion(records[i].Version, records[j].Version) > 0
+		})
+		if steps > len(records) {
+			steps = len(records)
+		}
+		for _, item := range records[:steps] {
+			plan, ok := byKey[item.Module+"/"+item.Version]
+			if !ok {
+				return fmt.Errorf("%w: %s/%s", ErrUnknownMigration, item.Module, item.Version)
+			}
+			if !plan.reversible() {
+				return fmt.Errorf("%w: %s", ErrIrreversible, plan)
+			}
+			if err := m.revert(ctx, plan); err != nil {
+				return fmt.Errorf("rollback %s failed: %w", plan, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) revert(ctx context.Context, plan planned) error {
+	run := func(tx *gorm.DB) error {
+		if err := runDown(tx, plan.Migration); err != nil {
+			return err
+		}
+		return tx.Table(m.table).Where("module = ? AND version = ?", plan.module, plan.Version).Delete(&record{}).Error
+	}
+	db := m.session(ctx)
+	var err error
+	if plan.NoTransaction {
+		err = run(db)
+	} else {
+		err = db.Transaction(run)
+	}
+	if err != nil {
+		return err
+	}
+	log.Infof("迁移 %s 已回滚", plan)
+	return nil
+}
+
+// DryRun 将待执行迁移的 SQL 输出到 w 而不执行，供上线前评审；
+// Models/UpFunc 迁移通过拦截写操作得到实际会执行的语句
+func (m *Migrator) DryRun(ctx context.Context, w io.Writer) error {
+	pending, err := m.pending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, plan := range pending {
+		if _, err := fmt.Fprintf(w, "-- %s (%s)\n", plan, m.dialect); err != nil {
+			return err
+		}
+		statements, err := m.render(ctx, plan.Migration)
+		if err != nil {
+			return fmt.Errorf("dry-run %s failed: %w", plan, err)
+		}
+		if len(statements) == 0 {
+			statements = []string{"-- 无结构变更"}
+		}
+		for _, stmt := range statements {
+			if !strings.HasPrefix(stmt, "--") {
+				stmt += ";"
+			}
+			if _, err := fmt.Fprintln(w, stmt); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render 得到迁移将执行的语句：SQL 脚本直接拆分，Models/UpFunc 在记录模式下执行
+func (m *Migrator) render(ctx context.Context, migration Migration) ([]string, error) {
+	statements := splitStatements(migration.Up)
+	if len(migration.Models) == 0 && migration.UpFunc == nil {
+		return statements, nil
+	}
+	tx := m.db.Session(&gorm.Session{NewDB: true, Context: orm.UsePrimary(ctx)})
+	rec := &recorder{ConnPool: tx.Statement.ConnPool, dialector: tx.Dialector}
+	tx.Statement.ConnPool = rec
+	if err := runUp(tx, Migration{Models: migration.Models, UpFunc: migration.UpFunc}); err != nil {
+		return nil, err
+	}
+	return append(statements, rec.statements...), nil
+}
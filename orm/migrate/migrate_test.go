@@ -0,0 +1,316 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/utils"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+type sourceFunc func(utils.DBType) ([]Migration, error)
+
+func (f sourceFunc) Migrations(dialect utils.DBType) ([]Migration, error) { return f(dialect) }
+
+type widgetTag struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"size:64"`
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	return orm.NewOrm(filepath.Join(t.TempDir(), "migrate.db"), utils.DBTypeSQLite, 5).GetDB()
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- 建表
+CREATE TABLE a (note VARCHAR(10) DEFAULT 'x;y'); /* 注释; */
+INSERT INTO a VALUES ("--;");
+CREATE FUNCTION f() RETURNS int AS $body$ BEGIN RETURN 1; END; $body$ LANGUAGE plpgsql;
+-- +migrate StatementBegin
+CREATE OR REPLACE PROCEDURE p AS
+BEGIN
+  NULL;
+END;
+-- +migrate StatementEnd
+-- 结尾注释
+`
+	got := splitStatements(script)
+	if len(got) != 4 {
+		t.Fatalf("splitStatements() = %q", got)
+	}
+	if !strings.HasSuffix(got[0], "DEFAULT 'x;y')") || got[1] != `/* 注释; */
+INSERT INTO a VALUES ("--;")` || !strings.Contains(got[2], "RETURN 1; END; $body$") || !strings.HasSuffix(got[3], "END;") {
+		t.Fatalf("splitStatements() = %q", got)
+	}
+}
+
+func TestFSDialectFallback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/default/0001_init.up.sql":    {Data: []byte("CREATE TABLE t (id INT)")},
+		"sql/default/0001_init.down.sql":  {Data: []byte("DROP TABLE t")},
+		"sql/default/0002_more.up.sql":    {Data: []byte("ALTER TABLE t ADD c INT")},
+		"sql/postgres/0002_more.up.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN c INT")},
+		"sql/kingbase/0001_init.up.sql":   {Data: []byte("CREATE TABLE t (id INT4)")},
+		"sql/kingbase/README.md":          {Data: []byte("ignored")},
+		"sql/dm/0003_bad-name.sql":        {Data: []byte("SELECT 1")},
+		"sql/mysql/0004_extra.up.sql":     {Data: []byte("SELECT 1")},
+		"sql/mysql/0004_extra.down.sql":   {Data: []byte("SELECT 2")},
+		"sql/mysql/0004_renamed.down.sql": {Data: []byte("SELECT 3")},
+	}
+	migrations, err := FS(fsys, "sql").Migrations(utils.DBTypeKingBase)
+	if err != nil {
+		t.Fatalf("Migrations(kingbase) error = %v", err)
+	}
+	byVersion := map[string]Migration{}
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+	// kingbase 覆盖整个版本(不混用 default 的 down)，0002 回退到 postgres
+	if len(byVersion) != 2 || byVersion["0001"].Up != "CREATE TABLE t (id INT4)" || byVersion["0001"].Down != "" ||
+		byVersion["0002"].Up != "ALTER TABLE t ADD COLUMN c INT" {
+		t.Fatalf("kingbase migrations = %+v", byVersion)
+	}
+	if migrations, _ = FS(fsys, "sql").Migrations(utils.DBTypeSQLite); len(migrations) != 2 {
+		t.Fatalf("sqlite migrations = %+v", migrations)
+	}
+	if _, err := FS(fsys, "sql").Migrations(utils.DBTypeDM); err == nil {
+		t.Fatal("invalid file name should fail")
+	}
+	if _, err := FS(fsys, "sql").Migrations(utils.DBTypeMySQL); err == nil {
+		t.Fatal("different names of the same version should fail")
+	}
+}
+
+func TestMigratorUpDownDryRun(t *testing.T) {
+	ctx := context.Background()
+	script := "CREATE TABLE widgets (id INTEGER PRIMARY KEY, name VARCHAR(64));\nINSERT INTO widgets (name) VALUES ('a;b');"
+	fsys := fstest.MapFS{
+		"m/default/202610180001_create_widgets.up.sql":   {Data: []byte(script)},
+		"m/default/202610180001_create_widgets.down.sql": {Data: []byte("DROP TABLE widgets;")},
+	}
+	Register("test_widgets", FS(fsys, "m"), sourceFunc(func(utils.DBType) ([]Migration, error) {
+		return []Migration{{
+			Version: "202610180002",
+			Name:    "create_widget_tags",
+			Models:  []any{&widgetTag{}},
+			UpFunc: func(tx *gorm.DB) error {
+				return tx.Create(&widgetTag{Name: "default"}).Error
+			},
+		}}, nil
+	}))
+	db := openDB(t)
+	m := New(db, WithModules("test_widgets"))
+
+	var out bytes.Buffer
+	if err := m.DryRun(ctx, &out); err != nil {
+		t.Fatalf("DryRun() error = %v", err)
+	}
+	plan := out.String()
+	for _, fragment := range []string{"-- test_widgets/202610180001_create_widgets (sqlite)", "VALUES ('a;b');", "CREATE TABLE `widget_tags`", `INSERT INTO ` + "`widget_tags`"} {
+		if !strings.Contains(plan, fragment) {
+			t.Fatalf("DryRun() output missing %q:\n%s", fragment, plan)
+		}
+	}
+	if db.Migrator().HasTable("widgets") || db.Migrator().HasTable(&widgetTag{}) {
+		t.Fatal("DryRun() must not change schema")
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("second Up() error = %v", err)
+	}
+	var widgets, tags int64
+	db.Table("widgets").Count(&widgets)
+	db.Model(&widgetTag{}).Count(&tags)
+	if widgets != 1 || tags != 1 {
+		t.Fatalf("rows = %d/%d", widgets, tags)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil || len(statuses) != 2 || !statuses[0].Applied || !statuses[1].Applied || statuses[0].Modified {
+		t.Fatalf("Status() = %+v, %v", statuses, err)
+	}
+
+	if err := m.Down(ctx, 1); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if db.Migrator().HasTable(&widgetTag{}) || !db.Migrator().HasTable("widgets") {
+		t.Fatal("Down(1) should only drop widget_tags")
+	}
+	if pending, _ := m.Pending(ctx); len(pending) != 1 || pending[0].Version != "202610180002" {
+		t.Fatalf("Pending() = %+v", pending)
+	}
+
+	// 修改已执行的脚本
+	fsys["m/default/202610180001_create_widgets.up.sql"] = &fstest.MapFile{Data: []byte(script + "\nSELECT 1;")}
+	if err := m.Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Up() after edit error = %v", err)
+	}
+	if statuses, _ = m.Status(ctx); !statuses[0].Modified {
+		t.Fatalf("Status() = %+v", statuses)
+	}
+}
+
+func TestModelMigrationEvolves(t *testing.T) {
+	ctx := context.Background()
+	type gadgetV1 struct {
+		ID   uint   `gorm:"primaryKey"`
+		Name string `gorm:"size:64"`
+	}
+	type gadgetV2 struct {
+		ID    uint   `gorm:"primaryKey"`
+		Name  string `gorm:"size:64"`
+		Color string `gorm:"size:16;index"`
+	}
+	model := any(TableModel{Name: "gadgets", Model: &gadgetV1{}})
+	var extra []Migration
+	Register("test_model_evolve", sourceFunc(func(utils.DBType) ([]Migration, error) {
+		return append([]Migration{{Version: "202610190001", Name: "create_gadgets", Models: []any{model}}}, extra...), nil
+	}))
+	db := openDB(t)
+	m := New(db, WithModules("test_model_evolve"))
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	// 模型加列后已执行的版本不应报校验和不一致，新增版本补列
+	model = TableModel{Name: "gadgets", Model: &gadgetV2{}}
+	extra = []Migration{{Version: "202610190002", Name: "add_gadgets_color", Models: []any{model}}}
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() after model change error = %v", err)
+	}
+	if !db.Migrator().HasColumn("gadgets", "color") {
+		t.Fatal("new version should add the color column")
+	}
+}
+
+func TestMigratorIrreversible(t *testing.T) {
+	ctx := context.Background()
+	Register("test_irreversible", List{{Version: "1", Name: "seed", Up: "CREATE TABLE seeds (id INTEGER)"}})
+	m := New(openDB(t), WithModules("test_irreversible"))
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if err := m.Down(ctx, 1); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("Down() error = %v", err)
+	}
+}
+
+func TestTableLock(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	first := NewTableLock(db, "schema_migrations_lock", 0)
+	second := NewTableLock(db, "schema_migrations_lock", 0)
+	_, unlock, err := first(ctx)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if _, _, err := second(waitCtx); !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("second Lock() error = %v", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("unlock() error = %v", err)
+	}
+	_, unlock, err = second(ctx)
+	if err != nil {
+		t.Fatalf("Lock() after unlock error = %v", err)
+	}
+	_ = unlock()
+
+	// 崩溃副本遗留的过期锁会被接管(过期时间按数据库时钟比较)
+	locks := NewLockTable(db, "schema_migrations_lock")
+	if err := db.Table("schema_migrations_lock").Where("name = ?", migrationLockName).
+		Updates(map[string]interface{}{"token": "crashed", "expires_at": locks.at(-time.Minute)}).Error; err != nil {
+		t.Fatalf("mark stale lock error = %v", err)
+	}
+	if _, unlock, err = first(ctx); err != nil {
+		t.Fatalf("Lock() over stale lock error = %v", err)
+	}
+	_ = unlock()
+}
+
+func TestTableLockLost(t *testing.T) {
+	db := openDB(t)
+	lost, unlock, err := NewTableLock(db, "schema_migrations_lock", 150*time.Millisecond)(context.Background())
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	defer unlock()
+	// 令牌被其他副本接管后续期失败，判定锁丢失
+	db.Table("schema_migrations_lock").Where("name = ?", migrationLockName).Update("token", "other")
+	select {
+	case <-lost:
+	case <-time.After(time.Second):
+		t.Fatalf("lost should be closed after the lock is taken over")
+	}
+}
+
+func TestMigratorAbortsWhenLockLost(t *testing.T) {
+	lost := make(chan struct{})
+	lock := func(ctx context.Context) (<-chan struct{}, func() error, error) {
+		return lost, func() error { return nil }, nil
+	}
+	Register("test_lock_lost", List{{Version: "1", Name: "slow", UpFunc: func(tx *gorm.DB) error {
+		close(lost)
+		<-tx.Statement.Context.Done()
+		return tx.Exec("CREATE TABLE lock_lost (id INTEGER)").Error
+	}}})
+	db := openDB(t)
+	m := New(db, WithModules("test_lock_lost"), WithLock(lock))
+	if err := m.Up(context.Background()); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("Up() error = %v, want ErrLockLost", err)
+	}
+	if pending, _ := m.Pending(context.Background()); len(pending) != 1 {
+		t.Fatalf("migration should stay pending after the lock is lost: %+v", pending)
+	}
+}
+
+func TestLockTableFence(t *testing.T) {
+	ctx := context.Background()
+	locks := NewLockTable(openDB(t), "locks")
+	if fence, err := locks.Acquire(ctx, "job", "a", time.Minute); err != nil || fence != 1 {
+		t.Fatalf("Acquire() = %d, %v", fence, err)
+	}
+	if _, err := locks.Acquire(ctx, "job", "b", time.Minute); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("second Acquire() error = %v", err)
+	}
+	if err := locks.Refresh(ctx, "job", "b", time.Minute); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("Refresh() with foreign token error = %v", err)
+	}
+	if err := locks.Release(ctx, "job", "a"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if fence, err := locks.Acquire(ctx, "job", "b", time.Minute); err != nil || fence != 2 {
+		t.Fatalf("Acquire() after release = %d, %v; want fence 2", fence, err)
+	}
+	if err := locks.Release(ctx, "job", "a"); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("Release() with stale token error = %v", err)
+	}
+}
+
+func TestApplyModuleVerify(t *testing.T) {
+	ctx := context.Background()
+	Register("test_verify", List{{Version: "1", Name: "init", Up: "CREATE TABLE verified (id INTEGER)"}})
+	db := openDB(t)
+	viper.Set(configMode, ModeVerify)
+	err := ApplyModule(ctx, db, "test_verify")
+	viper.Set(configMode, "")
+	if !errors.Is(err, ErrPendingMigrations) {
+		t.Fatalf("ApplyModule(verify) error = %v", err)
+	}
+	if err := ApplyModule(ctx, db, "test_verify"); err != nil || !db.Migrator().HasTable("verified") {
+		t.Fatalf("ApplyModule(auto) error = %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goodbye-jack/go-common/utils"
+)
+
+// DefaultDialectDir 与方言无关的脚本目录，方言目录中同版本的脚本会覆盖它
+const DefaultDialectDir = "default"
+
+// dialectFallbacks 方言目录缺少某个版本时依次查找的目录，人大金仓兼容 PostgreSQL 语法
+var dialectFallbacks = map[utils.DBType][]utils.DBType{
+	utils.DBTypeKingBase: {utils.DBTypePostgres},
+}
+
+// Source 迁移来源，按方言返回迁移列表
+type Source interface {
+	Migrations(dialect utils.DBType) ([]Migration, error)
+}
+
+// List 在代码中声明的迁移，与方言无关
+type List []Migration
+
+// Migrations 实现 Source
+func (l List) Migrations(utils.DBType) ([]Migration, error) {
+	return l, nil
+}
+
+// fsSource 从文件系统(通常为 embed.FS)加载按方言分目录的 SQL 脚本
+type fsSource struct {
+	fsys fs.FS
+	dir  string
+}
+
+// FS 从 fsys 的 dir 目录加载 SQL 迁移，目录结构为
+//
+//	<dir>/default/202610180001_create_orders.up.sql
+//	<dir>/default/202610180001_create_orders.down.sql
+//	<dir>/dm/202610180001_create_orders.up.sql
+//	<dir>/kingbase/...
+//
+// 同一版本优先使用当前方言目录，其次是兼容方言(kingbase → postgres)，最后是 default
+func FS(fsys fs.FS, dir string) Source {
+	return fsSource{fsys: fsys, dir: dir}
+}
+
+var scriptPattern = regexp.MustCompile(`^([0-9]+)_([A-Za-z0-9_\-]+)\.(up|down)\.sql$`)
+
+// Migrations 实现 Source
+func (s fsSource) Migrations(dialect utils.DBType) ([]Migration, error) {
+	dirs := []string{DefaultDialectDir}
+	fallbacks := dialectFallbacks[dialect]
+	for i := len(fallbacks) - 1; i >= 0; i-- {
+		dirs = append(dirs, string(fallbacks[i]))
+	}
+	if dialect != "" {
+		dirs = append(dirs, string(dialect))
+	}
+	byVersion := map[string]*Migration{}
+	for _, dir := range dirs {
+		loaded, err := s.readDir(path.Join(s.dir, dir))
+		if err != nil {
+			return nil, err
+		}
+		// 更具体的方言目录整体覆盖同版本的 up/down，避免混用不同方言的脚本
+		for version, migration := range loaded {
+			byVersion[version] = migration
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	return migrations, nil
+}
+
+func (s fsSource) readDir(dir string) (map[string]*Migration, error) {
+	entries, err := fs.ReadDir(s.fsys, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	loaded := map[string]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := scriptPattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s, want <version>_<name>.up.sql", path.Join(dir, entry.Name()))
+		}
+		content, err := fs.ReadFile(s.fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration := loaded[match[1]]
+		if migration == nil {
+			migration = &Migration{Version: match[1], Name: match[2]}
+			loaded[match[1]] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %s in %s has different names %s and %s", match[1], dir, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	return loaded, nil
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string][]Source{}
+	modules    []string
+)
+
+// Register 注册模块的迁移来源，通常在包的 init 中调用：
+//
+//	//go:embed migrations
+//	var migrationFS embed.FS
+//
+//	func init() {
+//		migrate.Register("app", migrate.FS(migrationFS, "migrations"))
+//	}
+func Register(module string, sources ...Source) {
+	module = strings.TrimSpace(module)
+	if module == "" {
+		panic("migrate: module name is empty")
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[module]; !ok {
+		modules = append(modules, module)
+	}
+	registry[module] = append(registry[module], sources...)
+}
+
+// Modules 已注册的模块，按注册顺序
+func Modules() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]string{}, modules...)
+}
+
+// load 加载模块在指定方言下的全部迁移，校验版本唯一并按版本排序
+func load(module string, dialect utils.DBType) ([]Migration, error) {
+	registryMu.RLock()
+	sources, ok := registry[module]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("migration module %s is not registered", module)
+	}
+	var migrations []Migration
+	seen := map[string]bool{}
+	for _, source := range sources {
+		items, err := source.Migrations(dialect)
+		if err != nil {
+			return nil, fmt.Errorf("load migrations of %s failed: %w", module, err)
+		}
+		for _, item := range items {
+			if err := item.validate(); err != nil {
+				return nil, fmt.Errorf("module %s: %w", module, err)
+			}
+			if seen[item.Version] {
+				return nil, fmt.Errorf("module %s has duplicate migration version %s", module, item.Version)
+			}
+			seen[item.Version] = true
+			migrations = append(migrations, item)
+		}
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return compareVersion(migrations[i].Version, migrations[j].Version) < 0
+	})
+	return migrations, nil
+}
+
+// checksum 迁移内容的 sha256，SQL 按语句规范化后计算。Models 不计入：模型随代码演进，
+// 已执行的版本只按版本号与名称记录，模型新增列或索引时新增一个引用同一模型的版本再次 AutoMigrate；
+// Go 函数无法计算内容，修改其行为时同样应新增版本
+func checksum(migration Migration) string {
+	h := sha256.New()
+	for _, stmt := range splitStatements(migration.Up) {
+		h.Write([]byte(stmt))
+		h.Write([]byte{';', '\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+const (
+	statementBegin = "-- +migrate statementbegin"
+	statementEnd   = "-- +migrate statementend"
+)
+
+var dollarTagPattern = regexp.MustCompile(`^\$[A-Za-z_0-9]*\$`)
+
+// splitStatements 按分号拆分 SQL 脚本，忽略字符串、注释与 PostgreSQL $$ 块中的分号；
+// StatementBegin/StatementEnd 之间的内容作为一条语句原样执行(如达梦/人大金仓的存储过程)
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		buf        strings.Builder
+		block      bool
+		quote      byte
+		comment    bool
+		dollarTag  string
+	)
+	flush := func() {
+		if stmt := strings.TrimSpace(buf.String()); stmt != "" && !onlyComments(stmt) {
+			statements = append(statements, stmt)
+		}
+		buf.Reset()
+	}
+	for _, line := range strings.SplitAfter(script, "\n") {
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case statementBegin:
+			flush()
+			block = true
+			continue
+		case statementEnd:
+			flush()
+			block = false
+			continue
+		}
+		if block {
+			buf.WriteString(line)
+			continue
+		}
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			switch {
+			case comment:
+				if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+					comment = false
+					buf.WriteString("*/")
+					i++
+					continue
+				}
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+			case dollarTag != "":
+				if strings.HasPrefix(line[i:], dollarTag) {
+					buf.WriteString(dollarTag)
+					i += len(dollarTag) - 1
+					dollarTag = ""
+					continue
+				}
+			case c == '\'' || c == '"':
+				quote = c
+			case c == '-' && i+1 < len(line) && line[i+1] == '-':
+				// 行注释：其余部分原样保留
+				buf.WriteString(line[i:])
+				i = len(line)
+				continue
+			case c == '/' && i+1 < len(line) && line[i+1] == '*':
+				comment = true
+				buf.WriteString("/*")
+				i++
+				continue
+			case c == '$':
+				if tag := dollarTagPattern.FindString(line[i:]); tag != "" {
+					dollarTag = tag
+					buf.WriteString(tag)
+					i += len(tag) - 1
+					continue
+				}
+			case c == ';':
+				flush()
+				continue
+			}
+			buf.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
+
+// onlyComments 语句只包含注释
+func onlyComments(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	glog "github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/orm/audit"
+	"github.com/goodbye-jack/go-common/orm/dbconfig"
+	ormdialect "github.com/goodbye-jack/go-common/orm/dialect"
+	"github.com/goodbye-jack/go-common/orm/fieldcrypt"
+	"github.com/goodbye-jack/go-common/utils"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/driver/sqlserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// 连接池与日志默认值
+const (
+	DefaultMaxOpenConns    = 100
+	DefaultMaxIdleConns    = 10
+	DefaultConnMaxLifetime = 3 * time.Minute
+	DefaultSlowThreshold   = 3 * time.Second
+)
+
+// ErrUnsupportedDBType 不支持的关系型数据库类型
+var ErrUnsupportedDBType = errors.New("unsupported db type")
+
+// Options 关系型数据库连接选项，零值字段使用默认值
+type Options struct {
+	MaxOpenConns    int             // 最大连接数，默认 100
+	MaxIdleConns    int             // 最大空闲连接数，默认 10
+	ConnMaxLifetime time.Duration   // 连接最大存活时间，默认 3 分钟
+	ConnMaxIdleTime time.Duration   // 连接最大空闲时间，默认不限制
+	PrepareStmt     *bool           // 预编译语句缓存，默认开启
+	NamingStrategy  schema.Namer    // 命名策略，默认使用方言自身的策略(如 Oracle 大写)
+	SlowThreshold   time.Duration   // 慢查询阈值，默认 3 秒
+	LogLevel        logger.LogLevel // gorm 日志级别，默认 Warn
+	SkipInitialPing bool            // 创建时不 Ping，连接暂不可用也返回实例(如只读副本，由健康检查剔除/恢复)
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxOpenConns <= 0 {
+		o.MaxOpenConns = DefaultMaxOpenConns
+	}
+	if o.MaxIdleConns <= 0 {
+		o.MaxIdleConns = DefaultMaxIdleConns
+	}
+	if o.MaxIdleConns > o.MaxOpenConns {
+		o.MaxIdleConns = o.MaxOpenConns
+	}
+	if o.ConnMaxLifetime <= 0 {
+		o.ConnMaxLifetime = DefaultConnMaxLifetime
+	}
+	if o.PrepareStmt == nil {
+		prepareStmt := true
+		o.PrepareStmt = &prepareStmt
+	}
+	if o.SlowThreshold <= 0 {
+		o.SlowThreshold = DefaultSlowThreshold
+	}
+	if o.LogLevel == 0 {
+		o.LogLevel = logger.Warn
+	}
+	return o
+}
+
+// OptionsFromConfig 由 dbconfig 配置生成连接选项，slowTime 单位为秒
+func OptionsFromConfig(cfg *dbconfig.Config, slowTime int) Options {
+	return Options{
+		MaxOpenConns:    cfg.MaxOpenConn,
+		MaxIdleConns:    cfg.MaxIdleConn,
+		ConnMaxLifetime: cfg.ConnMaxLifeTime,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		PrepareStmt:     cfg.PrepareStmt,
+		NamingStrategy:  namingStrategyOf(cfg),
+		SlowThreshold:   time.Duration(slowTime) * time.Second,
+		LogLevel:        cfg.GetLogMode(),
+	}
+}
+
+// namingStrategyOf 配置了表前缀或单数表名时生成命名策略，Oracle 保持大写
+func namingStrategyOf(cfg *dbconfig.Config) schema.Namer {
+	if cfg.TablePrefix == "" && !cfg.SingularTable {
+		return nil
+	}
+	namingStrategy := schema.NamingStrategy{TablePrefix: cfg.TablePrefix, SingularTable: cfg.SingularTable}
+	if cfg.DBType == utils.DBTypeOracle {
+		namingStrategy.IdentifierMaxLength = 128
+		return ormdialect.OracleNamingStrategy{NamingStrategy: namingStrategy}
+	}
+	return namingStrategy
+}
+
+// openDialector 按数据库类型创建 gorm 方言
+func openDialector(dsn string, dbType utils.DBType) (gorm.Dialector, error) {
+	switch dbType {
+	case utils.DBTypeMySQL:
+		return mysql.Open(dsn), nil
+	case utils.DBTypePostgres:
+		return postgres.Open(dsn), nil
+	case utils.DBTypeSqlserver:
+		return sqlserver.Open(dsn), nil
+	case utils.DBTypeSQLite:
+		return sqlite.Open(dsn), nil
+	case utils.DBTypeDM:
+		return ormdialect.OpenDM(dsn), nil
+	case utils.DBTypeKingBase:
+		return ormdialect.OpenKingbase(dsn), nil
+	case utils.DBTypeOracle:
+		return ormdialect.OpenOracle(dsn), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedDBType, dbType)
+}
+
+// Open 按选项创建 ORM 实例并配置连接池，DSN 为空、类型不支持或连接失败时返回错误
+func Open(dsn string, dbType utils.DBType, opts Options) (*Orm, error) {
+	glog.Infof("orm.Open param:dsn=%s", maskDSN(dsn, dbType))
+	if strings.TrimSpace(dsn) == "" {
+		return nil, errors.New("dsn is empty")
+	}
+	if dbType == "" {
+		glog.Warn("未指定DBType，默认使用mysql数据源")
+		dbType = utils.DBTypeMySQL
+	}
+	dialector, err := openDialector(dsn, dbType)
+	if err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+			SlowThreshold:             opts.SlowThreshold,
+			LogLevel:                  opts.LogLevel,
+			IgnoreRecordNotFoundError: true,
+			Colorful:                  true,
+		}).LogMode(opts.LogLevel),
+		NamingStrategy:                           opts.NamingStrategy,
+		DisableForeignKeyConstraintWhenMigrating: true,
+		DisableAutomaticPing:                     opts.SkipInitialPing,
+		PrepareStmt:                              *opts.PrepareStmt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s connect failed: %w", dbType, err)
+	}
+	// 字段加密插件：计算盲索引并加密 map 形式写入的加密字段，未使用加密字段的模型不受影响
+	if err := db.Use(fieldcrypt.Plugin{}); err != nil {
+		return nil, fmt.Errorf("%s register fieldcrypt failed: %w", dbType, err)
+	}
+	// 审计插件：只作用于声明了 audit 标签的模型(model.AuditBase/TenantBase/VersionBase)
+	if err := db.Use(audit.Plugin{}); err != nil {
+		return nil, fmt.Errorf("%s register audit failed: %w", dbType, err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("%s get sql.DB failed: %w", dbType, err)
+	}
+	sqlDB.SetMaxOpenConns(opts.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(opts.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	return &Orm{db: db}, nil
+}
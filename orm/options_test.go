@@ -0,0 +1,82 @@
+package orm
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/goodbye-jack/go-common/utils"
+	"github.com/spf13/viper"
+)
+
+func TestOpenAppliesOptions(t *testing.T) {
+	o, err := Open(filepath.Join(t.TempDir(), "options.db"), utils.DBTypeSQLite, Options{MaxOpenConns: 7, MaxIdleConns: 20})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	sqlDB, err := o.DB()
+	if err != nil {
+		t.Fatalf("DB() error = %v", err)
+	}
+	defer sqlDB.Close()
+	if got := sqlDB.Stats().MaxOpenConnections; got != 7 {
+		t.Fatalf("MaxOpenConnections = %d, want 7", got)
+	}
+	if !o.GetDB().Config.PrepareStmt {
+		t.Fatalf("PrepareStmt should default to true")
+	}
+
+	disabled := false
+	o, err = Open(filepath.Join(t.TempDir(), "nostmt.db"), utils.DBTypeSQLite, Options{PrepareStmt: &disabled})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if o.GetDB().Config.PrepareStmt {
+		t.Fatalf("PrepareStmt = true, want false")
+	}
+	if sqlDB, _ := o.DB(); sqlDB.Stats().MaxOpenConnections != DefaultMaxOpenConns {
+		t.Fatalf("MaxOpenConnections = %d, want %d", sqlDB.Stats().MaxOpenConnections, DefaultMaxOpenConns)
+	}
+}
+
+func TestOpenReturnsErrors(t *testing.T) {
+	if _, err := Open("", utils.DBTypeSQLite, Options{}); err == nil {
+		t.Fatalf("empty dsn should fail")
+	}
+	if _, err := Open("file.db", utils.DBType("access"), Options{}); !errors.Is(err, ErrUnsupportedDBType) {
+		t.Fatalf("unsupported type error = %v", err)
+	}
+	// 目录无法作为 sqlite 文件打开，gorm.Open 的 Ping 失败时应返回错误而不是空指针
+	if _, err := Open(t.TempDir(), utils.DBTypeSQLite, Options{}); err == nil {
+		t.Fatalf("failed gorm.Open should return error")
+	}
+	if o := NewOrm(t.TempDir(), utils.DBTypeSQLite, 1); o != nil {
+		t.Fatalf("NewOrm() = %v, want nil", o)
+	}
+}
+
+func TestInitAllDBSQLite(t *testing.T) {
+	prevDB, prevMap := DB, RelationalMap
+	RelationalMap = make(map[string]*Orm)
+	defer func() { DB, RelationalMap = prevDB, prevMap }()
+
+	v := viper.New()
+	v.Set("databases.sqlite3.default.database", filepath.Join(t.TempDir(), "app.db"))
+	v.Set("databases.sqlite3.default.max_open_conn", 3)
+	v.Set("databases.sqlite3.default.table_prefix", "t_")
+	v.Set("databases.sqlite3.default.singular_table", true)
+	if err := InitAllDB(v); err != nil {
+		t.Fatalf("InitAllDB() error = %v", err)
+	}
+	if DB == nil || RelationalMap["default"] != DB {
+		t.Fatalf("default sqlite instance not registered")
+	}
+	sqlDB, _ := DB.DB()
+	defer sqlDB.Close()
+	if got := sqlDB.Stats().MaxOpenConnections; got != 3 {
+		t.Fatalf("MaxOpenConnections = %d, want 3", got)
+	}
+	if got := DB.GetDB().NamingStrategy.TableName("Tester"); got != "t_tester" {
+		t.Fatalf("TableName = %q, want t_tester", got)
+	}
+}
@@ -3,21 +3,16 @@ package orm
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	_ "gitea.com/kingbase/gokb" // Kingbase 驱动
 	glog "github.com/goodbye-jack/go-common/log"
 	"github.com/goodbye-jack/go-common/utils"
-	dm "github.com/jasonlabz/gorm-dm-driver"
-	"gorm.io/driver/mysql"
-	"gorm.io/driver/postgres"
-	"gorm.io/driver/sqlite"
-	"gorm.io/driver/sqlserver"
+	_ "github.com/jasonlabz/gorm-dm-driver" // 达梦驱动
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
-	"log"
-	"os"
 	"regexp"
 	"strings"
 	"time"
@@ -75,7 +70,9 @@ func (s forcedLowerCaseNamingStrategy) ColumnName(table, column string) string {
 }
 
 type Orm struct {
-	db *gorm.DB
+	db     *gorm.DB
+	router *replicaRouter            // 读写分离路由，SetReplicas 后非空
+	scopes []func(*gorm.DB) *gorm.DB // Scopes 设置的条件，在 WithContext 时应用
 }
 
 // 新增：DB 暴露底层的*sql.DB，用于外部调整连接池参数
@@ -85,74 +82,29 @@ func (o *Orm) DB() (*sql.DB, error) {
 
 // 新增：GetDB 暴露底层的*gorm.DB（可选，兼容特殊场景）
 func (o *Orm) GetDB() *gorm.DB {
-	return o.db
+	return o.scoped(o.db)
 }
 
-// NewOrm 创建 ORM 实例
-func NewOrm(dsn string, dbtype utils.DBType, slowTime int, logLevels ...logger.LogLevel) *Orm {
-	glog.Infof("NewOrm param:dsn=%s", maskDSN(dsn, dbtype))
-	if dsn == "" {
-		glog.Error("NewOrm param dsn is empty:请检查您的DSN参数")
-		return nil
-	}
-	if dbtype == "" {
-		glog.Error("您没有输入DBType,默认使用mysql数据源")
-		dbtype = utils.DBTypeMySQL // 默认使用mysql
-	}
-	if slowTime <= 0 {
-		slowTime = 3
+// scoped 为 db 附加 Scopes 设置的条件
+func (o *Orm) scoped(db *gorm.DB) *gorm.DB {
+	if len(o.scopes) == 0 {
+		return db
 	}
-	gormLogLevel := logger.Warn
+	return db.Scopes(o.scopes...)
+}
+
+// NewOrm 创建 ORM 实例，使用默认连接池参数；失败时记录日志并返回 nil，需要错误信息时使用 Open
+func NewOrm(dsn string, dbtype utils.DBType, slowTime int, logLevels ...logger.LogLevel) *Orm {
+	opts := Options{SlowThreshold: time.Duration(slowTime) * time.Second}
 	if len(logLevels) > 0 {
-		gormLogLevel = logLevels[0]
+		opts.LogLevel = logLevels[0]
 	}
-	var dialect gorm.Dialector
-	switch dbtype {
-	case utils.DBTypeMySQL:
-		dialect = mysql.Open(dsn)
-	case utils.DBTypePostgres:
-		dialect = postgres.Open(dsn)
-	case utils.DBTypeSqlserver:
-		dialect = sqlserver.Open(dsn)
-	case utils.DBTypeSQLite:
-		dialect = sqlite.Open(dsn)
-	case utils.DBTypeDM:
-		dialect = dm.Open(dsn)
-	case utils.DBTypeKingBase:
-		dialect = postgres.New(postgres.Config{
-			DriverName: "kingbase",
-			DSN:        dsn,
-		})
-	default:
-		glog.Errorf("unsupported dbType: %s", dbtype)
-	}
-	dbConfig := &gorm.Config{ // 配置 GORM
-		Logger: logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
-			SlowThreshold:             time.Duration(slowTime) * time.Second, // 这个最小就是5,后面改成可传入数字
-			LogLevel:                  gormLogLevel,
-			IgnoreRecordNotFoundError: true,
-			Colorful:                  true,
-		}).LogMode(gormLogLevel),
-		DisableForeignKeyConstraintWhenMigrating: true,
-		PrepareStmt:                              true,
-	}
-	db, err := gorm.Open(dialect, dbConfig)
+	o, err := Open(dsn, dbtype, opts)
 	if err != nil {
-		glog.Errorf("%s connect failed, %v", dbtype, err)
-	}
-	sqlDB, _ := db.DB()
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Minute * 3)
-	orm := &Orm{
-		db: db,
-	}
-	if dbtype == utils.DBTypeDM {
-		orm.registerDMHooks()
-	} else if dbtype == utils.DBTypeKingBase {
-		orm.registerKingbaseHooks()
+		glog.Errorf("NewOrm failed: %v", err)
+		return nil
 	}
-	return orm
+	return o
 }
 
 func maskDSN(dsn string, dbType utils.DBType) string {
@@ -185,6 +137,9 @@ func maskDSN(dsn string, dbType utils.DBType) string {
 		}
 		return strings.Join(parts, ";")
 	case utils.DBTypeOracle:
+		if strings.Contains(trimmed, "://") {
+			return maskDSN(trimmed, utils.DBTypeRedis)
+		}
 		if at := strings.Index(trimmed, "@"); at > 0 {
 			if slash := strings.Index(trimmed[:at], "/"); slash >= 0 {
 				return trimmed[:slash+1] + "******" + trimmed[at:]
@@ -211,93 +166,13 @@ func maskDSN(dsn string, dbType utils.DBType) string {
 	return trimmed
 }
 
-// 注册达梦专用钩子
-func (o *Orm) registerDMHooks() {
-	//err := o.db.Callback().Query().Before("gorm:query").Register("dm:convert_limit", convertDMLimit)
-	//if err != nil {
-	//	log.Fatalf("register DM hooks failed, %v", err)
-	//}
-}
-
-// 注册人大金仓专用钩子
-func (o *Orm) registerKingbaseHooks() {
-	// 人大金仓基于 PostgreSQL，通常不需要特殊处理
-}
-
-// 达梦 LIMIT/OFFSET 转换钩子
-func convertDMLimit(db *gorm.DB) {
-	var limit, offset int
-	limitClause, hasLimit := db.Statement.Clauses["LIMIT"]
-	offsetClause, hasOffset := db.Statement.Clauses["OFFSET"]
-
-	if hasLimit {
-		if l, ok := limitClause.Expression.(clause.Limit); ok {
-			if l.Limit != nil {
-				limit = *l.Limit
-			}
-		}
-	}
-
-	if hasOffset {
-		if o, ok := offsetClause.Expression.(*clause.Limit); ok {
-			offset = o.Offset
-		}
-	}
-
-	if limit == 0 && offset == 0 {
-		return
-	}
-
-	if offset > 0 {
-		originalSQL := db.Statement.SQL.String()
-		orderExpr := "id ASC"
-
-		if orderClause, hasOrder := db.Statement.Clauses["ORDER BY"]; hasOrder {
-			if orderBy, ok := orderClause.Expression.(clause.OrderBy); ok {
-				orderExpr = ""
-				for _, col := range orderBy.Columns {
-					if col.Column.Name == "" {
-						continue
-					}
-					direction := "ASC"
-					if col.Desc {
-						direction = "DESC"
-					}
-					orderExpr += fmt.Sprintf("%s %s, ", col.Column.Name, direction)
-				}
-				if orderExpr != "" {
-					orderExpr = strings.TrimSuffix(orderExpr, ", ")
-				} else {
-					orderExpr = "id ASC"
-				}
-			}
-		}
-		newSQL := fmt.Sprintf(`
-			SELECT * FROM (
-				SELECT ROW_NUMBER() OVER (ORDER BY %s) AS rn, t.* 
-				FROM (%s) t
-			) WHERE rn > %d AND rn <= %d
-		`, orderExpr, originalSQL, offset, offset+limit)
-		db.Statement.SQL.Reset()
-		db.Statement.SQL.WriteString(newSQL)
-		delete(db.Statement.Clauses, "LIMIT")
-		delete(db.Statement.Clauses, "OFFSET")
-	} else if limit > 0 {
-		originalSQL := db.Statement.SQL.String()
-		newSQL := strings.Replace(originalSQL, "SELECT", fmt.Sprintf("SELECT TOP %d", limit), 1)
-
-		db.Statement.SQL.Reset()
-		db.Statement.SQL.WriteString(newSQL)
-		delete(db.Statement.Clauses, "LIMIT")
-	}
-}
-
-func (o *Orm) AutoMigrate(ptr interface{}) {
-	err := o.db.AutoMigrate(ptr)
-	if err != nil {
+// AutoMigrate 按模型建表/补列并返回错误；生产环境的结构变更建议使用 orm/migrate 的版本化迁移
+func (o *Orm) AutoMigrate(ptrs ...interface{}) error {
+	if err := o.db.AutoMigrate(ptrs...); err != nil {
 		glog.Errorf("AutoMigrate error: %v", err)
-		return
+		return err
 	}
+	return nil
 }
 
 func (o *Orm) Migrator(ptr interface{}, indexName string) {
@@ -307,18 +182,18 @@ func (o *Orm) Migrator(ptr interface{}, indexName string) {
 }
 
 func (o *Orm) Table(name string, args ...interface{}) (tx *gorm.DB) {
-	return o.db.Table(name, args...)
+	return o.scoped(o.db).Table(name, args...)
 }
 
-func (o *Orm) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) {
-	db := o.db.WithContext(ctx)
-	if err := db.Transaction(fn); err != nil {
-		glog.Errorf("Transaction error: %v", err)
-	}
+// Scopes 返回附带 GORM Scope 的 Orm，其查询方法都会带上这些条件，如数据权限：
+// orm.DB.Scopes(datascope.Scope(c, "orders")).Page(c, &orders, 1, 20, "id", "desc")
+func (o *Orm) Scopes(funcs ...func(*gorm.DB) *gorm.DB) *Orm {
+	scopes := append(append([]func(*gorm.DB) *gorm.DB{}, o.scopes...), funcs...)
+	return &Orm{db: o.db, router: o.router, scopes: scopes}
 }
 
 func (o *Orm) Create(ctx context.Context, ptr interface{}) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	return db.Create(ptr).Error
 }
 
@@ -351,17 +226,17 @@ func snakeToCamel(s string) string {
 
 // 修改First方法的查询条件处理
 func (o *Orm) First(ctx context.Context, res interface{}, filters ...interface{}) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	return db.First(res, filters...).Error
 }
 
 func (o *Orm) Last(ctx context.Context, res interface{}, filters ...interface{}) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	return db.Last(res, filters...).Error
 }
 
 func (o *Orm) FindAll(ctx context.Context, res interface{}, filters ...interface{}) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	if len(filters) > 0 {
 		return db.Where(filters[0], filters[1:]...).Find(res).Error
 	}
@@ -369,7 +244,7 @@ func (o *Orm) FindAll(ctx context.Context, res interface{}, filters ...interface
 }
 
 func (o *Orm) FindAllWithOrder(ctx context.Context, res interface{}, order interface{}, filters ...interface{}) error {
-	db := o.db.WithContext(ctx).Order(order)
+	db := o.WithContext(ctx).Order(order)
 	if len(filters) > 0 {
 		return db.Where(filters[0], filters[1:]...).Find(res).Error
 	}
@@ -377,7 +252,7 @@ func (o *Orm) FindAllWithOrder(ctx context.Context, res interface{}, order inter
 }
 
 func (o *Orm) Preload(key string, ctx context.Context, res interface{}, filters ...interface{}) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	if len(filters) > 0 {
 		db = db.Where(filters[0], filters[1:]...)
 	}
@@ -389,28 +264,67 @@ func (o *Orm) Preload(key string, ctx context.Context, res interface{}, filters
 }
 
 func (o *Orm) Association(column string) *gorm.Association {
-	return o.db.Association(column)
+	return o.scoped(o.db).Association(column)
 }
 
+// Page 分页查询，sortColumn 须为列名(可带表名前缀)、sortSc 为 asc/desc，否则返回 ErrInvalidSort；
+// 排序列来自请求参数时请使用 queryspec 按白名单解析
 func (o *Orm) Page(ctx context.Context, res interface{}, page, pageSize int, sortColumn string, sortSc string, filters ...interface{}) error {
-	sortBy := sortColumn + " " + sortSc
-	db := o.db.WithContext(ctx)
+	order, err := orderByColumn(sortColumn, sortSc)
+	if err != nil {
+		return err
+	}
+	db := o.WithContext(ctx)
+	if order != nil {
+		db = db.Order(*order)
+	}
 	if len(filters) > 0 {
-		return db.Where(filters[0], filters[1:]...).Order(sortBy).Limit(pageSize).Offset((page - 1) * pageSize).Find(res).Error
+		db = db.Where(filters[0], filters[1:]...)
+	}
+	return db.Limit(pageSize).Offset((page - 1) * pageSize).Find(res).Error
+}
+
+// ErrInvalidSort 排序列或排序方向不合法
+var ErrInvalidSort = errors.New("invalid sort column or direction")
+
+var sortColumnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// orderByColumn 校验并生成经方言转义的排序列，sortColumn 为空时不排序
+func orderByColumn(sortColumn, sortSc string) (*clause.OrderByColumn, error) {
+	sortColumn = strings.TrimSpace(sortColumn)
+	if sortColumn == "" {
+		return nil, nil
+	}
+	if !sortColumnPattern.MatchString(sortColumn) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSort, sortColumn)
+	}
+	desc := false
+	switch strings.ToLower(strings.TrimSpace(sortSc)) {
+	case "", "asc":
+	case "desc":
+		desc = true
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSort, sortSc)
+	}
+	column := clause.Column{Name: sortColumn}
+	if table, name, ok := strings.Cut(sortColumn, "."); ok {
+		column = clause.Column{Table: table, Name: name}
 	}
-	return db.Order(sortBy).Limit(pageSize).Offset((page - 1) * pageSize).Find(res).Error
+	return &clause.OrderByColumn{Column: column, Desc: desc}, nil
 }
 
+// Deprecated: whereCondition 为原生 SQL，不要拼接请求参数；列表查询请使用 queryspec
 func (o *Orm) FindJoins(tableName string, ctx context.Context, res interface{}, returnRows, whereCondition string, joins ...string) error {
-	db := o.db.WithContext(ctx).Table(tableName).Select(returnRows)
+	db := o.WithContext(ctx).Table(tableName).Select(returnRows)
 	for _, value := range joins {
 		db = db.Joins(value)
 	}
 	return db.Where(whereCondition).Find(res).Error
 }
 
+// Deprecated: whereCondition 为原生 SQL，不要拼接请求参数；列表查询请使用 queryspec
 func (o *Orm) PageJoins(tableName string, ctx context.Context, res interface{}, returnRows, whereCondition string, page, pageSize int, joins ...string) error {
-	db := o.db.WithContext(ctx).Table(tableName).Select(returnRows)
+	db := o.WithContext(ctx).Table(tableName).Select(returnRows)
 	for _, value := range joins {
 		db = db.Joins(value)
 	}
@@ -418,7 +332,7 @@ func (o *Orm) PageJoins(tableName string, ctx context.Context, res interface{},
 }
 
 func (o *Orm) PagePerLoadCondition(key string, ctx context.Context, res interface{}, page, pageSize int, subKey string, subCondition string, filters ...interface{}) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	if len(filters) > 0 {
 		db = db.Where(filters[0], filters[1:]...)
 	}
@@ -431,7 +345,7 @@ func (o *Orm) PagePerLoadCondition(key string, ctx context.Context, res interfac
 }
 
 func (o *Orm) PreloadCount(key string, ctx context.Context, res interface{}, total int64, filters ...interface{}) (int64, error) {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	if len(filters) > 0 {
 		db = db.Where(filters[0], filters[1:]...)
 	}
@@ -440,7 +354,7 @@ func (o *Orm) PreloadCount(key string, ctx context.Context, res interface{}, tot
 }
 
 func (o *Orm) PagePerLoad(key string, ctx context.Context, res interface{}, page, pageSize int, filters ...interface{}) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	if len(filters) > 0 {
 		db = db.Where(filters[0], filters[1:]...)
 	}
@@ -453,7 +367,7 @@ func (o *Orm) PagePerLoad(key string, ctx context.Context, res interface{}, page
 }
 
 func (o *Orm) Count(ctx context.Context, model interface{}, total *int64, filters ...interface{}) error {
-	db := o.db.WithContext(ctx).Model(&model)
+	db := o.WithContext(ctx).Model(&model)
 	if len(filters) > 0 {
 		return db.Where(filters[0], filters[1:]...).Count(total).Error
 	}
@@ -461,7 +375,7 @@ func (o *Orm) Count(ctx context.Context, model interface{}, total *int64, filter
 }
 
 func (o *Orm) CountIdx(ctx context.Context, model interface{}, selectColumns string, total *int64, filters ...interface{}) error {
-	db := o.db.WithContext(ctx).Model(&model).Select(selectColumns)
+	db := o.WithContext(ctx).Model(&model).Select(selectColumns)
 	if len(filters) > 0 {
 		return db.Where(filters[0], filters[1:]...).Count(total).Error
 	}
@@ -469,17 +383,17 @@ func (o *Orm) CountIdx(ctx context.Context, model interface{}, selectColumns str
 }
 
 func (o *Orm) Update(ctx context.Context, ptr interface{}) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	return db.Save(ptr).Error
 }
 
 func (o *Orm) Delete(ctx context.Context, ptr interface{}) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	return db.Delete(ptr).Error
 }
 
 func (o *Orm) DeleteCondition(ctx context.Context, ptr interface{}, filters ...interface{}) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	if len(filters) > 0 {
 		return db.Where(filters[0], filters[1:]...).Delete(ptr).Error
 	}
@@ -487,20 +401,39 @@ func (o *Orm) DeleteCondition(ctx context.Context, ptr interface{}, filters ...i
 }
 
 func (o *Orm) GroupBy(ctx context.Context, tableName string, selectColumns string, whereClause interface{}, results interface{}, groupColumns string) error {
-	db := o.db.WithContext(ctx)
+	db := o.WithContext(ctx)
 	return db.Table(tableName).Select(selectColumns).Where(whereClause).Group(groupColumns).Find(results).Error
 }
 
+// Top 分组后按 sortColumn 排序取前 limitCount 条，排序参数的校验同 Page，不合法时返回 ErrInvalidSort
 func (o *Orm) Top(ctx context.Context, tableName string, selectColumns string, whereClause interface{}, groupColumn string, sortColumn string, sortSc string, limitCount int, results interface{}) error {
-	db := o.db.WithContext(ctx)
-	sortBy := sortColumn + " " + sortSc
-	return db.Table(tableName).Select(selectColumns).Where(whereClause).Group(groupColumn).Order(sortBy).Limit(limitCount).Find(results).Error
+	order, err := orderByColumn(sortColumn, sortSc)
+	if err != nil {
+		return err
+	}
+	db := o.WithContext(ctx).Table(tableName).Select(selectColumns).Where(whereClause).Group(groupColumn)
+	if order != nil {
+		db = db.Order(*order)
+	}
+	return db.Limit(limitCount).Find(results).Error
 }
 
+// Exec 执行原生 SQL，不绑定上下文，也不会加入上下文中的事务；请使用 ExecContext
 func (o *Orm) Exec(sql string, value ...interface{}) error {
 	return o.db.Exec(sql, value...).Error
 }
 
+// ExecContext 在 ctx 上执行原生 SQL，上下文中有事务时加入该事务
+func (o *Orm) ExecContext(ctx context.Context, sql string, value ...interface{}) error {
+	return o.WithContext(ctx).Exec(sql, value...).Error
+}
+
+// Raw 执行原生查询并扫描到 result，不绑定上下文，也不会加入上下文中的事务；请使用 RawContext
 func (o *Orm) Raw(sql string, result interface{}, value ...interface{}) error {
 	return o.db.Raw(sql, value...).Scan(result).Error
 }
+
+// RawContext 在 ctx 上执行原生查询并扫描到 result，上下文中有事务时加入该事务
+func (o *Orm) RawContext(ctx context.Context, sql string, result interface{}, value ...interface{}) error {
+	return o.WithContext(ctx).Raw(sql, value...).Scan(result).Error
+}
@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/orm/dbconfig"
+	"github.com/goodbye-jack/go-common/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUnsupportedMode 不支持的 Redis 运行模式
+var ErrUnsupportedMode = errors.New("unsupported redis mode")
+
+// firstDuration 返回第一个大于 0 的时长
+func firstDuration(values ...time.Duration) time.Duration {
+	for _, value := range values {
+		if value > 0 {
+			return value
+		}
+	}
+	return 0
+}
+
+// UniversalOptions 由配置生成 go-redis 通用选项；单点模式下自定义 DSN 优先
+func UniversalOptions(cfg *Config) (*redis.UniversalOptions, error) {
+	if cfg == nil {
+		return nil, errors.New("redis config is nil")
+	}
+	mode := cfg.Mode
+	if mode == "" {
+		mode = utils.DBModeSingle
+	}
+	dbIndex := cfg.DB
+	if dbIndex == 0 {
+		dbIndex = cfg.DBIndex
+	}
+	if dbIndex == 0 && cfg.Database != "" {
+		fmt.Sscanf(cfg.Database, "%d", &dbIndex)
+	}
+	opts := &redis.UniversalOptions{
+		Username:     cfg.User,
+		Password:     cfg.Password,
+		DB:           dbIndex,
+		DialTimeout:  firstDuration(cfg.DialTimeout, cfg.ConnectTimeout, utils.DefaultRedisConnectTimeout),
+		ReadTimeout:  firstDuration(cfg.ReadTimeout, cfg.Config.ReadTimeout, utils.DefaultRedisReadTimeout),
+		WriteTimeout: firstDuration(cfg.WriteTimeout, utils.DefaultRedisWriteTimeout),
+		PoolSize:     cfg.MaxPoolSize,
+		MinIdleConns: cfg.MinPoolSize,
+	}
+	switch mode {
+	case utils.DBModeSingle:
+		if dsn := strings.TrimSpace(cfg.DSN); strings.HasPrefix(dsn, "redis://") || strings.HasPrefix(dsn, "rediss://") {
+			parsed, err := redis.ParseURL(dsn)
+			if err != nil {
+				return nil, fmt.Errorf("parse redis dsn failed: %w", err)
+			}
+			opts.Addrs = []string{parsed.Addr}
+			opts.Username, opts.Password, opts.DB = parsed.Username, parsed.Password, parsed.DB
+			opts.TLSConfig = parsed.TLSConfig
+			opts.DialTimeout = firstDuration(parsed.DialTimeout, opts.DialTimeout)
+			opts.ReadTimeout = firstDuration(parsed.ReadTimeout, opts.ReadTimeout)
+			opts.WriteTimeout = firstDuration(parsed.WriteTimeout, opts.WriteTimeout)
+			return opts, nil
+		}
+		opts.Addrs = cfg.RedisAddrs()
+		if len(opts.Addrs) > 1 {
+			opts.Addrs = opts.Addrs[:1]
+		}
+	case utils.DBModeSentinel:
+		if strings.TrimSpace(cfg.MasterName) == "" {
+			return nil, errors.New("redis sentinel mode requires master_name")
+		}
+		opts.Addrs = cfg.RedisAddrs()
+		opts.MasterName = cfg.MasterName
+		opts.SentinelPassword = cfg.SentinelPassword
+	case utils.DBModeCluster:
+		// 集群不支持 SELECT，DB 固定为 0
+		opts.Addrs = cfg.RedisAddrs()
+		opts.DB = 0
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMode, mode)
+	}
+	if len(opts.Addrs) == 0 {
+		return nil, fmt.Errorf("redis %s mode requires nodes or host", mode)
+	}
+	return opts, nil
+}
+
+// newUniversalClient 按模式显式创建客户端，避免只配置一个种子节点的集群被当作单点
+func newUniversalClient(mode utils.DBMode, opts *redis.UniversalOptions) redis.UniversalClient {
+	switch mode {
+	case utils.DBModeCluster:
+		return redis.NewClusterClient(opts.Cluster())
+	case utils.DBModeSentinel:
+		return redis.NewFailoverClient(opts.Failover())
+	}
+	return redis.NewClient(opts.Simple())
+}
+
+// Open 按配置创建 Redis 客户端(单点/哨兵/集群)，配置错误或 Ping 失败时返回错误
+func Open(cfg *Config) (*Redis, error) {
+	opts, err := UniversalOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mode := cfg.Mode
+	if mode == "" {
+		mode = utils.DBModeSingle
+	}
+	client := newUniversalClient(mode, opts)
+	ctx, cancel := context.WithTimeout(context.Background(), opts.DialTimeout)
+	defer cancel()
+	log.Infof("Connecting redis, mode=%s, addrs=%v, db=%d", mode, opts.Addrs, opts.DB)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("redis ping failed, mode=%s, addrs=%v: %w", mode, opts.Addrs, err)
+	}
+	log.Infof("Redis initialized, mode=%s, addrs=%v, db=%d", mode, opts.Addrs, opts.DB)
+	return &Redis{
+		client: client,
+		config: cfg,
+		ctx:    context.Background(),
+	}, nil
+}
+
+// NewRedisFromClient 包装已有客户端(如测试或自定义连接)，不做 Ping
+func NewRedisFromClient(client redis.UniversalClient, cfg *Config) *Redis {
+	if cfg == nil {
+		cfg = &Config{Config: dbconfig.Config{DBType: DBType, Mode: utils.DBModeSingle}}
+	}
+	return &Redis{client: client, config: cfg, ctx: context.Background()}
+}
@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/goodbye-jack/go-common/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// 订阅断线重连的退避区间
+const (
+	subscribeMinBackoff = 500 * time.Millisecond
+	subscribeMaxBackoff = 30 * time.Second
+)
+
+// MessageHandler 订阅消息处理函数，在订阅协程中串行调用
+type MessageHandler func(msg *redis.Message)
+
+// Subscription 后台订阅，连接断开后按指数退避自动重新订阅，Close 后停止
+type Subscription struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Close 停止订阅并等待后台协程退出
+func (s *Subscription) Close() {
+	if s == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+// Subscribe 订阅频道，首次订阅失败时返回错误；之后的断线由后台协程重连
+func (r *Redis) Subscribe(ctx context.Context, handler MessageHandler, channels ...string) (*Subscription, error) {
+	return r.subscribe(ctx, handler, false, channels)
+}
+
+// PSubscribe 按模式订阅频道，行为同 Subscribe
+func (r *Redis) PSubscribe(ctx context.Context, handler MessageHandler, patterns ...string) (*Subscription, error) {
+	return r.subscribe(ctx, handler, true, patterns)
+}
+
+func (r *Redis) subscribe(ctx context.Context, handler MessageHandler, pattern bool, channels []string) (*Subscription, error) {
+	if handler == nil || len(channels) == 0 {
+		return nil, errors.New("redis subscribe requires handler and channels")
+	}
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	open := func(ctx context.Context) (*redis.PubSub, error) {
+		var pubsub *redis.PubSub
+		if pattern {
+			pubsub = r.client.PSubscribe(ctx, channels...)
+		} else {
+			pubsub = r.client.Subscribe(ctx, channels...)
+		}
+		// 等待订阅确认，确保连接可用
+		if _, err := pubsub.Receive(ctx); err != nil {
+			_ = pubsub.Close()
+			return nil, err
+		}
+		return pubsub, nil
+	}
+	pubsub, err := open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &Subscription{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(sub.done)
+		backoff := subscribeMinBackoff
+		for {
+			if pubsub == nil {
+				if pubsub, err = open(ctx); err != nil {
+					if ctx.Err() != nil {
+						return
+					}
+					log.Warnf("Redis重新订阅失败，%s后重试，channels=%v, err=%v", backoff, channels, err)
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(backoff):
+					}
+					backoff = min(backoff*2, subscribeMaxBackoff)
+					continue
+				}
+				log.Infof("Redis重新订阅成功，channels=%v", channels)
+				backoff = subscribeMinBackoff
+			}
+			msg, err := pubsub.ReceiveMessage(ctx)
+			if err != nil {
+				_ = pubsub.Close()
+				pubsub = nil
+				if ctx.Err() != nil {
+					return
+				}
+				log.Warnf("Redis订阅连接中断，准备重连，channels=%v, err=%v", channels, err)
+				continue
+			}
+			handler(msg)
+		}
+	}()
+	return sub, nil
+}
@@ -2,23 +2,25 @@ package redis
 
 import (
 	"context"
-	"fmt"
+	"sync"
+	"time"
+
 	"github.com/goodbye-jack/go-common/log"
 	"github.com/goodbye-jack/go-common/orm/dbconfig"
 	"github.com/goodbye-jack/go-common/utils"
 	"github.com/redis/go-redis/v9"
-	"time"
 )
 
-// Redis Redis客户端封装（对齐ORM结构）
+// Redis Redis客户端封装（对齐ORM结构），底层为通用客户端，兼容单点/哨兵/集群
 type Redis struct {
-	client *redis.Client
-	config *Config
-	ctx    context.Context
+	client  redis.UniversalClient
+	config  *Config
+	ctx     context.Context
+	scripts sync.Map // 脚本源码 -> *redis.Script，缓存 SHA
 }
 
-// Client 暴露内部的redis.Client实例，供外部包提取配置
-func (r *Redis) Client() *redis.Client {
+// Client 暴露内部的通用客户端实例，供外部包直接调用底层API
+func (r *Redis) Client() redis.UniversalClient {
 	return r.client
 }
 
@@ -27,10 +29,11 @@ func (r *Redis) GetConfig() *Config {
 	return r.config
 }
 
-// NewRedis 初始化Redis客户端（核心修复：复用通用GenDSN，删除重复逻辑）
+// NewRedis 初始化Redis客户端，失败时记录日志并返回nil；需要错误信息时使用 Open
 func NewRedis(dsn string, dbType utils.DBType, timeout int, cfgFromYaml ...*dbconfig.Config) *Redis {
 	if dbType != DBType {
-		panic(fmt.Sprintf("unsupported db type: %s, expected: %s", dbType, DBType))
+		log.Errorf("unsupported db type: %s, expected: %s", dbType, DBType)
+		return nil
 	}
 	cfg := &Config{
 		Config: dbconfig.Config{
@@ -40,40 +43,15 @@ func NewRedis(dsn string, dbType utils.DBType, timeout int, cfgFromYaml ...*dbco
 		},
 	}
 	// 覆盖为yaml解析的原始配置（优先级最高）
-	if len(cfgFromYaml) > 0 {
-		cfg.Config = *cfgFromYaml[0] // 直接替换，持有Host/Port/Password等所有参数
-	}
-	// 把生成的DSN赋值回Config，方便后续查看
-	cfg.DSN = cfg.GenDSN()
-	// ========== 核心4：解析DSN并初始化客户端（容错逻辑） ==========
-	opt, err := redis.ParseURL(cfg.GenDSN())
-	if err != nil { // 解析失败时手动构建（兜底，兼容genRedisDSN的复杂格式）
-		log.Warnf("Redis DSN parse failed, fallback to manual options, dsn=%s, err=%v", cfg.GenDSN(), err)
-		var dbIndex int
-		fmt.Sscanf(cfg.Database, "%d", &dbIndex)
-		opt = &redis.Options{
-			Addr:         fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
-			Password:     cfg.Password,
-			DB:           dbIndex,
-			DialTimeout:  cfg.ConnectTimeout,
-			ReadTimeout:  cfg.ReadTimeout,
-			WriteTimeout: cfg.WriteTimeout,
-		}
-	}
-	client := redis.NewClient(opt) // 初始化客户端
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
-	defer cancel()
-	log.Infof("Connecting redis, addr=%s, db=%d, timeout=%ds", opt.Addr, opt.DB, timeout)
-	_, err = client.Ping(ctx).Result()
-	if err != nil {
-		panic(fmt.Sprintf("Redis Ping失败：%v | 最终DSN：%s | 连接地址：%s", err, cfg.GenDSN(), opt.Addr))
+	if len(cfgFromYaml) > 0 && cfgFromYaml[0] != nil {
+		cfg.Config = *cfgFromYaml[0]
 	}
-	log.Infof("Redis initialized, addr=%s, db=%d", opt.Addr, opt.DB)
-	return &Redis{
-		client: client,
-		config: cfg,
-		ctx:    context.Background(),
+	r, err := Open(cfg)
+	if err != nil {
+		log.Errorf("Redis初始化失败：%v", err)
+		return nil
 	}
+	return r
 }
 
 // Close 关闭连接 以下保留你的原有方法，API与官方go-redis/v9完全兼容，无需修改
@@ -205,6 +183,6 @@ func (r *Redis) Ping(ctx context.Context) error {
 }
 
 // GetClient 获取原始客户端（兼容底层API）
-func (r *Redis) GetClient() *redis.Client {
+func (r *Redis) GetClient() redis.UniversalClient {
 	return r.client
 }
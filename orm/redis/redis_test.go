@@ -0,0 +1,154 @@
+package redis
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goodbye-jack/go-common/orm/dbconfig"
+	"github.com/goodbye-jack/go-common/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestUniversalOptionsByMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        dbconfig.Config
+		wantAddrs  string
+		wantMaster string
+		wantDB     int
+	}{
+		{
+			name:      "single",
+			cfg:       dbconfig.Config{Mode: utils.DBModeSingle, Host: "127.0.0.1", Port: 6380, Database: "2"},
+			wantAddrs: "127.0.0.1:6380",
+			wantDB:    2,
+		},
+		{
+			name:      "single dsn",
+			cfg:       dbconfig.Config{Mode: utils.DBModeSingle, DSN: "redis://:secret@10.0.0.9:6379/3?dial_timeout=2s"},
+			wantAddrs: "10.0.0.9:6379",
+			wantDB:    3,
+		},
+		{
+			name:       "sentinel",
+			cfg:        dbconfig.Config{Mode: utils.DBModeSentinel, Nodes: []string{"10.0.0.1:26379", "10.0.0.2"}, Port: 26379, MasterName: "mymaster", DBIndex: 1},
+			wantAddrs:  "10.0.0.1:26379 10.0.0.2:26379",
+			wantMaster: "mymaster",
+			wantDB:     1,
+		},
+		{
+			name:      "cluster ignores db",
+			cfg:       dbconfig.Config{Mode: utils.DBModeCluster, Host: "10.0.0.1:7000,10.0.0.2:7001", Database: "5"},
+			wantAddrs: "10.0.0.1:7000 10.0.0.2:7001",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := UniversalOptions(&Config{Config: tt.cfg})
+			if err != nil {
+				t.Fatalf("UniversalOptions() error = %v", err)
+			}
+			if got := strings.Join(opts.Addrs, " "); got != tt.wantAddrs {
+				t.Fatalf("Addrs = %q, want %q", got, tt.wantAddrs)
+			}
+			if opts.MasterName != tt.wantMaster || opts.DB != tt.wantDB {
+				t.Fatalf("MasterName = %q, DB = %d", opts.MasterName, opts.DB)
+			}
+		})
+	}
+
+	if _, err := UniversalOptions(&Config{Config: dbconfig.Config{Mode: utils.DBModeSentinel, Host: "10.0.0.1"}}); err == nil {
+		t.Fatalf("sentinel without master name should fail")
+	}
+	if _, err := UniversalOptions(&Config{Config: dbconfig.Config{Mode: "ring", Host: "10.0.0.1"}}); err == nil {
+		t.Fatalf("unknown mode should fail")
+	}
+}
+
+func TestOpenReturnsErrorInsteadOfPanic(t *testing.T) {
+	cfg := &Config{Config: dbconfig.Config{DBType: DBType, Mode: utils.DBModeSingle, Host: "127.0.0.1", Port: 1, ConnectTimeout: 200 * time.Millisecond}}
+	if r, err := Open(cfg); err == nil || r != nil {
+		t.Fatalf("Open() = %v, %v; want error", r, err)
+	}
+	if r := NewRedis("", DBType, 1, &cfg.Config); r != nil {
+		t.Fatalf("NewRedis() = %v, want nil", r)
+	}
+}
+
+func TestTypedHelpers(t *testing.T) {
+	addr := os.Getenv("GO_COMMON_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("GO_COMMON_TEST_REDIS_ADDR is not configured")
+	}
+	r := NewRedisFromClient(redis.NewClient(&redis.Options{Addr: addr, Password: os.Getenv("GO_COMMON_TEST_REDIS_PASSWORD")}), nil)
+	defer r.Close()
+	ctx := context.Background()
+	prefix := "go-common:test:" + time.Now().Format("150405.000000") + ":"
+	defer r.Del(ctx, prefix+"json", prefix+"nx", prefix+"zset", prefix+"counter")
+
+	type payload struct {
+		Name string `json:"name"`
+	}
+	if err := SetJSON(ctx, r, prefix+"json", payload{Name: "alice"}, time.Minute); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	got, found, err := GetJSON[payload](ctx, r, prefix+"json")
+	if err != nil || !found || got.Name != "alice" {
+		t.Fatalf("GetJSON() = %+v, %v, %v", got, found, err)
+	}
+	if _, found, err := GetJSON[payload](ctx, r, prefix+"missing"); err != nil || found {
+		t.Fatalf("GetJSON(missing) found = %v, err = %v", found, err)
+	}
+
+	if ok, err := r.SetNX(ctx, prefix+"nx", "1", time.Minute); err != nil || !ok {
+		t.Fatalf("first SetNX() = %v, %v", ok, err)
+	}
+	if ok, _ := r.SetNX(ctx, prefix+"nx", "2", time.Minute); ok {
+		t.Fatalf("second SetNX() should not overwrite")
+	}
+
+	if _, err := r.ZAdd(ctx, prefix+"zset", redis.Z{Score: 2, Member: "b"}, redis.Z{Score: 1, Member: "a"}); err != nil {
+		t.Fatalf("ZAdd() error = %v", err)
+	}
+	members, err := r.ZRangeByScore(ctx, prefix+"zset", "-inf", "+inf", 0, 0)
+	if err != nil || len(members) != 2 || members[0].Member != "a" {
+		t.Fatalf("ZRangeByScore() = %+v, %v", members, err)
+	}
+
+	const incr = "return redis.call('INCRBY', KEYS[1], ARGV[1])"
+	for want := int64(2); want <= 4; want += 2 {
+		if n, err := r.Eval(ctx, incr, []string{prefix + "counter"}, 2).Int64(); err != nil || n != want {
+			t.Fatalf("Eval() = %d, %v; want %d", n, err, want)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	sub, err := r.Subscribe(ctx, func(msg *redis.Message) {
+		mu.Lock()
+		received = append(received, msg.Payload)
+		mu.Unlock()
+	}, prefix+"channel")
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer sub.Close()
+	r.Client().Publish(ctx, prefix+"channel", "hello")
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("subscription received nothing")
+}
@@ -0,0 +1,34 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// script 返回缓存的脚本对象，同一源码只计算一次 SHA
+func (r *Redis) script(src string) *redis.Script {
+	if cached, ok := r.scripts.Load(src); ok {
+		return cached.(*redis.Script)
+	}
+	cached, _ := r.scripts.LoadOrStore(src, redis.NewScript(src))
+	return cached.(*redis.Script)
+}
+
+// Eval 执行 Lua 脚本：优先 EVALSHA，服务端未缓存(NOSCRIPT)时回退 EVAL 并由服务端缓存
+//
+//	n, err := r.Eval(ctx, "return redis.call('INCRBY', KEYS[1], ARGV[1])", []string{"counter"}, 2).Int64()
+func (r *Redis) Eval(ctx context.Context, src string, keys []string, args ...interface{}) *redis.Cmd {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	return r.script(src).Run(ctx, r.client, keys, args...)
+}
+
+// ScriptLoad 预加载脚本并返回 SHA，集群模式下加载到所有主节点
+func (r *Redis) ScriptLoad(ctx context.Context, src string) (string, error) {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	return r.script(src).Load(ctx, r.client).Result()
+}
@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Nil 键不存在时 go-redis 返回的错误，可用 errors.Is 判断
+const Nil = redis.Nil
+
+// SetJSON 将 value 序列化为 JSON 写入，ttl 为 0 表示不过期
+func SetJSON(ctx context.Context, r *Redis, key string, value interface{}, ttl time.Duration) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return r.Set(ctx, key, payload, ttl)
+}
+
+// GetJSON 读取 JSON 并反序列化为 T，键不存在时 found 为 false 且不返回错误
+func GetJSON[T any](ctx context.Context, r *Redis, key string) (value T, found bool, err error) {
+	raw, err := r.Get(ctx, key)
+	if errors.Is(err, redis.Nil) {
+		return value, false, nil
+	}
+	if err != nil {
+		return value, false, err
+	}
+	if err = json.Unmarshal([]byte(raw), &value); err != nil {
+		return value, false, err
+	}
+	return value, true, nil
+}
+
+// SetNX 键不存在时写入并设置过期时间，返回是否写入成功
+func (r *Redis) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+// ZAdd 有序集合添加成员
+func (r *Redis) ZAdd(ctx context.Context, key string, members ...redis.Z) (int64, error) {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	return r.client.ZAdd(ctx, key, members...).Result()
+}
+
+// ZRem 有序集合删除成员
+func (r *Redis) ZRem(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	return r.client.ZRem(ctx, key, members...).Result()
+}
+
+// ZScore 获取成员分数
+func (r *Redis) ZScore(ctx context.Context, key, member string) (float64, error) {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	return r.client.ZScore(ctx, key, member).Result()
+}
+
+// ZCard 有序集合成员数
+func (r *Redis) ZCard(ctx context.Context, key string) (int64, error) {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	return r.client.ZCard(ctx, key).Result()
+}
+
+// ZRangeByScore 按分数升序分页获取成员(含分数)，min/max 支持 "-inf"、"(1" 等写法，count 为 0 时不分页
+func (r *Redis) ZRangeByScore(ctx context.Context, key, min, max string, offset, count int64) ([]redis.Z, error) {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	return r.client.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: min, Max: max, Offset: offset, Count: count}).Result()
+}
+
+// ZRevRange 按分数降序获取排名区间内的成员
+func (r *Redis) ZRevRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	return r.client.ZRevRange(ctx, key, start, stop).Result()
+}
+
+// Pipeline 批量发送命令(非事务)，返回各命令结果；任一命令失败时返回第一个错误
+func (r *Redis) Pipeline(ctx context.Context, fn func(pipe redis.Pipeliner) error) ([]redis.Cmder, error) {
+	if ctx == nil {
+		ctx = r.ctx
+	}
+	return r.client.Pipelined(ctx, fn)
+}
@@ -0,0 +1,312 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	replicaCallbackName = "go-common:read_replica"
+	replicaInstanceKey  = "go-common:replica"
+)
+
+type usePrimaryKey struct{}
+
+// UsePrimary 标记上下文中的查询强制走主库，用于写后立即读（read-your-writes）的场景：
+// orm.DB.First(orm.UsePrimary(ctx), &order, id)
+func UsePrimary(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, usePrimaryKey{}, true)
+}
+
+func isPrimaryContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	primary, _ := ctx.Value(usePrimaryKey{}).(bool)
+	return primary
+}
+
+// ReplicaOptions 读写分离选项
+type ReplicaOptions struct {
+	Strategy      utils.ReadStrategy // 副本选择策略，默认轮询
+	CheckInterval time.Duration      // 健康检查间隔，默认10秒
+	MaxFailures   int                // 连续失败多少次后剔除副本，默认3次
+}
+
+// Replica 只读副本，DB 通常由 NewOrm 以副本DSN创建，方言需与主库一致
+type Replica struct {
+	Name string
+	DB   *Orm
+}
+
+// ReplicaStatus 只读副本的健康状态
+type ReplicaStatus struct {
+	Name     string        `json:"name"`
+	Healthy  bool          `json:"healthy"`
+	Failures int           `json:"failures"`
+	Latency  time.Duration `json:"latency"`
+}
+
+type replicaNode struct {
+	name     string
+	pool     *sql.DB
+	healthy  atomic.Bool
+	failures atomic.Int32
+	latency  atomic.Int64
+}
+
+// markFailure 记录一次失败，连续失败达到阈值后剔除
+func (n *replicaNode) markFailure(maxFailures int, err error) {
+	if int(n.failures.Add(1)) >= maxFailures && n.healthy.CompareAndSwap(true, false) {
+		log.Warnf("【读写分离】只读副本[%s]连续%d次不可用，已剔除：%v", n.name, maxFailures, err)
+	}
+}
+
+// markHealthy 记录一次成功探测，延迟按指数加权平均平滑
+func (n *replicaNode) markHealthy(latency time.Duration) {
+	n.failures.Store(0)
+	if old := n.latency.Load(); old > 0 {
+		latency = time.Duration((old*7 + int64(latency)*3) / 10)
+	}
+	n.latency.Store(int64(latency))
+	if n.healthy.CompareAndSwap(false, true) {
+		log.Infof("【读写分离】只读副本[%s]已恢复，重新加入读负载", n.name)
+	}
+}
+
+type replicaSet struct {
+	nodes  []*replicaNode
+	opts   ReplicaOptions
+	next   atomic.Uint64
+	cancel context.CancelFunc
+}
+
+// pick 按策略从健康副本中选择一个，没有健康副本时返回nil（回退主库）
+func (s *replicaSet) pick() *replicaNode {
+	healthy := make([]*replicaNode, 0, len(s.nodes))
+	for _, node := range s.nodes {
+		if node.healthy.Load() {
+			healthy = append(healthy, node)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	switch s.opts.Strategy {
+	case utils.ReadStrategyRandom:
+		return healthy[rand.IntN(len(healthy))]
+	case utils.ReadStrategyLeastLatency:
+		best := healthy[0]
+		for _, node := range healthy[1:] {
+			if node.latency.Load() < best.latency.Load() {
+				best = node
+			}
+		}
+		return best
+	default:
+		return healthy[(s.next.Add(1)-1)%uint64(len(healthy))]
+	}
+}
+
+// probe Ping所有副本；initial 为true时首次探测失败直接剔除
+func (s *replicaSet) probe(ctx context.Context, initial bool) {
+	for _, node := range s.nodes {
+		pingCtx, cancel := context.WithTimeout(ctx, s.opts.CheckInterval)
+		start := time.Now()
+		err := node.pool.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			node.markHealthy(time.Since(start))
+			continue
+		}
+		if initial {
+			node.failures.Store(int32(s.opts.MaxFailures))
+			node.healthy.Store(false)
+			log.Warnf("【读写分离】只读副本[%s]初始化探测失败，暂不参与读负载：%v", node.name, err)
+			continue
+		}
+		node.markFailure(s.opts.MaxFailures, err)
+	}
+}
+
+func (s *replicaSet) watch(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.probe(ctx, false)
+		}
+	}
+}
+
+// replicaRouter 挂在主库 gorm.DB 的查询回调上，按需把读请求切换到副本连接
+type replicaRouter struct {
+	set atomic.Pointer[replicaSet]
+}
+
+func (r *replicaRouter) route(db *gorm.DB) {
+	set := r.set.Load()
+	if set == nil || db.Error != nil || db.Statement == nil {
+		return
+	}
+	// 事务、显式主库、加锁读、序列取值以及非查询的原生SQL均走主库
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return
+	}
+	if isPrimaryContext(db.Statement.Context) {
+		return
+	}
+	if _, locking := db.Statement.Clauses["FOR"]; locking {
+		return
+	}
+	if db.Statement.SQL.Len() > 0 && !isReadSQL(db.Statement.SQL.String()) {
+		return
+	}
+	for _, sel := range db.Statement.Selects {
+		if primaryOnlySQL.MatchString(sel) {
+			return
+		}
+	}
+	node := set.pick()
+	if node == nil {
+		return
+	}
+	db.Statement.ConnPool = node.pool
+	db.InstanceSet(replicaInstanceKey, node)
+}
+
+// observe 查询出现连接类错误时计入副本失败次数，加速剔除
+func (r *replicaRouter) observe(db *gorm.DB) {
+	set := r.set.Load()
+	if set == nil || db.Error == nil {
+		return
+	}
+	value, ok := db.InstanceGet(replicaInstanceKey)
+	if !ok {
+		return
+	}
+	if node, ok := value.(*replicaNode); ok && isConnectionError(db.Error) {
+		node.markFailure(set.opts.MaxFailures, db.Error)
+	}
+}
+
+// primaryOnlySQL 必须在主库执行的查询片段：加锁读(FOR UPDATE/FOR SHARE、LOCK IN SHARE MODE、
+// SQL Server 锁提示)与序列取值(nextval/currval、NEXT VALUE FOR、Oracle/达梦 seq.NEXTVAL)
+var primaryOnlySQL = regexp.MustCompile(`(?i)\bFOR\s+(NO\s+KEY\s+UPDATE|UPDATE|KEY\s+SHARE|SHARE)\b|\bLOCK\s+IN\s+SHARE\s+MODE\b|\b(UPDLOCK|XLOCK|HOLDLOCK)\b|\b(NEXTVAL|CURRVAL|SETVAL|LASTVAL)\b|\bNEXT\s+VALUE\s+FOR\b`)
+
+// isReadSQL 原生SQL是否可以在副本执行：以 SELECT 开头且不含加锁读与序列取值
+func isReadSQL(sql string) bool {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], "SELECT") {
+		return false
+	}
+	return !primaryOnlySQL.MatchString(sql)
+}
+
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr)
+}
+
+// SetReplicas 为当前实例配置只读副本：普通查询按策略分发到健康副本，
+// 写操作、事务、UsePrimary 上下文与加锁查询仍走主库；副本由后台健康检查自动剔除与恢复。
+// 重复调用会替换之前的副本集合，不传副本则关闭读写分离。
+func (o *Orm) SetReplicas(opts ReplicaOptions, replicas ...Replica) error {
+	if o == nil || o.db == nil {
+		return errors.New("orm实例未初始化")
+	}
+	if opts.Strategy == "" {
+		opts.Strategy = utils.ReadStrategyRoundRobin
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = utils.DefaultReplicaCheckInterval
+	}
+	if opts.MaxFailures <= 0 {
+		opts.MaxFailures = utils.DefaultReplicaMaxFailures
+	}
+	set := &replicaSet{opts: opts}
+	for i, replica := range replicas {
+		if replica.DB == nil || replica.DB.db == nil {
+			return fmt.Errorf("只读副本[%d]未初始化", i)
+		}
+		pool, err := replica.DB.DB()
+		if err != nil {
+			return fmt.Errorf("只读副本[%s]获取SQL DB失败：%w", replica.Name, err)
+		}
+		name := replica.Name
+		if name == "" {
+			name = fmt.Sprintf("replica-%d", i)
+		}
+		node := &replicaNode{name: name, pool: pool}
+		node.healthy.Store(true)
+		set.nodes = append(set.nodes, node)
+	}
+	if o.router == nil {
+		router := &replicaRouter{}
+		if err := o.db.Callback().Query().Before("gorm:query").Register(replicaCallbackName, router.route); err != nil {
+			return err
+		}
+		if err := o.db.Callback().Row().Before("gorm:row").Register(replicaCallbackName, router.route); err != nil {
+			return err
+		}
+		if err := o.db.Callback().Query().After("gorm:query").Register(replicaCallbackName+"_observe", router.observe); err != nil {
+			return err
+		}
+		if err := o.db.Callback().Row().After("gorm:row").Register(replicaCallbackName+"_observe", router.observe); err != nil {
+			return err
+		}
+		o.router = router
+	}
+	if len(set.nodes) == 0 {
+		set = nil
+	} else {
+		set.probe(context.Background(), true)
+		ctx, cancel := context.WithCancel(context.Background())
+		set.cancel = cancel
+		go set.watch(ctx)
+	}
+	if old := o.router.set.Swap(set); old != nil {
+		old.cancel()
+	}
+	return nil
+}
+
+// ReplicaStatus 返回只读副本的健康状态，未启用读写分离时为空
+func (o *Orm) ReplicaStatus() []ReplicaStatus {
+	if o == nil || o.router == nil {
+		return nil
+	}
+	set := o.router.set.Load()
+	if set == nil {
+		return nil
+	}
+	result := make([]ReplicaStatus, 0, len(set.nodes))
+	for _, node := range set.nodes {
+		result = append(result, ReplicaStatus{
+			Name:     node.name,
+			Healthy:  node.healthy.Load(),
+			Failures: int(node.failures.Load()),
+			Latency:  time.Duration(node.latency.Load()),
+		})
+	}
+	return result
+}
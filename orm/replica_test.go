@@ -0,0 +1,155 @@
+package orm
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goodbye-jack/go-common/utils"
+	"gorm.io/gorm"
+)
+
+func newSQLiteTester(t *testing.T, name, value string) *Orm {
+	t.Helper()
+	db := NewOrm(filepath.Join(t.TempDir(), name+".db"), utils.DBTypeSQLite, 5)
+	db.AutoMigrate(&Tester{})
+	if err := db.Create(context.Background(), &Tester{Name: value}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return db
+}
+
+func readName(t *testing.T, db *Orm, ctx context.Context) string {
+	t.Helper()
+	var tester Tester
+	if err := db.First(ctx, &tester); err != nil {
+		t.Fatalf("First() error = %v", err)
+	}
+	return tester.Name
+}
+
+func TestReplicaRouting(t *testing.T) {
+	ctx := context.Background()
+	primary := newSQLiteTester(t, "primary", "primary")
+	r1 := newSQLiteTester(t, "r1", "r1")
+	r2 := newSQLiteTester(t, "r2", "r2")
+	if err := primary.SetReplicas(ReplicaOptions{CheckInterval: time.Hour}, Replica{Name: "r1", DB: r1}, Replica{Name: "r2", DB: r2}); err != nil {
+		t.Fatalf("SetReplicas() error = %v", err)
+	}
+
+	if got := readName(t, primary, ctx) + "," + readName(t, primary, ctx) + "," + readName(t, primary, ctx); got != "r1,r2,r1" {
+		t.Fatalf("round robin reads = %s", got)
+	}
+	if got := readName(t, primary, UsePrimary(ctx)); got != "primary" {
+		t.Fatalf("UsePrimary read = %s", got)
+	}
+	var raw string
+	if err := primary.GetDB().Raw("SELECT name FROM testers LIMIT 1").Scan(&raw).Error; err != nil || raw == "primary" {
+		t.Fatalf("raw select should use replica, got %q %v", raw, err)
+	}
+
+	if err := primary.Create(ctx, &Tester{Name: "written"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	var count int64
+	primary.GetDB().WithContext(UsePrimary(ctx)).Model(&Tester{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("writes should go to primary, count = %d", count)
+	}
+	err := primary.GetDB().Transaction(func(tx *gorm.DB) error {
+		var tester Tester
+		if err := tx.Last(&tester).Error; err != nil {
+			return err
+		}
+		if tester.Name != "written" {
+			t.Errorf("transaction read = %s", tester.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction() error = %v", err)
+	}
+}
+
+func TestReplicaEjection(t *testing.T) {
+	ctx := context.Background()
+	primary := newSQLiteTester(t, "primary", "primary")
+	r1 := newSQLiteTester(t, "r1", "r1")
+	r2 := newSQLiteTester(t, "r2", "r2")
+	if err := primary.SetReplicas(ReplicaOptions{Strategy: utils.ReadStrategyLeastLatency, CheckInterval: time.Hour, MaxFailures: 2},
+		Replica{Name: "r1", DB: r1}, Replica{Name: "r2", DB: r2}); err != nil {
+		t.Fatalf("SetReplicas() error = %v", err)
+	}
+	set := primary.router.set.Load()
+	set.nodes[0].latency.Store(int64(time.Second))
+	set.nodes[1].latency.Store(int64(time.Millisecond))
+	if got := readName(t, primary, ctx); got != "r2" {
+		t.Fatalf("least latency read = %s", got)
+	}
+
+	pool, _ := r2.DB()
+	_ = pool.Close()
+	set.probe(ctx, false)
+	if status := primary.ReplicaStatus(); !status[1].Healthy || status[1].Failures != 1 {
+		t.Fatalf("single failure should not eject: %+v", status)
+	}
+	set.probe(ctx, false)
+	if status := primary.ReplicaStatus(); status[1].Healthy {
+		t.Fatalf("replica should be ejected: %+v", status)
+	}
+	if got := readName(t, primary, ctx); got != "r1" {
+		t.Fatalf("read after ejection = %s", got)
+	}
+
+	pool, _ = r1.DB()
+	_ = pool.Close()
+	set.probe(ctx, false)
+	set.probe(ctx, false)
+	if got := readName(t, primary, ctx); got != "primary" {
+		t.Fatalf("read without healthy replicas = %s", got)
+	}
+
+	if err := primary.SetReplicas(ReplicaOptions{}); err != nil || primary.ReplicaStatus() != nil {
+		t.Fatalf("SetReplicas() without replicas should disable routing: %v", err)
+	}
+}
+
+func TestReplicaPrimaryOnlySQL(t *testing.T) {
+	cases := map[string]bool{
+		"SELECT name FROM testers":                         true,
+		"select name from testers where note = 'for'":      true,
+		"SELECT * FROM orders WHERE id = ? FOR UPDATE":     false,
+		"select * from orders for share":                   false,
+		"SELECT * FROM orders LOCK IN SHARE MODE":          false,
+		"SELECT nextval('order_seq')":                      false,
+		"SELECT order_seq.NEXTVAL FROM dual":               false,
+		"SELECT NEXT VALUE FOR order_seq":                  false,
+		"SELECT * FROM orders WITH (UPDLOCK) WHERE id = 1": false,
+		"UPDATE orders SET status = 'paid'":                false,
+		"WITH t AS (SELECT 1) DELETE FROM orders USING t":  false,
+	}
+	for sql, want := range cases {
+		if got := isReadSQL(sql); got != want {
+			t.Errorf("isReadSQL(%q) = %v, want %v", sql, got, want)
+		}
+	}
+
+	ctx := context.Background()
+	primary := newSQLiteTester(t, "primary", "primary")
+	r1 := newSQLiteTester(t, "r1", "r1")
+	if err := primary.SetReplicas(ReplicaOptions{CheckInterval: time.Hour}, Replica{Name: "r1", DB: r1}); err != nil {
+		t.Fatalf("SetReplicas() error = %v", err)
+	}
+	var raw string
+	if err := primary.GetDB().WithContext(ctx).Raw("SELECT name FROM testers WHERE name <> 'nextval' LIMIT 1").Scan(&raw).Error; err != nil || raw != "primary" {
+		t.Fatalf("raw select with sequence keyword should use primary, got %q %v", raw, err)
+	}
+	var row struct{ Name, Seq string }
+	if err := primary.GetDB().WithContext(ctx).Model(&Tester{}).Select("name, 'nextval' AS seq").Limit(1).Scan(&row).Error; err != nil || row.Name != "primary" {
+		t.Fatalf("select with sequence call should use primary, got %q %v", row.Name, err)
+	}
+	if primary.GetDB().Callback().Row().Get(replicaCallbackName+"_observe") == nil {
+		t.Fatalf("row queries should be observed for replica failures")
+	}
+}
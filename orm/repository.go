@@ -0,0 +1,192 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const defaultRepositoryPageSize = 20
+
+// ErrNotSoftDeletable 模型没有 gorm.DeletedAt 字段（如未嵌入 model.ModelBase）时恢复删除返回该错误
+var ErrNotSoftDeletable = errors.New("model does not support soft delete")
+
+// Repository 基于 Orm 的泛型仓储，T 通常嵌入 model.ModelBase：
+//
+//	var orders = orm.NewRepository[Order](nil) // nil 表示使用全局 orm.DB
+//	err := orm.DB.InTransaction(ctx, func(ctx context.Context) error {
+//		return orders.Create(ctx, &Order{...})
+//	})
+//
+// 所有方法都会加入 ctx 中的事务；模型含 gorm.DeletedAt 时 Delete 为软删除，
+// 查询默认排除已删除记录，WithDeleted 返回包含已删除记录的仓储。
+type Repository[T any] struct {
+	orm         *Orm
+	withDeleted bool
+}
+
+// NewRepository 创建仓储，o 为 nil 时在调用时使用全局 orm.DB
+func NewRepository[T any](o *Orm) *Repository[T] {
+	return &Repository[T]{orm: o}
+}
+
+// Orm 返回仓储使用的 Orm 实例
+func (r *Repository[T]) Orm() *Orm {
+	if r.orm != nil {
+		return r.orm
+	}
+	return DB
+}
+
+// WithDeleted 返回包含软删除记录的仓储副本
+func (r *Repository[T]) WithDeleted() *Repository[T] {
+	return &Repository[T]{orm: r.orm, withDeleted: true}
+}
+
+// DB 返回绑定上下文（及事务）并设置了模型的 *gorm.DB，用于仓储方法之外的复杂查询
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	db := r.Orm().WithContext(ctx).Model(new(T))
+	if r.withDeleted {
+		db = db.Unscoped()
+	}
+	return db
+}
+
+// Transaction 在事务中执行 fn，等同于 Orm().InTransaction
+func (r *Repository[T]) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.Orm().InTransaction(ctx, fn)
+}
+
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	return r.DB(ctx).Create(entity).Error
+}
+
+// CreateInBatches 批量创建，batchSize<=0 时一次写入
+func (r *Repository[T]) CreateInBatches(ctx context.Context, entities []T, batchSize int) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = len(entities)
+	}
+	return r.DB(ctx).CreateInBatches(&entities, batchSize).Error
+}
+
+// Get 按主键查询，不存在时返回 gorm.ErrRecordNotFound
+func (r *Repository[T]) Get(ctx context.Context, id any) (*T, error) {
+	entity := new(T)
+	if err := r.DB(ctx).First(entity, id).Error; err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// First 按条件查询第一条，不存在时返回 gorm.ErrRecordNotFound
+func (r *Repository[T]) First(ctx context.Context, query any, args ...any) (*T, error) {
+	entity := new(T)
+	if err := applyWhere(r.DB(ctx), query, args).First(entity).Error; err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// Find 按条件查询全部，query 为 nil 时不加条件
+func (r *Repository[T]) Find(ctx context.Context, query any, args ...any) ([]T, error) {
+	var entities []T
+	if err := applyWhere(r.DB(ctx), query, args).Find(&entities).Error; err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+func (r *Repository[T]) Count(ctx context.Context, query any, args ...any) (int64, error) {
+	var total int64
+	err := applyWhere(r.DB(ctx), query, args).Count(&total).Error
+	return total, err
+}
+
+// Page 分页查询，返回当前页数据与总数；page 从1开始，order 为空时按主键升序
+func (r *Repository[T]) Page(ctx context.Context, page, pageSize int, order string, query any, args ...any) ([]T, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultRepositoryPageSize
+	}
+	total, err := r.Count(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	entities := []T{}
+	if total == 0 {
+		return entities, 0, nil
+	}
+	db := applyWhere(r.DB(ctx), query, args)
+	if order == "" {
+		order = r.primaryColumn()
+	}
+	db = db.Order(order)
+	if err := db.Limit(pageSize).Offset((page - 1) * pageSize).Find(&entities).Error; err != nil {
+		return nil, 0, err
+	}
+	return entities, total, nil
+}
+
+// Save 保存整条记录（含零值字段），无主键时插入
+func (r *Repository[T]) Save(ctx context.Context, entity *T) error {
+	return r.DB(ctx).Save(entity).Error
+}
+
+// Updates 按主键更新指定列，返回影响行数
+func (r *Repository[T]) Updates(ctx context.Context, id any, values map[string]any) (int64, error) {
+	result := r.DB(ctx).Where(r.primaryCondition(id)).Updates(values)
+	return result.RowsAffected, result.Error
+}
+
+// Delete 按主键删除，模型支持软删除时为软删除，返回影响行数
+func (r *Repository[T]) Delete(ctx context.Context, id any) (int64, error) {
+	result := r.DB(ctx).Delete(new(T), id)
+	return result.RowsAffected, result.Error
+}
+
+// HardDelete 按主键物理删除（包括已软删除的记录）
+func (r *Repository[T]) HardDelete(ctx context.Context, id any) (int64, error) {
+	result := r.DB(ctx).Unscoped().Delete(new(T), id)
+	return result.RowsAffected, result.Error
+}
+
+// Restore 恢复软删除的记录
+func (r *Repository[T]) Restore(ctx context.Context, id any) (int64, error) {
+	db := r.DB(ctx).Unscoped()
+	if err := db.Statement.Parse(new(T)); err != nil {
+		return 0, err
+	}
+	field := db.Statement.Schema.LookUpField("DeletedAt")
+	if field == nil {
+		return 0, fmt.Errorf("%s: %w", db.Statement.Schema.Name, ErrNotSoftDeletable)
+	}
+	result := db.Where(r.primaryCondition(id)).Where(field.DBName+" IS NOT NULL").Update(field.DBName, nil)
+	return result.RowsAffected, result.Error
+}
+
+// primaryCondition 生成主键条件，与 First(entity, id) 的写法保持一致
+func (r *Repository[T]) primaryCondition(id any) map[string]any {
+	return map[string]any{r.primaryColumn(): id}
+}
+
+func (r *Repository[T]) primaryColumn() string {
+	stmt := &gorm.Statement{DB: r.Orm().db}
+	if err := stmt.Parse(new(T)); err == nil && stmt.Schema.PrioritizedPrimaryField != nil {
+		return stmt.Schema.PrioritizedPrimaryField.DBName
+	}
+	return "id"
+}
+
+func applyWhere(db *gorm.DB, query any, args []any) *gorm.DB {
+	if query == nil {
+		return db
+	}
+	return db.Where(query, args...)
+}
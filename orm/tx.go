@@ -0,0 +1,129 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	glog "github.com/goodbye-jack/go-common/log"
+	"gorm.io/gorm"
+)
+
+// txKey 按底层连接池区分事务，多个 Orm 实例的事务可以同时存在于同一个上下文
+type txKey struct {
+	pool *sql.DB
+}
+
+// txState 上下文中的事务；嵌套事务(保存点)各有一份，提交后把回调并入上一层
+type txState struct {
+	db          *gorm.DB
+	afterCommit []func(ctx context.Context)
+}
+
+func (o *Orm) txKey() (txKey, bool) {
+	if o == nil || o.db == nil {
+		return txKey{}, false
+	}
+	pool, err := o.db.DB()
+	if err != nil {
+		return txKey{}, false
+	}
+	return txKey{pool: pool}, true
+}
+
+func (o *Orm) txFromContext(ctx context.Context) *txState {
+	if ctx == nil {
+		return nil
+	}
+	key, ok := o.txKey()
+	if !ok {
+		return nil
+	}
+	state, _ := ctx.Value(key).(*txState)
+	return state
+}
+
+// WithContext 返回绑定上下文的 *gorm.DB：上下文中有本实例的事务时使用该事务，否则使用普通连接；
+// 通过 Scopes 设置的条件同样生效。需要直接操作 gorm 的包应使用它而不是 GetDB().WithContext。
+func (o *Orm) WithContext(ctx context.Context) *gorm.DB {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	db := o.db
+	if state := o.txFromContext(ctx); state != nil {
+		db = state.db
+	}
+	return o.scoped(db.WithContext(ctx))
+}
+
+// InTransaction 在事务中执行 fn，事务随 ctx 传递：fn 内通过该 ctx 调用的 Orm 方法、Repository、
+// changeguard 存储与工作流任务记录都会加入同一事务。上下文中已有事务时以保存点嵌套，
+// 内层出错只回滚到保存点。最外层提交成功后按注册顺序执行 AfterCommit 回调。
+func (o *Orm) InTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...*sql.TxOptions) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	key, ok := o.txKey()
+	if !ok {
+		return fmt.Errorf("orm实例未初始化")
+	}
+	parent := o.txFromContext(ctx)
+	db := o.db
+	if parent != nil {
+		db = parent.db
+	}
+	state := &txState{}
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state.db = tx
+		return fn(context.WithValue(ctx, key, state))
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	if parent != nil {
+		parent.afterCommit = append(parent.afterCommit, state.afterCommit...)
+		return nil
+	}
+	for _, hook := range state.afterCommit {
+		runAfterCommit(ctx, hook)
+	}
+	return nil
+}
+
+// Transaction 在事务中执行 fn 并返回事务错误，与 InTransaction 一样会加入或嵌套上下文中的事务
+func (o *Orm) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	err := o.InTransaction(ctx, func(ctx context.Context) error {
+		return fn(o.txFromContext(ctx).db)
+	})
+	if err != nil {
+		glog.Errorf("Transaction error: %v", err)
+	}
+	return err
+}
+
+// AfterCommit 注册事务提交后执行的回调（如发布事件）；事务或所在保存点回滚时回调被丢弃，
+// 上下文中没有本实例的事务时立即执行
+func (o *Orm) AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if fn == nil {
+		return
+	}
+	if state := o.txFromContext(ctx); state != nil {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	runAfterCommit(ctx, fn)
+}
+
+// InTx 判断上下文中是否有本实例的事务
+func (o *Orm) InTx(ctx context.Context) bool {
+	return o.txFromContext(ctx) != nil
+}
+
+func runAfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Errorf("AfterCommit 回调异常: %v", r)
+		}
+	}()
+	fn(ctx)
+}
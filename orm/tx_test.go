@@ -0,0 +1,203 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/goodbye-jack/go-common/model"
+	"github.com/goodbye-jack/go-common/utils"
+	"gorm.io/gorm"
+)
+
+type repoItem struct {
+	model.ModelBase
+	Name string
+}
+
+func newTxTestOrm(t *testing.T) *Orm {
+	t.Helper()
+	db := NewOrm(filepath.Join(t.TempDir(), "tx.db"), utils.DBTypeSQLite, 5)
+	db.AutoMigrate(&repoItem{})
+	return db
+}
+
+func TestContextTransaction(t *testing.T) {
+	db := newTxTestOrm(t)
+	ctx := context.Background()
+	repo := NewRepository[repoItem](db)
+	rollback := errors.New("rollback")
+	var events []string
+
+	err := db.InTransaction(ctx, func(ctx context.Context) error {
+		if !db.InTx(ctx) {
+			t.Fatalf("context should carry the transaction")
+		}
+		if err := db.Create(ctx, &repoItem{Name: "outer"}); err != nil {
+			return err
+		}
+		db.AfterCommit(ctx, func(context.Context) { events = append(events, "outer") })
+		nestedErr := db.InTransaction(ctx, func(ctx context.Context) error {
+			if err := repo.Create(ctx, &repoItem{Name: "inner"}); err != nil {
+				return err
+			}
+			db.AfterCommit(ctx, func(context.Context) { events = append(events, "inner") })
+			return rollback
+		})
+		if !errors.Is(nestedErr, rollback) {
+			t.Fatalf("nested error = %v", nestedErr)
+		}
+		if err := db.Transaction(ctx, func(tx *gorm.DB) error {
+			return tx.Create(&repoItem{Name: "savepoint"}).Error
+		}); err != nil {
+			return err
+		}
+		db.AfterCommit(ctx, func(context.Context) { panic("hook panics are recovered") })
+		db.AfterCommit(ctx, func(context.Context) { events = append(events, "last") })
+		if len(events) != 0 {
+			t.Fatalf("hooks should wait for commit, got %v", events)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("InTransaction() error = %v", err)
+	}
+	names, _ := repo.Find(ctx, nil)
+	if len(names) != 2 || names[0].Name != "outer" || names[1].Name != "savepoint" {
+		t.Fatalf("committed rows = %+v", names)
+	}
+	if len(events) != 2 || events[0] != "outer" || events[1] != "last" {
+		t.Fatalf("after commit hooks = %v", events)
+	}
+
+	events = nil
+	err = db.InTransaction(ctx, func(ctx context.Context) error {
+		if _, err := repo.Delete(ctx, names[0].ID); err != nil {
+			return err
+		}
+		db.AfterCommit(ctx, func(context.Context) { events = append(events, "rolled back") })
+		return rollback
+	})
+	if !errors.Is(err, rollback) || len(events) != 0 {
+		t.Fatalf("rollback = %v, hooks = %v", err, events)
+	}
+	if total, _ := repo.Count(ctx, nil); total != 2 {
+		t.Fatalf("rolled back delete should keep rows, count = %d", total)
+	}
+	if err := db.Transaction(ctx, func(tx *gorm.DB) error { return rollback }); !errors.Is(err, rollback) {
+		t.Fatalf("Transaction() should return the error, got %v", err)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	db := newTxTestOrm(t)
+	ctx := context.Background()
+	repo := NewRepository[repoItem](db)
+	if err := repo.CreateInBatches(ctx, []repoItem{{Name: "a"}, {Name: "b"}, {Name: "c"}}, 2); err != nil {
+		t.Fatalf("CreateInBatches() error = %v", err)
+	}
+	items, total, err := repo.Page(ctx, 2, 2, "", nil)
+	if err != nil || total != 3 || len(items) != 1 || items[0].Name != "c" {
+		t.Fatalf("Page() = %+v, %d, %v", items, total, err)
+	}
+	first, err := repo.First(ctx, "name = ?", "b")
+	if err != nil {
+		t.Fatalf("First() error = %v", err)
+	}
+	if affected, err := repo.Updates(ctx, first.ID, map[string]any{"name": "b2"}); err != nil || affected != 1 {
+		t.Fatalf("Updates() = %d, %v", affected, err)
+	}
+	if got, _ := repo.Get(ctx, first.ID); got.Name != "b2" {
+		t.Fatalf("Get() after update = %+v", got)
+	}
+
+	if affected, err := repo.Delete(ctx, first.ID); err != nil || affected != 1 {
+		t.Fatalf("Delete() = %d, %v", affected, err)
+	}
+	if _, err := repo.Get(ctx, first.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("soft deleted row should be hidden, err = %v", err)
+	}
+	if total, _ := repo.WithDeleted().Count(ctx, nil); total != 3 {
+		t.Fatalf("WithDeleted count = %d", total)
+	}
+	if affected, err := repo.Restore(ctx, first.ID); err != nil || affected != 1 {
+		t.Fatalf("Restore() = %d, %v", affected, err)
+	}
+	if affected, err := repo.HardDelete(ctx, first.ID); err != nil || affected != 1 {
+		t.Fatalf("HardDelete() = %d, %v", affected, err)
+	}
+	if total, _ := repo.WithDeleted().Count(ctx, nil); total != 2 {
+		t.Fatalf("count after hard delete = %d", total)
+	}
+	type plain struct {
+		ID   uint
+		Name string
+	}
+	db.AutoMigrate(&plain{})
+	if _, err := NewRepository[plain](db).Restore(ctx, 1); !errors.Is(err, ErrNotSoftDeletable) {
+		t.Fatalf("Restore() on plain model error = %v", err)
+	}
+}
+
+func TestPageRejectsUnsafeSort(t *testing.T) {
+	db := newTxTestOrm(t)
+	ctx := context.Background()
+	var items []repoItem
+	for _, sort := range [][2]string{{"id; DROP TABLE repo_items", "asc"}, {"id", "desc, name"}} {
+		if err := db.Page(ctx, &items, 1, 10, sort[0], sort[1]); !errors.Is(err, ErrInvalidSort) {
+			t.Fatalf("Page(%q, %q) error = %v", sort[0], sort[1], err)
+		}
+	}
+	if err := db.Page(ctx, &items, 1, 10, "repo_items.id", "DESC"); err != nil {
+		t.Fatalf("Page() error = %v", err)
+	}
+}
+
+func TestTopRejectsUnsafeSort(t *testing.T) {
+	db := newTxTestOrm(t)
+	ctx := context.Background()
+	for _, name := range []string{"a", "a", "b"} {
+		if err := db.Create(ctx, &repoItem{Name: name}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	type nameCount struct {
+		Name  string
+		Total int
+	}
+	var rows []nameCount
+	err := db.Top(ctx, "repo_items", "name, COUNT(*) AS total", "1 = 1", "name", "total DESC, (SELECT 1)", "", 1, &rows)
+	if !errors.Is(err, ErrInvalidSort) {
+		t.Fatalf("Top(unsafe sort) error = %v", err)
+	}
+	if err := db.Top(ctx, "repo_items", "name, COUNT(*) AS total", "1 = 1", "name", "total", "desc", 1, &rows); err != nil {
+		t.Fatalf("Top() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0].Name != "a" || rows[0].Total != 2 {
+		t.Fatalf("Top() rows = %+v", rows)
+	}
+}
+
+func TestExecContextJoinsTransaction(t *testing.T) {
+	db := newTxTestOrm(t)
+	ctx := context.Background()
+	rollback := errors.New("rollback")
+	err := db.InTransaction(ctx, func(ctx context.Context) error {
+		if err := db.ExecContext(ctx, "INSERT INTO repo_items (name) VALUES (?)", "raw"); err != nil {
+			return err
+		}
+		var count int64
+		if err := db.RawContext(ctx, "SELECT COUNT(*) FROM repo_items WHERE name = ?", &count, "raw"); err != nil || count != 1 {
+			t.Fatalf("RawContext() in tx = %d, %v", count, err)
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("InTransaction() error = %v", err)
+	}
+	var count int64
+	if err := db.RawContext(ctx, "SELECT COUNT(*) FROM repo_items WHERE name = ?", &count, "raw"); err != nil || count != 0 {
+		t.Fatalf("ExecContext() should roll back with the transaction, count = %d, %v", count, err)
+	}
+}
@@ -0,0 +1,86 @@
+package queryspec
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// decodeCursor 解析游标：base64url 编码的 JSON 数组，依次为上一页最后一行各排序列的值
+func (q *Query) decodeCursor(raw string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, invalid("cursor %q", raw)
+	}
+	var values []any
+	if err := json.Unmarshal(data, &values); err != nil || len(values) != len(q.Sorts) {
+		return nil, invalid("cursor does not match sort")
+	}
+	for i, value := range values {
+		field := q.schema.fields[q.Sorts[i].Field]
+		converted, err := convertCursorValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = converted
+	}
+	return values, nil
+}
+
+func convertCursorValue(field Field, value any) (any, error) {
+	if value == nil {
+		return nil, invalid("cursor value of %s is null", field.Name)
+	}
+	switch v := value.(type) {
+	case string:
+		return convert(field, v)
+	case float64:
+		return convert(field, strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		return convert(field, strconv.FormatBool(v))
+	}
+	return nil, invalid("cursor value of %s", field.Name)
+}
+
+// EncodeCursor 由一行的排序列取值生成下一页游标，values 与 Sorts 一一对应
+func (q *Query) EncodeCursor(values []any) (string, error) {
+	if len(values) != len(q.Sorts) {
+		return "", fmt.Errorf("queryspec cursor expects %d values, got %d", len(q.Sorts), len(values))
+	}
+	encoded := make([]any, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case time.Time:
+			encoded[i] = v.Format(time.RFC3339Nano)
+		case primitive.DateTime:
+			encoded[i] = v.Time().Format(time.RFC3339Nano)
+		case *time.Time:
+			if v != nil {
+				encoded[i] = v.Format(time.RFC3339Nano)
+			}
+		case int64, uint64:
+			// 大整数以字符串保存，避免 JSON 浮点精度丢失
+			encoded[i] = fmt.Sprint(v)
+		default:
+			encoded[i] = v
+		}
+	}
+	data, err := json.Marshal(encoded)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// CursorFromDocument 由 Mongo 文档(或 map 形式的行)生成下一页游标
+func (q *Query) CursorFromDocument(doc map[string]any) (string, error) {
+	values := make([]any, len(q.Sorts))
+	for i, item := range q.Sorts {
+		values[i] = doc[item.Column]
+	}
+	return q.EncodeCursor(values)
+}
@@ -0,0 +1,170 @@
+package queryspec
+
+import (
+	"reflect"
+
+	"github.com/goodbye-jack/go-common/orm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func column(name string) clause.Column {
+	return clause.Column{Name: name}
+}
+
+// filterExpression 将过滤条件编译为 GORM 表达式
+func filterExpression(filter Filter) clause.Expression {
+	col := column(filter.Column)
+	switch filter.Op {
+	case OpNe:
+		return clause.Neq{Column: col, Value: filter.Value}
+	case OpGt:
+		return clause.Gt{Column: col, Value: filter.Value}
+	case OpGte:
+		return clause.Gte{Column: col, Value: filter.Value}
+	case OpLt:
+		return clause.Lt{Column: col, Value: filter.Value}
+	case OpLte:
+		return clause.Lte{Column: col, Value: filter.Value}
+	case OpLike:
+		return orm.Contains(filter.Column, filter.Value.(string))
+	case OpIn:
+		return clause.IN{Column: col, Values: filter.Value.([]any)}
+	case OpNotIn:
+		return clause.Not(clause.IN{Column: col, Values: filter.Value.([]any)})
+	case OpNull:
+		if filter.Value.(bool) {
+			return clause.Expr{SQL: "? IS NULL", Vars: []any{col}}
+		}
+		return clause.Expr{SQL: "? IS NOT NULL", Vars: []any{col}}
+	default:
+		return clause.Eq{Column: col, Value: filter.Value}
+	}
+}
+
+// cursorExpression 游标条件：(a, b, id) 在排序方向上位于游标之后，展开为
+// a > va OR (a = va AND b > vb) OR (a = va AND b = vb AND id > vid)
+func (q *Query) cursorExpression() clause.Expression {
+	ors := make([]clause.Expression, 0, len(q.Sorts))
+	for i, item := range q.Sorts {
+		ands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: column(q.Sorts[j].Column), Value: q.After[j]})
+		}
+		if item.Desc {
+			ands = append(ands, clause.Lt{Column: column(item.Column), Value: q.After[i]})
+		} else {
+			ands = append(ands, clause.Gt{Column: column(item.Column), Value: q.After[i]})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	return clause.Or(ors...)
+}
+
+// WhereScope 仅包含过滤条件的 Scope，用于计数或与其他查询组合
+func (q *Query) WhereScope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(q.Filters) == 0 {
+			return db
+		}
+		exprs := make([]clause.Expression, 0, len(q.Filters))
+		for _, filter := range q.Filters {
+			exprs = append(exprs, filterExpression(filter))
+		}
+		return db.Where(clause.And(exprs...))
+	}
+}
+
+// OrderScope 排序 Scope
+func (q *Query) OrderScope() func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(q.Sorts) == 0 {
+			return db
+		}
+		columns := make([]clause.OrderByColumn, 0, len(q.Sorts))
+		for _, item := range q.Sorts {
+			columns = append(columns, clause.OrderByColumn{Column: column(item.Column), Desc: item.Desc})
+		}
+		return db.Order(clause.OrderBy{Columns: columns})
+	}
+}
+
+// Scope 过滤、排序与分页的完整 Scope；游标分页时追加游标条件
+func (q *Query) Scope() func(*gorm.DB) *gorm.DB {
+	return q.scope(q.PageSize)
+}
+
+func (q *Query) scope(limit int) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Scopes(q.WhereScope(), q.OrderScope())
+		if q.Keyset {
+			if len(q.After) > 0 {
+				db = db.Where(q.cursorExpression())
+			}
+			return db.Limit(limit)
+		}
+		return db.Limit(limit).Offset(q.Offset())
+	}
+}
+
+// Result 分页结果，字段与 http.JsonResponsePage 对应；游标分页时 NextCursor 为空表示没有下一页
+type Result[T any] struct {
+	Items      []T    `json:"data"`
+	Page       int    `json:"page_no"`
+	PageSize   int    `json:"page_size"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Find 按查询描述分页查询模型 T，db 通常为 orm.DB.WithContext(ctx)(会加入上下文中的事务)：
+//
+//	q, err := orderSchema.ParseRequest(c.Request)
+//	result, err := queryspec.Find[Order](orm.DB.WithContext(c), q)
+//	queryspec.Respond(c, result, err)
+func Find[T any](db *gorm.DB, q *Query) (*Result[T], error) {
+	result := &Result[T]{Items: []T{}, Page: q.Page, PageSize: q.PageSize}
+	if err := db.Model(new(T)).Scopes(q.WhereScope()).Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+	if result.Total == 0 {
+		return result, nil
+	}
+	limit := q.PageSize
+	if q.Keyset {
+		// 多取一行判断是否还有下一页
+		limit++
+	}
+	tx := db.Model(new(T)).Scopes(q.scope(limit))
+	if err := tx.Find(&result.Items).Error; err != nil {
+		return nil, err
+	}
+	if q.Keyset && len(result.Items) > q.PageSize {
+		result.Items = result.Items[:q.PageSize]
+		cursor, err := q.cursorFromRow(tx, &result.Items[q.PageSize-1])
+		if err != nil {
+			return nil, err
+		}
+		result.NextCursor = cursor
+	}
+	return result, nil
+}
+
+// cursorFromRow 通过模型 schema 读取行的排序列取值
+func (q *Query) cursorFromRow(tx *gorm.DB, row any) (string, error) {
+	stmt := tx.Statement
+	if stmt.Schema == nil {
+		if err := stmt.Parse(row); err != nil {
+			return "", err
+		}
+	}
+	rv := reflect.ValueOf(row).Elem()
+	values := make([]any, len(q.Sorts))
+	for i, item := range q.Sorts {
+		field := stmt.Schema.LookUpField(item.Column)
+		if field == nil {
+			return "", invalid("sort column %s not found in model", item.Column)
+		}
+		values[i], _ = field.ValueOf(stmt.Context, rv)
+	}
+	return q.EncodeCursor(values)
+}
@@ -0,0 +1,39 @@
+package queryspec
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	commonhttp "github.com/goodbye-jack/go-common/http"
+)
+
+// ParseRequest 解析请求 URL 中的查询参数
+func (s *Schema) ParseRequest(r *http.Request) (*Query, error) {
+	return s.Parse(r.URL.Query())
+}
+
+// Respond 按 JsonResponsePage 结构输出分页结果，游标分页额外返回 next_cursor；
+// 查询参数不合法(ErrInvalidQuery)时返回 400
+func Respond[T any](c *gin.Context, result *Result[T], err error) {
+	if errors.Is(err, ErrInvalidQuery) {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if err != nil || result == nil {
+		commonhttp.JsonResponsePage(c, 0, 0, 0, nil, err)
+		return
+	}
+	if result.NextCursor == "" {
+		commonhttp.JsonResponsePage(c, result.Page, result.PageSize, result.Total, result.Items, nil)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data":        result.Items,
+		"page_no":     result.Page,
+		"page_size":   result.PageSize,
+		"total":       result.Total,
+		"next_cursor": result.NextCursor,
+		"message":     "success",
+	})
+}
@@ -0,0 +1,82 @@
+package queryspec
+
+import (
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var mongoOps = map[Op]string{
+	OpNe:    "$ne",
+	OpGt:    "$gt",
+	OpGte:   "$gte",
+	OpLt:    "$lt",
+	OpLte:   "$lte",
+	OpIn:    "$in",
+	OpNotIn: "$nin",
+}
+
+func filterDocument(filter Filter) bson.M {
+	switch filter.Op {
+	case OpEq:
+		return bson.M{filter.Column: filter.Value}
+	case OpLike:
+		return bson.M{filter.Column: bson.M{"$regex": regexp.QuoteMeta(filter.Value.(string)), "$options": "i"}}
+	case OpNull:
+		if filter.Value.(bool) {
+			return bson.M{filter.Column: nil}
+		}
+		return bson.M{filter.Column: bson.M{"$ne": nil}}
+	case OpIn, OpNotIn:
+		return bson.M{filter.Column: bson.M{mongoOps[filter.Op]: bson.A(filter.Value.([]any))}}
+	default:
+		return bson.M{filter.Column: bson.M{mongoOps[filter.Op]: filter.Value}}
+	}
+}
+
+// BsonFilter 将过滤条件(及游标条件)编译为 Mongo 过滤条件，可与 datascope.MergeFilter 合并：
+//
+//	rows, total, err := orm.Mongo.FindPageWithCtx(c, "orders", q.BsonFilter(), int64(q.Page), int64(q.PageSize), q.BsonSort())
+func (q *Query) BsonFilter() bson.M {
+	conds := make(bson.A, 0, len(q.Filters)+1)
+	for _, filter := range q.Filters {
+		conds = append(conds, filterDocument(filter))
+	}
+	if q.Keyset && len(q.After) > 0 {
+		ors := make(bson.A, 0, len(q.Sorts))
+		for i, item := range q.Sorts {
+			and := bson.M{}
+			for j := 0; j < i; j++ {
+				and[q.Sorts[j].Column] = q.After[j]
+			}
+			op := "$gt"
+			if item.Desc {
+				op = "$lt"
+			}
+			and[item.Column] = bson.M{op: q.After[i]}
+			ors = append(ors, and)
+		}
+		conds = append(conds, bson.M{"$or": ors})
+	}
+	switch len(conds) {
+	case 0:
+		return bson.M{}
+	case 1:
+		return conds[0].(bson.M)
+	default:
+		return bson.M{"$and": conds}
+	}
+}
+
+// BsonSort Mongo 排序
+func (q *Query) BsonSort() bson.D {
+	sort := make(bson.D, 0, len(q.Sorts))
+	for _, item := range q.Sorts {
+		direction := 1
+		if item.Desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: item.Column, Value: direction})
+	}
+	return sort
+}
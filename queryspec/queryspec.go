@@ -0,0 +1,513 @@
+// Package queryspec 将列表接口的查询参数解析为受白名单约束的查询描述，
+// 再编译为 GORM Scope 或 Mongo 过滤条件，避免各接口手工拼接 SQL：
+//
+//	?page=2&page_size=20&sort=-created_at,name&filter[status]=1&filter[name][like]=张
+//
+// 字段名、排序列与操作符都必须在 Schema 中声明，列名经方言转义，值按字段类型转换后以参数绑定。
+package queryspec
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Op 过滤操作符
+type Op string
+
+const (
+	OpEq    Op = "eq"
+	OpNe    Op = "ne"
+	OpGt    Op = "gt"
+	OpGte   Op = "gte"
+	OpLt    Op = "lt"
+	OpLte   Op = "lte"
+	OpLike  Op = "like" // 包含，通配符按字面量匹配
+	OpIn    Op = "in"   // 逗号分隔
+	OpNotIn Op = "nin"  // 逗号分隔
+	OpNull  Op = "null" // true 为空，false 非空
+)
+
+// Kind 字段值类型，决定查询串如何转换为比较值
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindInt
+	KindUint
+	KindFloat
+	KindBool
+	KindTime
+)
+
+// 查询参数名
+const (
+	ParamPage     = "page"
+	ParamPageSize = "page_size"
+	ParamSort     = "sort"
+	ParamCursor   = "cursor"
+	ParamFilter   = "filter"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// ErrInvalidQuery 查询参数不合法(未声明的字段、操作符或无法转换的值)，HTTP 层应返回 400
+var ErrInvalidQuery = errors.New("invalid query")
+
+var defaultOps = map[Kind][]Op{
+	KindString: {OpEq, OpNe, OpLike, OpIn, OpNotIn, OpNull},
+	KindInt:    {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNotIn, OpNull},
+	KindUint:   {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNotIn, OpNull},
+	KindFloat:  {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNotIn, OpNull},
+	KindBool:   {OpEq, OpNe, OpNull},
+	KindTime:   {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpNull},
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+// Field 可查询字段。Name 为查询参数中的字段名，Column 为数据库列名或 Mongo 字段名(默认同 Name)
+type Field struct {
+	Name       string
+	Column     string
+	Kind       Kind
+	Sortable   bool
+	Filterable bool
+	Ops        []Op // 允许的操作符，为空时按 Kind 取默认集合
+}
+
+func (f Field) allows(op Op) bool {
+	ops := f.Ops
+	if len(ops) == 0 {
+		ops = defaultOps[f.Kind]
+	}
+	for _, item := range ops {
+		if item == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema 列表接口的查询白名单
+type Schema struct {
+	fields          map[string]Field
+	defaultSort     string
+	keyField        string
+	defaultPageSize int
+	maxPageSize     int
+	sortable        []string
+	filterable      []string
+	namer           schema.Namer
+}
+
+// Option Schema 选项
+type Option func(*Schema)
+
+// WithDefaultSort 未传 sort 时的排序，格式同查询参数，如 "-created_at"
+func WithDefaultSort(sort string) Option {
+	return func(s *Schema) { s.defaultSort = sort }
+}
+
+// WithKeyField 唯一键字段，追加到排序末尾保证分页稳定，游标分页必需；ForModel 默认取主键
+func WithKeyField(name string) Option {
+	return func(s *Schema) { s.keyField = name }
+}
+
+// WithPageSize 默认每页条数与上限，超过上限按上限处理
+func WithPageSize(defaultSize, maxSize int) Option {
+	return func(s *Schema) {
+		s.defaultPageSize = defaultSize
+		s.maxPageSize = maxSize
+	}
+}
+
+// WithSortable 仅允许这些字段排序(ForModel 默认全部字段可排序)
+func WithSortable(names ...string) Option {
+	return func(s *Schema) { s.sortable = names }
+}
+
+// WithFilterable 仅允许这些字段过滤(ForModel 默认全部字段可过滤)
+func WithFilterable(names ...string) Option {
+	return func(s *Schema) { s.filterable = names }
+}
+
+// WithNamingStrategy ForModel 解析列名使用的命名策略，需与 Orm 实例一致(如达梦)
+func WithNamingStrategy(namer schema.Namer) Option {
+	return func(s *Schema) { s.namer = namer }
+}
+
+// New 按显式声明的字段创建 Schema
+func New(fields []Field, opts ...Option) (*Schema, error) {
+	s := &Schema{fields: map[string]Field{}, defaultPageSize: DefaultPageSize, maxPageSize: MaxPageSize}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for _, field := range fields {
+		if field.Name == "" {
+			return nil, errors.New("queryspec field name is empty")
+		}
+		if field.Column == "" {
+			field.Column = field.Name
+		}
+		s.fields[field.Name] = field
+	}
+	return s, s.finish()
+}
+
+// ForModel 按 GORM 模型生成 Schema：字段名取 json 标签(没有时取列名)，json:"-" 与关联字段不可查询
+func ForModel(model any, opts ...Option) (*Schema, error) {
+	s := &Schema{fields: map[string]Field{}, defaultPageSize: DefaultPageSize, maxPageSize: MaxPageSize, namer: schema.NamingStrategy{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	parsed, err := schema.Parse(model, &sync.Map{}, s.namer)
+	if err != nil {
+		return nil, fmt.Errorf("queryspec parse model: %w", err)
+	}
+	for _, field := range parsed.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		kind, ok := kindOf(field.FieldType)
+		if !ok {
+			continue
+		}
+		name := field.DBName
+		if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		s.fields[name] = Field{Name: name, Column: field.DBName, Kind: kind, Sortable: true, Filterable: true}
+		if field.PrimaryKey && s.keyField == "" {
+			s.keyField = name
+		}
+	}
+	return s, s.finish()
+}
+
+// MustForModel 同 ForModel，出错时 panic，用于包级变量初始化
+func MustForModel(model any, opts ...Option) *Schema {
+	s, err := ForModel(model, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *Schema) finish() error {
+	if s.sortable != nil {
+		if err := s.restrict(s.sortable, func(f *Field, on bool) { f.Sortable = on }); err != nil {
+			return err
+		}
+	}
+	if s.filterable != nil {
+		if err := s.restrict(s.filterable, func(f *Field, on bool) { f.Filterable = on }); err != nil {
+			return err
+		}
+	}
+	if s.keyField != "" {
+		if _, ok := s.fields[s.keyField]; !ok {
+			return fmt.Errorf("queryspec key field %s not declared", s.keyField)
+		}
+	}
+	if s.defaultPageSize <= 0 {
+		s.defaultPageSize = DefaultPageSize
+	}
+	if s.maxPageSize < s.defaultPageSize {
+		s.maxPageSize = s.defaultPageSize
+	}
+	if s.defaultSort != "" {
+		if _, err := s.parseSort(s.defaultSort); err != nil {
+			return fmt.Errorf("queryspec default sort: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) restrict(names []string, set func(*Field, bool)) error {
+	allowed := map[string]bool{}
+	for _, name := range names {
+		if _, ok := s.fields[name]; !ok {
+			return fmt.Errorf("queryspec field %s not declared", name)
+		}
+		allowed[name] = true
+	}
+	for name, field := range s.fields {
+		set(&field, allowed[name])
+		s.fields[name] = field
+	}
+	return nil
+}
+
+// Field 返回声明的字段
+func (s *Schema) Field(name string) (Field, bool) {
+	field, ok := s.fields[name]
+	return field, ok
+}
+
+// Sort 排序项
+type Sort struct {
+	Field  string
+	Column string
+	Desc   bool
+}
+
+// Filter 过滤条件，OpIn/OpNotIn 的 Value 为 []any，OpNull 的 Value 为 bool
+type Filter struct {
+	Field  string
+	Column string
+	Op     Op
+	Value  any
+}
+
+// Query 解析后的查询描述。Keyset 为 true 时使用游标分页(请求携带 cursor 参数，首页传空值)，忽略 Page
+type Query struct {
+	Page     int
+	PageSize int
+	Sorts    []Sort
+	Filters  []Filter
+	Keyset   bool
+	After    []any // 游标中上一页最后一行的排序值，与 Sorts 一一对应
+
+	schema *Schema
+}
+
+// Offset 偏移分页的起始行
+func (q *Query) Offset() int {
+	return (q.Page - 1) * q.PageSize
+}
+
+var filterKeyPattern = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidQuery, fmt.Sprintf(format, args...))
+}
+
+// Parse 解析查询参数，未声明的字段、不允许的操作符或无法转换的值返回 ErrInvalidQuery
+func (s *Schema) Parse(values url.Values) (*Query, error) {
+	q := &Query{Page: 1, PageSize: s.defaultPageSize, schema: s}
+	if raw := strings.TrimSpace(values.Get(ParamPage)); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return nil, invalid("page %q", raw)
+		}
+		q.Page = page
+	}
+	if raw := strings.TrimSpace(values.Get(ParamPageSize)); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 {
+			return nil, invalid("page_size %q", raw)
+		}
+		q.PageSize = min(size, s.maxPageSize)
+	}
+
+	sortExpr := strings.TrimSpace(values.Get(ParamSort))
+	if sortExpr == "" {
+		sortExpr = s.defaultSort
+	}
+	sorts, err := s.parseSort(sortExpr)
+	if err != nil {
+		return nil, err
+	}
+	q.Sorts = sorts
+	if s.keyField != "" && !hasSort(q.Sorts, s.keyField) {
+		key := s.fields[s.keyField]
+		desc := len(q.Sorts) > 0 && q.Sorts[len(q.Sorts)-1].Desc
+		q.Sorts = append(q.Sorts, Sort{Field: key.Name, Column: key.Column, Desc: desc})
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if strings.HasPrefix(key, ParamFilter+"[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		filter, err := s.parseFilter(key, values[key])
+		if err != nil {
+			return nil, err
+		}
+		q.Filters = append(q.Filters, filter)
+	}
+
+	if _, ok := values[ParamCursor]; ok {
+		if s.keyField == "" {
+			return nil, invalid("cursor pagination requires a key field")
+		}
+		q.Keyset = true
+		q.Page = 1
+		if raw := strings.TrimSpace(values.Get(ParamCursor)); raw != "" {
+			if q.After, err = q.decodeCursor(raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return q, nil
+}
+
+func hasSort(sorts []Sort, field string) bool {
+	for _, item := range sorts {
+		if item.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) parseSort(expr string) ([]Sort, error) {
+	var sorts []Sort
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		desc := strings.HasPrefix(part, "-")
+		name := strings.TrimLeft(part, "+-")
+		field, ok := s.fields[name]
+		if !ok || !field.Sortable {
+			return nil, invalid("sort field %q is not allowed", name)
+		}
+		if hasSort(sorts, name) {
+			continue
+		}
+		sorts = append(sorts, Sort{Field: name, Column: field.Column, Desc: desc})
+	}
+	return sorts, nil
+}
+
+func (s *Schema) parseFilter(key string, raws []string) (Filter, error) {
+	match := filterKeyPattern.FindStringSubmatch(key)
+	if match == nil {
+		return Filter{}, invalid("filter %q", key)
+	}
+	field, ok := s.fields[match[1]]
+	if !ok || !field.Filterable {
+		return Filter{}, invalid("filter field %q is not allowed", match[1])
+	}
+	op := OpEq
+	if match[2] != "" {
+		op = Op(strings.ToLower(match[2]))
+	}
+	if !field.allows(op) {
+		return Filter{}, invalid("filter operator %q is not allowed on %s", op, field.Name)
+	}
+	raw := ""
+	if len(raws) > 0 {
+		raw = raws[len(raws)-1]
+	}
+	filter := Filter{Field: field.Name, Column: field.Column, Op: op}
+	switch op {
+	case OpNull:
+		value, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return Filter{}, invalid("filter %s: %q is not a boolean", field.Name, raw)
+		}
+		filter.Value = value
+	case OpIn, OpNotIn:
+		var list []any
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			value, err := convert(field, item)
+			if err != nil {
+				return Filter{}, err
+			}
+			list = append(list, value)
+		}
+		if len(list) == 0 {
+			return Filter{}, invalid("filter %s: empty list", field.Name)
+		}
+		filter.Value = list
+	case OpLike:
+		if raw == "" {
+			return Filter{}, invalid("filter %s: empty like value", field.Name)
+		}
+		filter.Value = raw
+	default:
+		value, err := convert(field, raw)
+		if err != nil {
+			return Filter{}, err
+		}
+		filter.Value = value
+	}
+	return filter, nil
+}
+
+// convert 将查询串转换为字段类型的值
+func convert(field Field, raw string) (any, error) {
+	raw = strings.TrimSpace(raw)
+	var (
+		value any
+		err   error
+	)
+	switch field.Kind {
+	case KindInt:
+		value, err = strconv.ParseInt(raw, 10, 64)
+	case KindUint:
+		value, err = strconv.ParseUint(raw, 10, 64)
+	case KindFloat:
+		value, err = strconv.ParseFloat(raw, 64)
+	case KindBool:
+		value, err = strconv.ParseBool(raw)
+	case KindTime:
+		value, err = parseTime(raw)
+	default:
+		value = raw
+	}
+	if err != nil {
+		return nil, invalid("filter %s: %q", field.Name, raw)
+	}
+	return value, nil
+}
+
+func parseTime(raw string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, raw, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time %q", raw)
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+func kindOf(t reflect.Type) (Kind, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType || t == deletedAtType {
+		return KindTime, true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return KindString, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return KindInt, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return KindUint, true
+	case reflect.Float32, reflect.Float64:
+		return KindFloat, true
+	case reflect.Bool:
+		return KindBool, true
+	}
+	return 0, false
+}
@@ -0,0 +1,179 @@
+package queryspec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/model"
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type order struct {
+	model.ModelBase
+	Code   string  `json:"code"`
+	Status int     `json:"status"`
+	Amount float64 `json:"amount"`
+	Note   *string `json:"note"`
+	Secret string  `json:"-"`
+}
+
+func orderSchema(t *testing.T) *Schema {
+	t.Helper()
+	s, err := ForModel(&order{}, WithDefaultSort("-created_at"), WithSortable("id", "created_at", "amount", "code"), WithPageSize(2, 3))
+	if err != nil {
+		t.Fatalf("ForModel() error = %v", err)
+	}
+	return s
+}
+
+func TestParse(t *testing.T) {
+	s := orderSchema(t)
+	values, _ := url.ParseQuery("page=2&page_size=10&sort=-amount,code&filter[status][in]=1,2&filter[code][like]=A_&filter[note][null]=true")
+	q, err := s.Parse(values)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if q.Page != 2 || q.PageSize != 3 || q.Offset() != 3 {
+		t.Fatalf("paging = %d/%d", q.Page, q.PageSize)
+	}
+	if len(q.Sorts) != 3 || !q.Sorts[0].Desc || q.Sorts[1].Column != "code" || q.Sorts[2].Field != "id" {
+		t.Fatalf("sorts = %+v", q.Sorts)
+	}
+	if len(q.Filters) != 3 || q.Filters[1].Field != "note" || q.Filters[2].Value.([]any)[1] != int64(2) {
+		t.Fatalf("filters = %+v", q.Filters)
+	}
+
+	q, _ = s.Parse(url.Values{})
+	if q.Sorts[0].Field != "created_at" || !q.Sorts[0].Desc || q.PageSize != 2 {
+		t.Fatalf("default query = %+v", q)
+	}
+
+	for _, raw := range []string{
+		"sort=status",
+		"sort=secret",
+		"filter[secret]=x",
+		"filter[status][like]=1",
+		"filter[status]=abc",
+		"filter[created_at][gt]=yesterday",
+		"filter[status][regex]=1",
+		"page=0",
+		"cursor=bm90LWpzb24",
+	} {
+		values, _ := url.ParseQuery(raw)
+		if _, err := s.Parse(values); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Parse(%s) error = %v", raw, err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	db := orm.NewOrm(filepath.Join(t.TempDir(), "q.db"), utils.DBTypeSQLite, 5)
+	db.AutoMigrate(&order{})
+	note := "vip"
+	rows := []order{
+		{Code: "A_1", Status: 1, Amount: 30, Note: &note},
+		{Code: "AB1", Status: 1, Amount: 10},
+		{Code: "A%2", Status: 2, Amount: 30},
+		{Code: "B1", Status: 3, Amount: 20},
+		{Code: "C1", Status: 1, Amount: 50},
+	}
+	if err := db.GetDB().Create(&rows).Error; err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	s := orderSchema(t)
+	find := func(raw string) *Result[order] {
+		t.Helper()
+		values, _ := url.ParseQuery(raw)
+		q, err := s.Parse(values)
+		if err != nil {
+			t.Fatalf("Parse(%s) error = %v", raw, err)
+		}
+		result, err := Find[order](db.GetDB(), q)
+		if err != nil {
+			t.Fatalf("Find(%s) error = %v", raw, err)
+		}
+		return result
+	}
+
+	if result := find(url.Values{"filter[code][like]": {"A_"}}.Encode()); result.Total != 1 || result.Items[0].Code != "A_1" {
+		t.Fatalf("like should match literally, got %+v", result)
+	}
+	if result := find("filter[status][in]=1,2&filter[amount][gte]=30&sort=code"); result.Total != 3 || result.Items[0].Code != "A%2" || result.Items[1].Code != "A_1" {
+		t.Fatalf("in/gte = %+v", result)
+	}
+	if result := find("filter[note][null]=false"); result.Total != 1 {
+		t.Fatalf("null filter total = %d", result.Total)
+	}
+	if result := find("page=3&sort=amount"); result.Total != 5 || len(result.Items) != 1 || result.Items[0].Code != "C1" {
+		t.Fatalf("offset page = %+v", result)
+	}
+
+	var seen []string
+	cursor := ""
+	for i := 0; i < 5; i++ {
+		result := find(url.Values{"sort": {"-amount"}, "cursor": {cursor}}.Encode())
+		for _, item := range result.Items {
+			seen = append(seen, item.Code)
+		}
+		if cursor = result.NextCursor; cursor == "" {
+			break
+		}
+	}
+	if got := fmt.Sprint(seen); got != "[C1 A%2 A_1 B1 AB1]" {
+		t.Fatalf("keyset pages = %s", got)
+	}
+}
+
+func TestMongoAndRespond(t *testing.T) {
+	s := orderSchema(t)
+	values, _ := url.ParseQuery("sort=amount&filter[code][like]=a.b&filter[status][nin]=3&cursor=")
+	q, err := s.Parse(values)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	cursor, err := q.CursorFromDocument(map[string]any{"amount": 20.5, "id": 7})
+	if err != nil {
+		t.Fatalf("CursorFromDocument() error = %v", err)
+	}
+	values.Set("cursor", cursor)
+	if q, err = s.Parse(values); err != nil {
+		t.Fatalf("Parse(cursor) error = %v", err)
+	}
+	filter := q.BsonFilter()
+	and := filter["$and"].(bson.A)
+	if len(and) != 3 || and[0].(bson.M)["code"].(bson.M)["$regex"] != `a\.b` {
+		t.Fatalf("BsonFilter() = %v", filter)
+	}
+	if or := and[2].(bson.M)["$or"].(bson.A); or[1].(bson.M)["id"].(bson.M)["$gt"] != uint64(7) {
+		t.Fatalf("cursor filter = %v", or)
+	}
+	if sort := q.BsonSort(); len(sort) != 2 || sort[0].Key != "amount" || sort[0].Value != 1 {
+		t.Fatalf("BsonSort() = %v", sort)
+	}
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	_, err = s.Parse(url.Values{"sort": {"secret"}})
+	Respond[order](c, nil, err)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("invalid query status = %d", recorder.Code)
+	}
+	recorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	Respond(c, &Result[order]{Items: []order{}, Page: 1, PageSize: 2, Total: 3, NextCursor: "abc"}, nil)
+	var body map[string]any
+	_ = json.Unmarshal(recorder.Body.Bytes(), &body)
+	if body["total"].(float64) != 3 || body["next_cursor"] != "abc" || body["page_size"].(float64) != 2 {
+		t.Fatalf("Respond() body = %v", body)
+	}
+}
@@ -0,0 +1,673 @@
+package rbac
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/goodbye-jack/go-common/log"
+)
+
+// 条件表达式可访问的属性命名空间
+const (
+	AttrRequest   = "request"   // 请求参数：path/query/body 分来源存放，仅单一来源的参数有简写
+	AttrPrincipal = "principal" // 主体：subject/type/tenant_code/roles 及 Principal.Attributes
+	AttrEnv       = "env"       // 环境：now/hour/weekday/date/time，以及调用方补充的 ip/method/path
+)
+
+// Attributes 条件表达式的求值上下文，按命名空间组织，如 {"request": {"amount": 100}}
+type Attributes map[string]any
+
+// maxConditionLength 与 casbin_rule.v4 列宽一致，超长表达式无法持久化
+const maxConditionLength = 255
+
+var errConditionTooLong = errors.New("rbac condition is too long")
+
+// timeNow 便于测试替换
+var timeNow = time.Now
+
+// Condition 编译后的条件表达式。表达式只支持字面量、属性访问、比较、in、逻辑运算与括号，
+// 不支持函数调用与赋值，求值无副作用：
+//
+//	request.amount < 10000 && principal.dept_code == request.dept_code
+//	env.hour >= 9 and env.hour < 18 and env.weekday in [1, 2, 3, 4, 5]
+type Condition struct {
+	src  string
+	root condNode
+}
+
+var conditionCache sync.Map // src -> *Condition
+
+// CompileCondition 编译条件表达式，结果按源文本缓存
+func CompileCondition(src string) (*Condition, error) {
+	src = strings.TrimSpace(src)
+	if cached, ok := conditionCache.Load(src); ok {
+		return cached.(*Condition), nil
+	}
+	if len(src) > maxConditionLength {
+		return nil, errConditionTooLong
+	}
+	cond := &Condition{src: src}
+	if src != "" {
+		p := &condParser{}
+		if err := p.tokenize(src); err != nil {
+			return nil, err
+		}
+		root, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.tokens) {
+			return nil, fmt.Errorf("rbac condition: unexpected %q at %d", p.tokens[p.pos].text, p.tokens[p.pos].pos)
+		}
+		cond.root = root
+	}
+	actual, _ := conditionCache.LoadOrStore(src, cond)
+	return actual.(*Condition), nil
+}
+
+// String 返回表达式源文本
+func (c *Condition) String() string {
+	return c.src
+}
+
+// Eval 求值，空表达式恒为 true；结果不是布尔值时返回错误
+func (c *Condition) Eval(attrs Attributes) (bool, error) {
+	if c == nil || c.root == nil {
+		return true, nil
+	}
+	value, err := c.root.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("rbac condition %q: result is %T, not bool", c.src, value)
+	}
+	return result, nil
+}
+
+// EvalCondition 编译(命中缓存时跳过)并求值条件表达式
+func EvalCondition(src string, attrs Attributes) (bool, error) {
+	cond, err := CompileCondition(src)
+	if err != nil {
+		return false, err
+	}
+	return cond.Eval(attrs)
+}
+
+// abacMatch casbin 匹配器函数 abac(p2.cond, r2.env)，表达式错误按不满足处理
+func abacMatch(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return false, fmt.Errorf("abac expects 2 arguments, got %d", len(args))
+	}
+	src, _ := args[0].(string)
+	attrs, _ := args[1].(Attributes)
+	ok, err := EvalCondition(src, attrs)
+	if err != nil {
+		log.Warnf("RBAC条件求值失败，按不满足处理: %v", err)
+		return false, nil
+	}
+	return ok, nil
+}
+
+// withEnvDefaults 补齐 env 命名空间的时间属性，不覆盖调用方提供的值
+func withEnvDefaults(attrs Attributes) Attributes {
+	ans := make(Attributes, len(attrs)+1)
+	for k, v := range attrs {
+		ans[k] = v
+	}
+	now := timeNow()
+	env := map[string]any{
+		"now":     now.Unix(),
+		"hour":    now.Hour(),
+		"minute":  now.Minute(),
+		"weekday": int(now.Weekday()),
+		"date":    now.Format("2006-01-02"),
+		"time":    now.Format("15:04"),
+	}
+	if provided, ok := attrs[AttrEnv].(map[string]any); ok {
+		for k, v := range provided {
+			env[k] = v
+		}
+	}
+	ans[AttrEnv] = env
+	return ans
+}
+
+// asActionPolicy 取出 Policy 中的 ActionPolicy，其他实现返回 nil
+func asActionPolicy(p Policy) *ActionPolicy {
+	switch v := p.(type) {
+	case *ActionPolicy:
+		return v
+	case ActionPolicy:
+		return &v
+	}
+	return nil
+}
+
+// hasConditionalPolicies 没有条件策略时跳过条件匹配，普通请求不加载属性
+func hasConditionalPolicies(e casbin.IEnforcer) bool {
+	ast, ok := e.GetModel()["p"][ptypeConditionalAction]
+	return ok && len(ast.Policy) > 0
+}
+
+// ---- 词法与语法分析 ----
+
+type condTokenKind int
+
+const (
+	tokIdent condTokenKind = iota
+	tokNumber
+	tokString
+	tokOp
+)
+
+type condToken struct {
+	kind condTokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+type condParser struct {
+	tokens []condToken
+	pos    int
+}
+
+func (p *condParser) tokenize(src string) error {
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '.') {
+				i++
+			}
+			p.tokens = append(p.tokens, condToken{kind: tokIdent, text: string(runes[start:i]), pos: start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) && p.expectsOperand()):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == '_') {
+				i++
+			}
+			text := strings.ReplaceAll(string(runes[start:i]), "_", "")
+			num, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return fmt.Errorf("rbac condition: invalid number %q at %d", text, start)
+			}
+			p.tokens = append(p.tokens, condToken{kind: tokNumber, text: text, num: num, pos: start})
+		case r == '"' || r == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return fmt.Errorf("rbac condition: unterminated string at %d", start)
+			}
+			i++
+			p.tokens = append(p.tokens, condToken{kind: tokString, text: sb.String(), pos: start})
+		default:
+			two := ""
+			if i+1 < len(runes) {
+				two = string(runes[i : i+2])
+			}
+			switch two {
+			case "==", "!=", "<=", ">=", "&&", "||":
+				p.tokens = append(p.tokens, condToken{kind: tokOp, text: two, pos: i})
+				i += 2
+				continue
+			}
+			if !strings.ContainsRune("<>!()[],", r) {
+				return fmt.Errorf("rbac condition: unexpected %q at %d", string(r), i)
+			}
+			p.tokens = append(p.tokens, condToken{kind: tokOp, text: string(r), pos: i})
+			i++
+		}
+	}
+	return nil
+}
+
+// expectsOperand 负号只在需要操作数的位置作为数字的一部分
+func (p *condParser) expectsOperand() bool {
+	if len(p.tokens) == 0 {
+		return true
+	}
+	last := p.tokens[len(p.tokens)-1]
+	return last.kind == tokOp && last.text != ")" && last.text != "]" || last.kind == tokIdent && isKeyword(last.text)
+}
+
+func isKeyword(text string) bool {
+	switch strings.ToLower(text) {
+	case "and", "or", "not", "in":
+		return true
+	}
+	return false
+}
+
+func (p *condParser) peek() *condToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+// accept 当前记号为操作符 ops 之一(关键字不区分大小写)时前进并返回 true
+func (p *condParser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok == nil || tok.kind == tokNumber || tok.kind == tokString {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.kind == tokOp && tok.text == op || tok.kind == tokIdent && strings.EqualFold(tok.text, op) {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *condParser) parseOr() (condNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{or: true, left: left, right: right}
+	}
+}
+
+func (p *condParser) parseAnd() (condNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{left: left, right: right}
+	}
+}
+
+func (p *condParser) parseUnary() (condNode, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *condParser) parseCompare() (condNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">", "in")
+	if !ok {
+		if _, negated := p.accept("not"); negated {
+			if _, ok := p.accept("in"); !ok {
+				return nil, errors.New("rbac condition: expected 'in' after 'not'")
+			}
+			op = "not in"
+		} else {
+			return left, nil
+		}
+	}
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *condParser) parsePrimary() (condNode, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, errors.New("rbac condition: unexpected end of expression")
+	}
+	p.pos++
+	switch tok.kind {
+	case tokNumber:
+		return literalNode{value: tok.num}, nil
+	case tokString:
+		return literalNode{value: tok.text}, nil
+	case tokIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null", "nil":
+			return literalNode{value: nil}, nil
+		}
+		if isKeyword(tok.text) {
+			return nil, fmt.Errorf("rbac condition: unexpected %q at %d", tok.text, tok.pos)
+		}
+		return &attrNode{path: strings.Split(tok.text, ".")}, nil
+	}
+	switch tok.text {
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("rbac condition: missing ')' for '(' at %d", tok.pos)
+		}
+		return node, nil
+	case "[":
+		list := &listNode{}
+		if _, ok := p.accept("]"); ok {
+			return list, nil
+		}
+		for {
+			item, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			list.items = append(list.items, item)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			if _, ok := p.accept("]"); ok {
+				return list, nil
+			}
+			return nil, fmt.Errorf("rbac condition: missing ']' for '[' at %d", tok.pos)
+		}
+	}
+	return nil, fmt.Errorf("rbac condition: unexpected %q at %d", tok.text, tok.pos)
+}
+
+// ---- 求值 ----
+
+type condNode interface {
+	eval(attrs Attributes) (any, error)
+}
+
+type literalNode struct{ value any }
+
+func (n literalNode) eval(Attributes) (any, error) { return n.value, nil }
+
+type attrNode struct{ path []string }
+
+// eval 按路径逐级取值，任一级缺失时为 nil
+func (n *attrNode) eval(attrs Attributes) (any, error) {
+	var cur any = map[string]any(attrs)
+	for _, key := range n.path {
+		switch m := cur.(type) {
+		case map[string]any:
+			cur = m[key]
+		case Attributes:
+			cur = m[key]
+		case map[string]string:
+			cur = m[key]
+		default:
+			return nil, nil
+		}
+	}
+	return normalizeValue(cur), nil
+}
+
+type listNode struct{ items []condNode }
+
+func (n *listNode) eval(attrs Attributes) (any, error) {
+	values := make([]any, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(attrs)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type notNode struct{ operand condNode }
+
+func (n *notNode) eval(attrs Attributes) (any, error) {
+	value, err := n.operand.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(value), nil
+}
+
+type logicNode struct {
+	or          bool
+	left, right condNode
+}
+
+func (n *logicNode) eval(attrs Attributes) (any, error) {
+	left, err := n.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(left) == n.or { // 短路
+		return n.or, nil
+	}
+	right, err := n.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(right), nil
+}
+
+type compareNode struct {
+	op          string
+	left, right condNode
+}
+
+func (n *compareNode) eval(attrs Attributes) (any, error) {
+	left, err := n.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	// 缺失属性与非空值做否定比较(!=、not in)时不成立，避免缺参数的请求满足条件
+	missing := isMissingAttr(n.left, left) && right != nil || isMissingAttr(n.right, right) && left != nil
+	switch n.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !missing && !valuesEqual(left, right), nil
+	case "in", "not in":
+		if missing {
+			return false, nil
+		}
+		found := false
+		if list, ok := right.([]any); ok {
+			for _, item := range list {
+				if valuesEqual(left, item) {
+					found = true
+					break
+				}
+			}
+		} else if s, ok := right.(string); ok {
+			if sub, ok := left.(string); ok {
+				found = strings.Contains(s, sub)
+			}
+		}
+		return found == (n.op == "in"), nil
+	}
+	cmp, ok := compareValues(left, right)
+	if !ok { // 缺失属性或类型不可比较时不满足
+		return false, nil
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// isMissingAttr 属性节点取值为空即视为缺失
+func isMissingAttr(node condNode, value any) bool {
+	_, ok := node.(*attrNode)
+	return ok && value == nil
+}
+
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	}
+	return true
+}
+
+// normalizeValue 统一数值为 float64、字符串切片为 []any，便于比较
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int8:
+		return float64(v)
+	case int16:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint8:
+		return float64(v)
+	case uint16:
+		return float64(v)
+	case uint32:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []string:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			list = append(list, item)
+		}
+		return list
+	case []int:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			list = append(list, float64(item))
+		}
+		return list
+	case []any:
+		list := make([]any, 0, len(v))
+		for _, item := range v {
+			list = append(list, normalizeValue(item))
+		}
+		return list
+	}
+	return value
+}
+
+// asNumber 数值或可解析为数值的字符串(如查询参数 "5000")
+func asNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, !math.IsNaN(v)
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func valuesEqual(left, right any) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	_, leftIsNum := left.(float64)
+	_, rightIsNum := right.(float64)
+	if leftIsNum || rightIsNum {
+		l, lok := asNumber(left)
+		r, rok := asNumber(right)
+		return lok && rok && l == r
+	}
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		return ok && l == r
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	}
+	return false
+}
+
+func compareValues(left, right any) (int, bool) {
+	_, leftIsNum := left.(float64)
+	_, rightIsNum := right.(float64)
+	if leftIsNum || rightIsNum {
+		l, lok := asNumber(left)
+		r, rok := asNumber(right)
+		if !lok || !rok {
+			return 0, false
+		}
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		}
+		return 0, true
+	}
+	l, lok := left.(string)
+	r, rok := right.(string)
+	if !lok || !rok {
+		return 0, false
+	}
+	return strings.Compare(l, r), true
+}
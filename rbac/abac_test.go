@@ -0,0 +1,163 @@
+package rbac
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestEvalCondition(t *testing.T) {
+	attrs := Attributes{
+		AttrRequest:   map[string]any{"amount": 800, "dept_code": "sales", "status": "draft"},
+		AttrPrincipal: map[string]any{"dept_code": "sales", "roles": []string{"EDITOR"}, "level": "3"},
+		AttrEnv:       map[string]any{"hour": 10, "weekday": 2},
+	}
+	cases := []struct {
+		expr string
+		want bool
+	}{
+		{"", true},
+		{"request.amount < 1000", true},
+		{"request.amount >= 1000", false},
+		{"principal.dept_code == request.dept_code", true},
+		{"principal.level > 2", true}, // 数字字符串按数值比较
+		{"'EDITOR' in principal.roles && request.status != 'approved'", true},
+		{"request.status in ['approved', 'rejected']", false},
+		{"request.status not in ['approved', 'rejected']", true},
+		{"env.hour >= 9 and env.hour < 18 and env.weekday in [1, 2, 3, 4, 5]", true},
+		{"not (request.amount < 1000) or request.missing == null", true},
+		{"request.missing > 1", false}, // 缺失属性比较为 false
+		{"request.missing != 'approved'", false},
+		{"request.missing not in ['approved', 'rejected']", false},
+		{"request.missing in ['approved']", false},
+		{"request.missing != null", false},
+		{"request.status != null", true},
+		{"request.amount > -1", true},
+	}
+	for _, tc := range cases {
+		got, err := EvalCondition(tc.expr, attrs)
+		if err != nil || got != tc.want {
+			t.Errorf("EvalCondition(%q) = %v, %v, want %v", tc.expr, got, err, tc.want)
+		}
+	}
+
+	for _, expr := range []string{
+		"request.amount <",
+		"request.amount < 1000 extra",
+		"(request.amount < 1000",
+		"os.Exit(1)",
+		"request.amount = 1",
+		"'unterminated",
+		strings.Repeat("a", maxConditionLength+1),
+	} {
+		if _, err := CompileCondition(expr); err == nil {
+			t.Errorf("CompileCondition(%q) should fail", expr)
+		}
+	}
+	if _, err := EvalCondition("request.amount", attrs); err == nil {
+		t.Errorf("non-bool result should fail")
+	}
+
+	first, _ := CompileCondition("request.amount < 1")
+	second, _ := CompileCondition(" request.amount < 1 ")
+	if first != second {
+		t.Errorf("CompileCondition should reuse cached condition")
+	}
+}
+
+func TestEnforceConditionalPolicies(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	client, err := NewRbacClientWithOptions(Options{AdapterType: AdapterGorm, DB: db})
+	if err != nil {
+		t.Fatalf("NewRbacClientWithOptions() error = %v", err)
+	}
+	if err := client.AddActionPolicies([]Policy{
+		NewActionPolicy("svc", "MANAGER", "/refunds", "POST"),
+		NewConditionalActionPolicy("svc", "CLERK", "/refunds", "POST", "request.amount <= 1000 && principal.dept_code == request.dept_code"),
+	}); err != nil {
+		t.Fatalf("AddActionPolicies() error = %v", err)
+	}
+	if err := client.AddActionPolicies([]Policy{
+		NewConditionalActionPolicy("svc", "CLERK", "/refunds", "POST", "request.amount <"),
+	}); err == nil {
+		t.Fatalf("invalid condition should be rejected")
+	}
+	for sub, role := range map[string]string{"alice": "CLERK", "bob": "MANAGER"} {
+		if err := client.AddTenantGroupingPolicy(sub, role, "t1"); err != nil {
+			t.Fatalf("AddTenantGroupingPolicy() error = %v", err)
+		}
+	}
+
+	loaded := 0
+	refund := func(sub string, amount int) *Req {
+		return NewTenantReq(sub, "t1", "svc", "/refunds", "POST").WithAttributeLoader(func() Attributes {
+			loaded++
+			return Attributes{
+				AttrRequest:   map[string]any{"amount": amount, "dept_code": "sales"},
+				AttrPrincipal: map[string]any{"dept_code": "sales"},
+			}
+		})
+	}
+	if ok, err := client.Enforce(refund("alice", 500)); err != nil || !ok {
+		t.Fatalf("Enforce(alice, 500) = %v, %v, want allowed", ok, err)
+	}
+	if ok, _ := client.Enforce(refund("alice", 5000)); ok {
+		t.Fatalf("Enforce(alice, 5000) should be denied by condition")
+	}
+	loaded = 0
+	if ok, _ := client.Enforce(refund("bob", 5000)); !ok || loaded != 0 {
+		t.Fatalf("unconditional policy should allow bob without loading attributes, loaded=%d", loaded)
+	}
+
+	// 条件策略与普通策略一起持久化并可重新加载
+	reloaded, err := NewRbacClientWithOptions(Options{AdapterType: AdapterGorm, DB: db})
+	if err != nil {
+		t.Fatalf("reload error = %v", err)
+	}
+	if ok, _ := reloaded.Enforce(refund("alice", 500)); !ok {
+		t.Fatalf("reloaded client should keep conditional policy")
+	}
+	policies, err := reloaded.GetActionPolicies("CLERK")
+	if err != nil || len(policies) != 1 || policies[0].Cond == "" {
+		t.Fatalf("GetActionPolicies() = %+v, %v", policies, err)
+	}
+
+	ans, err := reloaded.Explain(refund("alice", 5000), nil)
+	if err != nil || ans.Allowed || len(ans.Conditions) != 1 || ans.Conditions[0].Result {
+		t.Fatalf("Explain() = %+v, %v", ans, err)
+	}
+
+	if err := reloaded.DeleteActionPolicy(policies[0]); err != nil {
+		t.Fatalf("DeleteActionPolicy() error = %v", err)
+	}
+	if ok, _ := reloaded.Enforce(refund("alice", 500)); ok {
+		t.Fatalf("deleted conditional policy should no longer allow")
+	}
+	if err := reloaded.DeletePoliciesByService("svc"); err != nil {
+		t.Fatalf("DeletePoliciesByService() error = %v", err)
+	}
+	if ok, _ := reloaded.Enforce(refund("bob", 1)); ok {
+		t.Fatalf("DeletePoliciesByService should remove service policies")
+	}
+}
+
+func TestEnvDefaults(t *testing.T) {
+	timeNow = func() time.Time { return time.Date(2024, 5, 6, 20, 30, 0, 0, time.Local) }
+	defer func() { timeNow = time.Now }()
+
+	attrs := withEnvDefaults(Attributes{AttrEnv: map[string]any{"ip": "10.0.0.1"}})
+	ok, err := EvalCondition("env.hour >= 9 && env.hour < 18", attrs)
+	if err != nil || ok {
+		t.Fatalf("working hours condition = %v, %v, want false", ok, err)
+	}
+	ok, err = EvalCondition("env.weekday == 1 && env.date == '2024-05-06' && env.ip == '10.0.0.1'", attrs)
+	if err != nil || !ok {
+		t.Fatalf("env condition = %v, %v, want true", ok, err)
+	}
+}
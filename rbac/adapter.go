@@ -0,0 +1,274 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	redisadapter "github.com/casbin/redis-adapter/v3"
+	rediswatcher "github.com/casbin/redis-watcher/v2"
+	"github.com/goodbye-jack/go-common/config"
+	"github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 策略存储类型
+const (
+	AdapterRedis  = "redis"
+	AdapterGorm   = "gorm"
+	AdapterMemory = "memory"
+)
+
+// 策略变更通知类型
+const (
+	WatcherRedis = "redis"
+	WatcherNone  = "none"
+)
+
+const (
+	ConfigKeyAdapter        = "rbac.adapter"
+	ConfigKeyWatcher        = "rbac.watcher"
+	ConfigKeyWatcherChannel = "rbac.watcher_channel"
+
+	DefaultWatcherChannel = "/casbin"
+)
+
+var (
+	errNilAdapterDB = errors.New("rbac gorm adapter db is nil")
+)
+
+// Options RBAC 客户端初始化参数。Adapter/Watcher 直接指定实例时优先于 AdapterType/WatcherType
+type Options struct {
+	Adapter     persist.Adapter
+	AdapterType string // redis | gorm | memory，默认 redis
+	// DB GORM 适配器使用的数据库，为空时复用 rbac_* 角色表所在的库
+	DB *gorm.DB
+
+	Watcher persist.Watcher
+	// WatcherType redis | none，为空时 Redis 存储配 Redis 通知，其余存储不通知
+	WatcherType    string
+	WatcherChannel string
+
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+}
+
+// AdapterFactory 按 Options 创建策略存储
+type AdapterFactory func(opts Options) (persist.Adapter, error)
+
+// WatcherFactory 按 Options 创建策略变更通知器
+type WatcherFactory func(opts Options) (persist.Watcher, error)
+
+var (
+	factoryMu        sync.RWMutex
+	adapterFactories = map[string]AdapterFactory{}
+	watcherFactories = map[string]WatcherFactory{}
+)
+
+func init() {
+	RegisterAdapter(AdapterRedis, newRedisAdapter)
+	RegisterAdapter(AdapterGorm, newGormAdapterFromOptions)
+	RegisterAdapter(AdapterMemory, func(Options) (persist.Adapter, error) { return NewMemoryAdapter(), nil })
+	RegisterWatcher(WatcherRedis, newRedisWatcher)
+	RegisterWatcher(WatcherNone, func(Options) (persist.Watcher, error) { return nil, nil })
+}
+
+// RegisterAdapter 注册自定义策略存储，名称可在 rbac.adapter 中引用
+func RegisterAdapter(name string, factory AdapterFactory) {
+	factoryMu.Lock()
+	defer factoryMu.Unlock()
+	adapterFactories[strings.ToLower(strings.TrimSpace(name))] = factory
+}
+
+// RegisterWatcher 注册自定义策略变更通知器，名称可在 rbac.watcher 中引用
+func RegisterWatcher(name string, factory WatcherFactory) {
+	factoryMu.Lock()
+	defer factoryMu.Unlock()
+	watcherFactories[strings.ToLower(strings.TrimSpace(name))] = factory
+}
+
+// OptionsFromConfig 从配置读取存储/通知类型，Redis 地址优先取 orm.Redis，
+// 其次取 redisAddrOpt（地址、密码），最后降级为 127.0.0.1:6379
+func OptionsFromConfig(redisAddrOpt ...string) Options {
+	opts := Options{
+		AdapterType:    strings.TrimSpace(config.GetConfigString(ConfigKeyAdapter)),
+		WatcherType:    strings.TrimSpace(config.GetConfigString(ConfigKeyWatcher)),
+		WatcherChannel: strings.TrimSpace(config.GetConfigString(ConfigKeyWatcherChannel)),
+	}
+	if orm.Redis != nil && orm.Redis.GetConfig() != nil {
+		cfg := orm.Redis.GetConfig()
+		opts.RedisAddr = fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+		opts.RedisPassword = cfg.Password
+		// 解析DB索引（兼容字符串/数字配置）
+		fmt.Sscanf(cfg.Database, "%d", &opts.RedisDB)
+	}
+	if opts.RedisAddr == "" || opts.RedisAddr == ":0" {
+		opts.RedisAddr = "127.0.0.1:6379"
+		if len(redisAddrOpt) > 0 && redisAddrOpt[0] != "" {
+			opts.RedisAddr = redisAddrOpt[0]
+		}
+		if len(redisAddrOpt) > 1 {
+			opts.RedisPassword = redisAddrOpt[1]
+		}
+		opts.RedisDB = 0
+	}
+	return opts
+}
+
+func (o Options) adapterType() string {
+	typ := strings.ToLower(strings.TrimSpace(o.AdapterType))
+	if typ == "" {
+		return AdapterRedis
+	}
+	return typ
+}
+
+func (o Options) watcherType() string {
+	typ := strings.ToLower(strings.TrimSpace(o.WatcherType))
+	if typ != "" {
+		return typ
+	}
+	if o.Adapter == nil && o.adapterType() == AdapterRedis {
+		return WatcherRedis
+	}
+	return WatcherNone
+}
+
+func (o Options) watcherChannel() string {
+	if o.WatcherChannel == "" {
+		return DefaultWatcherChannel
+	}
+	return o.WatcherChannel
+}
+
+func newAdapter(opts Options) (persist.Adapter, error) {
+	if opts.Adapter != nil {
+		return opts.Adapter, nil
+	}
+	factoryMu.RLock()
+	factory, ok := adapterFactories[opts.adapterType()]
+	factoryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported rbac adapter %q", opts.AdapterType)
+	}
+	return factory(opts)
+}
+
+func newWatcher(opts Options) (persist.Watcher, error) {
+	if opts.Watcher != nil {
+		return opts.Watcher, nil
+	}
+	factoryMu.RLock()
+	factory, ok := watcherFactories[opts.watcherType()]
+	factoryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported rbac watcher %q", opts.WatcherType)
+	}
+	return factory(opts)
+}
+
+// buildEnforcer 创建存储、加载策略并挂载通知器，失败时返回错误而不退出进程
+func buildEnforcer(opts Options) (*casbin.Enforcer, persist.Watcher, error) {
+	adapter, err := newAdapter(opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create rbac adapter: %w", err)
+	}
+	m, err := model.NewModelFromString(text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load rbac model: %w", err)
+	}
+	// NewEnforcer 内部会执行一次 LoadPolicy
+	e, err := casbin.NewEnforcer(m, newTenantCompatAdapter(adapter))
+	if err != nil {
+		return nil, nil, fmt.Errorf("create rbac enforcer: %w", err)
+	}
+	// 注册匹配函数后重建角色关系
+	registerFunctions(e)
+	if err := e.BuildRoleLinks(); err != nil {
+		return nil, nil, fmt.Errorf("build rbac role links: %w", err)
+	}
+	watcher, err := newWatcher(opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create rbac watcher: %w", err)
+	}
+	if watcher == nil {
+		return e, nil, nil
+	}
+	if err := e.SetWatcher(watcher); err != nil {
+		watcher.Close()
+		return nil, nil, fmt.Errorf("set rbac watcher: %w", err)
+	}
+	if err := watcher.SetUpdateCallback(func(string) {
+		// 其他副本变更了授予，重新计算下一个生效/到期时间点
+		invalidateAssignmentClock()
+		if err := e.LoadPolicy(); err != nil {
+			log.Errorf("RBAC策略变更后重新加载失败: %v", err)
+		}
+	}); err != nil {
+		watcher.Close()
+		return nil, nil, fmt.Errorf("set rbac watcher callback: %w", err)
+	}
+	return e, watcher, nil
+}
+
+// redisDSN 构造 casbin redis-adapter 兼容的 DSN：host:port?password=xxx&db=n
+func redisDSN(opts Options) string {
+	var dsnBuilder strings.Builder
+	dsnBuilder.WriteString(opts.RedisAddr)
+	params := []string{}
+	if opts.RedisPassword != "" {
+		params = append(params, fmt.Sprintf("password=%s", opts.RedisPassword))
+	}
+	if opts.RedisDB != 0 {
+		params = append(params, fmt.Sprintf("db=%d", opts.RedisDB))
+	}
+	if len(params) > 0 {
+		dsnBuilder.WriteString("?")
+		dsnBuilder.WriteString(strings.Join(params, "&"))
+	}
+	return dsnBuilder.String()
+}
+
+func newRedisAdapter(opts Options) (persist.Adapter, error) {
+	adapter, err := redisadapter.NewAdapter("tcp", redisDSN(opts))
+	if err != nil {
+		return nil, fmt.Errorf("redis adapter %s: %w", opts.RedisAddr, err)
+	}
+	log.Infof("RBAC策略存储: Redis | addr=%s | db=%d", opts.RedisAddr, opts.RedisDB)
+	return adapter, nil
+}
+
+func newGormAdapterFromOptions(opts Options) (persist.Adapter, error) {
+	db := opts.DB
+	if db == nil {
+		var err error
+		if db, err = getStoreDB(); err != nil {
+			return nil, err
+		}
+	}
+	log.Infof("RBAC策略存储: GORM | table=%s", CasbinRule{}.TableName())
+	return NewGormAdapter(db)
+}
+
+func newRedisWatcher(opts Options) (persist.Watcher, error) {
+	watcher, err := rediswatcher.NewWatcher(opts.RedisAddr, rediswatcher.WatcherOptions{
+		Options: redis.Options{
+			Addr:     opts.RedisAddr,
+			Password: opts.RedisPassword,
+			DB:       opts.RedisDB,
+		},
+		Channel:    opts.watcherChannel(),
+		IgnoreSelf: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis watcher %s: %w", opts.RedisAddr, err)
+	}
+	return watcher, nil
+}
@@ -0,0 +1,352 @@
+package rbac
+
+import (
+	"context"
+	"sync"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/goodbye-jack/go-common/orm/migrate"
+	"gorm.io/gorm"
+)
+
+// CasbinRule casbin 策略行，GORM 适配器落在 rbac_casbin_rules 表，与 rbac_* 角色表同库
+type CasbinRule struct {
+	ID    uint   `gorm:"primaryKey"`
+	Ptype string `gorm:"size:16;index:idx_rbac_casbin_rule_v0,priority:1"`
+	V0    string `gorm:"size:255;index:idx_rbac_casbin_rule_v0,priority:2"`
+	V1    string `gorm:"size:255;index"`
+	V2    string `gorm:"size:255"`
+	V3    string `gorm:"size:255"`
+	V4    string `gorm:"size:255"`
+	V5    string `gorm:"size:255"`
+}
+
+func (CasbinRule) TableName() string {
+	return "rbac_casbin_rules"
+}
+
+func newCasbinRule(ptype string, rule []string) CasbinRule {
+	line := CasbinRule{Ptype: ptype}
+	fields := []*string{&line.V0, &line.V1, &line.V2, &line.V3, &line.V4, &line.V5}
+	for i := 0; i < len(rule) && i < len(fields); i++ {
+		*fields[i] = rule[i]
+	}
+	return line
+}
+
+func (r CasbinRule) values() []string {
+	values := []string{r.V0, r.V1, r.V2, r.V3, r.V4, r.V5}
+	end := len(values)
+	for end > 0 && values[end-1] == "" {
+		end--
+	}
+	return values[:end]
+}
+
+// matches 判断规则是否命中 RemoveFilteredPolicy 的过滤条件，空值表示该字段不过滤
+func (r CasbinRule) matches(ptype string, fieldIndex int, fieldValues ...string) bool {
+	if r.Ptype != ptype {
+		return false
+	}
+	values := []string{r.V0, r.V1, r.V2, r.V3, r.V4, r.V5}
+	for i, v := range fieldValues {
+		idx := fieldIndex + i
+		if v == "" {
+			continue
+		}
+		if idx < 0 || idx >= len(values) || values[idx] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (r CasbinRule) equal(o CasbinRule) bool {
+	return r.Ptype == o.Ptype && r.V0 == o.V0 && r.V1 == o.V1 && r.V2 == o.V2 && r.V3 == o.V3 && r.V4 == o.V4 && r.V5 == o.V5
+}
+
+// ruleStore 策略行的存储后端，ruleAdapter 基于它实现 casbin 的全部持久化接口
+type ruleStore interface {
+	load() ([]CasbinRule, error)
+	replace(rules []CasbinRule) error
+	add(rules []CasbinRule) error
+	remove(rules []CasbinRule) error
+	update(oldRules, newRules []CasbinRule) error
+	removeFiltered(ptype string, fieldIndex int, fieldValues ...string) ([]CasbinRule, error)
+}
+
+// ruleAdapter 实现 persist.Adapter / BatchAdapter / UpdatableAdapter，
+// casbin 开启 autoSave 时会断言后两者，自定义适配器必须全部实现
+type ruleAdapter struct {
+	store ruleStore
+}
+
+var (
+	_ persist.BatchAdapter     = (*ruleAdapter)(nil)
+	_ persist.UpdatableAdapter = (*ruleAdapter)(nil)
+)
+
+func (a *ruleAdapter) LoadPolicy(m model.Model) error {
+	rules, err := a.store.load()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if err := persist.LoadPolicyArray(append([]string{rule.Ptype}, rule.values()...), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *ruleAdapter) SavePolicy(m model.Model) error {
+	var rules []CasbinRule
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range m[sec] {
+			for _, rule := range ast.Policy {
+				rules = append(rules, newCasbinRule(ptype, rule))
+			}
+		}
+	}
+	return a.store.replace(rules)
+}
+
+func (a *ruleAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.store.add([]CasbinRule{newCasbinRule(ptype, rule)})
+}
+
+func (a *ruleAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	return a.store.add(toCasbinRules(ptype, rules))
+}
+
+func (a *ruleAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return a.store.remove([]CasbinRule{newCasbinRule(ptype, rule)})
+}
+
+func (a *ruleAdapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	return a.store.remove(toCasbinRules(ptype, rules))
+}
+
+func (a *ruleAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	_, err := a.store.removeFiltered(ptype, fieldIndex, fieldValues...)
+	return err
+}
+
+func (a *ruleAdapter) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	return a.store.update([]CasbinRule{newCasbinRule(ptype, oldRule)}, []CasbinRule{newCasbinRule(ptype, newRule)})
+}
+
+func (a *ruleAdapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	return a.store.update(toCasbinRules(ptype, oldRules), toCasbinRules(ptype, newRules))
+}
+
+func (a *ruleAdapter) UpdateFilteredPolicies(sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	removed, err := a.store.removeFiltered(ptype, fieldIndex, fieldValues...)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.store.add(toCasbinRules(ptype, newRules)); err != nil {
+		return nil, err
+	}
+	oldRules := make([][]string, 0, len(removed))
+	for _, rule := range removed {
+		oldRules = append(oldRules, rule.values())
+	}
+	return oldRules, nil
+}
+
+func toCasbinRules(ptype string, rules [][]string) []CasbinRule {
+	lines := make([]CasbinRule, 0, len(rules))
+	for _, rule := range rules {
+		lines = append(lines, newCasbinRule(ptype, rule))
+	}
+	return lines
+}
+
+// MemoryAdapter 进程内策略存储，适用于单元测试与单实例场景，重启后策略丢失
+type MemoryAdapter struct {
+	ruleAdapter
+}
+
+// NewMemoryAdapter 创建内存适配器
+func NewMemoryAdapter() *MemoryAdapter {
+	return &MemoryAdapter{ruleAdapter{store: &memoryRuleStore{}}}
+}
+
+type memoryRuleStore struct {
+	mu    sync.Mutex
+	rules []CasbinRule
+}
+
+func (s *memoryRuleStore) load() ([]CasbinRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]CasbinRule(nil), s.rules...), nil
+}
+
+func (s *memoryRuleStore) replace(rules []CasbinRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append([]CasbinRule(nil), rules...)
+	return nil
+}
+
+func (s *memoryRuleStore) add(rules []CasbinRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(s.rules, rules...)
+	return nil
+}
+
+func (s *memoryRuleStore) remove(rules []CasbinRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(rules)
+	return nil
+}
+
+func (s *memoryRuleStore) removeLocked(rules []CasbinRule) {
+	kept := s.rules[:0]
+	for _, line := range s.rules {
+		drop := false
+		for _, rule := range rules {
+			if line.equal(rule) {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, line)
+		}
+	}
+	s.rules = kept
+}
+
+func (s *memoryRuleStore) update(oldRules, newRules []CasbinRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(oldRules)
+	s.rules = append(s.rules, newRules...)
+	return nil
+}
+
+func (s *memoryRuleStore) removeFiltered(ptype string, fieldIndex int, fieldValues ...string) ([]CasbinRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed []CasbinRule
+	kept := s.rules[:0]
+	for _, line := range s.rules {
+		if line.matches(ptype, fieldIndex, fieldValues...) {
+			removed = append(removed, line)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	s.rules = kept
+	return removed, nil
+}
+
+// GormAdapter 基于 GORM 的策略存储，策略行保存在 rbac_casbin_rules 表
+type GormAdapter struct {
+	ruleAdapter
+}
+
+// NewGormAdapter 创建 GORM 适配器并自动迁移 rbac_casbin_rules 表
+func NewGormAdapter(db *gorm.DB) (*GormAdapter, error) {
+	if db == nil {
+		return nil, errNilAdapterDB
+	}
+	if err := migrate.ApplyModule(context.Background(), db, CasbinMigrationModule); err != nil {
+		return nil, err
+	}
+	return &GormAdapter{ruleAdapter{store: &gormRuleStore{db: db}}}, nil
+}
+
+type gormRuleStore struct {
+	db *gorm.DB
+}
+
+func (s *gormRuleStore) load() ([]CasbinRule, error) {
+	var rules []CasbinRule
+	if err := s.db.Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (s *gormRuleStore) replace(rules []CasbinRule) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&CasbinRule{}).Error; err != nil {
+			return err
+		}
+		return insertRules(tx, rules)
+	})
+}
+
+func (s *gormRuleStore) add(rules []CasbinRule) error {
+	return insertRules(s.db, rules)
+}
+
+func (s *gormRuleStore) remove(rules []CasbinRule) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return deleteRules(tx, rules)
+	})
+}
+
+func (s *gormRuleStore) update(oldRules, newRules []CasbinRule) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteRules(tx, oldRules); err != nil {
+			return err
+		}
+		return insertRules(tx, newRules)
+	})
+}
+
+func (s *gormRuleStore) removeFiltered(ptype string, fieldIndex int, fieldValues ...string) ([]CasbinRule, error) {
+	var removed []CasbinRule
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("ptype = ?", ptype)
+		columns := []string{"v0", "v1", "v2", "v3", "v4", "v5"}
+		for i, v := range fieldValues {
+			idx := fieldIndex + i
+			if v == "" || idx < 0 || idx >= len(columns) {
+				continue
+			}
+			query = query.Where(columns[idx]+" = ?", v)
+		}
+		if err := query.Find(&removed).Error; err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		ids := make([]uint, 0, len(removed))
+		for _, rule := range removed {
+			ids = append(ids, rule.ID)
+		}
+		return tx.Where("id IN ?", ids).Delete(&CasbinRule{}).Error
+	})
+	return removed, err
+}
+
+func insertRules(db *gorm.DB, rules []CasbinRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	for i := range rules {
+		rules[i].ID = 0
+	}
+	return db.CreateInBatches(rules, 200).Error
+}
+
+func deleteRules(db *gorm.DB, rules []CasbinRule) error {
+	for _, rule := range rules {
+		err := db.Where("ptype = ? AND v0 = ? AND v1 = ? AND v2 = ? AND v3 = ? AND v4 = ? AND v5 = ?",
+			rule.Ptype, rule.V0, rule.V1, rule.V2, rule.V3, rule.V4, rule.V5).Delete(&CasbinRule{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
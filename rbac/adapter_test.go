@@ -0,0 +1,111 @@
+package rbac
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type countingWatcher struct {
+	updates  int
+	callback func(string)
+}
+
+func (w *countingWatcher) SetUpdateCallback(fn func(string)) error {
+	w.callback = fn
+	return nil
+}
+
+func (w *countingWatcher) Update() error {
+	w.updates++
+	return nil
+}
+
+func (w *countingWatcher) Close() {}
+
+func exercisePolicies(t *testing.T, client *RbacClient) {
+	t.Helper()
+	policies := []Policy{
+		NewActionPolicy("svc", "EDITOR", "/orders", "GET"),
+		NewActionPolicy("svc", "EDITOR", "/orders", "POST"),
+		NewActionPolicy("other", "EDITOR", "/orders", "GET"),
+	}
+	if err := client.AddActionPolicies(policies); err != nil {
+		t.Fatalf("AddActionPolicies() error = %v", err)
+	}
+	if err := client.AddGroupingPolicy("alice", "EDITOR"); err != nil {
+		t.Fatalf("AddGroupingPolicy() error = %v", err)
+	}
+	if ok, err := client.Enforce(NewReq("alice", "svc", "/orders", "POST")); err != nil || !ok {
+		t.Fatalf("Enforce() = %v, %v, want true", ok, err)
+	}
+	if err := client.DeletePoliciesByService("svc"); err != nil {
+		t.Fatalf("DeletePoliciesByService() error = %v", err)
+	}
+	if ok, _ := client.Enforce(NewReq("alice", "svc", "/orders", "POST")); ok {
+		t.Fatalf("policy of svc should be removed")
+	}
+	aps, err := client.GetActionPolicies("EDITOR")
+	if err != nil || len(aps) != 1 || aps[0].Dom != "other" {
+		t.Fatalf("GetActionPolicies() = %v, %v", aps, err)
+	}
+}
+
+func TestMemoryAdapterWithWatcher(t *testing.T) {
+	watcher := &countingWatcher{}
+	client, err := NewRbacClientWithOptions(Options{AdapterType: AdapterMemory, Watcher: watcher})
+	if err != nil {
+		t.Fatalf("NewRbacClientWithOptions() error = %v", err)
+	}
+	exercisePolicies(t, client)
+	if watcher.updates == 0 || watcher.callback == nil {
+		t.Fatalf("watcher not notified, updates = %d", watcher.updates)
+	}
+	if err := client.SetWatcher(nil); err != nil {
+		t.Fatalf("SetWatcher(nil) error = %v", err)
+	}
+	updates := watcher.updates
+	if err := client.AddGroupingPolicy("bob", "EDITOR"); err != nil {
+		t.Fatalf("AddGroupingPolicy() error = %v", err)
+	}
+	if watcher.updates != updates {
+		t.Fatalf("detached watcher still notified")
+	}
+}
+
+func TestGormAdapterPersistsPolicies(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	client, err := NewRbacClientWithOptions(Options{AdapterType: AdapterGorm, DB: db})
+	if err != nil {
+		t.Fatalf("NewRbacClientWithOptions() error = %v", err)
+	}
+	exercisePolicies(t, client)
+
+	// 另一个实例从同一张表加载到相同策略
+	reloaded, err := NewRbacClientWithOptions(Options{AdapterType: AdapterGorm, DB: db})
+	if err != nil {
+		t.Fatalf("reload error = %v", err)
+	}
+	if ok, _ := reloaded.Enforce(NewReq("alice", "other", "/orders", "GET")); !ok {
+		t.Fatalf("reloaded client should allow alice on other")
+	}
+	roles, err := reloaded.GetRolesForSubject("alice")
+	if err != nil || len(roles) != 1 || roles[0] != "EDITOR" {
+		t.Fatalf("GetRolesForSubject() = %v, %v", roles, err)
+	}
+}
+
+func TestLazyClientReturnsInitError(t *testing.T) {
+	client := &RbacClient{opts: Options{AdapterType: "unknown"}}
+	if _, err := client.Enforce(NewReq("alice", "svc", "/orders", "GET")); err == nil {
+		t.Fatalf("expected error for unsupported adapter")
+	}
+	client.reset(Options{AdapterType: AdapterMemory})
+	if ok, err := client.Enforce(NewReq("alice", "svc", "/orders", "GET")); err != nil || ok {
+		t.Fatalf("Enforce() = %v, %v", ok, err)
+	}
+}
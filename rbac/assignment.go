@@ -0,0 +1,592 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goodbye-jack/go-common/config"
+	"github.com/goodbye-jack/go-common/log"
+	"gorm.io/gorm"
+)
+
+// 授予历史动作
+const (
+	HistoryActionGrant    = "grant"    // 授予或更新有效期
+	HistoryActionRevoke   = "revoke"   // 撤销
+	HistoryActionDelegate = "delegate" // 委托授予
+	HistoryActionExpire   = "expire"   // 到期后由清理任务移除
+)
+
+const ConfigKeySweepInterval = "rbac.assignment.sweep_interval_seconds"
+
+// assignmentRetryInterval 授予存储不可用时，鉴权前同步有效期的重试间隔
+const assignmentRetryInterval = time.Minute
+
+// assignmentRecheckInterval 缓存的下一个生效/到期时间点的上限。
+// 其他副本写入的限时授予不会重置本进程的时间点，最迟在该间隔后重新读取存储
+const assignmentRecheckInterval = time.Minute
+
+var (
+	errInvalidValidity  = errors.New("rbac grant valid_until must be after valid_from and now")
+	errDelegationExpiry = errors.New("rbac delegation requires valid_until")
+	errSelfDelegation   = errors.New("rbac cannot delegate roles to oneself")
+	errPermanentGrant   = errors.New("rbac user already holds a permanent grant of the role")
+)
+
+// UserRoleHistory 角色授予与撤销的审计记录
+type UserRoleHistory struct {
+	ID            uint   `gorm:"primaryKey"`
+	UID           string `gorm:"size:128;index"`
+	RoleCode      string `gorm:"size:128;index"`
+	TenantCode    string `gorm:"size:64;default:''"`
+	Action        string `gorm:"size:16;index"`
+	Operator      string `gorm:"size:128"`
+	Reason        string `gorm:"size:255"`
+	DelegatedFrom string `gorm:"size:128;default:''"`
+	ValidFrom     *time.Time
+	ValidUntil    *time.Time
+	CreatedAt     time.Time `gorm:"index"`
+}
+
+func (UserRoleHistory) TableName() string {
+	return "rbac_user_role_histories"
+}
+
+// Temporary 带有效期或委托的授予
+func (r UserRole) Temporary() bool {
+	return r.ValidFrom != nil || r.ValidUntil != nil || r.DelegatedFrom != ""
+}
+
+func (r UserRole) activeAt(now time.Time) bool {
+	return (r.ValidFrom == nil || !r.ValidFrom.After(now)) && (r.ValidUntil == nil || r.ValidUntil.After(now))
+}
+
+// activeAt 筛选 now 时刻有效的授予
+func activeAt(now time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(valid_from IS NULL OR valid_from <= ?) AND (valid_until IS NULL OR valid_until > ?)", now, now)
+	}
+}
+
+func writeHistory(tx *gorm.DB, action string, row *UserRole, operator, reason string) error {
+	if operator == "" {
+		operator = row.Grantor
+	}
+	if reason == "" && action != HistoryActionRevoke {
+		reason = row.Reason
+	}
+	return tx.Create(&UserRoleHistory{
+		UID:           row.UID,
+		RoleCode:      row.RoleCode,
+		TenantCode:    row.TenantCode,
+		Action:        action,
+		Operator:      operator,
+		Reason:        reason,
+		DelegatedFrom: row.DelegatedFrom,
+		ValidFrom:     row.ValidFrom,
+		ValidUntil:    row.ValidUntil,
+	}).Error
+}
+
+// GrantOptions 授予选项，ValidFrom/ValidUntil 为零值表示不限
+type GrantOptions struct {
+	Tenant     string
+	ValidFrom  time.Time
+	ValidUntil time.Time
+	Grantor    string
+	Reason     string
+}
+
+func (o GrantOptions) validity(now time.Time) (*time.Time, *time.Time, error) {
+	var from, until *time.Time
+	if !o.ValidFrom.IsZero() {
+		v := o.ValidFrom
+		from = &v
+	}
+	if !o.ValidUntil.IsZero() {
+		v := o.ValidUntil
+		if !v.After(now) || (from != nil && !v.After(*from)) {
+			return nil, nil, errInvalidValidity
+		}
+		until = &v
+	}
+	return from, until, nil
+}
+
+// GrantUserRole 授予业务角色并记录授予人与原因，可指定有效期(如临时代理两周)。
+// 已存在的限时授予会被更新为新的有效期(不指定有效期即转为永久授予)；已存在永久授予时
+// 不能再以限时授予覆盖，返回错误，需先 RevokeUserRole。未到生效时间的授予在生效后写入 casbin
+func GrantUserRole(uid, roleCode string, opts GrantOptions) error {
+	uid, roleCode = strings.TrimSpace(uid), normalizeRoleCode(roleCode)
+	if uid == "" {
+		return errors.New("uid is empty")
+	}
+	row := UserRole{UID: uid, RoleCode: roleCode, TenantCode: strings.TrimSpace(opts.Tenant), Grantor: opts.Grantor, Reason: opts.Reason}
+	return upsertUserRoles(HistoryActionGrant, []UserRole{row}, opts)
+}
+
+// DelegationOptions 委托选项，Roles 为空时委托委托人在该租户内(精确匹配)当前生效的全部角色
+type DelegationOptions struct {
+	Tenant     string
+	Roles      []string
+	ValidFrom  time.Time
+	ValidUntil time.Time
+	Grantor    string
+	Reason     string
+}
+
+// DelegateRoles 委托人 from 在有效期内将角色委托给代理人 to，to 已持有的角色跳过。
+// 委托必须指定 ValidUntil，到期后由清理任务撤销
+func DelegateRoles(from, to string, opts DelegationOptions) error {
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if from == "" || to == "" {
+		return errors.New("uid is empty")
+	}
+	if from == to {
+		return errSelfDelegation
+	}
+	if opts.ValidUntil.IsZero() {
+		return errDelegationExpiry
+	}
+	tenant := strings.TrimSpace(opts.Tenant)
+	db, err := getStoreDB()
+	if err != nil {
+		return err
+	}
+	var owned []UserRole
+	if err := db.Scopes(activeAt(timeNow())).Where("uid = ? AND tenant_code = ?", from, tenant).Find(&owned).Error; err != nil {
+		return err
+	}
+	ownedSet := map[string]bool{}
+	for _, row := range owned {
+		if row.DelegatedFrom == "" { // 不允许转委托
+			ownedSet[row.RoleCode] = true
+		}
+	}
+	roles := opts.Roles
+	if len(roles) == 0 {
+		for _, row := range owned {
+			if ownedSet[row.RoleCode] {
+				roles = append(roles, row.RoleCode)
+			}
+		}
+	}
+	var held []string
+	if err := db.Model(&UserRole{}).Where("uid = ? AND tenant_code = ?", to, tenant).Pluck("role_code", &held).Error; err != nil {
+		return err
+	}
+	heldSet := map[string]bool{}
+	for _, code := range held {
+		heldSet[code] = true
+	}
+	grantor := opts.Grantor
+	if grantor == "" {
+		grantor = from
+	}
+	rows := make([]UserRole, 0, len(roles))
+	for _, code := range roles {
+		code = normalizeRoleCode(code)
+		if !ownedSet[code] {
+			return fmt.Errorf("role %s is not held by %s", code, from)
+		}
+		if heldSet[code] {
+			continue
+		}
+		rows = append(rows, UserRole{UID: to, RoleCode: code, TenantCode: tenant, Grantor: grantor, Reason: opts.Reason, DelegatedFrom: from})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return upsertUserRoles(HistoryActionDelegate, rows, GrantOptions{Tenant: tenant, ValidFrom: opts.ValidFrom, ValidUntil: opts.ValidUntil})
+}
+
+func upsertUserRoles(action string, rows []UserRole, opts GrantOptions) error {
+	now := timeNow()
+	from, until, err := opts.validity(now)
+	if err != nil {
+		return err
+	}
+	db, err := getStoreDB()
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		if rows[i].RoleCode == "" {
+			return errors.New("role code is empty")
+		}
+		if IsInternalRoleCode(rows[i].RoleCode) {
+			return fmt.Errorf("role %s is internal", rows[i].RoleCode)
+		}
+		ok, err := roleExists(db, rows[i].RoleCode, RoleTypeBusiness)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("business role %s not found", rows[i].RoleCode)
+		}
+		rows[i].ValidFrom, rows[i].ValidUntil = from, until
+	}
+
+	previous := make([]*UserRole, len(rows))
+	defer invalidateAssignmentClock()
+	return syncGroupingInTx(db, func(tx *gorm.DB) error {
+		for i := range rows {
+			row := &rows[i]
+			var existing UserRole
+			err := tx.Where("uid = ? AND role_code = ? AND tenant_code = ?", row.UID, row.RoleCode, row.TenantCode).First(&existing).Error
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				if err := tx.Create(row).Error; err != nil {
+					return err
+				}
+			case err != nil:
+				return err
+			default:
+				// 限时授予到期后会被删除，覆盖永久授予会让用户在到期后意外失去角色
+				if !existing.Temporary() && row.Temporary() {
+					return fmt.Errorf("%w: uid=%s, role=%s, tenant=%s", errPermanentGrant, row.UID, row.RoleCode, row.TenantCode)
+				}
+				previous[i] = &existing
+				row.ID, row.CreatedAt = existing.ID, existing.CreatedAt
+				if err := tx.Select("*").Save(row).Error; err != nil {
+					return err
+				}
+			}
+			if err := writeHistory(tx, action, row, "", ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(client *RbacClient) error {
+		for _, row := range rows {
+			if err := client.syncUserRole(row, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(client *RbacClient) {
+		for i, row := range rows {
+			if previous[i] != nil {
+				_ = client.syncUserRole(*previous[i], now)
+			} else {
+				_ = client.RemoveTenantGroupingPolicy(row.UID, row.RoleCode, row.TenantCode)
+			}
+		}
+	})
+}
+
+// syncUserRole 按授予在 now 时刻是否有效写入或移除 casbin 分组策略
+func (c *RbacClient) syncUserRole(row UserRole, now time.Time) error {
+	if row.activeAt(now) {
+		return c.AddTenantGroupingPolicy(row.UID, row.RoleCode, row.TenantCode)
+	}
+	return c.RemoveTenantGroupingPolicy(row.UID, row.RoleCode, row.TenantCode)
+}
+
+// RevokeUserRole 撤销租户内(精确匹配)的单条授予并记录操作人与原因
+func RevokeUserRole(uid, tenant, roleCode, operator, reason string) error {
+	uid, tenant, roleCode = strings.TrimSpace(uid), strings.TrimSpace(tenant), normalizeRoleCode(roleCode)
+	db, err := getStoreDB()
+	if err != nil {
+		return err
+	}
+	var rows []UserRole
+	if err := db.Where("uid = ? AND role_code = ? AND tenant_code = ?", uid, roleCode, tenant).Find(&rows).Error; err != nil {
+		return err
+	}
+	_, err = revokeUserRoles(db, rows, HistoryActionRevoke, operator, reason)
+	return err
+}
+
+// RevokeDelegation 提前结束 from 委托给 to 的全部角色
+func RevokeDelegation(from, to, tenant, operator, reason string) error {
+	db, err := getStoreDB()
+	if err != nil {
+		return err
+	}
+	var rows []UserRole
+	if err := db.Where("uid = ? AND delegated_from = ? AND tenant_code = ?", strings.TrimSpace(to), strings.TrimSpace(from), strings.TrimSpace(tenant)).Find(&rows).Error; err != nil {
+		return err
+	}
+	_, err = revokeUserRoles(db, rows, HistoryActionRevoke, operator, reason)
+	return err
+}
+
+// revokeUserRoles 删除授予并返回实际删除的条数。其他副本已删除的授予不再重复记录历史
+func revokeUserRoles(db *gorm.DB, rows []UserRole, action, operator, reason string) (int, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	now := timeNow()
+	defer invalidateAssignmentClock()
+	revoked := 0
+	err := syncGroupingInTx(db, func(tx *gorm.DB) error {
+		revoked = 0
+		for i := range rows {
+			result := tx.Delete(&UserRole{}, rows[i].ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			revoked++
+			if err := writeHistory(tx, action, &rows[i], operator, reason); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(client *RbacClient) error {
+		for _, row := range rows {
+			if err := client.RemoveTenantGroupingPolicy(row.UID, row.RoleCode, row.TenantCode); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(client *RbacClient) {
+		for _, row := range rows {
+			if row.activeAt(now) {
+				_ = client.AddTenantGroupingPolicy(row.UID, row.RoleCode, row.TenantCode)
+			}
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return revoked, nil
+}
+
+// Delegation 一条生效中的委托
+type Delegation struct {
+	From       string     `json:"from"`
+	To         string     `json:"to"`
+	TenantCode string     `json:"tenant_code"`
+	Roles      []string   `json:"roles"`
+	ValidUntil *time.Time `json:"valid_until"`
+	Reason     string     `json:"reason"`
+}
+
+// ListDelegationsFrom 返回 uid 当前委托出去的代理关系，按代理人聚合
+func ListDelegationsFrom(uid, tenant string) ([]Delegation, error) {
+	return listDelegations("delegated_from = ?", uid, tenant)
+}
+
+// ListDelegationsTo 返回 uid 当前作为代理人的委托关系，按委托人聚合
+func ListDelegationsTo(uid, tenant string) ([]Delegation, error) {
+	return listDelegations("uid = ? AND delegated_from <> ''", uid, tenant)
+}
+
+// ActiveDelegate 返回 uid 当前的代理人，多个时取最早建立的委托，没有时返回空串。
+// 工作流转办/委派未指定处理人时可据此选择代理人
+func ActiveDelegate(uid, tenant string) (string, error) {
+	delegations, err := ListDelegationsFrom(uid, tenant)
+	if err != nil || len(delegations) == 0 {
+		return "", err
+	}
+	return delegations[0].To, nil
+}
+
+func listDelegations(cond, uid, tenant string) ([]Delegation, error) {
+	uid = strings.TrimSpace(uid)
+	if uid == "" {
+		return nil, errors.New("uid is empty")
+	}
+	db, err := getStoreDB()
+	if err != nil {
+		return nil, err
+	}
+	var rows []UserRole
+	if err := db.Scopes(activeAt(timeNow())).Where(cond, uid).
+		Where("tenant_code IN ?", []string{"", strings.TrimSpace(tenant)}).Order("id asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	var ans []Delegation
+	index := map[[3]string]int{}
+	for _, row := range rows {
+		key := [3]string{row.DelegatedFrom, row.UID, row.TenantCode}
+		i, ok := index[key]
+		if !ok {
+			i = len(ans)
+			index[key] = i
+			ans = append(ans, Delegation{From: row.DelegatedFrom, To: row.UID, TenantCode: row.TenantCode, ValidUntil: row.ValidUntil, Reason: row.Reason})
+		}
+		ans[i].Roles = append(ans[i].Roles, row.RoleCode)
+	}
+	return ans, nil
+}
+
+// ListUserRoleGrants 返回用户在全部租户下的授予明细，含尚未生效的授予，供管理端展示有效期与来源
+func ListUserRoleGrants(uid string) ([]UserRole, error) {
+	uid = strings.TrimSpace(uid)
+	if uid == "" {
+		return nil, errors.New("uid is empty")
+	}
+	db, err := getStoreDB()
+	if err != nil {
+		return nil, err
+	}
+	now := timeNow()
+	var rows []UserRole
+	if err := db.Where("uid = ? AND (valid_until IS NULL OR valid_until > ?)", uid, now).
+		Order("tenant_code asc, id asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ListUserRoleHistory 按时间倒序返回用户的授予历史，limit<=0 时返回全部
+func ListUserRoleHistory(uid string, limit int) ([]UserRoleHistory, error) {
+	db, err := getStoreDB()
+	if err != nil {
+		return nil, err
+	}
+	query := db.Where("uid = ?", strings.TrimSpace(uid)).Order("id desc")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var rows []UserRoleHistory
+	return rows, query.Find(&rows).Error
+}
+
+// SweepReport 一次清理的结果
+type SweepReport struct {
+	Expired   int       `json:"expired"`
+	Activated int       `json:"activated"`
+	SweptAt   time.Time `json:"swept_at"`
+}
+
+// assignmentClock 下一个授予生效或到期的时间点(UnixNano，0 表示需要重新计算)。
+// 默认客户端鉴权前越过该时间点会先同步一次，不依赖清理任务的周期。该时间点只在本进程内缓存，
+// 最长 assignmentRecheckInterval，收到 watcher 策略变更通知时也会重置
+var assignmentClock struct {
+	next atomic.Int64
+	mu   sync.Mutex
+}
+
+func invalidateAssignmentClock() {
+	assignmentClock.next.Store(0)
+}
+
+// syncAssignmentsBeforeEnforce 越过下一个生效/到期时间点时同步授予到 casbin
+func syncAssignmentsBeforeEnforce() {
+	now := timeNow()
+	if next := assignmentClock.next.Load(); next != 0 && now.UnixNano() < next {
+		return
+	}
+	assignmentClock.mu.Lock()
+	defer assignmentClock.mu.Unlock()
+	if next := assignmentClock.next.Load(); next != 0 && now.UnixNano() < next {
+		return
+	}
+	if _, err := sweepRoleAssignments(now); err != nil {
+		// 未启用授予存储或存储暂不可用时稍后重试，避免每次鉴权都访问数据库
+		log.Debugf("RBAC鉴权前同步授予有效期失败: %v", err)
+		assignmentClock.next.Store(now.Add(assignmentRetryInterval).UnixNano())
+	}
+}
+
+// SweepRoleAssignments 删除已到期的授予(同时移除 casbin 分组策略并记录 expire 历史)，
+// 并将已到生效时间的授予写入 casbin。默认客户端在 Enforce 时会在授予到期/生效的时间点自动同步
+// (其他副本写入的授予最迟延迟 assignmentRecheckInterval)，周期清理任务只用于及时清理与记录历史
+func SweepRoleAssignments() (*SweepReport, error) {
+	return sweepRoleAssignments(timeNow())
+}
+
+func sweepRoleAssignments(now time.Time) (*SweepReport, error) {
+	db, err := getStoreDB()
+	if err != nil {
+		return nil, err
+	}
+	report := &SweepReport{SweptAt: now}
+	var expired []UserRole
+	if err := db.Where("valid_until IS NOT NULL AND valid_until <= ?", now).Find(&expired).Error; err != nil {
+		return nil, err
+	}
+	if report.Expired, err = revokeUserRoles(db, expired, HistoryActionExpire, "system", "expired"); err != nil {
+		return nil, err
+	}
+
+	var started []UserRole
+	if err := db.Scopes(activeAt(now)).Where("valid_from IS NOT NULL").Find(&started).Error; err != nil {
+		return nil, err
+	}
+	if len(started) > 0 {
+		client := NewRbacClient()
+		e, err := client.enforcer()
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range started {
+			has, err := e.HasGroupingPolicy(row.UID, row.RoleCode, TenantDomain(row.TenantCode))
+			if err != nil {
+				return nil, err
+			}
+			if has {
+				continue
+			}
+			if err := client.AddTenantGroupingPolicy(row.UID, row.RoleCode, row.TenantCode); err != nil {
+				return nil, err
+			}
+			report.Activated++
+		}
+	}
+	next, err := nextAssignmentBoundary(db, now)
+	if err != nil {
+		return nil, err
+	}
+	assignmentClock.next.Store(next.UnixNano())
+	return report, nil
+}
+
+// nextAssignmentBoundary now 之后最早的授予生效或到期时间，不晚于 now+assignmentRecheckInterval
+func nextAssignmentBoundary(db *gorm.DB, now time.Time) (time.Time, error) {
+	next := now.Add(assignmentRecheckInterval)
+	for _, column := range []string{"valid_from", "valid_until"} {
+		var row UserRole
+		err := db.Where(column+" > ?", now).Order(column).Take(&row).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			continue
+		case err != nil:
+			return time.Time{}, err
+		}
+		at := row.ValidFrom
+		if column == "valid_until" {
+			at = row.ValidUntil
+		}
+		if at != nil && at.Before(next) {
+			next = *at
+		}
+	}
+	return next, nil
+}
+
+// StartRoleSweeper 按 interval 周期清理到期授予，ctx 取消后退出。多副本时只有 leader 副本执行
+func StartRoleSweeper(ctx context.Context, interval time.Duration) {
+	startWorker(ctx, "sweep", interval, func() {
+		report, err := SweepRoleAssignments()
+		if err != nil {
+			log.Errorf("RBAC到期授予清理失败: %v", err)
+			return
+		}
+		if report.Expired > 0 || report.Activated > 0 {
+			log.Infof("RBAC到期授予清理完成: expired=%d activated=%d", report.Expired, report.Activated)
+		}
+	})
+}
+
+// StartRoleSweeperFromConfig 按 rbac.assignment.sweep_interval_seconds 启动清理任务，未配置时不启动
+func StartRoleSweeperFromConfig(ctx context.Context) bool {
+	seconds := config.GetConfigInt(ConfigKeySweepInterval)
+	if seconds <= 0 {
+		return false
+	}
+	StartRoleSweeper(ctx, time.Duration(seconds)*time.Second)
+	log.Infof("RBAC到期授予清理任务已启动, interval=%ds", seconds)
+	return true
+}
@@ -0,0 +1,248 @@
+package rbac
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeBoundAndDelegatedRoles(t *testing.T) {
+	useTestStore(t)
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	for _, code := range []string{"MANAGER", "VIEWER"} {
+		if err := EnsureBusinessRole(code, code, 1); err != nil {
+			t.Fatalf("EnsureBusinessRole(%s) error = %v", code, err)
+		}
+	}
+	client := NewRbacClient()
+	hasRole := func(sub, role string) bool {
+		roles, _ := client.GetTenantRolesForSubject(sub)
+		for _, r := range roles[""] {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 临时代理两周
+	if err := GrantUserRole("alice", "MANAGER", GrantOptions{ValidUntil: now.Add(14 * 24 * time.Hour), Grantor: "root", Reason: "acting manager"}); err != nil {
+		t.Fatalf("GrantUserRole() error = %v", err)
+	}
+	if err := GrantUserRole("alice", "MANAGER", GrantOptions{ValidUntil: now.Add(-time.Hour)}); err != errInvalidValidity {
+		t.Fatalf("GrantUserRole(past) error = %v", err)
+	}
+	// 下周才生效
+	if err := GrantUserRole("carol", "VIEWER", GrantOptions{ValidFrom: now.Add(7 * 24 * time.Hour)}); err != nil {
+		t.Fatalf("GrantUserRole(future) error = %v", err)
+	}
+	if !hasRole("alice", "MANAGER") || hasRole("carol", "VIEWER") {
+		t.Fatalf("only active grants should be in casbin")
+	}
+	if roles, _ := ListUserRoles("carol"); len(roles) != 0 {
+		t.Fatalf("pending grant should not be listed, got %v", roles)
+	}
+
+	// 永久角色覆盖不影响临时授予
+	if err := SetUserRoles("alice", []string{"VIEWER"}); err != nil {
+		t.Fatalf("SetUserRoles() error = %v", err)
+	}
+	if !hasRole("alice", "MANAGER") || !hasRole("alice", "VIEWER") {
+		t.Fatalf("SetUserRoles should keep temporary grant")
+	}
+
+	// 委托给 bob，bob 不能再转委托
+	if err := DelegateRoles("alice", "bob", DelegationOptions{Roles: []string{"MANAGER"}}); err != errDelegationExpiry {
+		t.Fatalf("DelegateRoles(no expiry) error = %v", err)
+	}
+	if err := DelegateRoles("alice", "bob", DelegationOptions{ValidUntil: now.Add(3 * 24 * time.Hour), Reason: "vacation"}); err != nil {
+		t.Fatalf("DelegateRoles() error = %v", err)
+	}
+	if !hasRole("bob", "MANAGER") || !hasRole("bob", "VIEWER") {
+		t.Fatalf("bob should hold delegated roles")
+	}
+	if err := DelegateRoles("bob", "dave", DelegationOptions{Roles: []string{"MANAGER"}, ValidUntil: now.Add(time.Hour)}); err == nil {
+		t.Fatalf("re-delegation should be rejected")
+	}
+	if delegate, err := ActiveDelegate("alice", ""); err != nil || delegate != "bob" {
+		t.Fatalf("ActiveDelegate() = %q, %v", delegate, err)
+	}
+	if list, _ := ListDelegationsTo("bob", ""); len(list) != 1 || len(list[0].Roles) != 2 {
+		t.Fatalf("ListDelegationsTo() = %+v", list)
+	}
+
+	// 一周后：委托到期，carol 的授予生效
+	now = now.Add(7 * 24 * time.Hour)
+	report, err := SweepRoleAssignments()
+	if err != nil || report.Expired != 2 || report.Activated != 1 {
+		t.Fatalf("SweepRoleAssignments() = %+v, %v", report, err)
+	}
+	if hasRole("bob", "MANAGER") || !hasRole("carol", "VIEWER") || !hasRole("alice", "MANAGER") {
+		t.Fatalf("unexpected grants after sweep")
+	}
+	if drift, _ := DiffGroupingPolicies(); !drift.InSync() {
+		t.Fatalf("drift after sweep = %+v", drift)
+	}
+
+	if err := RevokeUserRole("alice", "", "MANAGER", "root", "back to normal"); err != nil {
+		t.Fatalf("RevokeUserRole() error = %v", err)
+	}
+	history, err := ListUserRoleHistory("alice", 0)
+	if err != nil || len(history) != 3 || history[0].Action != HistoryActionRevoke || history[0].Operator != "root" {
+		t.Fatalf("alice history = %+v, %v", history, err)
+	}
+	history, _ = ListUserRoleHistory("bob", 0)
+	if len(history) != 4 || history[0].Action != HistoryActionExpire || history[3].DelegatedFrom != "alice" {
+		t.Fatalf("bob history = %+v", history)
+	}
+}
+
+func TestEnforceHonorsGrantValidity(t *testing.T) {
+	useTestStore(t)
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	for _, code := range []string{"MANAGER", "VIEWER"} {
+		if err := EnsureBusinessRole(code, code, 1); err != nil {
+			t.Fatalf("EnsureBusinessRole(%s) error = %v", code, err)
+		}
+	}
+	client := NewRbacClient()
+	if err := client.AddActionPolicies([]Policy{
+		NewActionPolicy("svc", "MANAGER", "/orders", "POST"),
+		NewActionPolicy("svc", "VIEWER", "/orders", "GET"),
+	}); err != nil {
+		t.Fatalf("AddActionPolicies() error = %v", err)
+	}
+	if err := GrantUserRole("alice", "MANAGER", GrantOptions{ValidUntil: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("GrantUserRole() error = %v", err)
+	}
+	if err := GrantUserRole("carol", "VIEWER", GrantOptions{ValidFrom: now.Add(30 * time.Minute)}); err != nil {
+		t.Fatalf("GrantUserRole(future) error = %v", err)
+	}
+	allowed := func(sub, act string) bool {
+		ok, err := client.Enforce(NewReq(sub, "svc", "/orders", act))
+		if err != nil {
+			t.Fatalf("Enforce() error = %v", err)
+		}
+		return ok
+	}
+	if !allowed("alice", "POST") || allowed("carol", "GET") {
+		t.Fatalf("unexpected decisions before validity boundaries")
+	}
+
+	// 未运行清理任务，越过生效/到期时间点后鉴权结果即时变化
+	now = now.Add(2 * time.Hour)
+	if allowed("alice", "POST") || !allowed("carol", "GET") {
+		t.Fatalf("Enforce should follow valid_from/valid_until without the sweeper")
+	}
+}
+
+func TestEnforceRechecksGrantsFromOtherReplicas(t *testing.T) {
+	useTestStore(t)
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	if err := EnsureBusinessRole("VIEWER", "VIEWER", 1); err != nil {
+		t.Fatalf("EnsureBusinessRole() error = %v", err)
+	}
+	client := NewRbacClient()
+	if err := client.AddActionPolicies([]Policy{NewActionPolicy("svc", "VIEWER", "/orders", "GET")}); err != nil {
+		t.Fatalf("AddActionPolicies() error = %v", err)
+	}
+	allowed := func() bool {
+		ok, err := client.Enforce(NewReq("carol", "svc", "/orders", "GET"))
+		if err != nil {
+			t.Fatalf("Enforce() error = %v", err)
+		}
+		return ok
+	}
+	if allowed() {
+		t.Fatalf("carol should not be allowed before any grant")
+	}
+	// 其他副本写入的授予不会重置本进程缓存的时间点
+	db, _ := getStoreDB()
+	validFrom := now.Add(10 * time.Second)
+	if err := db.Create(&UserRole{UID: "carol", RoleCode: "VIEWER", TenantCode: GlobalTenant, ValidFrom: &validFrom}).Error; err != nil {
+		t.Fatalf("create grant error = %v", err)
+	}
+	now = now.Add(assignmentRecheckInterval + time.Second)
+	if !allowed() {
+		t.Fatalf("grant from another replica should take effect within %s", assignmentRecheckInterval)
+	}
+}
+
+func TestConcurrentSweepRecordsExpiryOnce(t *testing.T) {
+	useTestStore(t)
+	now := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+
+	if err := EnsureBusinessRole("MANAGER", "MANAGER", 1); err != nil {
+		t.Fatalf("EnsureBusinessRole() error = %v", err)
+	}
+	if err := GrantUserRole("alice", "MANAGER", GrantOptions{ValidUntil: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("GrantUserRole() error = %v", err)
+	}
+	now = now.Add(2 * time.Hour)
+	db, _ := getStoreDB()
+	var expired []UserRole
+	if err := db.Where("valid_until <= ?", now).Find(&expired).Error; err != nil || len(expired) != 1 {
+		t.Fatalf("expired grants = %d, %v", len(expired), err)
+	}
+	// 两个副本读到同一批到期授予，只有实际删除的一方记录历史
+	for i, want := range []int{1, 0} {
+		revoked, err := revokeUserRoles(db, expired, HistoryActionExpire, "system", "expired")
+		if err != nil || revoked != want {
+			t.Fatalf("sweep %d revoked = %d, %v, want %d", i, revoked, err, want)
+		}
+	}
+	history, _ := ListUserRoleHistory("alice", 0)
+	expires := 0
+	for _, h := range history {
+		if h.Action == HistoryActionExpire {
+			expires++
+		}
+	}
+	if expires != 1 {
+		t.Fatalf("expire history = %d, want 1", expires)
+	}
+}
+
+func TestGrantUserRoleKeepsPermanentGrant(t *testing.T) {
+	useTestStore(t)
+	if err := EnsureBusinessRole("MANAGER", "MANAGER", 1); err != nil {
+		t.Fatalf("EnsureBusinessRole() error = %v", err)
+	}
+	if err := GrantUserRole("alice", "MANAGER", GrantOptions{}); err != nil {
+		t.Fatalf("GrantUserRole(permanent) error = %v", err)
+	}
+	err := GrantUserRole("alice", "MANAGER", GrantOptions{ValidUntil: time.Now().Add(time.Hour)})
+	if !errors.Is(err, errPermanentGrant) {
+		t.Fatalf("GrantUserRole(temporary over permanent) error = %v", err)
+	}
+	grants, _ := ListUserRoleGrants("alice")
+	if len(grants) != 1 || grants[0].Temporary() {
+		t.Fatalf("permanent grant should be kept, got %+v", grants)
+	}
+}
+
+func TestListRolesKeywordIsLiteral(t *testing.T) {
+	useTestStore(t)
+	for code, name := range map[string]string{"OPS_ADMIN": "运维管理", "OPSXADMIN": "运维100%", "AUDITOR": "审计100"} {
+		if err := EnsureBusinessRole(code, name, 1); err != nil {
+			t.Fatalf("EnsureBusinessRole(%s) error = %v", code, err)
+		}
+	}
+	for keyword, want := range map[string]string{"S_A": "OPS_ADMIN", "100%": "OPSXADMIN"} {
+		roles, total, err := ListRoles(RoleQuery{Keyword: keyword})
+		if err != nil || total != 1 || len(roles) != 1 || roles[0].Code != want {
+			t.Fatalf("ListRoles(%q) = %v, %d, %v, want only %s", keyword, roles, total, err, want)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goodbye-jack/go-common/config"
+	"github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/utils"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// 角色目录配置
+const (
+	ConfigKeyRoles             = "rbac.roles"                // 内联角色定义，元素字段同 RoleDefinition
+	ConfigKeyRolesFile         = "rbac.roles_file"           // 角色定义 YAML 文件(顶层 roles 列表)，在内联定义之后合并
+	ConfigKeyRolesReloadPeriod = "rbac.roles_reload_seconds" // 角色定义文件变更检查周期(秒)
+)
+
+// ErrInvalidRoleCatalog 角色定义校验失败
+var ErrInvalidRoleCatalog = errors.New("invalid rbac role catalog")
+
+// RoleDefinition 角色目录中的一项。持有角色即视为持有 Parents 及其上级的全部角色，
+// 因此路由要求某角色时其下级角色同样放行；Aliases 为 LDAP、工作流等外部来源的等价编码(不区分大小写)
+type RoleDefinition struct {
+	Code    string   `json:"code" yaml:"code" mapstructure:"code"`
+	Name    string   `json:"name" yaml:"name" mapstructure:"name"`
+	Parents []string `json:"parents,omitempty" yaml:"parents" mapstructure:"parents"`
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases" mapstructure:"aliases"`
+}
+
+// builtinRoles 未配置时的默认目录，与历史 RoleMapping 保持一致；配置的同名角色覆盖内置定义
+var builtinRoles = []RoleDefinition{
+	{Code: utils.UserAnonymous, Name: "匿名用户"},
+	{Code: utils.RoleAdministrator, Name: "管理员"},
+	{Code: utils.RoleDefault, Name: "默认角色"},
+	{Code: utils.RoleMuseum, Name: "博物馆"},
+	{Code: utils.RoleMuseumOffice, Name: "博物馆处", Parents: []string{utils.RoleMuseum}},
+	{Code: utils.RoleAppraisalStation, Name: "鉴定站"},
+}
+
+// RoleCatalog 校验通过的角色目录，创建后只读，重新加载时整体替换
+type RoleCatalog struct {
+	roles       []RoleDefinition
+	index       map[string]int
+	aliases     map[string]string   // 大写别名 -> 角色编码
+	ancestors   map[string][]string // 角色 -> 全部上级(不含自身)
+	descendants map[string][]string // 角色 -> 全部下级(不含自身)，按目录顺序
+}
+
+// NewRoleCatalog 校验并构建角色目录：编码不能为空或重复，别名不能与其他角色冲突，
+// 上级角色必须已定义且继承关系不能成环
+func NewRoleCatalog(defs ...RoleDefinition) (*RoleCatalog, error) {
+	c := &RoleCatalog{
+		index:       map[string]int{},
+		aliases:     map[string]string{},
+		ancestors:   map[string][]string{},
+		descendants: map[string][]string{},
+	}
+	for _, def := range defs {
+		def.Code = strings.TrimSpace(def.Code)
+		if def.Code == "" {
+			return nil, fmt.Errorf("%w: role code is empty", ErrInvalidRoleCatalog)
+		}
+		if _, ok := c.index[def.Code]; ok {
+			return nil, fmt.Errorf("%w: duplicate role %s", ErrInvalidRoleCatalog, def.Code)
+		}
+		def.Name = strings.TrimSpace(def.Name)
+		def.Parents = cleanCodes(def.Parents)
+		def.Aliases = cleanCodes(def.Aliases)
+		c.index[def.Code] = len(c.roles)
+		c.roles = append(c.roles, def)
+	}
+	for _, def := range c.roles {
+		for _, alias := range def.Aliases {
+			key := strings.ToUpper(alias)
+			if owner, ok := c.aliases[key]; ok && owner != def.Code {
+				return nil, fmt.Errorf("%w: alias %s used by %s and %s", ErrInvalidRoleCatalog, alias, owner, def.Code)
+			}
+			if _, ok := c.index[alias]; ok && alias != def.Code {
+				return nil, fmt.Errorf("%w: alias %s of %s is another role", ErrInvalidRoleCatalog, alias, def.Code)
+			}
+			c.aliases[key] = def.Code
+		}
+		for _, parent := range def.Parents {
+			if _, ok := c.index[parent]; !ok {
+				return nil, fmt.Errorf("%w: role %s has unknown parent %s", ErrInvalidRoleCatalog, def.Code, parent)
+			}
+		}
+	}
+	for _, def := range c.roles {
+		ancestors, err := c.collectAncestors(def.Code, []string{def.Code})
+		if err != nil {
+			return nil, err
+		}
+		c.ancestors[def.Code] = ancestors
+	}
+	for _, def := range c.roles {
+		for _, ancestor := range c.ancestors[def.Code] {
+			c.descendants[ancestor] = append(c.descendants[ancestor], def.Code)
+		}
+	}
+	return c, nil
+}
+
+// collectAncestors 深度优先收集上级，path 为当前继承链，用于报告环
+func (c *RoleCatalog) collectAncestors(code string, path []string) ([]string, error) {
+	var ans []string
+	seen := map[string]bool{}
+	for _, parent := range c.roles[c.index[code]].Parents {
+		for _, visited := range path {
+			if visited == parent {
+				return nil, fmt.Errorf("%w: role cycle %s", ErrInvalidRoleCatalog, strings.Join(append(path, parent), " -> "))
+			}
+		}
+		upper, err := c.collectAncestors(parent, append(path, parent))
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range append([]string{parent}, upper...) {
+			if !seen[role] {
+				seen[role] = true
+				ans = append(ans, role)
+			}
+		}
+	}
+	return ans, nil
+}
+
+func cleanCodes(codes []string) []string {
+	var ans []string
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if code = strings.TrimSpace(code); code != "" && !seen[code] {
+			seen[code] = true
+			ans = append(ans, code)
+		}
+	}
+	return ans
+}
+
+// Roles 按定义顺序返回全部角色
+func (c *RoleCatalog) Roles() []RoleDefinition {
+	return append([]RoleDefinition{}, c.roles...)
+}
+
+// Resolve 将角色编码或别名解析为目录中的角色编码
+func (c *RoleCatalog) Resolve(code string) (string, bool) {
+	code = strings.TrimSpace(code)
+	if _, ok := c.index[code]; ok {
+		return code, true
+	}
+	if canonical, ok := c.aliases[strings.ToUpper(code)]; ok {
+		return canonical, true
+	}
+	return code, false
+}
+
+// Aliases 返回 大写别名 -> 角色编码 映射
+func (c *RoleCatalog) Aliases() map[string]string {
+	ans := make(map[string]string, len(c.aliases))
+	for alias, code := range c.aliases {
+		ans[alias] = code
+	}
+	return ans
+}
+
+// Ancestors 返回角色的全部上级(不含自身)
+func (c *RoleCatalog) Ancestors(code string) []string {
+	code, _ = c.Resolve(code)
+	return append([]string{}, c.ancestors[code]...)
+}
+
+// HasRole 持有 userRole 是否满足 requiredRole：两者解析别名后相同，或 requiredRole 是 userRole 的上级
+func (c *RoleCatalog) HasRole(userRole, requiredRole string) bool {
+	user, _ := c.Resolve(userRole)
+	required, _ := c.Resolve(requiredRole)
+	if user == required {
+		return true
+	}
+	for _, ancestor := range c.ancestors[user] {
+		if ancestor == required {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteRoles 路由要求 role 时应授权的角色：role 本身及其全部下级；role 为空(utils.RoleIdle)时为全部角色。
+// role 不在目录中时返回 false
+func (c *RoleCatalog) RouteRoles(role string) ([]string, bool) {
+	if strings.TrimSpace(role) == utils.RoleIdle {
+		ans := make([]string, 0, len(c.roles))
+		for _, def := range c.roles {
+			ans = append(ans, def.Code)
+		}
+		return ans, true
+	}
+	code, ok := c.Resolve(role)
+	if !ok {
+		return nil, false
+	}
+	return append([]string{code}, c.descendants[code]...), true
+}
+
+// ExpandRoles 对路由声明的角色列表逐个展开下级并去重，不在目录中的角色原样保留
+func (c *RoleCatalog) ExpandRoles(roles []string) []string {
+	var ans []string
+	seen := map[string]bool{}
+	for _, role := range roles {
+		expanded, ok := c.RouteRoles(role)
+		if !ok || strings.TrimSpace(role) == utils.RoleIdle {
+			expanded = []string{role}
+		}
+		for _, code := range expanded {
+			if !seen[code] {
+				seen[code] = true
+				ans = append(ans, code)
+			}
+		}
+	}
+	return ans
+}
+
+var currentCatalog atomic.Pointer[RoleCatalog]
+
+func init() {
+	catalog, err := NewRoleCatalog(builtinRoles...)
+	if err != nil {
+		panic(err)
+	}
+	currentCatalog.Store(catalog)
+}
+
+// DefaultRoleCatalog 返回当前生效的角色目录，是 HasRole、路由默认角色与工作流角色别名的唯一来源
+func DefaultRoleCatalog() *RoleCatalog {
+	return currentCatalog.Load()
+}
+
+var (
+	catalogHooksMu sync.RWMutex
+	catalogHooks   []func(*RoleCatalog)
+)
+
+// OnRoleCatalogChange 注册角色目录替换后的回调(热加载、InitRoleMapping 等)，
+// 用于重新计算由目录派生的数据，如路由默认角色、RBAC 策略与工作流角色别名
+func OnRoleCatalogChange(fn func(*RoleCatalog)) {
+	if fn == nil {
+		return
+	}
+	catalogHooksMu.Lock()
+	defer catalogHooksMu.Unlock()
+	catalogHooks = append(catalogHooks, fn)
+}
+
+// SetRoleCatalog 替换当前角色目录并通知 OnRoleCatalogChange 注册的回调
+func SetRoleCatalog(catalog *RoleCatalog) {
+	if catalog == nil {
+		return
+	}
+	currentCatalog.Store(catalog)
+	catalogHooksMu.RLock()
+	hooks := append([]func(*RoleCatalog){}, catalogHooks...)
+	catalogHooksMu.RUnlock()
+	for _, hook := range hooks {
+		hook(catalog)
+	}
+}
+
+// RoleCatalogConfigured 是否配置了 rbac.roles 或 rbac.roles_file
+func RoleCatalogConfigured() bool {
+	return viper.IsSet(ConfigKeyRoles) || strings.TrimSpace(config.GetConfigString(ConfigKeyRolesFile)) != ""
+}
+
+// LoadRoleCatalogFromConfig 按 内置角色 -> rbac.roles -> rbac.roles_file 的顺序合并角色定义并校验，
+// 同名角色以后者为准；校验失败时返回错误且不替换当前目录
+func LoadRoleCatalogFromConfig() (*RoleCatalog, error) {
+	defs := append([]RoleDefinition{}, builtinRoles...)
+	var inline []RoleDefinition
+	if err := viper.UnmarshalKey(ConfigKeyRoles, &inline); err != nil {
+		return nil, err
+	}
+	defs = mergeRoleDefinitions(defs, inline)
+	if file := strings.TrimSpace(config.GetConfigString(ConfigKeyRolesFile)); file != "" {
+		fromFile, err := readRoleFile(file)
+		if err != nil {
+			return nil, err
+		}
+		defs = mergeRoleDefinitions(defs, fromFile)
+	}
+	catalog, err := NewRoleCatalog(defs...)
+	if err != nil {
+		return nil, err
+	}
+	SetRoleCatalog(catalog)
+	return catalog, nil
+}
+
+func readRoleFile(file string) ([]RoleDefinition, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Roles []RoleDefinition `yaml:"roles"`
+	}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("%w: parse %s: %v", ErrInvalidRoleCatalog, file, err)
+	}
+	return doc.Roles, nil
+}
+
+func mergeRoleDefinitions(base, overrides []RoleDefinition) []RoleDefinition {
+	index := map[string]int{}
+	for i, def := range base {
+		index[strings.TrimSpace(def.Code)] = i
+	}
+	for _, def := range overrides {
+		if i, ok := index[strings.TrimSpace(def.Code)]; ok {
+			base[i] = def
+			continue
+		}
+		index[strings.TrimSpace(def.Code)] = len(base)
+		base = append(base, def)
+	}
+	return base
+}
+
+var catalogWatchOnce sync.Once
+
+// WatchRoleCatalog 按 interval 检查 rbac.roles_file 的修改时间，变化时重新加载；
+// 新定义校验失败时保留当前目录并记录错误。加载成功后通过 OnRoleCatalogChange 通知，
+// HTTPServer 据此重新计算路由默认角色并同步 RBAC 策略，工作流角色别名在下次归一时生效
+func WatchRoleCatalog(ctx context.Context, interval time.Duration) {
+	file := strings.TrimSpace(config.GetConfigString(ConfigKeyRolesFile))
+	if interval <= 0 || file == "" {
+		return
+	}
+	modTime := func() time.Time {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+	go func() {
+		last := modTime()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current := modTime()
+				if current.Equal(last) {
+					continue
+				}
+				last = current
+				catalog, err := LoadRoleCatalogFromConfig()
+				if err != nil {
+					log.Errorf("RBAC角色目录重新加载失败，继续使用当前目录: %v", err)
+					continue
+				}
+				log.Infof("RBAC角色目录已重新加载, roles=%d", len(catalog.roles))
+			}
+		}
+	}()
+}
+
+// StartRoleCatalogFromConfig 加载角色目录，配置了 rbac.roles_file 与 rbac.roles_reload_seconds 时启动热加载，
+// 重复调用只生效一次
+func StartRoleCatalogFromConfig(ctx context.Context) error {
+	catalog, err := LoadRoleCatalogFromConfig()
+	if err != nil {
+		return err
+	}
+	log.Infof("RBAC角色目录已加载, roles=%d", len(catalog.roles))
+	if seconds := config.GetConfigInt(ConfigKeyRolesReloadPeriod); seconds > 0 {
+		catalogWatchOnce.Do(func() {
+			WatchRoleCatalog(ctx, time.Duration(seconds)*time.Second)
+		})
+	}
+	return nil
+}
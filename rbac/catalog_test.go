@@ -0,0 +1,110 @@
+package rbac
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goodbye-jack/go-common/utils"
+	"github.com/spf13/viper"
+)
+
+func TestRoleCatalogValidation(t *testing.T) {
+	cases := map[string][]RoleDefinition{
+		"empty code":     {{Code: " "}},
+		"duplicate":      {{Code: "A"}, {Code: "A"}},
+		"unknown parent": {{Code: "A", Parents: []string{"B"}}},
+		"cycle":          {{Code: "A", Parents: []string{"C"}}, {Code: "B", Parents: []string{"A"}}, {Code: "C", Parents: []string{"B"}}},
+		"alias conflict": {{Code: "A", Aliases: []string{"x"}}, {Code: "B", Aliases: []string{"X"}}},
+		"alias is role":  {{Code: "A", Aliases: []string{"B"}}, {Code: "B"}},
+	}
+	for name, defs := range cases {
+		if _, err := NewRoleCatalog(defs...); !errors.Is(err, ErrInvalidRoleCatalog) {
+			t.Errorf("%s: NewRoleCatalog() error = %v", name, err)
+		}
+	}
+	_, err := NewRoleCatalog(cases["cycle"]...)
+	if err == nil || !strings.Contains(err.Error(), "A -> C -> B -> A") {
+		t.Errorf("cycle error should show the path, got %v", err)
+	}
+}
+
+func TestRoleCatalogHierarchy(t *testing.T) {
+	catalog, err := NewRoleCatalog(
+		RoleDefinition{Code: "ADMIN"},
+		RoleDefinition{Code: "MANAGER", Parents: []string{"STAFF"}, Aliases: []string{"ldap_manager"}},
+		RoleDefinition{Code: "STAFF"},
+		RoleDefinition{Code: "LEAD", Parents: []string{"MANAGER", "STAFF"}},
+	)
+	if err != nil {
+		t.Fatalf("NewRoleCatalog() error = %v", err)
+	}
+	checks := []struct {
+		user, required string
+		want           bool
+	}{
+		{"LEAD", "STAFF", true},
+		{"LEAD", "MANAGER", true},
+		{"LDAP_MANAGER", "STAFF", true},
+		{"STAFF", "MANAGER", false},
+		{"ADMIN", "STAFF", false},
+		{"OTHER", "OTHER", true},
+	}
+	for _, tc := range checks {
+		if got := catalog.HasRole(tc.user, tc.required); got != tc.want {
+			t.Errorf("HasRole(%s, %s) = %v, want %v", tc.user, tc.required, got, tc.want)
+		}
+	}
+	if roles, ok := catalog.RouteRoles("STAFF"); !ok || strings.Join(roles, ",") != "STAFF,MANAGER,LEAD" {
+		t.Errorf("RouteRoles(STAFF) = %v, %v", roles, ok)
+	}
+	if roles, _ := catalog.RouteRoles(utils.RoleIdle); len(roles) != 4 {
+		t.Errorf("RouteRoles(idle) = %v", roles)
+	}
+	if _, ok := catalog.RouteRoles("UNKNOWN"); ok {
+		t.Errorf("RouteRoles(UNKNOWN) should be unknown")
+	}
+	if roles := catalog.ExpandRoles([]string{"MANAGER", "EXTERNAL", "LEAD"}); strings.Join(roles, ",") != "MANAGER,LEAD,EXTERNAL" {
+		t.Errorf("ExpandRoles() = %v", roles)
+	}
+}
+
+func TestLoadRoleCatalogFromConfig(t *testing.T) {
+	original := DefaultRoleCatalog()
+	defer SetRoleCatalog(original)
+	file := filepath.Join(t.TempDir(), "roles.yaml")
+	if err := os.WriteFile(file, []byte("roles:\n  - code: AUDITOR\n    parents: [REVIEWER]\n    aliases: [wf_auditor]\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	viper.Set(ConfigKeyRoles, []map[string]any{{"code": "REVIEWER", "name": "审核员"}})
+	viper.Set(ConfigKeyRolesFile, file)
+	defer func() {
+		viper.Set(ConfigKeyRoles, nil)
+		viper.Set(ConfigKeyRolesFile, "")
+	}()
+
+	catalog, err := LoadRoleCatalogFromConfig()
+	if err != nil {
+		t.Fatalf("LoadRoleCatalogFromConfig() error = %v", err)
+	}
+	if DefaultRoleCatalog() != catalog || !HasRole("WF_AUDITOR", "REVIEWER") || !HasRole(utils.RoleMuseumOffice, utils.RoleMuseum) {
+		t.Fatalf("catalog should merge builtin, inline and file roles")
+	}
+
+	if err := os.WriteFile(file, []byte("roles:\n  - code: AUDITOR\n    parents: [MISSING]\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := LoadRoleCatalogFromConfig(); !errors.Is(err, ErrInvalidRoleCatalog) {
+		t.Fatalf("invalid file error = %v", err)
+	}
+	if DefaultRoleCatalog() != catalog {
+		t.Fatalf("invalid definitions should keep the current catalog")
+	}
+
+	InitRoleMapping(RoleMappingConfig{RoleMapping: map[string]string{"SENIOR_AUDITOR": "AUDITOR"}})
+	if !HasRole("SENIOR_AUDITOR", "REVIEWER") {
+		t.Fatalf("legacy mapping should become a parent relation")
+	}
+}
@@ -0,0 +1,226 @@
+package rbac
+
+import (
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/goodbye-jack/go-common/log"
+)
+
+// RoleChange what-if 模拟的角色变更，只作用于本次解释，不写入存储
+type RoleChange struct {
+	Grant  []string `json:"grant"`  // 模拟在请求租户内新增授予的角色
+	Revoke []string `json:"revoke"` // 模拟撤销的直接授予(请求租户与全局)
+}
+
+func (rc *RoleChange) empty() bool {
+	return rc == nil || (len(rc.Grant) == 0 && len(rc.Revoke) == 0)
+}
+
+// RolePath 主体生效的一个角色及其继承路径，Path 从主体开始、以该角色结束
+type RolePath struct {
+	Role string   `json:"role"`
+	Path []string `json:"path"`
+}
+
+// Explanation 一次鉴权的判定过程
+type Explanation struct {
+	Subject string `json:"subject"`
+	Tenant  string `json:"tenant"`
+	Dom     string `json:"dom"`
+	Obj     string `json:"obj"`
+	Act     string `json:"act"`
+	Allowed bool   `json:"allowed"`
+	// MatchedPolicy 命中的策略行 (sub, dom, obj, act[, cond])，拒绝时为空
+	MatchedPolicy []string `json:"matched_policy,omitempty"`
+	// Roles 主体在租户内生效的全部角色(含继承)及继承路径
+	Roles []RolePath `json:"roles"`
+	// GrantingPolicies 可放行该请求的全部策略行，拒绝时用于判断缺少哪条授予
+	GrantingPolicies [][]string `json:"granting_policies"`
+	// MissingRoles 拒绝时可放行该请求但主体未持有的角色
+	MissingRoles []string `json:"missing_roles,omitempty"`
+	// Conditions 主体已持有角色的条件策略求值结果
+	Conditions []ConditionResult `json:"conditions,omitempty"`
+	Simulation *RoleChange       `json:"simulation,omitempty"`
+}
+
+// ConditionResult 一条条件策略的求值结果
+type ConditionResult struct {
+	Policy []string `json:"policy"`
+	Result bool     `json:"result"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// Explain 解释一次鉴权：命中或缺失的策略行、主体角色及继承路径。
+// change 非空时在策略副本上模拟角色变更后再判定，不影响实际授权
+func (c *RbacClient) Explain(r *Req, change *RoleChange) (*Explanation, error) {
+	e, err := c.enforcer()
+	if err != nil {
+		return nil, err
+	}
+	if !change.empty() {
+		if e, err = simulateEnforcer(e, r, change); err != nil {
+			log.Errorf("Explain(%v) simulate error, %v", r.ToArr(), err)
+			return nil, err
+		}
+	}
+	allowed, matched, err := e.EnforceEx(r.sub, r.ten, r.dom, r.obj, r.act)
+	var attrs Attributes
+	if err == nil && !allowed && hasConditionalPolicies(e) {
+		attrs = r.attributes()
+		allowed, matched, err = e.EnforceEx(conditionalContext, r.sub, r.ten, r.dom, r.obj, r.act, attrs)
+	}
+	if err != nil {
+		log.Errorf("Explain(%v) error, %v", r.ToArr(), err)
+		return nil, err
+	}
+	ans := &Explanation{
+		Subject:          r.sub,
+		Tenant:           r.ten,
+		Dom:              r.dom,
+		Obj:              r.obj,
+		Act:              r.act,
+		Allowed:          allowed,
+		MatchedPolicy:    matched,
+		GrantingPolicies: [][]string{},
+	}
+	if !change.empty() {
+		ans.Simulation = change
+	}
+	grouping, err := e.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+	ans.Roles = rolePaths(grouping, r.sub, TenantDomain(r.ten))
+	policies, err := grantingPolicies(e, ptypeAction, r)
+	if err != nil {
+		return nil, err
+	}
+	conditional, err := grantingPolicies(e, ptypeConditionalAction, r)
+	if err != nil {
+		return nil, err
+	}
+	held := map[string]bool{r.sub: true}
+	for _, rp := range ans.Roles {
+		held[rp.Role] = true
+	}
+	for _, p := range append(policies, conditional...) {
+		ans.GrantingPolicies = append(ans.GrantingPolicies, p)
+		if !allowed && len(p) > 0 && !held[p[0]] {
+			ans.MissingRoles = append(ans.MissingRoles, p[0])
+		}
+	}
+	for _, p := range conditional {
+		if len(p) < 5 || !held[p[0]] {
+			continue
+		}
+		if attrs == nil {
+			attrs = r.attributes()
+		}
+		result := ConditionResult{Policy: p}
+		if result.Result, err = EvalCondition(p[4], attrs); err != nil {
+			result.Error = err.Error()
+		}
+		ans.Conditions = append(ans.Conditions, result)
+	}
+	return ans, nil
+}
+
+// grantingPolicies 筛选 dom/obj/act(含通配)与请求匹配的策略行
+func grantingPolicies(e *casbin.Enforcer, ptype string, r *Req) ([][]string, error) {
+	rules, err := e.GetNamedPolicy(ptype)
+	if err != nil {
+		return nil, err
+	}
+	ans := [][]string{}
+	for _, rule := range rules {
+		if policyMatchesReq(rule, r) {
+			ans = append(ans, rule)
+		}
+	}
+	return ans, nil
+}
+
+// simulateEnforcer 复制当前策略到不带存储的临时 Enforcer，并在其上应用角色变更
+func simulateEnforcer(src *casbin.Enforcer, r *Req, change *RoleChange) (*casbin.Enforcer, error) {
+	m, err := model.NewModelFromString(text)
+	if err != nil {
+		return nil, err
+	}
+	sim, err := casbin.NewEnforcer(m)
+	if err != nil {
+		return nil, err
+	}
+	sim.EnableAutoNotifyWatcher(false)
+	registerFunctions(sim)
+	policies, err := src.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+	conditional, err := src.GetNamedPolicy(ptypeConditionalAction)
+	if err != nil {
+		return nil, err
+	}
+	grouping, err := src.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if len(policies) > 0 {
+		if _, err := sim.AddPolicies(policies); err != nil {
+			return nil, err
+		}
+	}
+	if len(conditional) > 0 {
+		if _, err := sim.AddNamedPolicies(ptypeConditionalAction, conditional); err != nil {
+			return nil, err
+		}
+	}
+	domain := TenantDomain(r.ten)
+	revoked := map[string]bool{}
+	for _, role := range change.Revoke {
+		revoked[role] = true
+	}
+	kept := make([][]string, 0, len(grouping)+len(change.Grant))
+	for _, rule := range grouping {
+		if len(rule) >= 3 && rule[0] == r.sub && revoked[rule[1]] && matchTenant(domain, rule[2]) {
+			continue
+		}
+		kept = append(kept, rule)
+	}
+	for _, role := range change.Grant {
+		kept = append(kept, []string{r.sub, role, domain})
+	}
+	if len(kept) > 0 {
+		if _, err := sim.AddGroupingPoliciesEx(kept); err != nil {
+			return nil, err
+		}
+	}
+	return sim, nil
+}
+
+// rolePaths 广度优先遍历分组策略，返回 sub 在租户内可达的角色及最短继承路径
+func rolePaths(grouping [][]string, sub, domain string) []RolePath {
+	edges := map[string][]string{}
+	for _, rule := range grouping {
+		if len(rule) < 3 || !matchTenant(domain, rule[2]) {
+			continue
+		}
+		edges[rule[0]] = append(edges[rule[0]], rule[1])
+	}
+	paths := []RolePath{}
+	visited := map[string]bool{sub: true}
+	queue := [][]string{{sub}}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		for _, role := range edges[path[len(path)-1]] {
+			if visited[role] {
+				continue
+			}
+			visited[role] = true
+			next := append(append([]string{}, path...), role)
+			paths = append(paths, RolePath{Role: role, Path: next})
+			queue = append(queue, next)
+		}
+	}
+	return paths
+}
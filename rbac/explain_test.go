@@ -0,0 +1,65 @@
+package rbac
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExplainAndSimulate(t *testing.T) {
+	client, err := NewRbacClientWithOptions(Options{AdapterType: AdapterMemory})
+	if err != nil {
+		t.Fatalf("NewRbacClientWithOptions() error = %v", err)
+	}
+	if err := client.AddActionPolicies([]Policy{
+		NewActionPolicy("svc", "EDITOR", "/orders", "POST"),
+		NewActionPolicy("svc", "AUDITOR", "/orders", "POST"),
+	}); err != nil {
+		t.Fatalf("AddActionPolicies() error = %v", err)
+	}
+	if err := client.AddGroupingPolicy("EDITOR", "VIEWER"); err != nil {
+		t.Fatalf("AddGroupingPolicy() error = %v", err)
+	}
+	if err := client.AddTenantGroupingPolicy("alice", "EDITOR", "t1"); err != nil {
+		t.Fatalf("AddTenantGroupingPolicy() error = %v", err)
+	}
+
+	ans, err := client.Explain(NewTenantReq("alice", "t1", "svc", "/orders", "POST"), nil)
+	if err != nil || !ans.Allowed {
+		t.Fatalf("Explain() = %+v, %v, want allowed", ans, err)
+	}
+	if !reflect.DeepEqual(ans.MatchedPolicy, []string{"EDITOR", "svc", "/orders", "POST"}) {
+		t.Fatalf("MatchedPolicy = %v", ans.MatchedPolicy)
+	}
+	want := []RolePath{
+		{Role: "EDITOR", Path: []string{"alice", "EDITOR"}},
+		{Role: "VIEWER", Path: []string{"alice", "EDITOR", "VIEWER"}},
+	}
+	if !reflect.DeepEqual(ans.Roles, want) {
+		t.Fatalf("Roles = %+v, want %+v", ans.Roles, want)
+	}
+
+	// 其他租户内 alice 没有授予
+	ans, err = client.Explain(NewTenantReq("alice", "t2", "svc", "/orders", "POST"), nil)
+	if err != nil || ans.Allowed || len(ans.Roles) != 0 {
+		t.Fatalf("Explain(t2) = %+v, %v", ans, err)
+	}
+	if !reflect.DeepEqual(ans.MissingRoles, []string{"EDITOR", "AUDITOR"}) && !reflect.DeepEqual(ans.MissingRoles, []string{"AUDITOR", "EDITOR"}) {
+		t.Fatalf("MissingRoles = %v", ans.MissingRoles)
+	}
+
+	// what-if：撤销 EDITOR 后拒绝，授予 AUDITOR 后放行，均不影响实际策略
+	ans, err = client.Explain(NewTenantReq("alice", "t1", "svc", "/orders", "POST"), &RoleChange{Revoke: []string{"EDITOR"}})
+	if err != nil || ans.Allowed || ans.Simulation == nil {
+		t.Fatalf("Explain(revoke) = %+v, %v", ans, err)
+	}
+	ans, err = client.Explain(NewTenantReq("bob", "t1", "svc", "/orders", "POST"), &RoleChange{Grant: []string{"AUDITOR"}})
+	if err != nil || !ans.Allowed {
+		t.Fatalf("Explain(grant) = %+v, %v", ans, err)
+	}
+	if ok, _ := client.Enforce(NewTenantReq("alice", "t1", "svc", "/orders", "POST")); !ok {
+		t.Fatalf("simulation should not change real policies")
+	}
+	if ok, _ := client.Enforce(NewTenantReq("bob", "t1", "svc", "/orders", "POST")); ok {
+		t.Fatalf("simulated grant leaked into real policies")
+	}
+}
@@ -0,0 +1,32 @@
+package rbac
+
+import (
+	"embed"
+
+	"github.com/goodbye-jack/go-common/orm/migrate"
+)
+
+const (
+	// MigrationModule 角色、继承与用户授权表的迁移模块
+	MigrationModule = "rbac"
+	// CasbinMigrationModule casbin_rule 表的迁移模块
+	CasbinMigrationModule = "rbac_casbin"
+)
+
+// migrationFS 按方言分目录的 SQL 迁移，DM/人大金仓等方言差异在各自目录中给出
+//
+//go:embed migrations
+var migrationFS embed.FS
+
+// 库内置表以 AutoMigrate 基线版本发布：已有库执行时只补齐缺失的表/列并记录版本，
+// 后续结构调整以新版本追加，DM/人大金仓等可通过 migrate.DryRun 预先评审 SQL
+func init() {
+	migrate.Register(MigrationModule, migrate.List{
+		{Version: "202610180001", Name: "create_rbac_store_tables", Models: storeModels()},
+	}, migrate.FS(migrationFS, "migrations"))
+	migrate.Register(CasbinMigrationModule, migrate.List{
+		{Version: "202610180001", Name: "create_casbin_rule", Models: []any{&CasbinRule{}}},
+		// 引入租户列之前的两列分组策略 (sub, role) 改写为全局授予 (sub, role, *)；Oracle 中空串即 NULL
+		{Version: "202610190001", Name: "rewrite_legacy_grouping_rules", Up: "UPDATE rbac_casbin_rules SET v2 = '" + GlobalTenant + "' WHERE ptype = 'g' AND (v2 IS NULL OR v2 = '')"},
+	})
+}
@@ -0,0 +1,2 @@
+-- 引入租户列之前的 (uid, role_code) 唯一索引，保留会导致同一角色无法授予到多个租户
+DROP INDEX IF EXISTS uniq_user_role;
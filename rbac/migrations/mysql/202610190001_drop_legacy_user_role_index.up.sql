@@ -0,0 +1,8 @@
+-- 引入租户列之前的 (uid, role_code) 唯一索引，保留会导致同一角色无法授予到多个租户；
+-- MySQL 不支持 DROP INDEX IF EXISTS，按数据字典判断后执行
+SET @rbac_ddl = (SELECT IF(COUNT(*) > 0, 'DROP INDEX uniq_user_role ON rbac_user_roles', 'SELECT 1')
+    FROM information_schema.statistics
+    WHERE table_schema = DATABASE() AND table_name = 'rbac_user_roles' AND index_name = 'uniq_user_role');
+PREPARE rbac_stmt FROM @rbac_ddl;
+EXECUTE rbac_stmt;
+DEALLOCATE PREPARE rbac_stmt;
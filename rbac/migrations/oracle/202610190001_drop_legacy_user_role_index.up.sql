@@ -0,0 +1,13 @@
+-- 引入租户列之前的 (uid, role_code) 唯一索引，保留会导致同一角色无法授予到多个租户；
+-- 索引由 GORM 以带引号的小写名创建，按数据字典取实际名称后删除
+-- +migrate StatementBegin
+DECLARE
+  idx VARCHAR2(128);
+BEGIN
+  SELECT INDEX_NAME INTO idx FROM USER_INDEXES
+   WHERE UPPER(TABLE_NAME) = 'RBAC_USER_ROLES' AND UPPER(INDEX_NAME) = 'UNIQ_USER_ROLE' AND ROWNUM = 1;
+  EXECUTE IMMEDIATE 'DROP INDEX "' || idx || '"';
+EXCEPTION
+  WHEN NO_DATA_FOUND THEN NULL;
+END;
+-- +migrate StatementEnd
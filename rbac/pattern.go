@@ -0,0 +1,193 @@
+package rbac
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// 策略中的通配写法
+const (
+	WildcardAll       = "*"   // dom/obj/act 为 * 时匹配全部
+	WildcardAnyMethod = "ANY" // act 为 ANY 时匹配全部方法，与 * 等价
+	MethodSeparator   = "|"   // act 方法列表分隔符，如 GET|POST
+	subtreeSuffix     = "/*"  // obj 以 /* 结尾时匹配该前缀本身及其下全部路径
+	anySegments       = "**"  // obj 中的 ** 段匹配任意多级路径(含零级)
+)
+
+// NewPrefixPolicy 授权角色访问服务内 prefix 下的全部路由，methods 为空时匹配全部方法
+func NewPrefixPolicy(dom, sub, prefix string, methods ...string) Policy {
+	return &ActionPolicy{
+		Dom: dom,
+		Sub: sub,
+		Obj: strings.TrimSuffix(strings.TrimSpace(prefix), "/") + subtreeSuffix,
+		Act: joinMethods(methods),
+	}
+}
+
+func joinMethods(methods []string) string {
+	list := make([]string, 0, len(methods))
+	for _, method := range methods {
+		if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+			list = append(list, method)
+		}
+	}
+	if len(list) == 0 {
+		return WildcardAll
+	}
+	return strings.Join(list, MethodSeparator)
+}
+
+// IsPatternPolicy 策略的 dom/obj/act 含通配或方法列表。路由注册只会生成精确策略，
+// 通配策略视为人工授权，DeletePoliciesByService 默认保留
+func (p ActionPolicy) IsPatternPolicy() bool {
+	return isPattern(p.Dom) || isObjectPattern(p.Obj) || p.Act == WildcardAll ||
+		strings.EqualFold(p.Act, WildcardAnyMethod) || strings.Contains(p.Act, MethodSeparator)
+}
+
+func isPattern(value string) bool {
+	return value == WildcardAll
+}
+
+// isObjectPattern gin 的 :param 与 *catchall 是路由模板本身，不算通配
+func isObjectPattern(obj string) bool {
+	if obj == WildcardAll || strings.HasSuffix(obj, subtreeSuffix) {
+		return true
+	}
+	for _, seg := range strings.Split(obj, "/") {
+		if seg == WildcardAll || seg == anySegments || strings.ContainsAny(seg, "?[") || strings.Contains(seg[min(1, len(seg)):], "*") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain casbin 匹配器函数 domMatch(r.dom, p.dom)
+func matchDomain(args ...interface{}) (interface{}, error) {
+	req, pattern, err := stringArgs("domMatch", args)
+	if err != nil {
+		return false, err
+	}
+	return pattern == WildcardAll || req == pattern, nil
+}
+
+// matchAction casbin 匹配器函数 actMatch(r.act, p.act)，支持 * / ANY 与 GET|POST 方法列表
+func matchAction(args ...interface{}) (interface{}, error) {
+	req, pattern, err := stringArgs("actMatch", args)
+	if err != nil {
+		return false, err
+	}
+	return actionMatches(req, pattern), nil
+}
+
+func actionMatches(req, pattern string) bool {
+	if req == pattern || pattern == WildcardAll || strings.EqualFold(pattern, WildcardAnyMethod) {
+		return true
+	}
+	if !strings.Contains(pattern, MethodSeparator) {
+		return strings.EqualFold(req, pattern)
+	}
+	for _, method := range strings.Split(pattern, MethodSeparator) {
+		if strings.EqualFold(req, strings.TrimSpace(method)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchObject casbin 匹配器函数 objMatch(r.obj, p.obj)
+func matchObject(args ...interface{}) (interface{}, error) {
+	req, pattern, err := stringArgs("objMatch", args)
+	if err != nil {
+		return false, err
+	}
+	return objectMatches(req, pattern), nil
+}
+
+// objectMatches 按路径段匹配，r.obj 通常是 gin 路由模板(c.FullPath())：
+//
+//	/orders/*        /orders 本身及其下全部路径
+//	/orders/**/items ** 匹配任意多级
+//	/orders/:id      :id 与 {id} 只匹配参数段，如模板 /orders/:oid；不匹配 /orders/export
+//	                 这类静态路由，避免参数路由的授权覆盖同级的静态路由
+//	/orders/v*       段内 * ? [] 按 path.Match 匹配单级
+func objectMatches(req, pattern string) bool {
+	if req == pattern || pattern == WildcardAll {
+		return true
+	}
+	if strings.HasSuffix(pattern, subtreeSuffix) {
+		prefix := strings.TrimSuffix(pattern, subtreeSuffix)
+		if req == prefix || (prefix == "" && strings.HasPrefix(req, "/")) {
+			return true
+		}
+		pattern = prefix + "/" + anySegments
+	}
+	return segmentsMatch(strings.Split(req, "/"), strings.Split(pattern, "/"))
+}
+
+func segmentsMatch(req, pattern []string) bool {
+	for len(pattern) > 0 {
+		seg := pattern[0]
+		if seg == anySegments {
+			for i := len(req); i >= 0; i-- {
+				if segmentsMatch(req[i:], pattern[1:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(req) == 0 || !segmentMatches(req[0], seg) {
+			return false
+		}
+		req, pattern = req[1:], pattern[1:]
+	}
+	return len(req) == 0
+}
+
+func segmentMatches(req, pattern string) bool {
+	if req == pattern {
+		return true
+	}
+	if isParamSegment(pattern) {
+		return isParamSegment(req)
+	}
+	if len(pattern) > 1 && pattern[0] == '*' && !strings.ContainsAny(pattern[1:], "*?[") {
+		return false // gin 的 *catchall 只与同名模板相等
+	}
+	ok, err := path.Match(pattern, req)
+	return err == nil && ok
+}
+
+func isParamSegment(seg string) bool {
+	return (strings.HasPrefix(seg, ":") && len(seg) > 1) ||
+		(strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") && len(seg) > 2)
+}
+
+func stringArgs(name string, args []interface{}) (string, string, error) {
+	if len(args) != 2 {
+		return "", "", fmt.Errorf("%s expects 2 arguments, got %d", name, len(args))
+	}
+	req, _ := args[0].(string)
+	pattern, _ := args[1].(string)
+	return req, pattern, nil
+}
+
+// policyMatchesReq 与匹配器中 dom/obj/act 部分一致，用于解释时筛选可放行的策略行
+func policyMatchesReq(rule []string, r *Req) bool {
+	if len(rule) < 4 {
+		return false
+	}
+	return (rule[1] == WildcardAll || rule[1] == r.dom) && objectMatches(r.obj, rule[2]) && actionMatches(r.act, rule[3])
+}
+
+// registerFunctions 注册模型匹配器依赖的函数，构建与模拟用的 Enforcer 共用
+func registerFunctions(e *casbin.Enforcer) {
+	// 全局租户(*)下的授予对所有租户生效
+	e.AddNamedDomainMatchingFunc("g", "tenant", matchTenant)
+	e.AddFunction("domMatch", matchDomain)
+	e.AddFunction("objMatch", matchObject)
+	e.AddFunction("actMatch", matchAction)
+	e.AddFunction("abac", abacMatch)
+}
@@ -0,0 +1,159 @@
+package rbac
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestObjectAndActionPatterns(t *testing.T) {
+	objects := []struct {
+		req, pattern string
+		want         bool
+	}{
+		{"/api/v1/orders/:id", "/api/v1/orders/:id", true},
+		{"/api/v1/orders/:id", "/api/v1/orders/*", true},
+		{"/api/v1/orders", "/api/v1/orders/*", true},
+		{"/api/v1/orders/:id/items", "/api/v1/orders/*", true},
+		{"/api/v1/ordersx", "/api/v1/orders/*", false},
+		{"/api/v1/orders/:oid", "/api/v1/orders/{id}", true},
+		{"/api/v1/orders/export", "/api/v1/orders/:id", false}, // 参数段不覆盖静态路由
+		{"/api/v1/orders/:id/items", "/api/**/items", true},
+		{"/api/items", "/api/**/items", true},
+		{"/api/v2/orders", "/api/v*/orders", true},
+		{"/api/v2/orders/1", "/api/*/orders", false},
+		{"/static/*filepath", "/static/*filepath", true},
+		{"/static/a.js", "/static/*filepath", false},
+		{"/anything", "*", true},
+	}
+	for _, tc := range objects {
+		if got := objectMatches(tc.req, tc.pattern); got != tc.want {
+			t.Errorf("objectMatches(%q, %q) = %v, want %v", tc.req, tc.pattern, got, tc.want)
+		}
+	}
+	actions := []struct {
+		req, pattern string
+		want         bool
+	}{
+		{"GET", "GET", true},
+		{"GET", "*", true},
+		{"DELETE", "ANY", true},
+		{"POST", "GET|post", true},
+		{"PUT", "GET|POST", false},
+	}
+	for _, tc := range actions {
+		if got := actionMatches(tc.req, tc.pattern); got != tc.want {
+			t.Errorf("actionMatches(%q, %q) = %v, want %v", tc.req, tc.pattern, got, tc.want)
+		}
+	}
+	if !(ActionPolicy{Obj: "/orders/*", Act: "GET"}).IsPatternPolicy() || (ActionPolicy{Dom: "svc", Obj: "/orders/:id", Act: "GET"}).IsPatternPolicy() {
+		t.Errorf("IsPatternPolicy() mismatch")
+	}
+}
+
+func TestPrefixPoliciesSurviveServiceCleanup(t *testing.T) {
+	client, err := NewRbacClientWithOptions(Options{AdapterType: AdapterMemory})
+	if err != nil {
+		t.Fatalf("NewRbacClientWithOptions() error = %v", err)
+	}
+	if err := client.AddActionPolicies([]Policy{
+		NewActionPolicy("svc", "EDITOR", "/api/v1/orders/:id", "GET"),
+		NewPrefixPolicy("svc", "AUDITOR", "/api/v1/orders/", "GET", "HEAD"),
+		NewPrefixPolicy("*", "OPS", "/api/v1/health"),
+	}); err != nil {
+		t.Fatalf("AddActionPolicies() error = %v", err)
+	}
+	for sub, role := range map[string]string{"alice": "EDITOR", "bob": "AUDITOR", "carol": "OPS"} {
+		if err := client.AddGroupingPolicy(sub, role); err != nil {
+			t.Fatalf("AddGroupingPolicy() error = %v", err)
+		}
+	}
+	cases := []struct {
+		sub, dom, obj, act string
+		want               bool
+	}{
+		{"alice", "svc", "/api/v1/orders/:id", "GET", true},
+		{"alice", "svc", "/api/v1/orders/:id/items", "GET", false},
+		{"bob", "svc", "/api/v1/orders/:id/items", "GET", true},
+		{"bob", "svc", "/api/v1/orders/:id", "DELETE", false},
+		{"bob", "other", "/api/v1/orders/:id", "GET", false},
+		{"carol", "other", "/api/v1/health", "POST", true},
+	}
+	for _, tc := range cases {
+		if ok, err := client.Enforce(NewReq(tc.sub, tc.dom, tc.obj, tc.act)); err != nil || ok != tc.want {
+			t.Errorf("Enforce(%s %s %s %s) = %v, %v, want %v", tc.sub, tc.dom, tc.obj, tc.act, ok, err, tc.want)
+		}
+	}
+	ans, err := client.Explain(NewReq("bob", "svc", "/api/v1/orders/:id/items", "GET"), nil)
+	if err != nil || !ans.Allowed || len(ans.GrantingPolicies) != 1 {
+		t.Fatalf("Explain() = %+v, %v", ans, err)
+	}
+
+	for _, service := range []string{"", "*"} {
+		if err := client.DeletePoliciesByService(service); err == nil {
+			t.Fatalf("DeletePoliciesByService(%q) should be rejected", service)
+		}
+	}
+	if err := client.DeletePoliciesByService("svc"); err != nil {
+		t.Fatalf("DeletePoliciesByService() error = %v", err)
+	}
+	if ok, _ := client.Enforce(NewReq("alice", "svc", "/api/v1/orders/:id", "GET")); ok {
+		t.Fatalf("route policy should be removed")
+	}
+	if ok, _ := client.Enforce(NewReq("bob", "svc", "/api/v1/orders/:id", "GET")); !ok {
+		t.Fatalf("prefix policy should be kept")
+	}
+	if err := client.DeletePoliciesByService("svc", IncludePatternPolicies()); err != nil {
+		t.Fatalf("DeletePoliciesByService(IncludePatternPolicies) error = %v", err)
+	}
+	if ok, _ := client.Enforce(NewReq("bob", "svc", "/api/v1/orders/:id", "GET")); ok {
+		t.Fatalf("prefix policy should be removed with IncludePatternPolicies")
+	}
+}
+
+func TestDeclaredPatternPoliciesCleanedByService(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	client, err := NewRbacClientWithOptions(Options{AdapterType: AdapterGorm, DB: db})
+	if err != nil {
+		t.Fatalf("NewRbacClientWithOptions() error = %v", err)
+	}
+	if err := client.AddActionPolicies([]Policy{NewPrefixPolicy("svc", "OPS", "/api/v1/admin")}); err != nil {
+		t.Fatalf("AddActionPolicies() error = %v", err)
+	}
+	if err := client.AddDeclaredPatternPolicies([]Policy{NewPrefixPolicy("svc", "AUDITOR", "/api/v1/orders", "GET")}); err != nil {
+		t.Fatalf("AddDeclaredPatternPolicies() error = %v", err)
+	}
+	if err := client.AddDeclaredPatternPolicies([]Policy{NewConditionalActionPolicy("svc", "AUDITOR", "/api/v1/*", "GET", "env.hour > 9")}); err == nil {
+		t.Fatalf("AddDeclaredPatternPolicies should reject conditional policies")
+	}
+	for sub, role := range map[string]string{"bob": "AUDITOR", "carol": "OPS"} {
+		if err := client.AddGroupingPolicy(sub, role); err != nil {
+			t.Fatalf("AddGroupingPolicy() error = %v", err)
+		}
+	}
+	if ok, _ := client.Enforce(NewReq("bob", "svc", "/api/v1/orders/:id", "GET")); !ok {
+		t.Fatalf("declared prefix policy should allow")
+	}
+
+	// 标记随策略持久化，重启后仍能识别代码声明的前缀授权
+	reloaded, err := NewRbacClientWithOptions(Options{AdapterType: AdapterGorm, DB: db})
+	if err != nil {
+		t.Fatalf("reload error = %v", err)
+	}
+	if err := reloaded.DeletePoliciesByService("svc"); err != nil {
+		t.Fatalf("DeletePoliciesByService() error = %v", err)
+	}
+	if ok, _ := reloaded.Enforce(NewReq("bob", "svc", "/api/v1/orders/:id", "GET")); ok {
+		t.Fatalf("declared prefix policy should be removed with the service")
+	}
+	if ok, _ := reloaded.Enforce(NewReq("carol", "svc", "/api/v1/admin/users", "POST")); !ok {
+		t.Fatalf("manual prefix policy should be kept")
+	}
+	if tags, _ := reloaded.e.GetNamedPolicy(ptypeDeclaredPattern); len(tags) != 0 {
+		t.Fatalf("declared tags = %v, want removed", tags)
+	}
+}
@@ -4,151 +4,189 @@ import (
 	"errors"
 	"fmt"
 	"github.com/casbin/casbin/v2"
-	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/persist"
-	redisadapter "github.com/casbin/redis-adapter/v3"
-	rediswatcher "github.com/casbin/redis-watcher/v2"
 	"github.com/goodbye-jack/go-common/log"
-	"github.com/goodbye-jack/go-common/orm"
-	"github.com/redis/go-redis/v9"
 	"strings"
 	"sync"
 )
 
-type Req struct{ dom, sub, obj, act string }
+type Req struct {
+	dom, ten, sub, obj, act string
+	attrs                   func() Attributes // 条件策略求值时才加载
+}
 type Policy interface{ ToArr() []string }
-type ActionPolicy struct{ Dom, Sub, Obj, Act string }
+
+// ActionPolicy 角色在服务(Dom)内对接口(Obj)方法(Act)的授权，Cond 非空时为条件策略，
+// 仅当条件表达式对请求属性求值为 true 时生效
+type ActionPolicy struct{ Dom, Sub, Obj, Act, Cond string }
+
+// 策略类型：p 为普通授权，p2 为带条件的授权，p3 标记由服务代码声明的通配策略(不参与匹配)
+const (
+	ptypeAction            = "p"
+	ptypeConditionalAction = "p2"
+	ptypeDeclaredPattern   = "p3"
+)
+
+// Deprecated: TenantPolicy 从未参与鉴权，租户维度改由 RolePolicy.Tenant 表达
 type TenantPolicy struct{ ten, dom string }
-type RolePolicy struct{ User, Role string }
+
+// RolePolicy 用户(或角色)到角色的授予关系，Tenant 为空表示对所有租户生效
+type RolePolicy struct{ User, Role, Tenant string }
 type RbacClient struct {
-	e *casbin.Enforcer
-	w persist.Watcher
-	m sync.Mutex
+	e      *casbin.Enforcer
+	w      persist.Watcher
+	m      sync.Mutex
+	opts   Options
+	initMu sync.Mutex // 保护 e/w 的延迟初始化
 }
 
-var rbacClient *RbacClient = nil
+var (
+	rbacClient   *RbacClient = nil
+	rbacClientMu sync.Mutex
+)
 
 const text = `
 [request_definition]
-r = sub, dom, obj, act
+r = sub, ten, dom, obj, act
+r2 = sub, ten, dom, obj, act, env
 
 [policy_definition]
 p = sub, dom, obj, act
+p2 = sub, dom, obj, act, cond
+p3 = sub, dom, obj, act
 
 [role_definition]
-g = _, _
+g = _, _, _
 
 [policy_effect]
 e = some(where (p.eft == allow))
 
 [matchers]
-m = g(r.sub, p.sub) && r.dom == p.dom && r.obj == p.obj && r.act == p.act
+m = g(r.sub, p.sub, r.ten) && domMatch(r.dom, p.dom) && objMatch(r.obj, p.obj) && actMatch(r.act, p.act)
+m2 = g(r2.sub, p2.sub, r2.ten) && domMatch(r2.dom, p2.dom) && objMatch(r2.obj, p2.obj) && actMatch(r2.act, p2.act) && abac(p2.cond, r2.env)
 `
 
-// NewRbacClient 兼容参数：
+// conditionalContext 条件策略使用 r2/p2/m2，效果沿用 e
+var conditionalContext = casbin.EnforceContext{RType: "r2", PType: "p2", EType: "e", MType: "m2"}
+
+// NewRbacClient 返回进程级默认客户端。客户端延迟初始化，首次读写策略时才连接存储，
+// 连接失败以错误返回且下次调用会重试。存储与通知方式由 rbac.adapter / rbac.watcher 决定，默认 Redis。
+// 兼容参数：
 //   - NewRbacClient()                        使用 orm.Redis 配置或默认值
 //   - NewRbacClient(redisAddr)               指定地址
 //   - NewRbacClient(redisAddr, redisPassword) 指定地址和密码
 func NewRbacClient(redisAddrOpt ...string) *RbacClient {
+	rbacClientMu.Lock()
+	defer rbacClientMu.Unlock()
 	if rbacClient != nil {
 		return rbacClient
 	}
+	rbacClient = &RbacClient{opts: OptionsFromConfig(redisAddrOpt...)}
+	return rbacClient
+}
 
-	// ========== 步骤1：从orm.Redis读取原始配置参数（无冗余封装） ==========
-	var (
-		redisAddr     string // host:port
-		redisPassword string
-		redisDB       int
-	)
-
-	if orm.Redis != nil && orm.Redis.GetConfig() != nil {
-		cfg := orm.Redis.GetConfig()
-		// 直接读取原始参数，无需封装方法
-		redisAddr = fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
-		redisPassword = cfg.Password
-		// 解析DB索引（兼容字符串/数字配置）
-		fmt.Sscanf(cfg.Database, "%d", &redisDB)
-		log.Infof("NewRbacClient: 使用orm.Redis配置 | addr=%s | db=%d", redisAddr, redisDB)
-	}
-
-	// ========== 步骤2：降级逻辑 ==========
-	if redisAddr == "" || redisAddr == ":0" {
-		if len(redisAddrOpt) > 0 && redisAddrOpt[0] != "" {
-			redisAddr = redisAddrOpt[0]
-		} else {
-			redisAddr = "127.0.0.1:6379"
-		}
-		if len(redisAddrOpt) > 1 {
-			redisPassword = redisAddrOpt[1]
-		}
-		redisDB = 0
-		log.Infof("NewRbacClient: 使用降级配置 | addr=%s | db=%d", redisAddr, redisDB)
-	}
+// isDefault 是否为 NewRbacClient 返回的默认客户端(授予存储只同步到默认客户端)
+func (c *RbacClient) isDefault() bool {
+	rbacClientMu.Lock()
+	defer rbacClientMu.Unlock()
+	return c == rbacClient
+}
 
-	// ========== 步骤3：构造casbin-adapter兼容的DSN ==========
-	var dsnBuilder strings.Builder
-	dsnBuilder.WriteString(redisAddr)
-	params := []string{}
-	if redisPassword != "" {
-		params = append(params, fmt.Sprintf("password=%s", redisPassword))
-	}
-	if redisDB != 0 {
-		params = append(params, fmt.Sprintf("db=%d", redisDB))
-	}
-	if len(params) > 0 {
-		dsnBuilder.WriteString("?")
-		dsnBuilder.WriteString(strings.Join(params, "&"))
+// NewRbacClientWithOptions 按指定参数创建独立客户端并立即初始化，不影响默认客户端
+func NewRbacClientWithOptions(opts Options) (*RbacClient, error) {
+	c := &RbacClient{opts: opts}
+	if _, err := c.enforcer(); err != nil {
+		return nil, err
 	}
-	finalDSN := dsnBuilder.String()
+	return c, nil
+}
 
-	// ========== 步骤4：初始化Adapter（基础API，零报错） ==========
-	adapter, err := redisadapter.NewAdapter("tcp", finalDSN)
-	if err != nil {
-		log.Fatalf("NewAdapter失败: %v | DSN: %s", err, finalDSN)
-	}
+// Configure 替换默认客户端的初始化参数并立即初始化；已初始化的客户端会关闭原通知器后重建，
+// 已持有默认客户端指针的调用方（如 http.RbacClient）无需更新
+func Configure(opts Options) error {
+	c := NewRbacClient()
+	c.reset(opts)
+	_, err := c.enforcer()
+	return err
+}
 
-	// ========== 后续步骤（无修改） ==========
-	m, err := model.NewModelFromString(text)
-	if err != nil {
-		log.Fatalf("NewModelFromString失败: %v", err)
+func (c *RbacClient) reset(opts Options) {
+	c.initMu.Lock()
+	defer c.initMu.Unlock()
+	if c.w != nil {
+		c.w.Close()
 	}
+	c.e, c.w, c.opts = nil, nil, opts
+}
 
-	e, err := casbin.NewEnforcer(m, adapter)
-	if err != nil {
-		log.Fatalf("NewEnforcer失败: %v", err)
+// enforcer 返回已初始化的 casbin Enforcer，未初始化时按 opts 创建
+func (c *RbacClient) enforcer() (*casbin.Enforcer, error) {
+	c.initMu.Lock()
+	defer c.initMu.Unlock()
+	if c.e != nil {
+		return c.e, nil
 	}
-	if err := e.LoadPolicy(); err != nil {
-		log.Fatalf("LoadPolicy失败: %v", err)
+	e, w, err := buildEnforcer(c.opts)
+	if err != nil {
+		log.Errorf("RBAC客户端初始化失败: %v", err)
+		return nil, err
 	}
+	c.e, c.w = e, w
+	log.Infof("RBAC客户端初始化成功 | adapter=%s | watcher=%s", c.opts.adapterType(), c.opts.watcherType())
+	return e, nil
+}
 
-	watcher, err := rediswatcher.NewWatcher(redisAddr, rediswatcher.WatcherOptions{
-		Options: redis.Options{
-			Addr:     redisAddr,
-			Password: redisPassword,
-			DB:       redisDB,
-		},
-		Channel:    "/casbin",
-		IgnoreSelf: true,
-	})
+func (c *RbacClient) watcher() persist.Watcher {
+	c.initMu.Lock()
+	defer c.initMu.Unlock()
+	return c.w
+}
+
+// SetWatcher 替换策略变更通知器，传 nil 表示不再通知其他实例
+func (c *RbacClient) SetWatcher(w persist.Watcher) error {
+	e, err := c.enforcer()
 	if err != nil {
-		log.Fatalf("NewWatcher失败: %v", err)
+		return err
 	}
-
-	if err := e.SetWatcher(watcher); err != nil {
-		log.Fatalf("SetWatcher失败: %v", err)
+	c.initMu.Lock()
+	defer c.initMu.Unlock()
+	if c.w != nil && c.w != w {
+		c.w.Close()
 	}
-	if err := watcher.SetUpdateCallback(func(string) {
-		_ = e.LoadPolicy()
-	}); err != nil {
-		log.Fatalf("SetUpdateCallback失败: %v", err)
+	c.w = w
+	c.opts.Watcher = w
+	if w == nil {
+		e.EnableAutoNotifyWatcher(false)
+		return nil
 	}
+	e.EnableAutoNotifyWatcher(true)
+	if err := e.SetWatcher(w); err != nil {
+		return err
+	}
+	return w.SetUpdateCallback(func(string) {
+		invalidateAssignmentClock()
+		if err := e.LoadPolicy(); err != nil {
+			log.Errorf("RBAC策略变更后重新加载失败: %v", err)
+		}
+	})
+}
 
-	rbacClient = &RbacClient{
-		e: e,
-		w: watcher,
+// LoadPolicy 从存储重新加载全部策略
+func (c *RbacClient) LoadPolicy() error {
+	e, err := c.enforcer()
+	if err != nil {
+		return err
 	}
-	log.Infof("RBAC客户端初始化成功 | Redis地址: %s", redisAddr)
-	return rbacClient
+	return e.LoadPolicy()
+}
+
+// Close 关闭通知器，客户端下次使用时重新初始化
+func (c *RbacClient) Close() {
+	c.initMu.Lock()
+	opts := c.opts
+	c.initMu.Unlock()
+	opts.Watcher = nil
+	c.reset(opts)
 }
 
 //// NewRbacClient 终极兼容版：适配所有casbin-redis-adapter/v3版本 + 零报错
@@ -547,10 +585,40 @@ func NewActionPolicy(dom, sub, obj, act string) Policy {
 	}
 }
 
+// NewConditionalActionPolicy 带条件的授权，cond 语法见 Condition
+func NewConditionalActionPolicy(dom, sub, obj, act, cond string) Policy {
+	return &ActionPolicy{
+		Dom:  dom,
+		Sub:  sub,
+		Obj:  obj,
+		Act:  act,
+		Cond: strings.TrimSpace(cond),
+	}
+}
+
 func (p ActionPolicy) ToArr() []string {
+	if p.Cond != "" {
+		return []string{p.Sub, p.Dom, p.Obj, p.Act, p.Cond}
+	}
 	return []string{p.Sub, p.Dom, p.Obj, p.Act}
 }
 
+func (p ActionPolicy) ptype() string {
+	if p.Cond != "" {
+		return ptypeConditionalAction
+	}
+	return ptypeAction
+}
+
+func newActionPolicy(rule []string) *ActionPolicy {
+	ap := &ActionPolicy{Sub: rule[0], Dom: rule[1], Obj: rule[2], Act: rule[3]}
+	if len(rule) > 4 {
+		ap.Cond = rule[4]
+	}
+	return ap
+}
+
+// Deprecated: 使用 RolePolicy.Tenant 或 AddTenantGroupingPolicy
 func NewTenantPolicy(ten, dom string) Policy {
 	return &TenantPolicy{
 		ten,
@@ -563,12 +631,19 @@ func (p TenantPolicy) ToArr() []string {
 }
 
 func (p *RolePolicy) ToArr() []string {
-	return []string{p.User, p.Role}
+	return []string{p.User, p.Role, TenantDomain(p.Tenant)}
 }
 
+// NewReq 构造不带租户的鉴权请求，只有全局授予的角色生效
 func NewReq(sub, dom, obj, act string) *Req {
+	return NewTenantReq(sub, "", dom, obj, act)
+}
+
+// NewTenantReq 构造租户内的鉴权请求，全局授予与该租户内授予的角色均生效
+func NewTenantReq(sub, tenant, dom, obj, act string) *Req {
 	return &Req{
 		dom: dom,
+		ten: TenantDomain(tenant),
 		sub: sub,
 		obj: obj,
 		act: act,
@@ -576,11 +651,37 @@ func NewReq(sub, dom, obj, act string) *Req {
 }
 
 func (r Req) ToArr() []string {
-	return []string{r.sub, r.dom, r.obj, r.act}
+	return []string{r.sub, r.ten, r.dom, r.obj, r.act}
+}
+
+// WithAttributes 附带条件策略求值使用的属性
+func (r *Req) WithAttributes(attrs Attributes) *Req {
+	r.attrs = func() Attributes { return attrs }
+	return r
+}
+
+// WithAttributeLoader 附带属性加载函数，仅在普通策略未放行且存在条件策略时调用，
+// 避免每个请求都解析请求体
+func (r *Req) WithAttributeLoader(loader func() Attributes) *Req {
+	r.attrs = loader
+	return r
+}
+
+// attributes 加载请求属性并补齐 env 时间属性
+func (r *Req) attributes() Attributes {
+	var attrs Attributes
+	if r.attrs != nil {
+		attrs = r.attrs()
+	}
+	return withEnvDefaults(attrs)
 }
 
 func (c *RbacClient) GetRolePolicy(sub string) (*RolePolicy, error) {
-	policies, err := c.e.GetFilteredGroupingPolicy(0, sub)
+	e, err := c.enforcer()
+	if err != nil {
+		return nil, err
+	}
+	policies, err := e.GetFilteredGroupingPolicy(0, sub)
 	if err != nil {
 		log.Errorf("GetRolePolicy/GetFilteredPolicy(0, %s) error, %v", sub, err)
 		return nil, err
@@ -593,15 +694,33 @@ func (c *RbacClient) GetRolePolicy(sub string) (*RolePolicy, error) {
 	}
 
 	log.Debugf("GetRolePolicy(%s) loaded, count=%d", sub, len(policies))
-	return &RolePolicy{
-		Role: policies[0][1],
-		User: policies[0][0],
-	}, nil
+	return newRolePolicy(policies[0]), nil
+}
+
+// getRolePolicyInTenant 返回用户在指定租户(精确匹配，不含全局)下的第一条授予
+func (c *RbacClient) getRolePolicyInTenant(sub, tenant string) (*RolePolicy, error) {
+	e, err := c.enforcer()
+	if err != nil {
+		return nil, err
+	}
+	policies, err := e.GetFilteredGroupingPolicy(0, sub, "", TenantDomain(tenant))
+	if err != nil || len(policies) == 0 {
+		return nil, err
+	}
+	return newRolePolicy(policies[0]), nil
+}
+
+func newRolePolicy(rule []string) *RolePolicy {
+	rp := &RolePolicy{User: rule[0], Role: rule[1]}
+	if len(rule) > 2 {
+		rp.Tenant = tenantFromDomain(rule[2])
+	}
+	return rp
 }
 
 func (c *RbacClient) AddRolePolicy(rp *RolePolicy) error {
 	log.Infof("AddRolePolicy(%v)", *rp)
-	_rp, err := c.GetRolePolicy(rp.User)
+	_rp, err := c.getRolePolicyInTenant(rp.User, rp.Tenant)
 	if err != nil {
 		log.Errorf("AddRolePolicy/GetRolePolicy(%v) error, %v", *rp, err)
 		return err
@@ -612,7 +731,11 @@ func (c *RbacClient) AddRolePolicy(rp *RolePolicy) error {
 			return errDR
 		}
 	}
-	added, err := c.e.AddGroupingPolicy(rp.ToArr())
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	added, err := e.AddGroupingPolicy(rp.ToArr())
 	if err != nil {
 		log.Errorf("AddRolePolicy/AddGroupingPolicy(%v) error, %v", *rp, err)
 		return err
@@ -627,10 +750,15 @@ func (c *RbacClient) UpdateRolePolicy(rp *RolePolicy, role string) error {
 	c.m.Lock()
 	defer c.m.Unlock()
 	newRp := &RolePolicy{
-		User: rp.User,
-		Role: role,
+		User:   rp.User,
+		Role:   role,
+		Tenant: rp.Tenant,
+	}
+	e, err := c.enforcer()
+	if err != nil {
+		return err
 	}
-	updated, err := c.e.UpdatePolicy(rp.ToArr(), newRp.ToArr())
+	updated, err := e.UpdateGroupingPolicy(rp.ToArr(), newRp.ToArr())
 	if err != nil {
 		return err
 	}
@@ -646,7 +774,11 @@ func (c *RbacClient) UpdateRolePolicy(rp *RolePolicy, role string) error {
 func (c *RbacClient) DeleteRolePolicy(rp *RolePolicy) error {
 	c.m.Lock()
 	defer c.m.Unlock()
-	removed, err := c.e.RemoveFilteredGroupingPolicy(0, rp.User)
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	removed, err := e.RemoveFilteredGroupingPolicy(0, rp.User, "", TenantDomain(rp.Tenant))
 	if err != nil {
 		return err
 	}
@@ -658,7 +790,11 @@ func (c *RbacClient) DeleteRolePolicy(rp *RolePolicy) error {
 }
 
 func (c *RbacClient) save() error {
-	if err := c.e.SavePolicy(); err != nil {
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	if err := e.SavePolicy(); err != nil {
 		log.Errorf("save/SavePolicy, %v", err)
 		return err
 	}
@@ -666,7 +802,11 @@ func (c *RbacClient) save() error {
 }
 
 func (c *RbacClient) notifyWatcher() error {
-	if err := c.w.Update(); err != nil {
+	w := c.watcher()
+	if w == nil {
+		return nil
+	}
+	if err := w.Update(); err != nil {
 		log.Errorf("save/Update, %v", err)
 		return err
 	}
@@ -680,42 +820,95 @@ func (c *RbacClient) AddActionPolicies(policies []Policy) error {
 		return nil
 	}
 	_policies := [][]string{}
+	_conditional := [][]string{}
 	for _, p := range policies {
+		if ap := asActionPolicy(p); ap != nil && ap.Cond != "" {
+			if _, err := CompileCondition(ap.Cond); err != nil {
+				log.Errorf("AddActionPolicies invalid condition, policy=%v, err=%v", ap.ToArr(), err)
+				return err
+			}
+			_conditional = append(_conditional, ap.ToArr())
+			continue
+		}
 		_policy := p.ToArr()
 		_policies = append(_policies, _policy)
 	}
-	log.Infof("AddActionPolicies begin, count=%d", len(_policies))
+	log.Infof("AddActionPolicies begin, count=%d, conditional=%d", len(_policies), len(_conditional))
 
-	ok, err := c.e.AddPoliciesEx(_policies)
+	e, err := c.enforcer()
 	if err != nil {
-		log.Errorf("AddPolicies failed, count=%d, err=%v", len(_policies), err)
 		return err
 	}
-	if !ok {
-		log.Errorf("AddPolicies, casbin Enforcer.AddPolices not ok")
-		return errors.New("AddPolices Failed")
+	for ptype, rules := range map[string][][]string{ptypeAction: _policies, ptypeConditionalAction: _conditional} {
+		if len(rules) == 0 {
+			continue
+		}
+		ok, err := e.AddNamedPoliciesEx(ptype, rules)
+		if err != nil {
+			log.Errorf("AddPolicies failed, ptype=%s, count=%d, err=%v", ptype, len(rules), err)
+			return err
+		}
+		if !ok {
+			log.Errorf("AddPolicies, casbin Enforcer.AddPolices not ok")
+			return errors.New("AddPolices Failed")
+		}
 	}
-	log.Infof("AddActionPolicies success, count=%d", len(_policies))
+	log.Infof("AddActionPolicies success, count=%d", len(_policies)+len(_conditional))
+	return c.save()
+}
+
+// AddDeclaredPatternPolicies 写入服务代码声明的通配策略(如 HTTPServer.GrantPrefix)，并以 p3 标记其来源，
+// 下次 DeletePoliciesByService 时随路由策略一并清理，代码中删除的声明因此不会残留。
+// 与人工授权完全相同的策略同样会被标记；不支持带条件的策略
+func (c *RbacClient) AddDeclaredPatternPolicies(policies []Policy) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if len(policies) == 0 {
+		return nil
+	}
+	rules := make([][]string, 0, len(policies))
+	for _, p := range policies {
+		ap := asActionPolicy(p)
+		if ap == nil || ap.Cond != "" {
+			return fmt.Errorf("AddDeclaredPatternPolicies: unsupported policy %v", p.ToArr())
+		}
+		rules = append(rules, ap.ToArr())
+	}
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	for _, ptype := range []string{ptypeAction, ptypeDeclaredPattern} {
+		if _, err := e.AddNamedPoliciesEx(ptype, rules); err != nil {
+			log.Errorf("AddDeclaredPatternPolicies failed, ptype=%s, count=%d, err=%v", ptype, len(rules), err)
+			return err
+		}
+	}
+	log.Infof("AddDeclaredPatternPolicies success, count=%d", len(rules))
 	return c.save()
 }
 
 func (c *RbacClient) GetActionPolicies(role string) ([]*ActionPolicy, error) {
 	log.Debugf("GetActionPolicies requested, role=%s", role)
-	content, err := c.e.GetFilteredPolicy(0, role)
+	e, err := c.enforcer()
+	if err != nil {
+		return nil, err
+	}
+	content, err := e.GetFilteredPolicy(0, role)
 	if err != nil {
 		log.Errorf("GetActionPolicies/GetFilteredPolicy failed, %v, error, %v", content, err)
 		return nil, err
 	}
+	conditional, err := e.GetFilteredNamedPolicy(ptypeConditionalAction, 0, role)
+	if err != nil {
+		log.Errorf("GetActionPolicies/GetFilteredNamedPolicy failed, %v, error, %v", conditional, err)
+		return nil, err
+	}
 
-	log.Debugf("GetActionPolicies loaded, role=%s, count=%d", role, len(content))
+	log.Debugf("GetActionPolicies loaded, role=%s, count=%d", role, len(content)+len(conditional))
 	ans := []*ActionPolicy{}
-	for _, item := range content {
-		ans = append(ans, &ActionPolicy{
-			Sub: item[0],
-			Dom: item[1],
-			Obj: item[2],
-			Act: item[3],
-		})
+	for _, item := range append(content, conditional...) {
+		ans = append(ans, newActionPolicy(item))
 	}
 	return ans, nil
 }
@@ -723,7 +916,11 @@ func (c *RbacClient) GetActionPolicies(role string) ([]*ActionPolicy, error) {
 func (c *RbacClient) DeleteActionPolicy(ap *ActionPolicy) error {
 	c.m.Lock()
 	defer c.m.Unlock()
-	removed, err := c.e.RemovePolicy(ap.ToArr())
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	removed, err := e.RemoveNamedPolicy(ap.ptype(), ap.ToArr())
 	if err != nil {
 		log.Errorf("DeleteActionPolicy/RemovePolicy, error %v", err)
 		return err
@@ -737,48 +934,121 @@ func (c *RbacClient) DeleteActionPolicy(ap *ActionPolicy) error {
 }
 
 func (c *RbacClient) Enforce(r *Req) (bool, error) {
-	ok, err := c.e.Enforce(r.sub, r.dom, r.obj, r.act)
+	if c.isDefault() {
+		// 限时/委托授予越过生效或到期时间点时先同步到 casbin
+		syncAssignmentsBeforeEnforce()
+	}
+	e, err := c.enforcer()
 	if err != nil {
-		log.Errorf("Enforce(%v) error, %v", *r, err)
 		return false, err
 	}
-	log.Infof("Enforce(%v) result , %v", *r, ok)
+	ok, err := e.Enforce(r.sub, r.ten, r.dom, r.obj, r.act)
+	if err == nil && !ok && hasConditionalPolicies(e) {
+		ok, err = e.Enforce(conditionalContext, r.sub, r.ten, r.dom, r.obj, r.act, r.attributes())
+	}
+	if err != nil {
+		log.Errorf("Enforce(%v) error, %v", r.ToArr(), err)
+		return false, err
+	}
+	log.Infof("Enforce(%v) result , %v", r.ToArr(), ok)
 	return ok, nil
 }
 
-func (c *RbacClient) DeletePoliciesByService(service string) error {
+// DeleteOption DeletePoliciesByService 的选项
+type DeleteOption func(*deleteOptions)
+
+type deleteOptions struct {
+	includePatterns bool
+}
+
+// IncludePatternPolicies 同时删除服务下的通配策略(通常为人工授权)
+func IncludePatternPolicies() DeleteOption {
+	return func(o *deleteOptions) {
+		o.includePatterns = true
+	}
+}
+
+var errInvalidService = errors.New("rbac DeletePoliciesByService requires a concrete service name")
+
+// DeletePoliciesByService 删除服务下由路由注册生成的策略，以及经 AddDeclaredPatternPolicies 声明的通配策略。
+// 服务名为空或 * 时拒绝执行；其余通配策略(见 ActionPolicy.IsPatternPolicy)默认保留，需显式传入 IncludePatternPolicies
+func (c *RbacClient) DeletePoliciesByService(service string, opts ...DeleteOption) error {
+	o := deleteOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if service = strings.TrimSpace(service); service == "" || service == WildcardAll {
+		return errInvalidService
+	}
 	c.m.Lock()
 	defer c.m.Unlock()
-	removed, err := c.e.RemoveFilteredPolicy(1, service)
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	declared, err := e.GetFilteredNamedPolicy(ptypeDeclaredPattern, 1, service)
 	if err != nil {
 		log.Errorf("DeletePoliciesByService(%s) error, %v", service, err)
 		return err
 	}
-	log.Infof("DeletePoliciesByService(%s) removed=%v", service, removed)
+	isDeclared := make(map[string]bool, len(declared))
+	for _, rule := range declared {
+		isDeclared[strings.Join(rule, ",")] = true
+	}
+	removed, kept := false, 0
+	for _, ptype := range []string{ptypeAction, ptypeConditionalAction} {
+		rules, err := e.GetFilteredNamedPolicy(ptype, 1, service)
+		if err != nil {
+			log.Errorf("DeletePoliciesByService(%s) error, %v", service, err)
+			return err
+		}
+		targets := make([][]string, 0, len(rules))
+		for _, rule := range rules {
+			declaredRule := ptype == ptypeAction && isDeclared[strings.Join(rule, ",")]
+			if !o.includePatterns && !declaredRule && newActionPolicy(rule).IsPatternPolicy() {
+				kept++
+				continue
+			}
+			targets = append(targets, rule)
+		}
+		if len(targets) == 0 {
+			continue
+		}
+		ok, err := e.RemoveNamedPolicies(ptype, targets)
+		if err != nil {
+			log.Errorf("DeletePoliciesByService(%s) ptype=%s error, %v", service, ptype, err)
+			return err
+		}
+		removed = removed || ok
+	}
+	if len(declared) > 0 {
+		if _, err := e.RemoveNamedPolicies(ptypeDeclaredPattern, declared); err != nil {
+			log.Errorf("DeletePoliciesByService(%s) ptype=%s error, %v", service, ptypeDeclaredPattern, err)
+			return err
+		}
+		removed = true
+	}
+	log.Infof("DeletePoliciesByService(%s) removed=%v, declared_patterns=%d, kept_patterns=%d", service, removed, len(declared), kept)
 	if removed {
 		return c.save()
 	}
 	return nil
 }
 
+// AddGroupingPolicy 全局授予角色(对所有租户生效)
 func (c *RbacClient) AddGroupingPolicy(sub, role string) error {
-	c.m.Lock()
-	defer c.m.Unlock()
-	added, err := c.e.AddGroupingPolicy(sub, role)
-	if err != nil {
-		log.Errorf("AddGroupingPolicy(%s,%s) error, %v", sub, role, err)
-		return err
-	}
-	if added {
-		return c.notifyWatcher()
-	}
-	return nil
+	return c.AddTenantGroupingPolicy(sub, role, "")
 }
 
+// RemoveGroupingPoliciesForSubject 移除 sub 在所有租户下的授予
 func (c *RbacClient) RemoveGroupingPoliciesForSubject(sub string) error {
 	c.m.Lock()
 	defer c.m.Unlock()
-	removed, err := c.e.RemoveFilteredGroupingPolicy(0, sub)
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	removed, err := e.RemoveFilteredGroupingPolicy(0, sub)
 	if err != nil {
 		log.Errorf("RemoveGroupingPoliciesForSubject(%s) error, %v", sub, err)
 		return err
@@ -792,7 +1062,11 @@ func (c *RbacClient) RemoveGroupingPoliciesForSubject(sub string) error {
 func (c *RbacClient) RemoveGroupingPoliciesForRole(role string) error {
 	c.m.Lock()
 	defer c.m.Unlock()
-	removed, err := c.e.RemoveFilteredGroupingPolicy(1, role)
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	removed, err := e.RemoveFilteredGroupingPolicy(1, role)
 	if err != nil {
 		log.Errorf("RemoveGroupingPoliciesForRole(%s) error, %v", role, err)
 		return err
@@ -803,8 +1077,13 @@ func (c *RbacClient) RemoveGroupingPoliciesForRole(role string) error {
 	return nil
 }
 
+// GetRolesForSubject 返回 sub 全局授予的直接角色，租户内授予见 GetRolesForSubjectInTenant
 func (c *RbacClient) GetRolesForSubject(sub string) ([]string, error) {
-	policies, err := c.e.GetFilteredGroupingPolicy(0, sub)
+	e, err := c.enforcer()
+	if err != nil {
+		return nil, err
+	}
+	policies, err := e.GetFilteredGroupingPolicy(0, sub, "", GlobalTenant)
 	if err != nil {
 		log.Errorf("GetRolesForSubject(%s) error, %v", sub, err)
 		return nil, err
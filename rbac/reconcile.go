@@ -0,0 +1,260 @@
+package rbac
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goodbye-jack/go-common/config"
+	"github.com/goodbye-jack/go-common/coord"
+	"github.com/goodbye-jack/go-common/log"
+	"gorm.io/gorm"
+)
+
+const (
+	ConfigKeyReconcileInterval    = "rbac.reconcile.interval_seconds"
+	ConfigKeyReconcileRemoveExtra = "rbac.reconcile.remove_extra"
+)
+
+// GroupingRule 一条角色授予关系，Tenant 为空表示全局授予
+type GroupingRule struct {
+	Subject string `json:"subject"`
+	Role    string `json:"role"`
+	Tenant  string `json:"tenant"`
+}
+
+func (r GroupingRule) toArr() []string {
+	return []string{r.Subject, r.Role, TenantDomain(r.Tenant)}
+}
+
+// DriftReport rbac_* 表与 casbin 分组策略的差异。表为权威数据：
+// Missing 为表中存在但 casbin 缺失的授予(不生效)，Extra 为 casbin 中存在但表中没有的授予(越权)
+type DriftReport struct {
+	Missing   []GroupingRule `json:"missing"`
+	Extra     []GroupingRule `json:"extra"`
+	Added     int            `json:"added"`
+	Removed   int            `json:"removed"`
+	CheckedAt time.Time      `json:"checked_at"`
+}
+
+// InSync 表与 casbin 是否一致
+func (r *DriftReport) InSync() bool {
+	return r != nil && len(r.Missing) == 0 && len(r.Extra) == 0
+}
+
+// ReconcileOptions 修复选项。RemoveExtra 为 false 时保留 casbin 独有的授予
+// (如仍通过 AddRolePolicy 直接写 casbin 的历史调用方)，只补齐缺失授予
+type ReconcileOptions struct {
+	RemoveExtra bool
+}
+
+// DiffGroupingPolicies 对比 rbac_* 表与 casbin 分组策略，只读不修复
+func DiffGroupingPolicies() (*DriftReport, error) {
+	db, err := getStoreDB()
+	if err != nil {
+		return nil, err
+	}
+	return diffGroupingPolicies(db, NewRbacClient())
+}
+
+// ReconcileGroupingPolicies 以 rbac_* 表为准修复 casbin 分组策略，返回修复前的差异与修复条数
+func ReconcileGroupingPolicies(opts ReconcileOptions) (*DriftReport, error) {
+	db, err := getStoreDB()
+	if err != nil {
+		return nil, err
+	}
+	client := NewRbacClient()
+	report, err := diffGroupingPolicies(db, client)
+	if err != nil || report.InSync() {
+		return report, err
+	}
+	remove := []GroupingRule(nil)
+	if opts.RemoveExtra {
+		remove = report.Extra
+	}
+	if err := client.applyGroupingDiff(report.Missing, remove); err != nil {
+		return report, err
+	}
+	report.Added, report.Removed = len(report.Missing), len(remove)
+	log.Infof("ReconcileGroupingPolicies added=%d removed=%d extra_kept=%d", report.Added, report.Removed, len(report.Extra)-report.Removed)
+	return report, nil
+}
+
+var workerLocker struct {
+	mu     sync.RWMutex
+	locker coord.Locker
+	set    bool
+}
+
+// SetWorkerLocker 指定对账与到期清理任务选举使用的分布式锁，未指定时按 orm.Redis -> orm.DB 自动选择；
+// 传 nil 时任务在每个副本各自运行(如每个副本各自持有内存 adapter)
+func SetWorkerLocker(locker coord.Locker) {
+	workerLocker.mu.Lock()
+	workerLocker.locker, workerLocker.set = locker, true
+	workerLocker.mu.Unlock()
+}
+
+func resolveWorkerLocker() coord.Locker {
+	workerLocker.mu.RLock()
+	defer workerLocker.mu.RUnlock()
+	if workerLocker.set {
+		return workerLocker.locker
+	}
+	return coord.DefaultLocker()
+}
+
+// startWorker 以集群单例方式周期运行 fn，没有可用的锁后端时每个副本各自运行
+func startWorker(ctx context.Context, name string, interval time.Duration, fn func()) {
+	if interval <= 0 {
+		return
+	}
+	go coord.RunPeriodic(ctx, resolveWorkerLocker(), "rbac:"+name, interval, func(context.Context) { fn() })
+}
+
+// StartReconciler 按 interval 周期修复漂移，ctx 取消后退出。多副本时只有 leader 副本执行，修复结果经 watcher 同步到其他副本
+func StartReconciler(ctx context.Context, interval time.Duration, opts ReconcileOptions) {
+	startWorker(ctx, "reconcile", interval, func() {
+		report, err := ReconcileGroupingPolicies(opts)
+		if err != nil {
+			log.Errorf("RBAC分组策略对账失败: %v", err)
+			return
+		}
+		if !report.InSync() {
+			log.Warnf("RBAC分组策略存在漂移: missing=%d extra=%d removed=%d", len(report.Missing), len(report.Extra), report.Removed)
+		}
+	})
+}
+
+// StartReconcilerFromConfig 按 rbac.reconcile.* 配置启动对账任务，未配置间隔时不启动
+func StartReconcilerFromConfig(ctx context.Context) bool {
+	seconds := config.GetConfigInt(ConfigKeyReconcileInterval)
+	if seconds <= 0 {
+		return false
+	}
+	StartReconciler(ctx, time.Duration(seconds)*time.Second, ReconcileOptions{
+		RemoveExtra: config.GetConfigBool(ConfigKeyReconcileRemoveExtra),
+	})
+	log.Infof("RBAC分组策略对账任务已启动, interval=%ds", seconds)
+	return true
+}
+
+// expectedGroupingRules 由 rbac_role_inherits(全局) 与 rbac_user_roles(有效期内) 推导应有的分组策略
+func expectedGroupingRules(db *gorm.DB) ([]GroupingRule, error) {
+	var roleInherits []RoleInherit
+	if err := db.Find(&roleInherits).Error; err != nil {
+		return nil, err
+	}
+	var userRoles []UserRole
+	if err := db.Scopes(activeAt(timeNow())).Find(&userRoles).Error; err != nil {
+		return nil, err
+	}
+	rules := make([]GroupingRule, 0, len(roleInherits)+len(userRoles))
+	for _, row := range roleInherits {
+		rules = append(rules, GroupingRule{Subject: row.RoleCode, Role: row.InheritCode})
+	}
+	for _, row := range userRoles {
+		rules = append(rules, GroupingRule{Subject: row.UID, Role: row.RoleCode, Tenant: row.TenantCode})
+	}
+	return rules, nil
+}
+
+func diffGroupingPolicies(db *gorm.DB, client *RbacClient) (*DriftReport, error) {
+	expected, err := expectedGroupingRules(db)
+	if err != nil {
+		return nil, err
+	}
+	e, err := client.enforcer()
+	if err != nil {
+		return nil, err
+	}
+	existing, err := e.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+	actual := make(map[GroupingRule]bool, len(existing))
+	for _, item := range existing {
+		if len(item) < 3 {
+			continue
+		}
+		actual[GroupingRule{Subject: item[0], Role: item[1], Tenant: tenantFromDomain(item[2])}] = true
+	}
+	report := &DriftReport{CheckedAt: time.Now()}
+	wanted := make(map[GroupingRule]bool, len(expected))
+	for _, rule := range expected {
+		wanted[rule] = true
+		if !actual[rule] {
+			report.Missing = append(report.Missing, rule)
+		}
+	}
+	for rule := range actual {
+		if !wanted[rule] {
+			report.Extra = append(report.Extra, rule)
+		}
+	}
+	sortGroupingRules(report.Missing)
+	sortGroupingRules(report.Extra)
+	return report, nil
+}
+
+func sortGroupingRules(rules []GroupingRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Subject != rules[j].Subject {
+			return rules[i].Subject < rules[j].Subject
+		}
+		if rules[i].Tenant != rules[j].Tenant {
+			return rules[i].Tenant < rules[j].Tenant
+		}
+		return rules[i].Role < rules[j].Role
+	})
+}
+
+func (c *RbacClient) applyGroupingDiff(add, remove []GroupingRule) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	if len(remove) > 0 {
+		rules := make([][]string, 0, len(remove))
+		for _, rule := range remove {
+			rules = append(rules, rule.toArr())
+		}
+		if _, err := e.RemoveGroupingPolicies(rules); err != nil {
+			return err
+		}
+	}
+	if len(add) > 0 {
+		rules := make([][]string, 0, len(add))
+		for _, rule := range add {
+			rules = append(rules, rule.toArr())
+		}
+		if _, err := e.AddGroupingPoliciesEx(rules); err != nil {
+			return err
+		}
+	}
+	return c.notifyWatcher()
+}
+
+// syncGroupingInTx 在同一事务中写 rbac_* 表并同步 casbin：casbin 同步失败则回滚事务，
+// 事务提交失败则执行 revert 撤销已写入 casbin 的变更，保证两侧要么都生效要么都不生效
+func syncGroupingInTx(db *gorm.DB, write func(tx *gorm.DB) error, apply func(client *RbacClient) error, revert func(client *RbacClient)) error {
+	client := NewRbacClient()
+	applied := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+		if err := apply(client); err != nil {
+			revert(client)
+			return err
+		}
+		applied = true
+		return nil
+	})
+	if err != nil && applied {
+		revert(client)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package rbac
+
+import "testing"
+
+func TestReconcileGroupingPolicies(t *testing.T) {
+	useTestStore(t)
+	for _, code := range []string{"ADMIN", "VIEWER"} {
+		if err := EnsureBusinessRole(code, code, 1); err != nil {
+			t.Fatalf("EnsureBusinessRole(%s) error = %v", code, err)
+		}
+	}
+	if err := SetUserRoles("alice", []string{"VIEWER"}); err != nil {
+		t.Fatalf("SetUserRoles() error = %v", err)
+	}
+	report, err := DiffGroupingPolicies()
+	if err != nil || !report.InSync() {
+		t.Fatalf("DiffGroupingPolicies() = %+v, %v, want in sync", report, err)
+	}
+
+	client := NewRbacClient()
+	_ = client.AddGroupingPolicy("mallory", "ADMIN")
+	_ = client.RemoveTenantGroupingPolicy("alice", "VIEWER", "")
+	report, _ = DiffGroupingPolicies()
+	if len(report.Missing) != 1 || report.Missing[0].Subject != "alice" || len(report.Extra) != 1 || report.Extra[0].Subject != "mallory" {
+		t.Fatalf("drift report = %+v", report)
+	}
+
+	report, err = ReconcileGroupingPolicies(ReconcileOptions{})
+	if err != nil || report.Added != 1 || report.Removed != 0 {
+		t.Fatalf("Reconcile(keep extra) = %+v, %v", report, err)
+	}
+	if roles, _ := client.GetRolesForSubject("mallory"); len(roles) != 1 {
+		t.Fatalf("extra grant should be kept, roles = %v", roles)
+	}
+	if err := SyncGroupingPoliciesFromDB(); err != nil {
+		t.Fatalf("SyncGroupingPoliciesFromDB() error = %v", err)
+	}
+	report, _ = DiffGroupingPolicies()
+	if !report.InSync() {
+		t.Fatalf("after sync report = %+v", report)
+	}
+}
+
+func TestSetUserRolesRollsBackWhenCasbinFails(t *testing.T) {
+	useTestStore(t)
+	if err := EnsureBusinessRole("VIEWER", "VIEWER", 1); err != nil {
+		t.Fatalf("EnsureBusinessRole() error = %v", err)
+	}
+	NewRbacClient().reset(Options{AdapterType: "unavailable"})
+	if err := SetUserRoles("alice", []string{"VIEWER"}); err == nil {
+		t.Fatalf("SetUserRoles() should fail when casbin is unavailable")
+	}
+	roles, err := ListUserRoles("alice")
+	if err != nil || len(roles) != 0 {
+		t.Fatalf("ListUserRoles() = %v, %v, want rolled back", roles, err)
+	}
+}
@@ -1,109 +1,70 @@
 package rbac
 
 import (
+	"strings"
+
 	"github.com/goodbye-jack/go-common/log"
-	"github.com/goodbye-jack/go-common/utils"
-	"sync"
 )
 
 // RoleMappingConfig 定义角色映射配置结构
+//
+// Deprecated: 使用 RoleDefinition 与 rbac.roles 配置，映射 A->B(A!=B) 等价于角色 A 的上级为 B
 type RoleMappingConfig struct {
 	RoleMapping map[string]string
 }
 
-// 默认配置
-var defaultRoleMappingConfig = RoleMappingConfig{
-	RoleMapping: map[string]string{
-		utils.RoleDefault:          utils.RoleDefault,
-		utils.RoleMuseum:           utils.RoleMuseum,
-		utils.RoleMuseumOffice:     utils.RoleMuseum,
-		utils.RoleAppraisalStation: utils.RoleAppraisalStation,
-		utils.RoleAdministrator:    utils.RoleAdministrator,
-		utils.UserAnonymous:        utils.UserAnonymous,
-	},
-}
-
-// 全局配置实例
-var (
-	roleConfig     RoleMappingConfig
-	roleConfigOnce sync.Once
-)
-
-// InitRoleMapping 初始化角色映射配置
-// 支持从角色列表初始化，也支持从完整配置初始化
+// InitRoleMapping 初始化角色映射配置，支持角色列表或完整配置，在内置角色目录上追加
+//
+// Deprecated: 使用 rbac.roles 配置与 LoadRoleCatalogFromConfig / SetRoleCatalog
 func InitRoleMapping(config interface{}) {
-	roleConfigOnce.Do(func() {
-		switch cfg := config.(type) {
-		case []string:
-			// 从角色列表初始化
-			roleConfig = initFromRoleList(cfg)
-			log.Info("Role mapping initialized from role list")
-		case RoleMappingConfig:
-			// 从完整配置初始化
-			roleConfig = cfg
-			validateAndMergeConfig(&roleConfig)
-			log.Info("Role mapping initialized from full config")
-		default:
-			// 使用默认配置
-			roleConfig = defaultRoleMappingConfig
-			log.Info("Role mapping initialized from default config")
-		}
-	})
-}
-
-// validateAndMergeConfig 验证并合并配置
-func validateAndMergeConfig(config *RoleMappingConfig) {
-	// 如果没有提供任何映射，使用默认映射
-	if config.RoleMapping == nil || len(config.RoleMapping) == 0 {
-		config.RoleMapping = defaultRoleMappingConfig.RoleMapping
-		return
+	defs := DefaultRoleCatalog().Roles()
+	index := map[string]int{}
+	for i, def := range defs {
+		index[def.Code] = i
 	}
-	// 合并默认映射（保留用户提供的映射，添加默认映射中没有的）
-	for role, mappedRole := range defaultRoleMappingConfig.RoleMapping {
-		if _, exists := config.RoleMapping[role]; !exists {
-			config.RoleMapping[role] = mappedRole
+	var add func(role, parent string)
+	add = func(role, parent string) {
+		if role = strings.TrimSpace(role); role == "" {
+			return
+		}
+		i, ok := index[role]
+		if !ok {
+			i = len(defs)
+			index[role] = i
+			defs = append(defs, RoleDefinition{Code: role})
+		}
+		if parent != "" && parent != role {
+			add(parent, "")
+			defs[i].Parents = append(append([]string{}, defs[i].Parents...), parent)
 		}
 	}
-}
-
-// initFromRoleList 从角色列表初始化角色配置
-func initFromRoleList(roleList []string) RoleMappingConfig {
-	config := defaultRoleMappingConfig
-	// 如果角色列表为空，返回默认配置
-	if len(roleList) == 0 {
-		return config
-	}
-	// 创建新的映射，保留默认映射并添加/覆盖角色列表中的角色
-	preciseMapping := make(map[string]string)
-	// 复制默认映射
-	for role, mappedRole := range config.RoleMapping {
-		preciseMapping[role] = mappedRole
+	switch cfg := config.(type) {
+	case []string:
+		for _, role := range cfg {
+			add(role, "")
+		}
+	case RoleMappingConfig:
+		for role, mapped := range cfg.RoleMapping {
+			add(role, mapped)
+		}
 	}
-	// 添加/覆盖角色列表中的角色
-	for _, role := range roleList {
-		preciseMapping[role] = role
+	catalog, err := NewRoleCatalog(defs...)
+	if err != nil {
+		log.Errorf("InitRoleMapping 角色映射无效，保留当前角色目录: %v", err)
+		return
 	}
-	config.RoleMapping = preciseMapping
-	return config
+	SetRoleCatalog(catalog)
+	log.Infof("Role mapping initialized, roles=%d", len(catalog.roles))
 }
 
-// GetPreciseRoleMapping 获取精确角色映射
+// GetRoleMapping 返回角色(或别名)在目录中的编码及是否存在
+//
+// Deprecated: 使用 DefaultRoleCatalog().Resolve
 func GetRoleMapping(role string) (string, bool) {
-	if roleConfig.RoleMapping == nil {
-		return "", false
-	}
-	mappedRole, exists := roleConfig.RoleMapping[role]
-	return mappedRole, exists
+	return DefaultRoleCatalog().Resolve(role)
 }
 
-// HasRole 检查用户是否拥有特定角色
+// HasRole 检查持有 userRole 是否满足 requiredRole，按角色目录解析别名与上级
 func HasRole(userRole, requiredRole string) bool {
-	// 检查精确映射
-	if preciseRole, exists := GetRoleMapping(userRole); exists {
-		if preciseRole == requiredRole {
-			return true
-		}
-	}
-	// 如果用户角色与所需角色直接匹配，也返回 true
-	return userRole == requiredRole
+	return DefaultRoleCatalog().HasRole(userRole, requiredRole)
 }
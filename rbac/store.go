@@ -1,6 +1,7 @@
 package rbac
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -9,8 +10,10 @@ import (
 
 	"github.com/goodbye-jack/go-common/config"
 	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/orm/migrate"
 	"github.com/goodbye-jack/go-common/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
@@ -49,11 +52,19 @@ func (RoleInherit) TableName() string {
 	return "rbac_role_inherits"
 }
 
+// UserRole 用户角色授予，TenantCode 为空表示全局授予(对所有租户生效)。
+// ValidFrom/ValidUntil 为空表示不限，仅在有效期内的授予写入 casbin；DelegatedFrom 非空表示委托授予
 type UserRole struct {
-	ID       uint      `gorm:"primaryKey"`
-	UID      string    `gorm:"size:128;index;uniqueIndex:uniq_user_role"`
-	RoleCode string    `gorm:"size:128;index;uniqueIndex:uniq_user_role"`
-	CreatedAt time.Time
+	ID            uint       `gorm:"primaryKey"`
+	UID           string     `gorm:"size:128;index;uniqueIndex:uniq_user_tenant_role"`
+	RoleCode      string     `gorm:"size:128;index;uniqueIndex:uniq_user_tenant_role"`
+	TenantCode    string     `gorm:"size:64;index;uniqueIndex:uniq_user_tenant_role;default:''"`
+	ValidFrom     *time.Time `gorm:"index"`
+	ValidUntil    *time.Time `gorm:"index"`
+	Grantor       string     `gorm:"size:128"`
+	Reason        string     `gorm:"size:255"`
+	DelegatedFrom string     `gorm:"size:128;index;default:''"`
+	CreatedAt     time.Time
 }
 
 func (UserRole) TableName() string {
@@ -81,7 +92,7 @@ func getStoreDB() (*gorm.DB, error) {
 			storeErr = errors.New("rbac store db not initialized")
 			return
 		}
-		if err := storeDB.AutoMigrate(&Role{}, &RoleInherit{}, &UserRole{}); err != nil {
+		if err := migrate.ApplyModule(context.Background(), storeDB, MigrationModule); err != nil {
 			storeErr = err
 			return
 		}
@@ -89,6 +100,10 @@ func getStoreDB() (*gorm.DB, error) {
 	return storeDB, storeErr
 }
 
+func storeModels() []interface{} {
+	return []interface{}{&Role{}, &RoleInherit{}, &UserRole{}, &UserRoleHistory{}}
+}
+
 func initStoreDBFromLegacyConfig() (*gorm.DB, error) {
 	dsn := strings.TrimSpace(config.GetConfigString("db_dsn"))
 	dbType := strings.TrimSpace(config.GetConfigString("db_type"))
@@ -242,36 +257,33 @@ func DeleteBusinessRole(code string) error {
 		return err
 	}
 
-	client := NewRbacClient()
-	if err := client.RemoveGroupingPoliciesForSubject(code); err != nil {
-		return err
-	}
-	if err := client.RemoveGroupingPoliciesForRole(code); err != nil {
-		return err
-	}
-
-	if err := db.Transaction(func(tx *gorm.DB) error {
+	return syncGroupingInTx(db, func(tx *gorm.DB) error {
 		if err := tx.Where("role_code = ?", code).Delete(&RoleInherit{}).Error; err != nil {
 			return err
 		}
 		if err := tx.Where("role_code = ?", code).Delete(&UserRole{}).Error; err != nil {
 			return err
 		}
-		if err := tx.Where("code = ?", code).Delete(&Role{}).Error; err != nil {
-			return err
+		for i := range oldUsers {
+			if err := writeHistory(tx, HistoryActionRevoke, &oldUsers[i], "", "role deleted"); err != nil {
+				return err
+			}
 		}
-		return nil
-	}); err != nil {
-		_ = client.RemoveGroupingPoliciesForSubject(code)
-		for _, inherit := range oldInherits {
-			_ = client.AddGroupingPolicy(code, inherit)
+		return tx.Where("code = ?", code).Delete(&Role{}).Error
+	}, func(client *RbacClient) error {
+		if err := client.RemoveGroupingPoliciesForSubject(code); err != nil {
+			return err
 		}
-		for _, uid := range oldUsers {
-			_ = client.AddGroupingPolicy(uid, code)
+		return client.RemoveGroupingPoliciesForRole(code)
+	}, func(client *RbacClient) {
+		_ = client.ReplaceGroupingPolicies(code, "", oldInherits)
+		now := timeNow()
+		for _, row := range oldUsers {
+			if row.activeAt(now) {
+				_ = client.AddTenantGroupingPolicy(row.UID, code, row.TenantCode)
+			}
 		}
-		return err
-	}
-	return nil
+	})
 }
 
 func ListRolesByType(typ string) ([]Role, error) {
@@ -294,6 +306,67 @@ func ListBusinessRoles() ([]Role, error) {
 	return ListRolesByType(RoleTypeBusiness)
 }
 
+// ErrRoleNotFound GetRole 查询的角色不存在
+var ErrRoleNotFound = errors.New("role not found")
+
+// RoleQuery 角色分页查询条件，Type 为空不限类型，Keyword 模糊匹配编码与名称
+type RoleQuery struct {
+	Type     string
+	Keyword  string
+	Page     int
+	PageSize int
+}
+
+// GetRole 按编码查询角色
+func GetRole(code string) (*Role, error) {
+	code = normalizeRoleCode(code)
+	if code == "" {
+		return nil, errors.New("role code is empty")
+	}
+	db, err := getStoreDB()
+	if err != nil {
+		return nil, err
+	}
+	var role Role
+	if err := db.Where("code = ?", code).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// ListRoles 分页查询角色，返回当前页与总数；Page 从 1 开始，PageSize<=0 时默认 20
+func ListRoles(q RoleQuery) ([]Role, int64, error) {
+	db, err := getStoreDB()
+	if err != nil {
+		return nil, 0, err
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = 20
+	}
+	query := db.Model(&Role{})
+	if typ := strings.TrimSpace(q.Type); typ != "" {
+		query = query.Where("type = ?", typ)
+	}
+	if keyword := strings.TrimSpace(q.Keyword); keyword != "" {
+		query = query.Where(clause.Or(orm.Contains("code", keyword), orm.Contains("name", keyword)))
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var roles []Role
+	if err := query.Order("id asc").Offset((q.Page - 1) * q.PageSize).Limit(q.PageSize).Find(&roles).Error; err != nil {
+		return nil, 0, err
+	}
+	return roles, total, nil
+}
+
 func ListRoleInherits(roleCode string) ([]string, error) {
 	roleCode = normalizeRoleCode(roleCode)
 	if roleCode == "" {
@@ -314,7 +387,43 @@ func ListRoleInherits(roleCode string) ([]string, error) {
 	return ans, nil
 }
 
+// ListUserRoles 返回用户全局授予的角色
 func ListUserRoles(uid string) ([]string, error) {
+	return listUserRoles(uid, func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_code = ?", "")
+	})
+}
+
+// ListUserRolesInTenant 返回用户在租户内生效的角色（全局授予 + 该租户授予，已去重）
+func ListUserRolesInTenant(uid, tenant string) ([]string, error) {
+	tenant = strings.TrimSpace(tenant)
+	return listUserRoles(uid, func(db *gorm.DB) *gorm.DB {
+		return db.Where("tenant_code IN ?", []string{"", tenant})
+	})
+}
+
+// ListUserTenantRoles 按租户分组返回用户当前生效的角色，全局授予的键为空串
+func ListUserTenantRoles(uid string) (map[string][]string, error) {
+	uid = strings.TrimSpace(uid)
+	if uid == "" {
+		return nil, errors.New("uid is empty")
+	}
+	db, err := getStoreDB()
+	if err != nil {
+		return nil, err
+	}
+	var rows []UserRole
+	if err := db.Scopes(activeAt(timeNow())).Where("uid = ?", uid).Order("id asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	ans := map[string][]string{}
+	for _, row := range rows {
+		ans[row.TenantCode] = append(ans[row.TenantCode], row.RoleCode)
+	}
+	return ans, nil
+}
+
+func listUserRoles(uid string, scope func(db *gorm.DB) *gorm.DB) ([]string, error) {
 	uid = strings.TrimSpace(uid)
 	if uid == "" {
 		return nil, errors.New("uid is empty")
@@ -324,17 +433,28 @@ func ListUserRoles(uid string) ([]string, error) {
 		return nil, err
 	}
 	var rows []UserRole
-	if err := db.Where("uid = ?", uid).Find(&rows).Error; err != nil {
+	if err := db.Scopes(scope, activeAt(timeNow())).Where("uid = ?", uid).Order("id asc").Find(&rows).Error; err != nil {
 		return nil, err
 	}
+	seen := map[string]bool{}
 	ans := make([]string, 0, len(rows))
 	for _, row := range rows {
-		ans = append(ans, row.RoleCode)
+		if !seen[row.RoleCode] {
+			seen[row.RoleCode] = true
+			ans = append(ans, row.RoleCode)
+		}
 	}
 	return ans, nil
 }
 
+// EnsureUserRole 全局授予用户业务角色
 func EnsureUserRole(uid, roleCode string) error {
+	return EnsureTenantUserRole(uid, "", roleCode)
+}
+
+// EnsureTenantUserRole 在租户内授予用户业务角色，tenant 为空时全局授予
+func EnsureTenantUserRole(uid, tenant, roleCode string) error {
+	tenant = strings.TrimSpace(tenant)
 	uid = strings.TrimSpace(uid)
 	if uid == "" {
 		return errors.New("uid is empty")
@@ -359,25 +479,29 @@ func EnsureUserRole(uid, roleCode string) error {
 		return fmt.Errorf("business role %s not found", roleCode)
 	}
 
-	var row UserRole
-	if err := db.Where("uid = ? AND role_code = ?", uid, roleCode).First(&row).Error; err != nil {
+	created := false
+	return syncGroupingInTx(db, func(tx *gorm.DB) error {
+		var row UserRole
+		err := tx.Where("uid = ? AND role_code = ? AND tenant_code = ?", uid, roleCode, tenant).First(&row).Error
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			if err := db.Create(&UserRole{UID: uid, RoleCode: roleCode}).Error; err != nil {
+			created = true
+			row = UserRole{UID: uid, RoleCode: roleCode, TenantCode: tenant}
+			if err := tx.Create(&row).Error; err != nil {
 				return err
 			}
-		} else {
-			return err
+			return writeHistory(tx, HistoryActionGrant, &row, "", "")
 		}
-	}
-
-	client := NewRbacClient()
-	if err := client.AddGroupingPolicy(uid, roleCode); err != nil {
 		return err
-	}
-	return nil
+	}, func(client *RbacClient) error {
+		return client.AddTenantGroupingPolicy(uid, roleCode, tenant)
+	}, func(client *RbacClient) {
+		if created {
+			_ = client.RemoveTenantGroupingPolicy(uid, roleCode, tenant)
+		}
+	})
 }
 
-func listUsersForRole(roleCode string) ([]string, error) {
+func listUsersForRole(roleCode string) ([]UserRole, error) {
 	roleCode = normalizeRoleCode(roleCode)
 	if roleCode == "" {
 		return nil, errors.New("role code is empty")
@@ -390,11 +514,7 @@ func listUsersForRole(roleCode string) ([]string, error) {
 	if err := db.Where("role_code = ?", roleCode).Find(&rows).Error; err != nil {
 		return nil, err
 	}
-	users := make([]string, 0, len(rows))
-	for _, row := range rows {
-		users = append(users, row.UID)
-	}
-	return users, nil
+	return rows, nil
 }
 
 func SetRoleInherits(roleCode string, inheritCodes []string) error {
@@ -447,17 +567,7 @@ func SetRoleInherits(roleCode string, inheritCodes []string) error {
 		return err
 	}
 
-	client := NewRbacClient()
-	if err := client.RemoveGroupingPoliciesForSubject(roleCode); err != nil {
-		return err
-	}
-	for _, code := range clean {
-		if err := client.AddGroupingPolicy(roleCode, code); err != nil {
-			return err
-		}
-	}
-
-	if err := db.Transaction(func(tx *gorm.DB) error {
+	return syncGroupingInTx(db, func(tx *gorm.DB) error {
 		if err := tx.Where("role_code = ?", roleCode).Delete(&RoleInherit{}).Error; err != nil {
 			return err
 		}
@@ -469,17 +579,22 @@ func SetRoleInherits(roleCode string, inheritCodes []string) error {
 			rows = append(rows, RoleInherit{RoleCode: roleCode, InheritCode: code})
 		}
 		return tx.Create(&rows).Error
-	}); err != nil {
-		_ = client.RemoveGroupingPoliciesForSubject(roleCode)
-		for _, code := range oldInherits {
-			_ = client.AddGroupingPolicy(roleCode, code)
-		}
-		return err
-	}
-	return nil
+	}, func(client *RbacClient) error {
+		return client.ReplaceGroupingPolicies(roleCode, "", clean)
+	}, func(client *RbacClient) {
+		_ = client.ReplaceGroupingPolicies(roleCode, "", oldInherits)
+	})
 }
 
+// SetUserRoles 覆盖用户全局授予的角色，不影响租户内授予
 func SetUserRoles(uid string, roleCodes []string) error {
+	return SetTenantUserRoles(uid, "", roleCodes)
+}
+
+// SetTenantUserRoles 覆盖用户在租户内(精确匹配，tenant 为空即全局)永久授予的角色，
+// 有效期内的临时或委托授予不受影响
+func SetTenantUserRoles(uid, tenant string, roleCodes []string) error {
+	tenant = strings.TrimSpace(tenant)
 	uid = strings.TrimSpace(uid)
 	if uid == "" {
 		return errors.New("uid is empty")
@@ -514,41 +629,56 @@ func SetUserRoles(uid string, roleCodes []string) error {
 		}
 	}
 
-	oldRoles, err := ListUserRoles(uid)
-	if err != nil {
+	var existing []UserRole
+	if err := db.Where("uid = ? AND tenant_code = ?", uid, tenant).Find(&existing).Error; err != nil {
 		return err
 	}
-
-	client := NewRbacClient()
-	if err := client.RemoveGroupingPoliciesForSubject(uid); err != nil {
-		return err
-	}
-	for _, code := range clean {
-		if err := client.AddGroupingPolicy(uid, code); err != nil {
-			return err
+	// 有效期内的临时/委托授予保留，除非被本次覆盖为永久授予
+	now := timeNow()
+	var oldRoles, kept []string
+	permanent := map[string]bool{}
+	for _, row := range existing {
+		if row.activeAt(now) {
+			oldRoles = append(oldRoles, row.RoleCode)
+		}
+		if !row.Temporary() {
+			permanent[row.RoleCode] = true
+		} else if !seen[row.RoleCode] && row.activeAt(now) {
+			kept = append(kept, row.RoleCode)
 		}
 	}
 
-	if err := db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("uid = ?", uid).Delete(&UserRole{}).Error; err != nil {
-			return err
-		}
-		if len(clean) == 0 {
-			return nil
+	return syncGroupingInTx(db, func(tx *gorm.DB) error {
+		for _, row := range existing {
+			if row.Temporary() && !seen[row.RoleCode] {
+				continue
+			}
+			if err := tx.Delete(&UserRole{}, row.ID).Error; err != nil {
+				return err
+			}
+			if !seen[row.RoleCode] {
+				if err := writeHistory(tx, HistoryActionRevoke, &row, "", ""); err != nil {
+					return err
+				}
+			}
 		}
-		rows := make([]UserRole, 0, len(clean))
 		for _, code := range clean {
-			rows = append(rows, UserRole{UID: uid, RoleCode: code})
-		}
-		return tx.Create(&rows).Error
-	}); err != nil {
-		_ = client.RemoveGroupingPoliciesForSubject(uid)
-		for _, code := range oldRoles {
-			_ = client.AddGroupingPolicy(uid, code)
+			row := UserRole{UID: uid, RoleCode: code, TenantCode: tenant}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+			if !permanent[code] {
+				if err := writeHistory(tx, HistoryActionGrant, &row, "", ""); err != nil {
+					return err
+				}
+			}
 		}
-		return err
-	}
-	return nil
+		return nil
+	}, func(client *RbacClient) error {
+		return client.ReplaceGroupingPolicies(uid, tenant, append(append([]string{}, clean...), kept...))
+	}, func(client *RbacClient) {
+		_ = client.ReplaceGroupingPolicies(uid, tenant, oldRoles)
+	})
 }
 
 func roleExists(db *gorm.DB, code string, typ string) (bool, error) {
@@ -578,46 +708,28 @@ func EnsureAdminInheritsInternal(adminRole string) error {
 	return SetRoleInherits(adminRole, codes)
 }
 
-func SyncGroupingPoliciesFromDB() error {
-	db, err := getStoreDB()
-	if err != nil {
-		return err
-	}
-	var roleInherits []RoleInherit
-	if err := db.Find(&roleInherits).Error; err != nil {
-		return err
-	}
-	var userRoles []UserRole
-	if err := db.Find(&userRoles).Error; err != nil {
-		return err
-	}
+// legacyUserRoleIndex 引入租户列之前 rbac_user_roles 的 (uid, role_code) 唯一索引
+const legacyUserRoleIndex = "uniq_user_role"
 
-	client := NewRbacClient()
-	existing, err := client.e.GetGroupingPolicy()
+// MigrateTenantRoles 迁移到租户化的角色授予。rbac_user_roles 旧唯一索引的删除与 GORM 策略存储中
+// 旧版两列分组策略的改写已随 rbac/rbac_casbin 迁移发布，启动时自动执行；本方法确保迁移已执行，
+// 并为其他策略存储(如 Redis)持久化改写结果。可重复执行
+func MigrateTenantRoles() error {
+	db, err := getStoreDB()
 	if err != nil {
 		return err
 	}
-	for _, policy := range existing {
-		args := make([]interface{}, len(policy))
-		for i, v := range policy {
-			args[i] = v
-		}
-		if _, err := client.e.RemoveGroupingPolicy(args...); err != nil {
-			return err
-		}
+	if db.Migrator().HasIndex(&UserRole{}, legacyUserRoleIndex) {
+		return fmt.Errorf("legacy index %s still exists, check migrations of module %s", legacyUserRoleIndex, MigrationModule)
 	}
+	_, err = NewRbacClient().MigrateLegacyGroupingPolicies()
+	return err
+}
 
-	for _, row := range roleInherits {
-		if _, err := client.e.AddGroupingPolicy(row.RoleCode, row.InheritCode); err != nil {
-			return err
-		}
-	}
-	for _, row := range userRoles {
-		if _, err := client.e.AddGroupingPolicy(row.UID, row.RoleCode); err != nil {
-			return err
-		}
-	}
-	return client.save()
+// SyncGroupingPoliciesFromDB 以 rbac_* 表为准重建 casbin 分组策略，casbin 独有的授予会被移除
+func SyncGroupingPoliciesFromDB() error {
+	_, err := ReconcileGroupingPolicies(ReconcileOptions{RemoveExtra: true})
+	return err
 }
 
 func BuildInternalRole(resource, action string) (string, error) {
@@ -0,0 +1,280 @@
+package rbac
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/goodbye-jack/go-common/log"
+)
+
+// GlobalTenant casbin 分组策略第三列的全局租户，在该租户下授予的角色对所有租户生效
+const GlobalTenant = "*"
+
+var errAdapterUnsupported = errors.New("rbac adapter does not support this operation")
+
+// TenantDomain 将租户编码转换为 casbin 分组策略的租户列，空租户即全局
+func TenantDomain(tenant string) string {
+	tenant = strings.TrimSpace(tenant)
+	if tenant == "" {
+		return GlobalTenant
+	}
+	return tenant
+}
+
+func tenantFromDomain(domain string) string {
+	if domain == GlobalTenant {
+		return ""
+	}
+	return domain
+}
+
+// matchTenant casbin 租户匹配：请求租户命中同名租户与全局租户下的授予
+func matchTenant(tenant, pattern string) bool {
+	return pattern == GlobalTenant || tenant == pattern
+}
+
+// AddTenantGroupingPolicy 在租户内授予角色，tenant 为空时全局授予
+func (c *RbacClient) AddTenantGroupingPolicy(sub, role, tenant string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	added, err := e.AddGroupingPolicy(sub, role, TenantDomain(tenant))
+	if err != nil {
+		log.Errorf("AddTenantGroupingPolicy(%s,%s,%s) error, %v", sub, role, tenant, err)
+		return err
+	}
+	if added {
+		return c.notifyWatcher()
+	}
+	return nil
+}
+
+// RemoveTenantGroupingPolicy 撤销租户内的单条角色授予
+func (c *RbacClient) RemoveTenantGroupingPolicy(sub, role, tenant string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	removed, err := e.RemoveGroupingPolicy(sub, role, TenantDomain(tenant))
+	if err != nil {
+		log.Errorf("RemoveTenantGroupingPolicy(%s,%s,%s) error, %v", sub, role, tenant, err)
+		return err
+	}
+	if removed {
+		return c.notifyWatcher()
+	}
+	return nil
+}
+
+// RemoveGroupingPoliciesForSubjectInTenant 移除 sub 在指定租户(精确匹配)下的全部授予
+func (c *RbacClient) RemoveGroupingPoliciesForSubjectInTenant(sub, tenant string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	removed, err := e.RemoveFilteredGroupingPolicy(0, sub, "", TenantDomain(tenant))
+	if err != nil {
+		log.Errorf("RemoveGroupingPoliciesForSubjectInTenant(%s,%s) error, %v", sub, tenant, err)
+		return err
+	}
+	if removed {
+		return c.notifyWatcher()
+	}
+	return nil
+}
+
+// GetRolesForSubjectInTenant 返回 sub 在租户内生效的直接角色（全局授予 + 该租户授予，已去重）
+func (c *RbacClient) GetRolesForSubjectInTenant(sub, tenant string) ([]string, error) {
+	e, err := c.enforcer()
+	if err != nil {
+		return nil, err
+	}
+	policies, err := e.GetFilteredGroupingPolicy(0, sub)
+	if err != nil {
+		log.Errorf("GetRolesForSubjectInTenant(%s,%s) error, %v", sub, tenant, err)
+		return nil, err
+	}
+	domain := TenantDomain(tenant)
+	seen := map[string]bool{}
+	roles := make([]string, 0, len(policies))
+	for _, item := range policies {
+		if len(item) < 3 || !matchTenant(domain, item[2]) || seen[item[1]] {
+			continue
+		}
+		seen[item[1]] = true
+		roles = append(roles, item[1])
+	}
+	return roles, nil
+}
+
+// GetTenantRolesForSubject 按租户分组返回 sub 的直接角色，全局授予的键为空串
+func (c *RbacClient) GetTenantRolesForSubject(sub string) (map[string][]string, error) {
+	e, err := c.enforcer()
+	if err != nil {
+		return nil, err
+	}
+	policies, err := e.GetFilteredGroupingPolicy(0, sub)
+	if err != nil {
+		log.Errorf("GetTenantRolesForSubject(%s) error, %v", sub, err)
+		return nil, err
+	}
+	ans := map[string][]string{}
+	for _, item := range policies {
+		if len(item) < 3 {
+			continue
+		}
+		tenant := tenantFromDomain(item[2])
+		ans[tenant] = append(ans[tenant], item[1])
+	}
+	return ans, nil
+}
+
+// MigrateLegacyGroupingPolicies 将存储中两列的旧分组策略 (sub, role) 改写为全局租户 (sub, role, *)。
+// 加载时旧策略已在内存中按全局授予处理，本方法负责持久化改写结果，返回改写条数
+func (c *RbacClient) MigrateLegacyGroupingPolicies() (int, error) {
+	e, err := c.enforcer()
+	if err != nil {
+		return 0, err
+	}
+	compat, _ := e.GetAdapter().(*tenantCompatAdapter)
+	if compat == nil {
+		return 0, nil
+	}
+	count := int(compat.legacy.Load())
+	if count == 0 {
+		return 0, nil
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	if err := c.save(); err != nil {
+		return 0, err
+	}
+	compat.legacy.Store(0)
+	log.Infof("MigrateLegacyGroupingPolicies migrated=%d", count)
+	return count, nil
+}
+
+// tenantCompatAdapter 加载策略时把旧版两列分组策略补齐为全局租户，避免引入租户列后旧数据无法加载
+type tenantCompatAdapter struct {
+	persist.Adapter
+	legacy atomic.Int64
+}
+
+var (
+	_ persist.BatchAdapter     = (*tenantCompatAdapter)(nil)
+	_ persist.UpdatableAdapter = (*tenantCompatAdapter)(nil)
+)
+
+func newTenantCompatAdapter(adapter persist.Adapter) *tenantCompatAdapter {
+	return &tenantCompatAdapter{Adapter: adapter}
+}
+
+// LoadPolicy 先以两列分组定义加载到临时模型(两列、三列策略均可通过长度校验)，
+// 再写入真实模型，旧版两列策略补齐为全局租户
+func (a *tenantCompatAdapter) LoadPolicy(m model.Model) error {
+	tmp := m.Copy()
+	tmp.ClearPolicy()
+	for _, ast := range tmp["g"] {
+		if len(ast.Tokens) > 2 {
+			ast.Tokens = ast.Tokens[:2]
+		}
+	}
+	if err := a.Adapter.LoadPolicy(tmp); err != nil {
+		return err
+	}
+	legacy := 0
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range tmp[sec] {
+			for _, rule := range ast.Policy {
+				if sec == "g" && len(rule) == 2 {
+					rule = append(rule[:2:2], GlobalTenant)
+					legacy++
+				}
+				if err := persist.LoadPolicyArray(append([]string{ptype}, rule...), m); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	a.legacy.Store(int64(legacy))
+	if legacy > 0 {
+		log.Warnf("RBAC加载到 %d 条旧版分组策略，已按全局租户处理，可调用 MigrateLegacyGroupingPolicies 持久化", legacy)
+	}
+	return nil
+}
+
+func (a *tenantCompatAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	batch, ok := a.Adapter.(persist.BatchAdapter)
+	if !ok {
+		return errAdapterUnsupported
+	}
+	return batch.AddPolicies(sec, ptype, rules)
+}
+
+func (a *tenantCompatAdapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	batch, ok := a.Adapter.(persist.BatchAdapter)
+	if !ok {
+		return errAdapterUnsupported
+	}
+	return batch.RemovePolicies(sec, ptype, rules)
+}
+
+func (a *tenantCompatAdapter) UpdatePolicy(sec string, ptype string, oldRule, newRule []string) error {
+	updatable, ok := a.Adapter.(persist.UpdatableAdapter)
+	if !ok {
+		return errAdapterUnsupported
+	}
+	return updatable.UpdatePolicy(sec, ptype, oldRule, newRule)
+}
+
+func (a *tenantCompatAdapter) UpdatePolicies(sec string, ptype string, oldRules, newRules [][]string) error {
+	updatable, ok := a.Adapter.(persist.UpdatableAdapter)
+	if !ok {
+		return errAdapterUnsupported
+	}
+	return updatable.UpdatePolicies(sec, ptype, oldRules, newRules)
+}
+
+func (a *tenantCompatAdapter) UpdateFilteredPolicies(sec string, ptype string, newRules [][]string, fieldIndex int, fieldValues ...string) ([][]string, error) {
+	updatable, ok := a.Adapter.(persist.UpdatableAdapter)
+	if !ok {
+		return nil, errAdapterUnsupported
+	}
+	return updatable.UpdateFilteredPolicies(sec, ptype, newRules, fieldIndex, fieldValues...)
+}
+
+// ReplaceGroupingPolicies 将 sub 在租户内(精确匹配)的授予整体替换为 roles
+func (c *RbacClient) ReplaceGroupingPolicies(sub, tenant string, roles []string) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	e, err := c.enforcer()
+	if err != nil {
+		return err
+	}
+	domain := TenantDomain(tenant)
+	if _, err := e.RemoveFilteredGroupingPolicy(0, sub, "", domain); err != nil {
+		log.Errorf("ReplaceGroupingPolicies(%s,%s) remove error, %v", sub, tenant, err)
+		return err
+	}
+	if len(roles) > 0 {
+		rules := make([][]string, 0, len(roles))
+		for _, role := range roles {
+			rules = append(rules, []string{sub, role, domain})
+		}
+		if _, err := e.AddGroupingPoliciesEx(rules); err != nil {
+			log.Errorf("ReplaceGroupingPolicies(%s,%s) add error, %v", sub, tenant, err)
+			return err
+		}
+	}
+	return c.notifyWatcher()
+}
@@ -0,0 +1,167 @@
+package rbac
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/goodbye-jack/go-common/orm/migrate"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func useTestStore(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	if err := db.AutoMigrate(storeModels()...); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+	storeOnce = sync.Once{}
+	storeOnce.Do(func() { storeDB, storeErr = db, nil })
+	invalidateAssignmentClock()
+	if err := Configure(Options{AdapterType: AdapterMemory}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+}
+
+func TestTenantScopedEnforce(t *testing.T) {
+	client, err := NewRbacClientWithOptions(Options{AdapterType: AdapterMemory})
+	if err != nil {
+		t.Fatalf("NewRbacClientWithOptions() error = %v", err)
+	}
+	if err := client.AddActionPolicies([]Policy{
+		NewActionPolicy("svc", "ADMIN", "/orders", "POST"),
+		NewActionPolicy("svc", "VIEWER", "/orders", "GET"),
+	}); err != nil {
+		t.Fatalf("AddActionPolicies() error = %v", err)
+	}
+	_ = client.AddTenantGroupingPolicy("alice", "ADMIN", "tenant-a")
+	_ = client.AddTenantGroupingPolicy("alice", "VIEWER", "tenant-b")
+	_ = client.AddGroupingPolicy("bob", "VIEWER")
+
+	cases := []struct {
+		sub, tenant, act string
+		want             bool
+	}{
+		{"alice", "tenant-a", "POST", true},
+		{"alice", "tenant-b", "POST", false},
+		{"alice", "tenant-b", "GET", true},
+		{"alice", "", "GET", false},
+		{"bob", "tenant-a", "GET", true},
+		{"bob", "", "GET", true},
+	}
+	for _, tc := range cases {
+		ok, err := client.Enforce(NewTenantReq(tc.sub, tc.tenant, "svc", "/orders", tc.act))
+		if err != nil || ok != tc.want {
+			t.Fatalf("Enforce(%s,%s,%s) = %v, %v, want %v", tc.sub, tc.tenant, tc.act, ok, err, tc.want)
+		}
+	}
+
+	roles, _ := client.GetTenantRolesForSubject("alice")
+	if len(roles["tenant-a"]) != 1 || roles["tenant-a"][0] != "ADMIN" || len(roles[""]) != 0 {
+		t.Fatalf("GetTenantRolesForSubject() = %v", roles)
+	}
+	inTenant, _ := client.GetRolesForSubjectInTenant("bob", "tenant-a")
+	if len(inTenant) != 1 || inTenant[0] != "VIEWER" {
+		t.Fatalf("GetRolesForSubjectInTenant() = %v", inTenant)
+	}
+}
+
+func TestLegacyGroupingPoliciesMigrated(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	_ = adapter.AddPolicy("p", "p", []string{"EDITOR", "svc", "/orders", "GET"})
+	_ = adapter.AddPolicy("g", "g", []string{"alice", "EDITOR"})
+	client, err := NewRbacClientWithOptions(Options{Adapter: adapter})
+	if err != nil {
+		t.Fatalf("load legacy policies error = %v", err)
+	}
+	if ok, _ := client.Enforce(NewTenantReq("alice", "tenant-a", "svc", "/orders", "GET")); !ok {
+		t.Fatalf("legacy grant should apply to every tenant")
+	}
+	migrated, err := client.MigrateLegacyGroupingPolicies()
+	if err != nil || migrated != 1 {
+		t.Fatalf("MigrateLegacyGroupingPolicies() = %d, %v", migrated, err)
+	}
+	rules, _ := adapter.store.load()
+	for _, rule := range rules {
+		if rule.Ptype == "g" && rule.V2 != GlobalTenant {
+			t.Fatalf("rule not migrated: %+v", rule)
+		}
+	}
+}
+
+func TestSetTenantUserRoles(t *testing.T) {
+	useTestStore(t)
+	for _, code := range []string{"ADMIN", "VIEWER"} {
+		if err := EnsureBusinessRole(code, code, 1); err != nil {
+			t.Fatalf("EnsureBusinessRole(%s) error = %v", code, err)
+		}
+	}
+	if err := SetUserRoles("alice", []string{"VIEWER"}); err != nil {
+		t.Fatalf("SetUserRoles() error = %v", err)
+	}
+	if err := SetTenantUserRoles("alice", "tenant-a", []string{"ADMIN", "VIEWER"}); err != nil {
+		t.Fatalf("SetTenantUserRoles() error = %v", err)
+	}
+	if err := SetTenantUserRoles("alice", "tenant-b", nil); err != nil {
+		t.Fatalf("SetTenantUserRoles(empty) error = %v", err)
+	}
+
+	global, _ := ListUserRoles("alice")
+	if len(global) != 1 || global[0] != "VIEWER" {
+		t.Fatalf("ListUserRoles() = %v", global)
+	}
+	inTenant, _ := ListUserRolesInTenant("alice", "tenant-a")
+	if len(inTenant) != 2 {
+		t.Fatalf("ListUserRolesInTenant() = %v", inTenant)
+	}
+	byTenant, _ := ListUserTenantRoles("alice")
+	if len(byTenant[""]) != 1 || len(byTenant["tenant-a"]) != 2 {
+		t.Fatalf("ListUserTenantRoles() = %v", byTenant)
+	}
+	roles, _ := NewRbacClient().GetRolesForSubjectInTenant("alice", "tenant-b")
+	if len(roles) != 1 || roles[0] != "VIEWER" {
+		t.Fatalf("casbin roles in tenant-b = %v", roles)
+	}
+	if err := MigrateTenantRoles(); err != nil {
+		t.Fatalf("MigrateTenantRoles() error = %v", err)
+	}
+}
+
+func TestTenantMigrationsUpgradeLegacySchema(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "rbac.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	// 引入租户列之前的库：(uid, role_code) 唯一索引与两列分组策略
+	if err := db.AutoMigrate(&UserRole{}, &CasbinRule{}); err != nil {
+		t.Fatalf("AutoMigrate() error = %v", err)
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX " + legacyUserRoleIndex + " ON rbac_user_roles (uid, role_code)").Error; err != nil {
+		t.Fatalf("create legacy index error = %v", err)
+	}
+	if err := db.Create(&CasbinRule{Ptype: "g", V0: "alice", V1: "EDITOR"}).Error; err != nil {
+		t.Fatalf("create legacy rule error = %v", err)
+	}
+
+	ctx := context.Background()
+	if err := migrate.ApplyModule(ctx, db, MigrationModule, CasbinMigrationModule); err != nil {
+		t.Fatalf("ApplyModule() error = %v", err)
+	}
+	if db.Migrator().HasIndex(&UserRole{}, legacyUserRoleIndex) {
+		t.Fatalf("legacy index %s should be dropped by migration", legacyUserRoleIndex)
+	}
+	for _, tenant := range []string{"tenant-a", "tenant-b"} {
+		if err := db.Create(&UserRole{UID: "bob", RoleCode: "EDITOR", TenantCode: tenant}).Error; err != nil {
+			t.Fatalf("grant EDITOR in %s error = %v", tenant, err)
+		}
+	}
+	var rule CasbinRule
+	if err := db.Where("ptype = ? AND v0 = ?", "g", "alice").Take(&rule).Error; err != nil || rule.V2 != GlobalTenant {
+		t.Fatalf("legacy rule = %+v, %v, want tenant %s", rule, err, GlobalTenant)
+	}
+}
@@ -0,0 +1,439 @@
+package rbacadmin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/changeguard"
+	commonhttp "github.com/goodbye-jack/go-common/http"
+	"github.com/goodbye-jack/go-common/rbac"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultRoutePrefix = "/api/v1/rbac"
+	defaultPageSize    = 20
+	maxPageSize        = 100
+)
+
+// Options 管理模块选项
+type Options struct {
+	// Prefix 路由前缀，为空时使用 DefaultRoutePrefix
+	Prefix string
+	// Guard 非空时为角色、继承与用户授予的变更注册 changeguard 审计绑定，
+	// 调用方需在全部路由注册完成后、Prepare 之前执行 Guard.Bind(server)
+	Guard *changeguard.Engine
+	// SecondFactorMode 非空时变更需二次验证，取值见 changeguard.SecondFactorMode*
+	SecondFactorMode string
+	// RiskLevel 审计事件风险等级，为空时为 high
+	RiskLevel string
+}
+
+type roleRequest struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Status *int   `json:"status"`
+}
+
+type inheritsRequest struct {
+	Inherits []string `json:"inherits"`
+}
+
+type userRolesRequest struct {
+	Tenant    string   `json:"tenant"`
+	RoleCodes []string `json:"role_codes"`
+}
+
+type grantRequest struct {
+	RoleCode   string     `json:"role_code" binding:"required"`
+	Tenant     string     `json:"tenant"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+	Reason     string     `json:"reason"`
+}
+
+// RoleView 角色及其继承的内部角色
+type RoleView struct {
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Status    int       `json:"status"`
+	Inherits  []string  `json:"inherits,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GrantView 用户的一条角色授予
+type GrantView struct {
+	RoleCode      string     `json:"role_code"`
+	TenantCode    string     `json:"tenant_code"`
+	ValidFrom     *time.Time `json:"valid_from,omitempty"`
+	ValidUntil    *time.Time `json:"valid_until,omitempty"`
+	Grantor       string     `json:"grantor,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+	DelegatedFrom string     `json:"delegated_from,omitempty"`
+	Active        bool       `json:"active"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Register 注册 RBAC 管理接口：角色 CRUD、角色继承、用户授予与路由权限查询，全部需 Admin
+func Register(server *commonhttp.HTTPServer, opts ...Options) {
+	if server == nil {
+		return
+	}
+	var opt Options
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	prefix := strings.TrimSuffix(opt.Prefix, "/")
+	if prefix == "" {
+		prefix = DefaultRoutePrefix
+	}
+	h := &handlers{server: server}
+	server.RouteWithPolicy(prefix+"/roles", "角色列表", []string{http.MethodGet}, commonhttp.Admin(), h.listRoles)
+	server.RouteWithPolicy(prefix+"/roles", "新建业务角色", []string{http.MethodPost}, commonhttp.Admin(), h.createRole)
+	server.RouteWithPolicy(prefix+"/roles/:code", "角色详情", []string{http.MethodGet}, commonhttp.Admin(), h.getRole)
+	server.RouteWithPolicy(prefix+"/roles/:code", "更新业务角色", []string{http.MethodPut}, commonhttp.Admin(), h.updateRole)
+	server.RouteWithPolicy(prefix+"/roles/:code", "删除业务角色", []string{http.MethodDelete}, commonhttp.Admin(), h.deleteRole)
+	server.RouteWithPolicy(prefix+"/roles/:code/inherits", "角色继承", []string{http.MethodGet}, commonhttp.Admin(), h.getInherits)
+	server.RouteWithPolicy(prefix+"/roles/:code/inherits", "设置角色继承", []string{http.MethodPut}, commonhttp.Admin(), h.setInherits)
+	server.RouteWithPolicy(prefix+"/roles/:code/policies", "角色权限策略", []string{http.MethodGet}, commonhttp.Admin(), h.rolePolicies)
+	server.RouteWithPolicy(prefix+"/users/:uid/roles", "用户角色", []string{http.MethodGet}, commonhttp.Admin(), h.userRoles)
+	server.RouteWithPolicy(prefix+"/users/:uid/roles", "覆盖用户角色", []string{http.MethodPut}, commonhttp.Admin(), h.setUserRoles)
+	server.RouteWithPolicy(prefix+"/users/:uid/roles/grant", "授予用户角色", []string{http.MethodPost}, commonhttp.Admin(), h.grant)
+	server.RouteWithPolicy(prefix+"/users/:uid/roles/history", "用户授予历史", []string{http.MethodGet}, commonhttp.Admin(), h.history)
+	server.RouteWithPolicy(prefix+"/users/:uid/roles/:code", "撤销用户角色", []string{http.MethodDelete}, commonhttp.Admin(), h.revoke)
+	server.RouteWithPolicy(prefix+"/permissions", "路由权限列表", []string{http.MethodGet}, commonhttp.Admin(), h.permissions)
+	if opt.Guard != nil {
+		registerGuard(opt.Guard, prefix, opt)
+	}
+}
+
+type handlers struct {
+	server *commonhttp.HTTPServer
+}
+
+func (h *handlers) listRoles(c *gin.Context) {
+	page, pageSize := parsePage(c)
+	roles, total, err := rbac.ListRoles(rbac.RoleQuery{
+		Type:     c.Query("type"),
+		Keyword:  c.Query("keyword"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	views := make([]RoleView, 0, len(roles))
+	for _, role := range roles {
+		views = append(views, newRoleView(role, nil))
+	}
+	commonhttp.JsonResponsePage(c, page, pageSize, total, views, err)
+}
+
+func (h *handlers) getRole(c *gin.Context) {
+	view, err := loadRoleView(c.Param("code"))
+	commonhttp.JsonResponseNew(c, view, wrapError(err))
+}
+
+func (h *handlers) createRole(c *gin.Context) {
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	code := strings.TrimSpace(req.Code)
+	if code == "" || rbac.IsInternalRoleCode(code) {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: "invalid business role code"})
+		return
+	}
+	if _, err := rbac.GetRole(code); err == nil {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.BusinessError{Code: http.StatusConflict, Message: fmt.Sprintf("role %s already exists", code)})
+		return
+	} else if !errors.Is(err, rbac.ErrRoleNotFound) {
+		commonhttp.JsonResponseNew(c, nil, err)
+		return
+	}
+	status := 1
+	if req.Status != nil {
+		status = *req.Status
+	}
+	if err := rbac.EnsureBusinessRole(code, req.Name, status); err != nil {
+		commonhttp.JsonResponseNew(c, nil, err)
+		return
+	}
+	view, err := loadRoleView(code)
+	commonhttp.JsonResponseNew(c, view, wrapError(err))
+}
+
+func (h *handlers) updateRole(c *gin.Context) {
+	code, ok := businessRole(c)
+	if !ok {
+		return
+	}
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	status := -1
+	if req.Status != nil {
+		status = *req.Status
+	}
+	if err := rbac.UpdateBusinessRole(code, req.Name, status); err != nil {
+		commonhttp.JsonResponseNew(c, nil, wrapError(err))
+		return
+	}
+	view, err := loadRoleView(code)
+	commonhttp.JsonResponseNew(c, view, wrapError(err))
+}
+
+func (h *handlers) deleteRole(c *gin.Context) {
+	code, ok := businessRole(c)
+	if !ok {
+		return
+	}
+	err := rbac.DeleteBusinessRole(code)
+	commonhttp.JsonResponseNew(c, gin.H{"code": code}, wrapError(err))
+}
+
+func (h *handlers) getInherits(c *gin.Context) {
+	code, ok := businessRole(c)
+	if !ok {
+		return
+	}
+	inherits, err := rbac.ListRoleInherits(code)
+	commonhttp.JsonResponseNew(c, gin.H{"code": code, "inherits": inherits}, err)
+}
+
+func (h *handlers) setInherits(c *gin.Context) {
+	code, ok := businessRole(c)
+	if !ok {
+		return
+	}
+	var req inheritsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	for _, inherit := range req.Inherits {
+		if inherit = strings.TrimSpace(inherit); inherit != "" && !rbac.IsInternalRoleCode(inherit) {
+			commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: fmt.Sprintf("inherit role %s is not internal", inherit)})
+			return
+		}
+	}
+	if err := rbac.SetRoleInherits(code, req.Inherits); err != nil {
+		commonhttp.JsonResponseNew(c, nil, wrapError(err))
+		return
+	}
+	inherits, err := rbac.ListRoleInherits(code)
+	commonhttp.JsonResponseNew(c, gin.H{"code": code, "inherits": inherits}, err)
+}
+
+func (h *handlers) rolePolicies(c *gin.Context) {
+	code := strings.TrimSpace(c.Param("code"))
+	if _, err := rbac.GetRole(code); err != nil {
+		commonhttp.JsonResponseNew(c, nil, wrapError(err))
+		return
+	}
+	policies, err := commonhttp.RbacClient.GetActionPolicies(code)
+	commonhttp.JsonResponseNew(c, policies, err)
+}
+
+func (h *handlers) userRoles(c *gin.Context) {
+	grants, err := loadGrants(c.Param("uid"))
+	commonhttp.JsonResponseNew(c, grants, err)
+}
+
+func (h *handlers) setUserRoles(c *gin.Context) {
+	uid := c.Param("uid")
+	var req userRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if !rolesExist(c, req.RoleCodes...) {
+		return
+	}
+	if err := rbac.SetTenantUserRoles(uid, req.Tenant, req.RoleCodes); err != nil {
+		commonhttp.JsonResponseNew(c, nil, err)
+		return
+	}
+	grants, err := loadGrants(uid)
+	commonhttp.JsonResponseNew(c, grants, err)
+}
+
+func (h *handlers) grant(c *gin.Context) {
+	uid := c.Param("uid")
+	var req grantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if !rolesExist(c, req.RoleCode) {
+		return
+	}
+	opts := rbac.GrantOptions{Tenant: req.Tenant, Grantor: commonhttp.GetUser(c), Reason: req.Reason}
+	if req.ValidFrom != nil {
+		opts.ValidFrom = *req.ValidFrom
+	}
+	if req.ValidUntil != nil {
+		opts.ValidUntil = *req.ValidUntil
+	}
+	if err := rbac.GrantUserRole(uid, req.RoleCode, opts); err != nil {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	grants, err := loadGrants(uid)
+	commonhttp.JsonResponseNew(c, grants, err)
+}
+
+func (h *handlers) revoke(c *gin.Context) {
+	uid, code, tenant := c.Param("uid"), c.Param("code"), c.Query("tenant")
+	err := rbac.RevokeUserRole(uid, tenant, code, commonhttp.GetUser(c), c.Query("reason"))
+	commonhttp.JsonResponseNew(c, gin.H{"uid": uid, "role_code": code, "tenant": tenant}, err)
+}
+
+func (h *handlers) history(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	history, err := rbac.ListUserRoleHistory(c.Param("uid"), limit)
+	commonhttp.JsonResponseNew(c, history, err)
+}
+
+// permissions 本服务已注册路由的鉴权要求，支持 role(要求该角色)、keyword(路径或说明) 过滤
+func (h *handlers) permissions(c *gin.Context) {
+	page, pageSize := parsePage(c)
+	role, keyword := strings.TrimSpace(c.Query("role")), strings.TrimSpace(c.Query("keyword"))
+	entries := commonhttp.BuildAuthRouteRegistry(h.server.GetRoutes())
+	filtered := make([]commonhttp.AuthRouteRegistryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if role != "" && !contains(entry.RequiredRoles, role) {
+			continue
+		}
+		if keyword != "" && !strings.Contains(entry.Path, keyword) && !strings.Contains(entry.Tips, keyword) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].Path < filtered[j].Path })
+	total := int64(len(filtered))
+	start := min((page-1)*pageSize, len(filtered))
+	end := min(start+pageSize, len(filtered))
+	commonhttp.JsonResponsePage(c, page, pageSize, total, filtered[start:end], nil)
+}
+
+func newRoleView(role rbac.Role, inherits []string) RoleView {
+	return RoleView{
+		Code:      role.Code,
+		Name:      role.Name,
+		Type:      role.Type,
+		Status:    role.Status,
+		Inherits:  inherits,
+		CreatedAt: role.CreatedAt,
+		UpdatedAt: role.UpdatedAt,
+	}
+}
+
+func loadRoleView(code string) (*RoleView, error) {
+	role, err := rbac.GetRole(code)
+	if err != nil {
+		return nil, err
+	}
+	var inherits []string
+	if role.Type == rbac.RoleTypeBusiness {
+		if inherits, err = rbac.ListRoleInherits(role.Code); err != nil {
+			return nil, err
+		}
+	}
+	view := newRoleView(*role, inherits)
+	return &view, nil
+}
+
+func loadGrants(uid string) ([]GrantView, error) {
+	rows, err := rbac.ListUserRoleGrants(uid)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	views := make([]GrantView, 0, len(rows))
+	for _, row := range rows {
+		views = append(views, GrantView{
+			RoleCode:      row.RoleCode,
+			TenantCode:    row.TenantCode,
+			ValidFrom:     row.ValidFrom,
+			ValidUntil:    row.ValidUntil,
+			Grantor:       row.Grantor,
+			Reason:        row.Reason,
+			DelegatedFrom: row.DelegatedFrom,
+			Active:        row.ValidFrom == nil || !row.ValidFrom.After(now),
+			CreatedAt:     row.CreatedAt,
+		})
+	}
+	return views, nil
+}
+
+// businessRole 读取路径中的角色编码并确认是已存在的业务角色，失败时已写响应
+func businessRole(c *gin.Context) (string, bool) {
+	code := strings.TrimSpace(c.Param("code"))
+	role, err := rbac.GetRole(code)
+	if err != nil {
+		commonhttp.JsonResponseNew(c, nil, wrapError(err))
+		return "", false
+	}
+	if role.Type != rbac.RoleTypeBusiness {
+		commonhttp.JsonResponseNew(c, nil, &commonhttp.ParameterError{Code: http.StatusBadRequest, Message: fmt.Sprintf("role %s is not business", code)})
+		return "", false
+	}
+	return role.Code, true
+}
+
+func rolesExist(c *gin.Context, codes ...string) bool {
+	for _, code := range codes {
+		if strings.TrimSpace(code) == "" {
+			continue
+		}
+		if _, err := rbac.GetRole(code); err != nil {
+			commonhttp.JsonResponseNew(c, nil, wrapError(fmt.Errorf("role %s: %w", code, err)))
+			return false
+		}
+	}
+	return true
+}
+
+func parsePage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return page, pageSize
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func wrapError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, rbac.ErrRoleNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return &commonhttp.BusinessError{Code: http.StatusNotFound, Message: err.Error()}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,124 @@
+package rbacadmin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/changeguard"
+	commonhttp "github.com/goodbye-jack/go-common/http"
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/rbac"
+	"github.com/goodbye-jack/go-common/utils"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	orm.DB = orm.NewOrm(filepath.Join(t.TempDir(), "rbac.db"), utils.DBTypeSQLite, 5)
+	defer func() { orm.DB = nil }()
+	if err := rbac.Configure(rbac.Options{AdapterType: rbac.AdapterMemory}); err != nil {
+		t.Fatalf("rbac.Configure() error = %v", err)
+	}
+	if _, err := rbac.EnsureInternalRole("orders", rbac.ActionRead); err != nil {
+		t.Fatalf("EnsureInternalRole() error = %v", err)
+	}
+
+	server := commonhttp.NewHTTPServer("svc")
+	Register(server)
+	for _, route := range server.GetRoutes() {
+		if !strings.HasPrefix(route.Url, DefaultRoutePrefix) {
+			continue
+		}
+		if policy := route.EffectiveAuthPolicy(); policy == nil || policy.Name != commonhttp.Admin().Name {
+			t.Fatalf("route %s should require Admin, got %+v", route.Url, policy)
+		}
+	}
+
+	h := &handlers{server: server}
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) { c.Set("UserID", "root") })
+	engine.GET(DefaultRoutePrefix+"/roles", h.listRoles)
+	engine.POST(DefaultRoutePrefix+"/roles", h.createRole)
+	engine.PUT(DefaultRoutePrefix+"/roles/:code", h.updateRole)
+	engine.DELETE(DefaultRoutePrefix+"/roles/:code", h.deleteRole)
+	engine.PUT(DefaultRoutePrefix+"/roles/:code/inherits", h.setInherits)
+	engine.GET(DefaultRoutePrefix+"/users/:uid/roles", h.userRoles)
+	engine.POST(DefaultRoutePrefix+"/users/:uid/roles/grant", h.grant)
+	engine.DELETE(DefaultRoutePrefix+"/users/:uid/roles/:code", h.revoke)
+	engine.GET(DefaultRoutePrefix+"/users/:uid/roles/history", h.history)
+	engine.GET(DefaultRoutePrefix+"/permissions", h.permissions)
+
+	serve := func(method, path, body string) (int, map[string]any) {
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, httptest.NewRequest(method, DefaultRoutePrefix+path, bytes.NewBufferString(body)))
+		var resp map[string]any
+		_ = json.Unmarshal(recorder.Body.Bytes(), &resp)
+		return recorder.Code, resp
+	}
+
+	if code, _ := serve("POST", "/roles", `{"code":"MANAGER","name":"经理"}`); code != http.StatusOK {
+		t.Fatalf("create role status = %d", code)
+	}
+	if code, _ := serve("POST", "/roles", `{"code":"MANAGER"}`); code != http.StatusConflict {
+		t.Fatalf("duplicate role status = %d", code)
+	}
+	if code, _ := serve("PUT", "/roles/NOPE", `{"name":"x"}`); code != http.StatusNotFound {
+		t.Fatalf("update missing role status = %d", code)
+	}
+	if code, _ := serve("PUT", "/roles/MANAGER/inherits", `{"inherits":["MANAGER"]}`); code != http.StatusBadRequest {
+		t.Fatalf("inherit business role status = %d", code)
+	}
+	if code, _ := serve("PUT", "/roles/MANAGER/inherits", `{"inherits":["internal.orders.read"]}`); code != http.StatusOK {
+		t.Fatalf("set inherits status = %d", code)
+	}
+	code, resp := serve("GET", "/roles?type=business&page_size=1", "")
+	if code != http.StatusOK || resp["total"].(float64) != 1 || len(resp["data"].([]any)) != 1 {
+		t.Fatalf("list roles = %d %v", code, resp)
+	}
+
+	if code, _ := serve("POST", "/users/alice/roles/grant", `{"role_code":"NOPE"}`); code != http.StatusNotFound {
+		t.Fatalf("grant missing role status = %d", code)
+	}
+	if code, _ := serve("POST", "/users/alice/roles/grant", `{"role_code":"MANAGER","tenant":"t1","reason":"onboard"}`); code != http.StatusOK {
+		t.Fatalf("grant status = %d", code)
+	}
+	if roles, _ := rbac.ListUserTenantRoles("alice"); len(roles["t1"]) != 1 {
+		t.Fatalf("alice roles = %v", roles)
+	}
+	session := &changeguard.Session{Context: paramContext("uid", "alice")}
+	if state, err := userRolesSnapshot(session); err != nil || state.Value["grants"].([]string)[0] != "t1/MANAGER" {
+		t.Fatalf("userRolesSnapshot() = %+v, %v", state, err)
+	}
+	if code, _ := serve("DELETE", "/users/alice/roles/MANAGER?tenant=t1&reason=offboard", ""); code != http.StatusOK {
+		t.Fatalf("revoke status = %d", code)
+	}
+	code, resp = serve("GET", "/users/alice/roles/history", "")
+	if history := resp["data"].([]any); code != http.StatusOK || len(history) != 2 || history[0].(map[string]any)["Operator"] != "root" {
+		t.Fatalf("history = %d %v", code, resp)
+	}
+
+	if code, _ := serve("DELETE", "/roles/MANAGER", ""); code != http.StatusOK {
+		t.Fatalf("delete role status = %d", code)
+	}
+	session = &changeguard.Session{Context: paramContext("code", "MANAGER")}
+	if state, err := roleSnapshot(session); err != nil || state != nil {
+		t.Fatalf("roleSnapshot(deleted) = %+v, %v", state, err)
+	}
+
+	code, resp = serve("GET", "/permissions?keyword=/users/&page_size=2", "")
+	if code != http.StatusOK || resp["total"].(float64) != 5 || len(resp["data"].([]any)) != 2 {
+		t.Fatalf("permissions = %d %v", code, resp)
+	}
+}
+
+func paramContext(key, value string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Params = gin.Params{{Key: key, Value: value}}
+	return c
+}
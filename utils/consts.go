@@ -35,8 +35,18 @@ const (
 type DBMode string
 
 const (
-	DBModeSingle  DBMode = "single"  // 单点
-	DBModeCluster DBMode = "cluster" // 集群
+	DBModeSingle   DBMode = "single"   // 单点
+	DBModeCluster  DBMode = "cluster"  // 集群
+	DBModeSentinel DBMode = "sentinel" // 哨兵(目前仅 Redis)
+)
+
+// ReadStrategy 集群模式下只读副本的选择策略
+type ReadStrategy string
+
+const (
+	ReadStrategyRoundRobin   ReadStrategy = "round_robin"   // 轮询
+	ReadStrategyRandom       ReadStrategy = "random"        // 随机
+	ReadStrategyLeastLatency ReadStrategy = "least_latency" // 最低延迟
 )
 
 type LogMode string
@@ -67,7 +77,7 @@ const (
 var DBDsnMap = map[DBType]string{
 	DBTypeSQLite:    "%s",
 	DBTypeDM:        "dm://%s:%s@%s:%d?schema=%s",
-	DBTypeOracle:    "%s/%s@%s:%d/%s",
+	DBTypeOracle:    "oracle://%s:%s@%s:%d/%s",
 	DBTypeMySQL:     "%s:%s@tcp(%s:%d)/%s?parseTime=True&loc=Local",
 	DBTypePostgres:  "user=%s password=%s host=%s port=%d dbname=%s sslmode=disable TimeZone=Asia/Shanghai",
 	DBTypeKingBase:  "user=%s password=%s host=%s port=%d dbname=%s sslmode=disable TimeZone=Asia/Shanghai",
@@ -83,6 +93,9 @@ const (
 	DefaultMySQLMaxOpenConn     = 100
 	DefaultMySQLMaxIdleConn     = 10
 	DefaultMySQLConnMaxLifeTime = 5 * time.Minute
+	// 只读副本默认值
+	DefaultReplicaCheckInterval = 10 * time.Second
+	DefaultReplicaMaxFailures   = 3
 	// Mongo默认值
 	DefaultMongoMaxPoolSize    = 20
 	DefaultMongoMinPoolSize    = 5
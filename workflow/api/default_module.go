@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -13,10 +14,14 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/approval"
 	"github.com/goodbye-jack/go-common/config"
 	commonhttp "github.com/goodbye-jack/go-common/http"
 	"github.com/goodbye-jack/go-common/log"
+	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/rbac"
 	"github.com/goodbye-jack/go-common/utils"
+	"github.com/goodbye-jack/go-common/workflow/approvalbridge"
 	"github.com/goodbye-jack/go-common/workflow/assignment"
 	workflowcontext "github.com/goodbye-jack/go-common/workflow/context"
 	"github.com/goodbye-jack/go-common/workflow/contract"
@@ -59,6 +64,8 @@ type DefaultModule struct {
 	requireSSO   bool
 	callbackPath string
 	callbackKey  string
+	listeners    []CallbackListener
+	delegates    DelegateResolver
 }
 
 type workflowRouteDefinition struct {
@@ -90,10 +97,38 @@ func RegisterFromConfigWithOptions(server *commonhttp.HTTPServer, options Regist
 		return err
 	}
 	logStartupSummary(module, provider, assignmentProvider)
+	if err := module.registerApproval(server, options.ApprovalStore); err != nil {
+		return err
+	}
 	module.Register(server)
 	return nil
 }
 
+// registerApproval workflow.approval.enabled=true 时以 Flowable 流程作为业务审批处理器，
+// 路由需在 RouteAPI 中声明 businessApproval=true，并在 workflow.approval.routes 中配置流程映射
+func (m *DefaultModule) registerApproval(server *commonhttp.HTTPServer, store approval.Store) error {
+	if server == nil || !approvalbridge.EnabledFromConfig() {
+		return nil
+	}
+	if store == nil {
+		if orm.DB == nil {
+			return errors.New("workflow approval requires orm.DB or RegisterOptions.ApprovalStore")
+		}
+		store = approval.NewGormStore(orm.DB.GetDB())
+	}
+	service, err := approvalbridge.NewServiceFromConfig(m.flowable, m.resolver, store, server.GetServiceName())
+	if err != nil {
+		return err
+	}
+	server.UseApprovalService(service)
+	m.WithCallbackListener(approvalbridge.NewCallbackListener(service))
+	if approvalbridge.PollEnabledFromConfig() {
+		service.Start(context.Background())
+	}
+	log.Infof("【workflow】业务审批已接入 Flowable，poll=%v", approvalbridge.PollEnabledFromConfig())
+	return nil
+}
+
 func MustRegisterFromConfig(server *commonhttp.HTTPServer) {
 	MustRegisterFromConfigWithOptions(server, RegisterOptions{})
 }
@@ -186,6 +221,8 @@ func newModuleWithProviderFromConfig(options RegisterOptions) (*DefaultModule, s
 		AssignmentService: options.AssignmentService,
 		FormRefService:    options.FormRefService,
 		ContractPolicy:    options.ContractPolicy,
+		CallbackListeners: options.CallbackListeners,
+		DelegateResolver:  options.DelegateResolver,
 	})
 	if err != nil {
 		return nil, provider, assignmentProvider, err
@@ -196,6 +233,9 @@ func newModuleWithProviderFromConfig(options RegisterOptions) (*DefaultModule, s
 	if options.FormRefService == nil {
 		module.formref = formref.NewFlowableService(flowableClient)
 	}
+	if options.DelegateResolver == nil {
+		module.delegates = RbacDelegateResolver
+	}
 	return module, provider, assignmentProvider, nil
 }
 
@@ -227,6 +267,17 @@ func (m *DefaultModule) WithContractPolicy(policy *contract.Policy) *DefaultModu
 	return m
 }
 
+// WithCallbackListener 追加 Flowable 回调监听器，在内置投影处理之后按注册顺序执行
+func (m *DefaultModule) WithCallbackListener(listener CallbackListener) *DefaultModule {
+	if m == nil {
+		return nil
+	}
+	if listener != nil {
+		m.listeners = append(m.listeners, listener)
+	}
+	return m
+}
+
 func (m *DefaultModule) applyOptions(options RegisterOptions) {
 	if m == nil {
 		return
@@ -243,6 +294,12 @@ func (m *DefaultModule) applyOptions(options RegisterOptions) {
 	if options.ContractPolicy != nil {
 		m.contract = options.ContractPolicy
 	}
+	if options.DelegateResolver != nil {
+		m.delegates = options.DelegateResolver
+	}
+	for _, listener := range options.CallbackListeners {
+		m.WithCallbackListener(listener)
+	}
 	if m.contract == nil {
 		m.contract = contract.DefaultPolicy()
 	}
@@ -662,6 +719,9 @@ func (m *DefaultModule) handleDelegateTask(c *gin.Context) {
 		writeError(c, http.StatusBadRequest, "invalid delegate task request")
 		return
 	}
+	if strings.TrimSpace(request.Assignee) == "" {
+		request.Assignee = m.resolveDelegate(c.Request.Context(), user)
+	}
 	response, err := m.flowable.DelegateTask(c.Request.Context(), strings.TrimSpace(c.Param("id")), request, user)
 	if err != nil {
 		writeWorkflowError(c, err)
@@ -698,6 +758,9 @@ func (m *DefaultModule) handleTransferTask(c *gin.Context) {
 		writeError(c, http.StatusBadRequest, "invalid transfer task request")
 		return
 	}
+	if strings.TrimSpace(request.Assignee) == "" {
+		request.Assignee = m.resolveDelegate(c.Request.Context(), user)
+	}
 	response, err := m.flowable.TransferTask(c.Request.Context(), strings.TrimSpace(c.Param("id")), request, user)
 	if err != nil {
 		writeWorkflowError(c, err)
@@ -868,9 +931,34 @@ func (m *DefaultModule) handleCallback(c *gin.Context) {
 		writeWorkflowError(c, err)
 		return
 	}
+	for _, listener := range m.listeners {
+		if err := listener(c.Request.Context(), &payload); err != nil {
+			log.Warnf("【workflow】回调监听器处理失败，event=%s，process_instance_id=%s，err=%v", payload.EventType, payload.ProcessInstanceID, err)
+			writeWorkflowError(c, err)
+			return
+		}
+	}
 	writeOK(c, gin.H{"accepted": true})
 }
 
+// resolveDelegate 查询用户当前的代理人，失败时记录日志并返回空串，由后续校验提示缺少处理人
+func (m *DefaultModule) resolveDelegate(ctx context.Context, user *workflowcontext.UserContext) string {
+	if m.delegates == nil || user == nil {
+		return ""
+	}
+	delegate, err := m.delegates(ctx, user)
+	if err != nil {
+		log.Warnf("【workflow】查询代理人失败, user=%s, err=%v", user.UserID, err)
+		return ""
+	}
+	return delegate
+}
+
+// RbacDelegateResolver 使用 rbac.DelegateRoles 登记的委托关系选择代理人
+func RbacDelegateResolver(_ context.Context, user *workflowcontext.UserContext) (string, error) {
+	return rbac.ActiveDelegate(user.UserID, user.TenantID)
+}
+
 func (m *DefaultModule) requireUser(c *gin.Context) (*workflowcontext.UserContext, bool) {
 	user, err := m.resolver.Resolve(c)
 	if err != nil {
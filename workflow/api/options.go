@@ -1,15 +1,31 @@
 package api
 
 import (
+	"context"
+
+	"github.com/goodbye-jack/go-common/approval"
 	"github.com/goodbye-jack/go-common/workflow/assignment"
+	workflowcontext "github.com/goodbye-jack/go-common/workflow/context"
 	"github.com/goodbye-jack/go-common/workflow/contract"
 	"github.com/goodbye-jack/go-common/workflow/directory"
 	"github.com/goodbye-jack/go-common/workflow/formref"
+	"github.com/goodbye-jack/go-common/workflow/types"
 )
 
+// CallbackListener Flowable 回调处理完成后追加执行的监听器
+type CallbackListener func(ctx context.Context, payload *types.FlowableCallbackPayload) error
+
+// DelegateResolver 返回用户当前的代理人，委派/转办请求未指定处理人时使用，没有代理人时返回空串
+type DelegateResolver func(ctx context.Context, user *workflowcontext.UserContext) (string, error)
+
 type RegisterOptions struct {
 	DirectoryService  directory.Service
 	AssignmentService assignment.Service
 	FormRefService    formref.Service
 	ContractPolicy    *contract.Policy
+	CallbackListeners []CallbackListener
+	// ApprovalStore 业务审批请求存储，workflow.approval.enabled=true 时使用，为空则基于 orm.DB
+	ApprovalStore approval.Store
+	// DelegateResolver 为空时按配置创建的模块使用 RbacDelegateResolver
+	DelegateResolver DelegateResolver
 }
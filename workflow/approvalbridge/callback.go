@@ -0,0 +1,109 @@
+package approvalbridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goodbye-jack/go-common/approval"
+	"github.com/goodbye-jack/go-common/config"
+	"github.com/goodbye-jack/go-common/log"
+	workflowcontext "github.com/goodbye-jack/go-common/workflow/context"
+	"github.com/goodbye-jack/go-common/workflow/engine/flowable"
+	"github.com/goodbye-jack/go-common/workflow/types"
+)
+
+// 流程结束时用于判断审批结果的变量，按顺序取第一个存在的值
+var approvalResultVariables = []string{"approvalResult", "approved", "result", "outcome"}
+
+// NewCallbackListener PROCESS_ENDED 回调时根据流程变量记录审批结果后返回，审批通过的请求在后台回放，
+// 回放不占用 Flowable 的回调请求；非审批中间件发起的流程（找不到待审批记录）直接忽略
+func NewCallbackListener(service *approval.Service) func(ctx context.Context, payload *types.FlowableCallbackPayload) error {
+	return func(ctx context.Context, payload *types.FlowableCallbackPayload) error {
+		if service == nil || payload == nil {
+			return nil
+		}
+		if !strings.EqualFold(strings.TrimSpace(payload.EventType), "PROCESS_ENDED") {
+			return nil
+		}
+		status := ResolveEndedStatus(payload.Variables)
+		err := service.Record(ctx, strings.TrimSpace(payload.ProcessInstanceID), status)
+		switch {
+		case err == nil:
+			log.Infof("【workflow】审批流程结束，process_instance_id=%s，status=%s", payload.ProcessInstanceID, status)
+			return nil
+		case errors.Is(err, approval.ErrPendingRequestNotFound), errors.Is(err, approval.ErrStatusConflict):
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+// ResolveEndedStatus 将流程结束变量转换为审批状态；只有显式的通过结果才视为通过，
+// 未携带结果变量或结果无法识别(如 "pending"、"退回")时按驳回处理，避免误放行
+func ResolveEndedStatus(variables map[string]interface{}) approval.Status {
+	for _, key := range approvalResultVariables {
+		value, ok := variables[key]
+		if !ok || value == nil {
+			continue
+		}
+		switch typed := value.(type) {
+		case bool:
+			if typed {
+				return approval.StatusApproved
+			}
+			return approval.StatusRejected
+		default:
+			switch strings.ToLower(strings.TrimSpace(fmt.Sprint(typed))) {
+			case "approved", "approve", "pass", "passed", "agree", "true", "yes":
+				return approval.StatusApproved
+			case "rejected", "reject", "deny", "denied", "disagree", "false", "no":
+				return approval.StatusRejected
+			case "cancelled", "canceled", "terminated":
+				return approval.StatusCancelled
+			}
+			log.Warnf("【workflow】无法识别的审批结果变量，按驳回处理，%s=%v", key, typed)
+			return approval.StatusRejected
+		}
+	}
+	log.Warnf("【workflow】流程结束但未携带审批结果变量(%s)，按驳回处理", strings.Join(approvalResultVariables, "/"))
+	return approval.StatusRejected
+}
+
+// NewServiceFromConfig 按 workflow.approval 配置构建审批服务：
+// 路由 -> 流程定义映射决定是否需要审批，审批请求持久化到 store
+func NewServiceFromConfig(client flowable.Client, resolver workflowcontext.Resolver, store approval.Store, serviceName string) (*approval.Service, error) {
+	bindings, err := LoadRouteBindingsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	routeBindings, err := NewRouteBindings(bindings)
+	if err != nil {
+		return nil, err
+	}
+	handler, err := NewFlowableHandler(client, resolver, routeBindings.BuildStartRequest)
+	if err != nil {
+		return nil, err
+	}
+	handler.WithShouldApprove(routeBindings.ShouldApprove).WithRouteCheck(routeBindings.HasRoute)
+	opts := []approval.ServiceOption{approval.WithServiceName(serviceName)}
+	if ttl := config.GetConfigInt(configApprovalTTLSeconds); ttl > 0 {
+		opts = append(opts, approval.WithRequestTTL(time.Duration(ttl)*time.Second))
+	}
+	if interval := config.GetConfigInt(configApprovalPollInterval); interval > 0 {
+		opts = append(opts, approval.WithPollInterval(time.Duration(interval)*time.Second))
+	}
+	if timeout := config.GetConfigInt(configApprovalReplayTimeout); timeout > 0 {
+		opts = append(opts, approval.WithReplayTimeout(time.Duration(timeout)*time.Second))
+	}
+	log.Infof("【workflow】业务审批路由映射加载完成，routes=%d", routeBindings.Len())
+	return approval.NewService(handler, store, opts...)
+}
+
+// PollEnabledFromConfig 是否启用审批状态轮询（回调不可达时的兜底）
+func PollEnabledFromConfig() bool {
+	return config.GetConfigInt(configApprovalPollInterval) > 0
+}
@@ -13,14 +13,16 @@ import (
 )
 
 const (
-	StatusPending  = 1
-	StatusApproved = 2
+	StatusPending  = approval.StatusPending
+	StatusApproved = approval.StatusApproved
+	StatusRejected = approval.StatusRejected
 )
 
 type ShouldApproveFunc func(c *gin.Context) bool
 type StartRequestBuilder func(c *gin.Context, requestData map[string]interface{}) (*types.StartProcessRequest, error)
-type StatusResolver func(view *types.ProcessProgressViewResponse) (int, error)
+type StatusResolver func(view *types.ProcessProgressViewResponse) (approval.Status, error)
 type ApprovedExecutor func(c *gin.Context, processID string, requestData []byte) error
+type RouteCheckFunc func(routePath, method string) bool
 
 type FlowableHandler struct {
 	client          flowable.Client
@@ -29,9 +31,13 @@ type FlowableHandler struct {
 	buildRequest    StartRequestBuilder
 	resolveStatus   StatusResolver
 	executeApproved ApprovedExecutor
+	hasRoute        RouteCheckFunc
 }
 
-var _ approval.ApprovalHandler = (*FlowableHandler)(nil)
+var (
+	_ approval.ApprovalHandler = (*FlowableHandler)(nil)
+	_ approval.RouteChecker    = (*FlowableHandler)(nil)
+)
 
 func NewFlowableHandler(client flowable.Client, resolver workflowcontext.Resolver, builder StartRequestBuilder) (*FlowableHandler, error) {
 	if client == nil {
@@ -72,6 +78,22 @@ func (h *FlowableHandler) WithApprovedExecutor(fn ApprovedExecutor) *FlowableHan
 	return h
 }
 
+// WithRouteCheck 声明已配置审批流程的路由，未配置的 businessApproval 路由将被拒绝
+func (h *FlowableHandler) WithRouteCheck(fn RouteCheckFunc) *FlowableHandler {
+	if h == nil || fn == nil {
+		return h
+	}
+	h.hasRoute = fn
+	return h
+}
+
+func (h *FlowableHandler) HasRoute(routePath, method string) bool {
+	if h != nil && h.hasRoute != nil {
+		return h.hasRoute(routePath, method)
+	}
+	return true
+}
+
 func (h *FlowableHandler) ShouldApprove(c *gin.Context) bool {
 	if h != nil && h.shouldApprove != nil {
 		return h.shouldApprove(c)
@@ -116,14 +138,14 @@ func (h *FlowableHandler) CreateApprovalProcess(c *gin.Context, requestData map[
 	return response.ProcessInstanceID, nil
 }
 
-func (h *FlowableHandler) GetApprovalProcess(c *gin.Context, processID string) (int, error) {
+func (h *FlowableHandler) GetApprovalProcess(c *gin.Context, processID string) (approval.Status, error) {
 	if h == nil || h.client == nil {
-		return 0, errors.New("workflow approval handler is not initialized")
+		return approval.StatusUnknown, errors.New("workflow approval handler is not initialized")
 	}
 	user, _ := h.resolveUser(c)
 	view, err := h.client.GetProgressView(contextOrBackground(c), strings.TrimSpace(processID), user)
 	if err != nil {
-		return 0, err
+		return approval.StatusUnknown, err
 	}
 	if h.resolveStatus == nil {
 		h.resolveStatus = defaultStatusResolver
@@ -145,12 +167,20 @@ func (h *FlowableHandler) resolveUser(c *gin.Context) (*workflowcontext.UserCont
 	return h.resolver.Resolve(c)
 }
 
-func defaultStatusResolver(view *types.ProcessProgressViewResponse) (int, error) {
+// defaultStatusResolver 进度视图只反映流程是否结束，不携带审批结果：
+// 仅显式的 approved/passed 视为通过；completed 无法区分通过与驳回，返回 StatusUnknown
+// 交由 PROCESS_ENDED 回调按结果变量判定(未回调时到期按过期处理)，需要轮询判定可通过 WithStatusResolver 自定义
+func defaultStatusResolver(view *types.ProcessProgressViewResponse) (approval.Status, error) {
 	if view == nil {
 		return StatusPending, nil
 	}
-	if strings.EqualFold(strings.TrimSpace(view.Summary.Status), "completed") {
+	switch strings.ToLower(strings.TrimSpace(view.Summary.Status)) {
+	case "approved", "passed":
 		return StatusApproved, nil
+	case "completed":
+		return approval.StatusUnknown, nil
+	case "rejected", "terminated", "cancelled", "canceled":
+		return StatusRejected, nil
 	}
 	return StatusPending, nil
 }
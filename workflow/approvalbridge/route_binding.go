@@ -0,0 +1,230 @@
+package approvalbridge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/config"
+	"github.com/goodbye-jack/go-common/workflow/types"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+const (
+	configApprovalEnabled       = "workflow.approval.enabled"
+	configApprovalRoutes        = "workflow.approval.routes"
+	configApprovalTTLSeconds    = "workflow.approval.ttl_seconds"
+	configApprovalPollInterval  = "workflow.approval.poll_interval_seconds"
+	configApprovalReplayTimeout = "workflow.approval.replay_timeout_seconds"
+)
+
+// RouteBinding 业务审批路由与 Flowable 流程定义的映射
+type RouteBinding struct {
+	// Path 路由模板路径，与 RouteAPI 注册时的 path 一致，如 /api/orders/:id
+	Path string `mapstructure:"path" json:"path"`
+	// Method 为空时匹配该路径的所有方法
+	Method               string `mapstructure:"method" json:"method"`
+	ProcessDefinitionKey string `mapstructure:"process_definition_key" json:"process_definition_key"`
+	// BusinessKeyTemplate text/template 模板，可用字段见 bindingTemplateData，为空时使用 流程key:request_id
+	BusinessKeyTemplate string `mapstructure:"business_key_template" json:"business_key_template"`
+	TitleTemplate       string `mapstructure:"title_template" json:"title_template"`
+	BizType             string `mapstructure:"biz_type" json:"biz_type"`
+}
+
+type compiledBinding struct {
+	RouteBinding
+	businessKey *template.Template
+	title       *template.Template
+}
+
+// RouteBindings 已编译的路由映射，提供 ShouldApproveFunc 与 StartRequestBuilder
+type RouteBindings struct {
+	bindings []compiledBinding
+}
+
+// bindingTemplateData 模板渲染数据
+type bindingTemplateData struct {
+	RequestID string
+	Method    string
+	Path      string
+	RoutePath string
+	UserID    string
+	Params    map[string]string
+	Query     map[string]string
+	Body      map[string]interface{}
+	Fields    map[string]interface{}
+}
+
+func EnabledFromConfig() bool {
+	return config.GetConfigBool(configApprovalEnabled)
+}
+
+// LoadRouteBindingsFromConfig 读取 workflow.approval.routes 配置
+func LoadRouteBindingsFromConfig() ([]RouteBinding, error) {
+	var bindings []RouteBinding
+	if err := viper.UnmarshalKey(configApprovalRoutes, &bindings); err != nil {
+		return nil, fmt.Errorf("decode %s failed: %w", configApprovalRoutes, err)
+	}
+	return bindings, nil
+}
+
+func NewRouteBindings(bindings []RouteBinding) (*RouteBindings, error) {
+	result := &RouteBindings{bindings: make([]compiledBinding, 0, len(bindings))}
+	for i, binding := range bindings {
+		binding.Path = strings.TrimSpace(binding.Path)
+		binding.Method = strings.ToUpper(strings.TrimSpace(binding.Method))
+		binding.ProcessDefinitionKey = strings.TrimSpace(binding.ProcessDefinitionKey)
+		if binding.Path == "" {
+			return nil, fmt.Errorf("%s[%d].path is required", configApprovalRoutes, i)
+		}
+		if binding.ProcessDefinitionKey == "" {
+			return nil, fmt.Errorf("%s[%d].process_definition_key is required", configApprovalRoutes, i)
+		}
+		compiled := compiledBinding{RouteBinding: binding}
+		var err error
+		if compiled.businessKey, err = parseBindingTemplate("business_key", binding.BusinessKeyTemplate); err != nil {
+			return nil, fmt.Errorf("%s[%d].business_key_template: %w", configApprovalRoutes, i, err)
+		}
+		if compiled.title, err = parseBindingTemplate("title", binding.TitleTemplate); err != nil {
+			return nil, fmt.Errorf("%s[%d].title_template: %w", configApprovalRoutes, i, err)
+		}
+		result.bindings = append(result.bindings, compiled)
+	}
+	return result, nil
+}
+
+func parseBindingTemplate(name string, text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	return template.New(name).Option("missingkey=zero").Parse(text)
+}
+
+func (b *RouteBindings) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.bindings)
+}
+
+func (b *RouteBindings) match(c *gin.Context) *compiledBinding {
+	if b == nil || c == nil || c.Request == nil {
+		return nil
+	}
+	routePath := c.FullPath()
+	if routePath == "" {
+		routePath = c.Request.URL.Path
+	}
+	return b.find(routePath, c.Request.Method)
+}
+
+func (b *RouteBindings) find(routePath, method string) *compiledBinding {
+	if b == nil {
+		return nil
+	}
+	for i := range b.bindings {
+		binding := &b.bindings[i]
+		if binding.Path != routePath {
+			continue
+		}
+		if binding.Method == "" || binding.Method == method {
+			return binding
+		}
+	}
+	return nil
+}
+
+// HasRoute 路由是否配置了流程映射，实现 approval.RouteChecker，
+// 使 businessApproval=true 但未配置映射的路由被拒绝而不是跳过审批
+func (b *RouteBindings) HasRoute(routePath, method string) bool {
+	return b.find(routePath, strings.ToUpper(strings.TrimSpace(method))) != nil
+}
+
+// ShouldApprove 仅对配置了流程映射的路由发起审批
+func (b *RouteBindings) ShouldApprove(c *gin.Context) bool {
+	return b.match(c) != nil
+}
+
+// BuildStartRequest 按路由映射生成启动流程请求
+func (b *RouteBindings) BuildStartRequest(c *gin.Context, requestData map[string]interface{}) (*types.StartProcessRequest, error) {
+	binding := b.match(c)
+	if binding == nil {
+		return nil, errors.New("workflow approval route binding not found")
+	}
+	data := newBindingTemplateData(c, requestData)
+	businessKey := binding.ProcessDefinitionKey + ":" + data.RequestID
+	if binding.businessKey != nil {
+		rendered, err := renderBindingTemplate(binding.businessKey, data)
+		if err != nil {
+			return nil, err
+		}
+		businessKey = rendered
+	}
+	title := binding.ProcessDefinitionKey
+	if binding.title != nil {
+		rendered, err := renderBindingTemplate(binding.title, data)
+		if err != nil {
+			return nil, err
+		}
+		title = rendered
+	}
+	return &types.StartProcessRequest{
+		ProcessDefinitionKey: binding.ProcessDefinitionKey,
+		BusinessKey:          businessKey,
+		BizID:                businessKey,
+		BizType:              binding.BizType,
+		Title:                title,
+		Name:                 title,
+		Variables: map[string]interface{}{
+			"bizId":             businessKey,
+			"bizType":           binding.BizType,
+			"title":             title,
+			"approvalRequestId": data.RequestID,
+		},
+	}, nil
+}
+
+func renderBindingTemplate(tpl *template.Template, data bindingTemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("render workflow approval %s template failed: %w", tpl.Name(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func newBindingTemplateData(c *gin.Context, requestData map[string]interface{}) bindingTemplateData {
+	data := bindingTemplateData{
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		RoutePath: c.FullPath(),
+		UserID:    c.GetString("UserID"),
+		Params:    map[string]string{},
+		Query:     map[string]string{},
+		Body:      map[string]interface{}{},
+		Fields:    map[string]interface{}{},
+	}
+	for _, param := range c.Params {
+		data.Params[param.Key] = param.Value
+	}
+	for key, values := range c.Request.URL.Query() {
+		if len(values) > 0 {
+			data.Query[key] = values[0]
+		}
+	}
+	if requestID, ok := requestData["request_id"].(string); ok && requestID != "" {
+		data.RequestID = requestID
+	} else {
+		data.RequestID = uuid.NewString()
+	}
+	if body, ok := requestData["body"].(map[string]interface{}); ok {
+		data.Body = body
+	}
+	if fields, ok := requestData["fields"].(map[string]interface{}); ok {
+		data.Fields = fields
+	}
+	return data
+}
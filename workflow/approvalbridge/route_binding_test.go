@@ -0,0 +1,171 @@
+package approvalbridge
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goodbye-jack/go-common/approval"
+	"github.com/goodbye-jack/go-common/workflow/types"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRouteBindingsBuildStartRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bindings, err := NewRouteBindings([]RouteBinding{{
+		Path:                 "/api/orders/:id",
+		Method:               "put",
+		ProcessDefinitionKey: "order_change",
+		BusinessKeyTemplate:  "order-{{.Params.id}}-{{.Body.version}}",
+		TitleTemplate:        "{{.UserID}} 修改订单 {{.Params.id}}",
+		BizType:              "order",
+	}})
+	if err != nil {
+		t.Fatalf("NewRouteBindings() error = %v", err)
+	}
+
+	var started *types.StartProcessRequest
+	var shouldApprove bool
+	engine := gin.New()
+	engine.PUT("/api/orders/:id", func(c *gin.Context) {
+		c.Set("UserID", "alice")
+		shouldApprove = bindings.ShouldApprove(c)
+		started, err = bindings.BuildStartRequest(c, map[string]interface{}{
+			"request_id": "R-1",
+			"body":       map[string]interface{}{"version": 3},
+		})
+	})
+	engine.GET("/api/orders/:id", func(c *gin.Context) {
+		if bindings.ShouldApprove(c) {
+			t.Fatalf("GET should not require approval")
+		}
+	})
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/api/orders/42", bytes.NewReader(nil)))
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/orders/42", nil))
+
+	if err != nil || !shouldApprove || started == nil {
+		t.Fatalf("started = %+v, shouldApprove = %v, err = %v", started, shouldApprove, err)
+	}
+	if started.ProcessDefinitionKey != "order_change" || started.BusinessKey != "order-42-3" || started.Title != "alice 修改订单 42" {
+		t.Fatalf("started = %+v", started)
+	}
+	if started.Variables["approvalRequestId"] != "R-1" {
+		t.Fatalf("variables = %v", started.Variables)
+	}
+}
+
+func TestApprovalMiddlewareRejectsRouteWithoutBinding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	bindings, err := NewRouteBindings([]RouteBinding{{Path: "/api/orders/:id", Method: "PUT", ProcessDefinitionKey: "order_change"}})
+	if err != nil {
+		t.Fatalf("NewRouteBindings() error = %v", err)
+	}
+	if !bindings.HasRoute("/api/orders/:id", "put") || bindings.HasRoute("/api/orders/:id", "DELETE") {
+		t.Fatalf("HasRoute mismatch")
+	}
+	handler := (&FlowableHandler{}).WithShouldApprove(bindings.ShouldApprove).WithRouteCheck(bindings.HasRoute)
+
+	executed := false
+	engine := gin.New()
+	engine.DELETE("/api/orders/:id", approval.ApprovalMiddleware(approval.Config{
+		BusinessApproval: true,
+		Handler:          handler,
+		RoutePath:        "/api/orders/:id",
+	}), func(c *gin.Context) {
+		executed = true
+	})
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/api/orders/42", nil))
+	if recorder.Code != http.StatusForbidden || executed {
+		t.Fatalf("status = %d, executed = %v; want 403 without executing", recorder.Code, executed)
+	}
+}
+
+func TestNewRouteBindingsRequiresProcessKey(t *testing.T) {
+	if _, err := NewRouteBindings([]RouteBinding{{Path: "/api/orders"}}); err == nil {
+		t.Fatalf("expected error for missing process_definition_key")
+	}
+}
+
+func TestResolveEndedStatus(t *testing.T) {
+	cases := []struct {
+		variables map[string]interface{}
+		want      approval.Status
+	}{
+		{nil, approval.StatusRejected},
+		{map[string]interface{}{"approvalResult": "pending"}, approval.StatusRejected},
+		{map[string]interface{}{"result": "退回"}, approval.StatusRejected},
+		{map[string]interface{}{"approved": true}, approval.StatusApproved},
+		{map[string]interface{}{"approved": false}, approval.StatusRejected},
+		{map[string]interface{}{"approvalResult": "REJECTED"}, approval.StatusRejected},
+		{map[string]interface{}{"result": "pass"}, approval.StatusApproved},
+		{map[string]interface{}{"outcome": "terminated"}, approval.StatusCancelled},
+	}
+	for _, tc := range cases {
+		if got := ResolveEndedStatus(tc.variables); got != tc.want {
+			t.Fatalf("ResolveEndedStatus(%v) = %s, want %s", tc.variables, got, tc.want)
+		}
+	}
+}
+
+func TestDefaultStatusResolverDoesNotApproveCompleted(t *testing.T) {
+	cases := map[string]approval.Status{
+		"completed": approval.StatusUnknown,
+		"approved":  approval.StatusApproved,
+		"rejected":  approval.StatusRejected,
+		"running":   approval.StatusPending,
+	}
+	for status, want := range cases {
+		view := &types.ProcessProgressViewResponse{Summary: types.ProcessProgressSummary{Status: status}}
+		if got, _ := defaultStatusResolver(view); got != want {
+			t.Fatalf("defaultStatusResolver(%s) = %s, want %s", status, got, want)
+		}
+	}
+}
+
+type staticHandler struct{}
+
+func (staticHandler) ShouldApprove(c *gin.Context) bool { return true }
+func (staticHandler) CreateApprovalProcess(c *gin.Context, requestData map[string]interface{}) (string, error) {
+	return "PI-1", nil
+}
+func (staticHandler) GetApprovalProcess(c *gin.Context, processID string) (approval.Status, error) {
+	return approval.StatusPending, nil
+}
+func (staticHandler) ExecuteApprovedRequest(c *gin.Context, processID string, requestData []byte) error {
+	return nil
+}
+
+func TestCallbackListenerRejectsParkedRequest(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite error = %v", err)
+	}
+	service, err := approval.NewService(staticHandler{}, approval.NewGormStore(db))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := service.Submit(ctx, &approval.PendingRequest{RequestID: "R-1", ProcessID: "PI-1", Method: http.MethodPost, Path: "/orders"}); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	listener := NewCallbackListener(service)
+
+	if err := listener(ctx, &types.FlowableCallbackPayload{EventType: "NODE_ENDED", ProcessInstanceID: "PI-1"}); err != nil {
+		t.Fatalf("NODE_ENDED error = %v", err)
+	}
+	if err := listener(ctx, &types.FlowableCallbackPayload{EventType: "PROCESS_ENDED", ProcessInstanceID: "PI-unknown"}); err != nil {
+		t.Fatalf("unknown process should be ignored, err = %v", err)
+	}
+	if err := listener(ctx, &types.FlowableCallbackPayload{EventType: "PROCESS_ENDED", ProcessInstanceID: "PI-1", Variables: map[string]interface{}{"approved": false}}); err != nil {
+		t.Fatalf("PROCESS_ENDED error = %v", err)
+	}
+	record, err := service.Get(ctx, "R-1")
+	if err != nil || record.Status != approval.StatusRejected {
+		t.Fatalf("record = %+v, err = %v", record, err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/goodbye-jack/go-common/log"
 	commonmodel "github.com/goodbye-jack/go-common/model"
 	"github.com/goodbye-jack/go-common/orm"
+	"github.com/goodbye-jack/go-common/orm/migrate"
 	workflowcontext "github.com/goodbye-jack/go-common/workflow/context"
 	"github.com/goodbye-jack/go-common/workflow/types"
 	"gorm.io/gorm"
@@ -23,6 +24,15 @@ const (
 	taskRecordStartActivityName = "流程发起"
 )
 
+// TaskRecordMigrationModule 流程任务记录表的迁移模块
+const TaskRecordMigrationModule = "workflow_task_records"
+
+func init() {
+	migrate.Register(TaskRecordMigrationModule, migrate.List{
+		{Version: "202610180001", Name: "create_workflow_task_records", Models: []any{&workflowTaskRecordModel{}}},
+	})
+}
+
 var (
 	taskRecordMigrateMu sync.Mutex
 	taskRecordMigrated  = map[string]bool{}
@@ -83,7 +93,7 @@ func ensureTaskRecordTable() error {
 	if taskRecordMigrated[key] {
 		return nil
 	}
-	if err := db.GetDB().AutoMigrate(&workflowTaskRecordModel{}); err != nil {
+	if err := migrate.ApplyModule(stdcontext.Background(), db.GetDB(), TaskRecordMigrationModule); err != nil {
 		return err
 	}
 	taskRecordMigrated[key] = true
@@ -207,7 +217,7 @@ func (c *RESTClient) insertTaskRecord(ctx stdcontext.Context, record workflowTas
 	if record.ActionTime.IsZero() {
 		record.ActionTime = time.Now().UTC()
 	}
-	if err := db.GetDB().WithContext(ctx).Create(&record).Error; err != nil {
+	if err := db.WithContext(ctx).Create(&record).Error; err != nil {
 		log.Warnf("【workflow】workflow_task_records 写入失败: %v", err)
 	}
 }
@@ -255,7 +265,7 @@ func (c *RESTClient) ListTaskRecords(ctx stdcontext.Context, user *workflowconte
 	if err := ensureTaskRecordTable(); err != nil {
 		return nil, err
 	}
-	base := db.GetDB().WithContext(ctx).Model(&workflowTaskRecordModel{}).
+	base := db.WithContext(ctx).Model(&workflowTaskRecordModel{}).
 		Where("operator_user_id = ?", strings.TrimSpace(user.UserID))
 	if strings.TrimSpace(user.TenantID) != "" {
 		base = base.Where("(tenant_id = ? OR tenant_id = '')", strings.TrimSpace(user.TenantID))
@@ -320,7 +330,7 @@ func (c *RESTClient) loadTaskRecordItemsByRootProcessInstanceID(ctx stdcontext.C
 		return nil, err
 	}
 	rows := make([]workflowTaskRecordModel, 0)
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.WithContext(ctx).
 		Where("root_process_instance_id = ?", strings.TrimSpace(rootProcessInstanceID)).
 		Order("action_time ASC").Order("id ASC").
 		Find(&rows).Error; err != nil {
@@ -3,7 +3,9 @@ package identity
 import (
 	"fmt"
 	"strings"
+	"sync"
 
+	"github.com/goodbye-jack/go-common/rbac"
 	"github.com/goodbye-jack/go-common/workflow/types"
 	"github.com/spf13/viper"
 )
@@ -18,20 +20,37 @@ const (
 // Normalizer 用于把不同来源的身份编码归一为统一的工作流角色/组编码。
 // 目的：让 HTTP / LDAP 等不同目录提供方在进入 Flowable 前，尽量落到同一套契约。
 type Normalizer struct {
-	roleAliases  map[string]string
-	groupAliases map[string]string
-	rolePrefix   string
-	groupPrefix  string
+	roleOverrides map[string]string
+	groupAliases  map[string]string
+	rolePrefix    string
+	groupPrefix   string
+
+	mu          sync.Mutex
+	catalog     *rbac.RoleCatalog // 计算 roleAliases 时的角色目录，目录热加载后重新合并
+	roleAliases map[string]string
 }
 
 // NewNormalizerFromConfig 根据 workflow.identity.* 和 workflow.flowable.* 配置创建归一器。
+// 角色别名以 RBAC 角色目录(rbac.roles 的 aliases)为准，workflow.identity.role_aliases 仅作兼容补充，同名时覆盖目录。
 func NewNormalizerFromConfig() *Normalizer {
 	return &Normalizer{
-		roleAliases:  loadAliasMap(configRoleAliases),
-		groupAliases: loadAliasMap(configGroupAliases),
-		rolePrefix:   strings.TrimSpace(viper.GetString(configRolePrefix)),
-		groupPrefix:  strings.TrimSpace(viper.GetString(configGroupPrefix)),
+		roleOverrides: loadAliasMap(configRoleAliases),
+		groupAliases:  loadAliasMap(configGroupAliases),
+		rolePrefix:    strings.TrimSpace(viper.GetString(configRolePrefix)),
+		groupPrefix:   strings.TrimSpace(viper.GetString(configGroupPrefix)),
+	}
+}
+
+// currentRoleAliases 返回与当前角色目录合并后的角色别名，目录被替换(热加载)后重新合并
+func (n *Normalizer) currentRoleAliases() map[string]string {
+	catalog := rbac.DefaultRoleCatalog()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.catalog != catalog {
+		n.roleAliases = catalogRoleAliases(catalog, n.roleOverrides)
+		n.catalog = catalog
 	}
+	return n.roleAliases
 }
 
 // RoleAliasCount 返回当前配置的角色别名数量。
@@ -39,7 +58,7 @@ func (n *Normalizer) RoleAliasCount() int {
 	if n == nil {
 		return 0
 	}
-	return len(n.roleAliases)
+	return len(n.currentRoleAliases())
 }
 
 // GroupAliasCount 返回当前配置的组别名数量。
@@ -55,7 +74,7 @@ func (n *Normalizer) NormalizeRole(role string) string {
 	if n == nil {
 		return strings.TrimSpace(role)
 	}
-	return normalizeValue(role, n.roleAliases)
+	return normalizeValue(role, n.currentRoleAliases())
 }
 
 // NormalizeGroup 把原始组编码归一为规范组编码；未命中映射时保持原值。
@@ -134,6 +153,17 @@ func loadAliasMap(key string) map[string]string {
 	return result
 }
 
+func catalogRoleAliases(catalog *rbac.RoleCatalog, overrides map[string]string) map[string]string {
+	result := catalog.Aliases()
+	for alias, canonical := range overrides {
+		result[alias] = canonical
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
 func normalizeValue(value string, aliases map[string]string) string {
 	trimmed := strings.TrimSpace(value)
 	if trimmed == "" {
@@ -3,6 +3,7 @@ package identity
 import (
 	"testing"
 
+	"github.com/goodbye-jack/go-common/rbac"
 	"github.com/goodbye-jack/go-common/workflow/types"
 	"github.com/spf13/viper"
 )
@@ -70,3 +71,21 @@ func TestCandidateGroupIDsForProfile(t *testing.T) {
 		t.Fatalf("expected role_APP_ROLE_COUNTY_REVIEW, got %#v", groups)
 	}
 }
+
+func TestNormalizerFollowsRoleCatalogReload(t *testing.T) {
+	original := rbac.DefaultRoleCatalog()
+	t.Cleanup(func() { rbac.SetRoleCatalog(original) })
+
+	normalizer := NewNormalizerFromConfig()
+	if got := normalizer.NormalizeRole("wf_auditor"); got != "wf_auditor" {
+		t.Fatalf("expected unknown alias kept, got %q", got)
+	}
+	catalog, err := rbac.NewRoleCatalog(append(original.Roles(), rbac.RoleDefinition{Code: "AUDITOR", Aliases: []string{"wf_auditor"}})...)
+	if err != nil {
+		t.Fatalf("NewRoleCatalog() error = %v", err)
+	}
+	rbac.SetRoleCatalog(catalog)
+	if got := normalizer.NormalizeRole("wf_auditor"); got != "AUDITOR" {
+		t.Fatalf("expected alias from reloaded catalog, got %q", got)
+	}
+}